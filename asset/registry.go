@@ -0,0 +1,261 @@
+package asset
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shouni/go-remote-io/remoteio"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// MemoryRegistry は、プロセス内でのみ記録を保持する ports.AssetRegistry です。
+type MemoryRegistry struct {
+	mu      sync.RWMutex
+	records map[string]ports.AssetRecord
+}
+
+// NewMemoryRegistry は空の MemoryRegistry を生成します。
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{records: make(map[string]ports.AssetRecord)}
+}
+
+// Get は ports.AssetRegistry の実装です。
+func (r *MemoryRegistry) Get(_ context.Context, referenceURL string) (ports.AssetRecord, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rec, ok := r.records[referenceURL]
+	return rec, ok, nil
+}
+
+// Put は ports.AssetRegistry の実装です。
+func (r *MemoryRegistry) Put(_ context.Context, record ports.AssetRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records[record.ReferenceURL] = record
+	return nil
+}
+
+// Delete は ports.AssetRegistry の実装です。
+func (r *MemoryRegistry) Delete(_ context.Context, referenceURL string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.records, referenceURL)
+	return nil
+}
+
+// JSONRegistry は、記録を1つの JSON ファイルとして永続化する ports.AssetRegistry です。
+// 初回アクセス時にファイルを読み込み、更新のたびにファイル全体を書き戻します。
+// 書き戻す際に有効期限の切れた記録を取り除くため、ファイルは無制限には大きくなりません。
+// 複数プロセスから同じファイルを更新する場合は後勝ちになります。
+type JSONRegistry struct {
+	mu      sync.Mutex
+	loaded  bool
+	records map[string]ports.AssetRecord
+	load    func(ctx context.Context) ([]byte, error)
+	save    func(ctx context.Context, data []byte) error
+}
+
+// errRegistryNotFound は、レジストリファイルがまだ存在しないことを表します。
+var errRegistryNotFound = errors.New("registry file not found")
+
+// NewFileRegistry は、ローカルファイル path に記録を保存する JSONRegistry を生成します。
+// 書き込みは一時ファイルへの書き出しとリネームで行います。
+func NewFileRegistry(path string) *JSONRegistry {
+	return &JSONRegistry{
+		load: func(context.Context) ([]byte, error) {
+			data, err := os.ReadFile(path)
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, errRegistryNotFound
+			}
+			return data, err
+		},
+		save: func(_ context.Context, data []byte) error {
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return err
+			}
+			tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+			if err != nil {
+				return err
+			}
+			defer os.Remove(tmp.Name())
+			if _, err := tmp.Write(data); err != nil {
+				tmp.Close()
+				return err
+			}
+			if err := tmp.Close(); err != nil {
+				return err
+			}
+			return os.Rename(tmp.Name(), path)
+		},
+	}
+}
+
+// NewRemoteRegistry は、remoteio を介して path（GCS/S3/ローカル）に記録を保存する JSONRegistry を生成します。
+// exister を渡すと、初回読み込み時にファイルの有無を確認します。nil の場合は、reader が fs.ErrNotExist を
+// 返したときのみファイルが存在しないものとして扱い、それ以外の読み込みエラーは Get・Put・Delete の
+// エラーとして返します（一時的な障害で既存のファイルを空の記録で上書きしないためです）。
+// reader が存在しないファイルに fs.ErrNotExist を返さない場合は exister を渡してください。
+func NewRemoteRegistry(reader ports.ContentReader, writer remoteio.Writer, exister remoteio.Exister, path string) *JSONRegistry {
+	return &JSONRegistry{
+		load: func(ctx context.Context) ([]byte, error) {
+			if exister != nil {
+				ok, err := exister.Exists(ctx, path)
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					return nil, errRegistryNotFound
+				}
+			}
+			rc, err := reader.Open(ctx, path)
+			if err != nil {
+				if exister == nil && errors.Is(err, fs.ErrNotExist) {
+					return nil, errRegistryNotFound
+				}
+				return nil, err
+			}
+			defer rc.Close()
+			return io.ReadAll(rc)
+		},
+		save: func(ctx context.Context, data []byte) error {
+			return writer.Write(ctx, path, bytes.NewReader(data),
+				remoteio.WithContentType("application/json"),
+				remoteio.WithCacheControl("no-cache"),
+			)
+		},
+	}
+}
+
+// Get は ports.AssetRegistry の実装です。
+func (r *JSONRegistry) Get(ctx context.Context, referenceURL string) (ports.AssetRecord, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.ensureLoaded(ctx); err != nil {
+		return ports.AssetRecord{}, false, err
+	}
+	rec, ok := r.records[referenceURL]
+	return rec, ok, nil
+}
+
+// Put は ports.AssetRegistry の実装です。
+func (r *JSONRegistry) Put(ctx context.Context, record ports.AssetRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.ensureLoaded(ctx); err != nil {
+		return err
+	}
+	r.records[record.ReferenceURL] = record
+	return r.flush(ctx)
+}
+
+// Delete は ports.AssetRegistry の実装です。
+func (r *JSONRegistry) Delete(ctx context.Context, referenceURL string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.ensureLoaded(ctx); err != nil {
+		return err
+	}
+	if _, ok := r.records[referenceURL]; !ok {
+		return nil
+	}
+	delete(r.records, referenceURL)
+	return r.flush(ctx)
+}
+
+// ensureLoaded は記録を未読み込みの場合に読み込みます。呼び出し元は mu を保持します。
+func (r *JSONRegistry) ensureLoaded(ctx context.Context) error {
+	if r.loaded {
+		return nil
+	}
+	r.records = make(map[string]ports.AssetRecord)
+
+	data, err := r.load(ctx)
+	switch {
+	case errors.Is(err, errRegistryNotFound):
+	case err != nil:
+		return fmt.Errorf("アセットレジストリの読み込みに失敗しました: %w", err)
+	case len(bytes.TrimSpace(data)) > 0:
+		var records []ports.AssetRecord
+		if err := json.Unmarshal(data, &records); err != nil {
+			return fmt.Errorf("アセットレジストリの解析に失敗しました: %w", err)
+		}
+		for _, rec := range records {
+			r.records[rec.ReferenceURL] = rec
+		}
+	}
+	r.loaded = true
+	return nil
+}
+
+// flush は有効期限の切れた記録を取り除き、残りを ReferenceURL 順の JSON 配列として書き戻します。
+// 呼び出し元は mu を保持します。
+func (r *JSONRegistry) flush(ctx context.Context) error {
+	now := time.Now()
+	records := make([]ports.AssetRecord, 0, len(r.records))
+	for key, rec := range r.records {
+		if rec.Expired(now, 0) {
+			delete(r.records, key)
+			continue
+		}
+		records = append(records, rec)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ReferenceURL < records[j].ReferenceURL })
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("アセットレジストリの変換に失敗しました: %w", err)
+	}
+	if err := r.save(ctx, data); err != nil {
+		return fmt.Errorf("アセットレジストリの保存に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// scopedRegistry は、キーに接頭辞を付けて1つのレジストリを複数の利用者で共有します。
+type scopedRegistry struct {
+	inner ports.AssetRegistry
+	scope string
+}
+
+// NewScopedRegistry は、scope ごとに記録を分けて inner を共有する ports.AssetRegistry を返します。
+// File API のファイルは API キー（プロジェクト）ごとに独立しているため、異なるクライアントで
+// 1つのレジストリを共有する場合に使います。
+func NewScopedRegistry(inner ports.AssetRegistry, scope string) ports.AssetRegistry {
+	if scope == "" {
+		return inner
+	}
+	return &scopedRegistry{inner: inner, scope: scope}
+}
+
+func (r *scopedRegistry) key(referenceURL string) string {
+	return r.scope + "|" + referenceURL
+}
+
+// Get は ports.AssetRegistry の実装です。
+func (r *scopedRegistry) Get(ctx context.Context, referenceURL string) (ports.AssetRecord, bool, error) {
+	rec, ok, err := r.inner.Get(ctx, r.key(referenceURL))
+	rec.ReferenceURL = referenceURL
+	return rec, ok, err
+}
+
+// Put は ports.AssetRegistry の実装です。
+func (r *scopedRegistry) Put(ctx context.Context, record ports.AssetRecord) error {
+	record.ReferenceURL = r.key(record.ReferenceURL)
+	return r.inner.Put(ctx, record)
+}
+
+// Delete は ports.AssetRegistry の実装です。
+func (r *scopedRegistry) Delete(ctx context.Context, referenceURL string) error {
+	return r.inner.Delete(ctx, r.key(referenceURL))
+}
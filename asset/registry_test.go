@@ -0,0 +1,172 @@
+package asset
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shouni/go-manga-kit/mangakittest"
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+func testRecord(url string) ports.AssetRecord {
+	uploadedAt := time.Now().UTC().Truncate(time.Second)
+	return ports.AssetRecord{
+		ReferenceURL: url,
+		ContentHash:  "abc123",
+		FileAPIURI:   "https://generativelanguage.googleapis.com/v1beta/files/" + filepath.Base(url),
+		UploadedAt:   uploadedAt,
+		ExpiresAt:    uploadedAt.Add(ports.DefaultFileAPITTL),
+	}
+}
+
+// exerciseRegistry は Put・Get・Delete の基本動作を検証します。
+func exerciseRegistry(t *testing.T, r ports.AssetRegistry) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, ok, err := r.Get(ctx, "https://example.com/a.png"); err != nil || ok {
+		t.Fatalf("Get on empty registry = (%v, %v), want (false, nil)", ok, err)
+	}
+
+	want := testRecord("https://example.com/a.png")
+	if err := r.Put(ctx, want); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := r.Put(ctx, testRecord("https://example.com/b.png")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	got, ok, err := r.Get(ctx, want.ReferenceURL)
+	if err != nil || !ok {
+		t.Fatalf("Get = (%v, %v), want a record", ok, err)
+	}
+	if !got.UploadedAt.Equal(want.UploadedAt) || !got.ExpiresAt.Equal(want.ExpiresAt) {
+		t.Errorf("times = %v/%v, want %v/%v", got.UploadedAt, got.ExpiresAt, want.UploadedAt, want.ExpiresAt)
+	}
+	got.UploadedAt, got.ExpiresAt = want.UploadedAt, want.ExpiresAt
+	if got != want {
+		t.Errorf("Get = %+v, want %+v", got, want)
+	}
+
+	if err := r.Delete(ctx, want.ReferenceURL); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, ok, _ := r.Get(ctx, want.ReferenceURL); ok {
+		t.Error("record still present after Delete")
+	}
+	if err := r.Delete(ctx, "https://example.com/missing.png"); err != nil {
+		t.Errorf("Delete of a missing record = %v, want nil", err)
+	}
+}
+
+func TestMemoryRegistry(t *testing.T) {
+	exerciseRegistry(t, NewMemoryRegistry())
+}
+
+func TestFileRegistry_PersistsAcrossInstances(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "assets.json")
+	exerciseRegistry(t, NewFileRegistry(path))
+
+	// 別のインスタンス（再起動後のプロセス）から同じファイルを読み込めること。
+	reopened := NewFileRegistry(path)
+	if _, ok, err := reopened.Get(context.Background(), "https://example.com/b.png"); err != nil || !ok {
+		t.Fatalf("reopened Get = (%v, %v), want the persisted record", ok, err)
+	}
+	if _, ok, _ := reopened.Get(context.Background(), "https://example.com/a.png"); ok {
+		t.Error("deleted record was persisted")
+	}
+}
+
+func TestRemoteRegistry_PersistsAcrossInstances(t *testing.T) {
+	ctx := context.Background()
+	const path = "gs://bucket/state/assets.json"
+	store := &mangakittest.Writer{}
+	reader := &mangakittest.ContentReader{}
+
+	exerciseRegistry(t, NewRemoteRegistry(reader, store, store, path))
+
+	data, ok := store.File(path)
+	if !ok {
+		t.Fatalf("registry was not written to %s", path)
+	}
+	reader.SetFile(path, data)
+	reopened := NewRemoteRegistry(reader, store, store, path)
+	if _, ok, err := reopened.Get(ctx, "https://example.com/b.png"); err != nil || !ok {
+		t.Fatalf("reopened Get = (%v, %v), want the persisted record", ok, err)
+	}
+}
+
+func TestRemoteRegistry_WithoutExister(t *testing.T) {
+	ctx := context.Background()
+	const path = "gs://bucket/state/assets.json"
+
+	t.Run("missing file is treated as empty", func(t *testing.T) {
+		store := &mangakittest.Writer{}
+		exerciseRegistry(t, NewRemoteRegistry(&mangakittest.ContentReader{}, store, nil, path))
+		if _, ok := store.File(path); !ok {
+			t.Errorf("registry was not written to %s", path)
+		}
+	})
+
+	t.Run("read errors do not overwrite the file", func(t *testing.T) {
+		store := &mangakittest.Writer{}
+		reader := &mangakittest.ContentReader{Err: errors.New("permission denied")}
+		r := NewRemoteRegistry(reader, store, nil, path)
+		if err := r.Put(ctx, testRecord("https://example.com/a.png")); err == nil {
+			t.Fatal("Put succeeded, want the read error")
+		}
+		if _, ok := store.File(path); ok {
+			t.Error("registry file was overwritten after a failed read")
+		}
+	})
+}
+
+func TestJSONRegistry_PrunesExpiredRecords(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "assets.json")
+	r := NewFileRegistry(path)
+
+	expired := testRecord("https://example.com/old.png")
+	expired.UploadedAt = expired.UploadedAt.Add(-2 * ports.DefaultFileAPITTL)
+	expired.ExpiresAt = expired.UploadedAt.Add(ports.DefaultFileAPITTL)
+	if err := r.Put(ctx, expired); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Put(ctx, testRecord("https://example.com/new.png")); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := NewFileRegistry(path)
+	if _, ok, _ := reopened.Get(ctx, expired.ReferenceURL); ok {
+		t.Error("expired record was persisted")
+	}
+	if _, ok, _ := reopened.Get(ctx, "https://example.com/new.png"); !ok {
+		t.Error("valid record was pruned")
+	}
+}
+
+func TestScopedRegistry_SeparatesScopes(t *testing.T) {
+	ctx := context.Background()
+	shared := NewMemoryRegistry()
+	standard := NewScopedRegistry(shared, "")
+	quality := NewScopedRegistry(shared, "quality")
+
+	rec := testRecord("https://example.com/a.png")
+	if err := standard.Put(ctx, rec); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := quality.Get(ctx, rec.ReferenceURL); ok {
+		t.Error("quality scope must not see records of the standard scope")
+	}
+
+	if err := quality.Put(ctx, rec); err != nil {
+		t.Fatal(err)
+	}
+	got, ok, _ := quality.Get(ctx, rec.ReferenceURL)
+	if !ok || got.ReferenceURL != rec.ReferenceURL {
+		t.Errorf("scoped Get = (%+v, %v), want the unprefixed reference URL", got, ok)
+	}
+}
@@ -0,0 +1,23 @@
+// Command mangaschema は、台本 JSON（ports.MangaResponse）の JSON Schema を標準出力に書き出します。
+//
+//	go run github.com/shouni/go-manga-kit/cmd/mangaschema > manga_response.schema.json
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/shouni/go-manga-kit/schema"
+)
+
+func main() {
+	data, err := schema.MangaResponseJSON()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if _, err := os.Stdout.Write(data); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
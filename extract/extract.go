@@ -0,0 +1,143 @@
+// Package extract は、台本の入力（HTML・Markdown・PDF・テキスト）から本文とタイトルを抽出します。
+//
+// ContentReader が返すバイト列をそのままプロンプトに渡すと、Web の記事はナビゲーションや
+// スクリプトを含む HTML のまま、PDF はバイナリのまま AI に届きます。Registry は MIME タイプ・
+// 拡張子・内容から形式を判定して抽出器を選び、整形した本文とタイトルを返します。
+package extract
+
+import (
+	"bytes"
+	"context"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// Registry は、MIME タイプと拡張子ごとの抽出器の登録簿です。
+// 登録は使用前に行ってください。登録後の Extract・Lookup は並行して呼び出せます。
+type Registry struct {
+	byType   map[string]ports.TextExtractor
+	byExt    map[string]ports.TextExtractor
+	fallback ports.TextExtractor
+}
+
+// NewRegistry は、形式を判定できない入力をテキストとして扱う、空の Registry を作成します。
+func NewRegistry() *Registry {
+	return &Registry{
+		byType:   make(map[string]ports.TextExtractor),
+		byExt:    make(map[string]ports.TextExtractor),
+		fallback: Text{},
+	}
+}
+
+// Default は、HTML・Markdown・PDF・テキストの抽出器を登録した Registry を作成します。
+// 呼び出しごとに新しい Registry を返すため、返り値に抽出器を追加しても他に影響しません。
+func Default() *Registry {
+	r := NewRegistry()
+	r.RegisterContentType(HTML{}, "text/html", "application/xhtml+xml")
+	r.RegisterExtension(HTML{}, ".html", ".htm", ".xhtml")
+	r.RegisterContentType(Markdown{}, "text/markdown", "text/x-markdown")
+	r.RegisterExtension(Markdown{}, ".md", ".markdown")
+	r.RegisterContentType(PDF{}, "application/pdf")
+	r.RegisterExtension(PDF{}, ".pdf")
+	r.RegisterContentType(Text{}, "text/plain")
+	r.RegisterExtension(Text{}, ".txt")
+	return r
+}
+
+// RegisterContentType は、MIME タイプ（例: "text/html"）の抽出器を登録します。
+func (r *Registry) RegisterContentType(e ports.TextExtractor, contentTypes ...string) {
+	for _, ct := range contentTypes {
+		r.byType[mediaType(ct)] = e
+	}
+}
+
+// RegisterExtension は、拡張子（例: ".html"）の抽出器を登録します。
+func (r *Registry) RegisterExtension(e ports.TextExtractor, exts ...string) {
+	for _, ext := range exts {
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		r.byExt[strings.ToLower(ext)] = e
+	}
+}
+
+// SetFallback は、形式を判定できない入力に使う抽出器を設定します。既定は Text です。
+func (r *Registry) SetFallback(e ports.TextExtractor) {
+	if e != nil {
+		r.fallback = e
+	}
+}
+
+// Lookup は入力に使う抽出器を返します。contentType（MIME タイプ）、name（URI やファイル名）の拡張子、
+// data の内容から判定した MIME タイプの順に照合し、いずれにも該当しない場合は既定の抽出器を返します。
+func (r *Registry) Lookup(contentType, name string, data []byte) ports.TextExtractor {
+	if e, ok := r.byType[mediaType(contentType)]; ok {
+		return e
+	}
+	if e, ok := r.byExt[extension(name)]; ok {
+		return e
+	}
+	if e, ok := r.byType[Sniff(data)]; ok {
+		return e
+	}
+	return r.fallback
+}
+
+// Extract は Lookup で選んだ抽出器で data から本文を抽出します。
+func (r *Registry) Extract(ctx context.Context, contentType, name string, data []byte) (*ports.ExtractedText, error) {
+	return r.Lookup(contentType, name, data).Extract(ctx, data)
+}
+
+// Sniff は data の内容から MIME タイプを判定します。PDF・HTML・front matter 付きの Markdown を判定でき、
+// それ以外は net/http.DetectContentType の結果（パラメーターを除く）を返します。
+func Sniff(data []byte) string {
+	if bytes.HasPrefix(data, []byte("%PDF-")) {
+		return "application/pdf"
+	}
+	head := bytes.TrimLeft(bytes.TrimPrefix(data, utf8BOM), " \t\r\n")
+	if bytes.HasPrefix(head, []byte("---\n")) || bytes.HasPrefix(head, []byte("---\r\n")) ||
+		bytes.HasPrefix(head, []byte("+++\n")) || bytes.HasPrefix(head, []byte("+++\r\n")) {
+		return "text/markdown"
+	}
+	return mediaType(http.DetectContentType(data))
+}
+
+// mediaType は MIME タイプからパラメーター（charset など）を除き、小文字にします。
+func mediaType(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mt
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+// extension は URI やファイル名の拡張子を小文字で返します。URL のクエリ・フラグメントは無視します。
+func extension(name string) string {
+	if u, err := url.Parse(name); err == nil && u.Scheme != "" {
+		name = u.Path
+	}
+	return strings.ToLower(path.Ext(name))
+}
+
+// utf8BOM は UTF-8 のバイト順マークです。
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// Text は入力をそのままテキストとして扱う抽出器です。先頭のバイト順マークと改行コードの違いのみ整えます。
+type Text struct{}
+
+// Extract は ports.TextExtractor の実装です。
+func (Text) Extract(_ context.Context, data []byte) (*ports.ExtractedText, error) {
+	return &ports.ExtractedText{Text: normalizeNewlines(string(bytes.TrimPrefix(data, utf8BOM)))}, nil
+}
+
+// normalizeNewlines は改行コードを "\n" に揃えます。
+func normalizeNewlines(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\r", "\n")
+}
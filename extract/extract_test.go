@@ -0,0 +1,139 @@
+package extract
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+const articleHTML = `<!DOCTYPE html>
+<html><head>
+<meta charset="utf-8">
+<title>ずんだ餅の歴史 | 東北ニュース</title>
+<meta property="og:title" content="ずんだ餅の歴史">
+<script>var tracking = "ignored";</script>
+<style>body { color: red; }</style>
+</head><body>
+<header><a href="/">トップ</a></header>
+<nav class="global-nav"><a href="/a">記事一覧</a><a href="/b">ランキング</a></nav>
+<div class="ad-banner">広告です</div>
+<div id="content">
+  <h1>ずんだ餅の歴史</h1>
+  <p>ずんだ餅は、枝豆をすりつぶして砂糖を加えた餡を、餅に絡めた東北地方の郷土菓子です。</p>
+  <h2>名前の由来</h2>
+  <p>名前の由来には諸説あり、豆を打つ「豆打（ずだ）」が訛ったという説や、伊達政宗にちなむ説があります。</p>
+  <ul><li>宮城県</li><li>山形県</li></ul>
+</div>
+<div class="sidebar"><p>関連記事: 牛たんの歴史、笹かまの歴史、はらこ飯の歴史をまとめて読む</p></div>
+<footer>Copyright</footer>
+</body></html>`
+
+func TestHTML_ExtractsMainContent(t *testing.T) {
+	got, err := HTML{}.Extract(context.Background(), []byte(articleHTML))
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if got.Title != "ずんだ餅の歴史" {
+		t.Errorf("Title = %q, want og:title", got.Title)
+	}
+	want := "# ずんだ餅の歴史\n\n" +
+		"ずんだ餅は、枝豆をすりつぶして砂糖を加えた餡を、餅に絡めた東北地方の郷土菓子です。\n\n" +
+		"## 名前の由来\n\n" +
+		"名前の由来には諸説あり、豆を打つ「豆打（ずだ）」が訛ったという説や、伊達政宗にちなむ説があります。\n\n" +
+		"- 宮城県\n- 山形県"
+	if got.Text != want {
+		t.Errorf("Text =\n%s\nwant\n%s", got.Text, want)
+	}
+}
+
+func TestHTML_PrefersArticleAndConvertsCharset(t *testing.T) {
+	body := strings.Repeat("本文です。", 50)
+	doc, err := japanese.ShiftJIS.NewEncoder().String("<html><head><meta charset=\"shift_jis\"><title>記事</title></head><body>" +
+		"<div class=\"menu\">メニュー</div><article><p>" + body + "</p></article></body></html>")
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	got, err := HTML{}.Extract(context.Background(), []byte(doc))
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if got.Title != "記事" {
+		t.Errorf("Title = %q, want %q", got.Title, "記事")
+	}
+	if strings.Contains(got.Text, "メニュー") || !strings.HasPrefix(got.Text, "本文です。") {
+		t.Errorf("Text = %q, want the article body only", got.Text)
+	}
+}
+
+func TestMarkdown_StripsFrontMatter(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantTitle string
+		wantText  string
+	}{
+		{"yaml", "---\ntitle: \"Front Matter の題名\"\ntags: [a]\n---\n# 見出し\n\n本文\n", "Front Matter の題名", "# 見出し\n\n本文"},
+		{"toml", "+++\ntitle = 'TOML の題名'\n+++\n本文\n", "TOML の題名", "本文"},
+		{"heading", "```\n# コード内\n```\n# 最初の見出し #\n本文\n", "最初の見出し", "```\n# コード内\n```\n# 最初の見出し #\n本文"},
+		{"crlf", "---\r\ndraft: true\r\n---\r\n本文\r\n", "", "本文"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Markdown{}.Extract(context.Background(), []byte(tt.input))
+			if err != nil {
+				t.Fatalf("Extract failed: %v", err)
+			}
+			if got.Title != tt.wantTitle || got.Text != tt.wantText {
+				t.Errorf("Extract = {%q, %q}, want {%q, %q}", got.Title, got.Text, tt.wantTitle, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestRegistry_Lookup(t *testing.T) {
+	r := Default()
+	tests := []struct {
+		name        string
+		contentType string
+		uri         string
+		data        string
+		want        any
+	}{
+		{"content type wins", "text/html; charset=utf-8", "a.md", "x", HTML{}},
+		{"extension", "", "https://example.com/post.md?ref=top#intro", "x", Markdown{}},
+		{"gcs pdf", "", "gs://bucket/report.PDF", "x", PDF{}},
+		{"sniff pdf", "", "https://example.com/download", "%PDF-1.7\n", PDF{}},
+		{"sniff html", "", "https://example.com/", "<!doctype html><p>x", HTML{}},
+		{"sniff front matter", "", "notes", "---\ntitle: a\n---\n", Markdown{}},
+		{"plain text", "", "https://example.com/", "ただのテキスト", Text{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Lookup(tt.contentType, tt.uri, []byte(tt.data)); got != tt.want {
+				t.Errorf("Lookup = %T, want %T", got, tt.want)
+			}
+		})
+	}
+}
+
+type upperExtractor struct{}
+
+func (upperExtractor) Extract(_ context.Context, data []byte) (*ports.ExtractedText, error) {
+	return &ports.ExtractedText{Text: strings.ToUpper(string(data))}, nil
+}
+
+func TestRegistry_CustomExtractor(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterExtension(upperExtractor{}, "shout")
+	got, err := r.Extract(context.Background(), "", "a.shout", []byte("hi"))
+	if err != nil || got.Text != "HI" {
+		t.Errorf("Extract = %+v, %v; want the registered extractor", got, err)
+	}
+	if got, _ := r.Extract(context.Background(), "", "a.html", []byte("<p>hi</p>")); got.Text != "<p>hi</p>" {
+		t.Errorf("empty registry Extract = %q, want the text fallback", got.Text)
+	}
+}
@@ -0,0 +1,359 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// minArticleLength は、<article>・<main> を採点せずに本文として採用する最小の文字数です。
+const minArticleLength = 200
+
+// minParagraphLength は、本文の候補の採点に数える段落の最小の文字数です。
+const minParagraphLength = 25
+
+var (
+	// unlikelyRegex は、class・id がナビゲーションや広告などを示す要素です。
+	unlikelyRegex = regexp.MustCompile(`(?i)(^|[-_\s])(nav|navi|navigation|menu|footer|sidebar|side-bar|comments?|share|social|banner|advert|ads?|promo|related|breadcrumbs?|cookie|popup|modal|subscribe|newsletter|pager|pagination)($|[-_\s])`)
+	// likelyRegex は、unlikelyRegex に一致しても本文の可能性がある要素です。
+	likelyRegex = regexp.MustCompile(`(?i)article|content|main|body|entry|post|story`)
+	// blankLinesRegex は3行以上続く改行です。
+	blankLinesRegex = regexp.MustCompile(`\n{3,}`)
+)
+
+// removedAtoms は本文として扱わない要素です。
+var removedAtoms = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Iframe: true, atom.Svg: true,
+	atom.Canvas: true, atom.Form: true, atom.Button: true, atom.Input: true, atom.Select: true,
+	atom.Textarea: true, atom.Nav: true, atom.Header: true, atom.Footer: true, atom.Aside: true,
+	atom.Template: true, atom.Object: true, atom.Embed: true, atom.Head: true,
+}
+
+// blockAtoms は前後で段落を区切る要素です。
+var blockAtoms = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.Blockquote: true, atom.Pre: true, atom.Ul: true, atom.Ol: true, atom.Dl: true,
+	atom.Table: true, atom.Figure: true, atom.Figcaption: true, atom.Hr: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+}
+
+// lineAtoms は前後で行を区切る要素です。
+var lineAtoms = map[atom.Atom]bool{
+	atom.Li: true, atom.Tr: true, atom.Dt: true, atom.Dd: true, atom.Br: true,
+}
+
+// HTML は readability 風に HTML から本文を抽出する抽出器です。スクリプト・ナビゲーション・広告などを
+// 取り除き、<article>・<main>、または段落の文字数と読点の数で採点した要素を本文とします。
+// 見出しは長文モードでの分割に使えるよう "# " 形式で残します。タイトルは og:title、<title>、
+// 最初の <h1> の順に取得します。文字コードは <meta charset> などから判定して UTF-8 に変換します。
+type HTML struct{}
+
+// Extract は ports.TextExtractor の実装です。
+func (HTML) Extract(_ context.Context, data []byte) (*ports.ExtractedText, error) {
+	r, err := charset.NewReader(bytes.NewReader(data), "text/html")
+	if err != nil {
+		return nil, fmt.Errorf("HTML の文字コードの変換に失敗しました: %w", err)
+	}
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("HTML の解析に失敗しました: %w", err)
+	}
+
+	title := htmlTitle(doc)
+	prune(doc)
+	var w textWriter
+	w.render(mainContent(doc), false)
+	text := w.String()
+	if text == "" {
+		return nil, errors.New("HTML から本文を抽出できませんでした")
+	}
+	return &ports.ExtractedText{Title: title, Text: text}, nil
+}
+
+// htmlTitle は og:title、<title>、最初の <h1> の順に文書のタイトルを返します。
+func htmlTitle(doc *html.Node) string {
+	var ogTitle, title, h1 string
+	for n := range doc.Descendants() {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		switch n.DataAtom {
+		case atom.Meta:
+			if prop := attr(n, "property"); (prop == "og:title" || attr(n, "name") == "og:title") && ogTitle == "" {
+				ogTitle = strings.TrimSpace(attr(n, "content"))
+			}
+		case atom.Title:
+			if title == "" {
+				title = collapseSpaces(textContent(n))
+			}
+		case atom.H1:
+			if h1 == "" {
+				h1 = collapseSpaces(textContent(n))
+			}
+		}
+	}
+	for _, t := range []string{ogTitle, title, h1} {
+		if t != "" {
+			return t
+		}
+	}
+	return ""
+}
+
+// prune は本文として扱わない要素（スクリプト・ナビゲーション・非表示の要素・広告らしい要素など）を取り除きます。
+func prune(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode || (c.Type == html.ElementNode && unlikely(c)) {
+			n.RemoveChild(c)
+		} else {
+			prune(c)
+		}
+		c = next
+	}
+}
+
+// unlikely は要素が本文でない可能性が高いかを返します。
+func unlikely(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Html, atom.Body, atom.Article, atom.Main:
+		return false
+	}
+	if removedAtoms[n.DataAtom] || hasAttr(n, "hidden") || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	if role := attr(n, "role"); role == "navigation" || role == "banner" || role == "contentinfo" || role == "complementary" {
+		return true
+	}
+	classID := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyRegex.MatchString(classID) && !likelyRegex.MatchString(classID)
+}
+
+// mainContent は本文の要素を返します。十分な文字数の <article>・<main> があればそのうち最長のもの、
+// 無ければ段落を採点して最も点の高い要素、それも無ければ <body>（または文書全体）を返します。
+func mainContent(doc *html.Node) *html.Node {
+	var best *html.Node
+	bestLen := 0
+	for n := range doc.Descendants() {
+		if n.Type == html.ElementNode && (n.DataAtom == atom.Article || n.DataAtom == atom.Main || attr(n, "role") == "main") {
+			if l := utf8.RuneCountInString(collapseSpaces(textContent(n))); l > bestLen {
+				best, bestLen = n, l
+			}
+		}
+	}
+	if best != nil && bestLen >= minArticleLength {
+		return best
+	}
+	if candidate := scoreCandidates(doc); candidate != nil {
+		return candidate
+	}
+	for n := range doc.Descendants() {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Body {
+			return n
+		}
+	}
+	return doc
+}
+
+// scoreCandidates は段落の親と祖父母の要素を、段落の文字数と読点の数で採点し、リンクの割合で
+// 割り引いた点が最も高い要素を返します。採点できる段落が無い場合は nil です。
+func scoreCandidates(doc *html.Node) *html.Node {
+	scores := make(map[*html.Node]float64)
+	for n := range doc.Descendants() {
+		if n.Type != html.ElementNode || (n.DataAtom != atom.P && n.DataAtom != atom.Pre && n.DataAtom != atom.Td) {
+			continue
+		}
+		text := collapseSpaces(textContent(n))
+		length := utf8.RuneCountInString(text)
+		if length < minParagraphLength {
+			continue
+		}
+		score := 1 + float64(strings.Count(text, "、")+strings.Count(text, "，")+strings.Count(text, ",")) +
+			min(float64(length)/100, 3)
+		if parent := n.Parent; parent != nil {
+			scores[parent] += score
+			if grand := parent.Parent; grand != nil {
+				scores[grand] += score / 2
+			}
+		}
+	}
+	var best *html.Node
+	bestScore := 0.0
+	for n := range doc.Descendants() {
+		score, ok := scores[n]
+		if !ok {
+			continue
+		}
+		score *= 1 - linkDensity(n)
+		if score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	return best
+}
+
+// linkDensity は要素の文字数のうち、リンクの文字数が占める割合を返します。
+func linkDensity(n *html.Node) float64 {
+	total := utf8.RuneCountInString(collapseSpaces(textContent(n)))
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	for d := range n.Descendants() {
+		if d.Type == html.ElementNode && d.DataAtom == atom.A {
+			links += utf8.RuneCountInString(collapseSpaces(textContent(d)))
+		}
+	}
+	return min(float64(links)/float64(total), 1)
+}
+
+// textWriter は HTML の要素を、段落・行の区切りを保ったテキストに変換します。
+type textWriter struct {
+	b strings.Builder
+	// breaks は次のテキストの前に入れる改行の数です。
+	breaks int
+}
+
+// render は n 以下のテキストを書き込みます。pre が true の場合は空白を保ちます。
+func (w *textWriter) render(n *html.Node, pre bool) {
+	switch n.Type {
+	case html.TextNode:
+		if pre {
+			w.write(n.Data)
+		} else if s := collapseSpaces(n.Data); s != "" {
+			if startsWithSpace(n.Data) && w.b.Len() > 0 && w.breaks == 0 && !strings.HasSuffix(w.b.String(), " ") {
+				s = " " + s
+			}
+			if endsWithSpace(n.Data) {
+				s += " "
+			}
+			w.write(s)
+		}
+		return
+	case html.ElementNode:
+		if n.DataAtom == atom.Img {
+			if alt := collapseSpaces(attr(n, "alt")); alt != "" {
+				w.write(alt)
+			}
+			return
+		}
+	}
+
+	block, line := blockAtoms[n.DataAtom], lineAtoms[n.DataAtom]
+	switch {
+	case block:
+		w.breakLines(2)
+	case line:
+		w.breakLines(1)
+	}
+	if level := headingLevel(n.DataAtom); level > 0 {
+		w.write(strings.Repeat("#", level) + " ")
+	} else if n.DataAtom == atom.Li {
+		w.write("- ")
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.render(c, pre || n.DataAtom == atom.Pre)
+	}
+	switch {
+	case block:
+		w.breakLines(2)
+	case line:
+		w.breakLines(1)
+	}
+}
+
+// breakLines は次のテキストの前に n 個以上の改行を入れます。
+func (w *textWriter) breakLines(n int) {
+	w.breaks = max(w.breaks, n)
+}
+
+func (w *textWriter) write(s string) {
+	if w.b.Len() > 0 && w.breaks > 0 {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return
+		}
+		w.b.WriteString(strings.Repeat("\n", w.breaks))
+	}
+	w.breaks = 0
+	w.b.WriteString(s)
+}
+
+// String は書き込んだテキストを、各行の末尾の空白と連続する空行を取り除いて返します。
+func (w *textWriter) String() string {
+	lines := strings.Split(w.b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(blankLinesRegex.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+func headingLevel(a atom.Atom) int {
+	switch a {
+	case atom.H1:
+		return 1
+	case atom.H2:
+		return 2
+	case atom.H3:
+		return 3
+	case atom.H4:
+		return 4
+	case atom.H5:
+		return 5
+	case atom.H6:
+		return 6
+	}
+	return 0
+}
+
+// textContent は n 以下のテキストをすべて連結して返します。
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	for d := range n.Descendants() {
+		if d.Type == html.TextNode {
+			b.WriteString(d.Data)
+		}
+	}
+	return b.String()
+}
+
+// collapseSpaces は連続する空白を1つの半角スペースにまとめ、前後の空白を取り除きます。
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func startsWithSpace(s string) bool {
+	return s != "" && strings.TrimLeft(s[:1], " \t\r\n") == ""
+}
+
+func endsWithSpace(s string) bool {
+	return s != "" && strings.TrimRight(s[len(s)-1:], " \t\r\n") == ""
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// Markdown は Markdown から front matter（YAML の "---"、TOML の "+++" で囲んだ先頭のブロック）を
+// 取り除く抽出器です。タイトルは front matter の title、無ければ最初の "# " 見出しから取得します。
+// 見出しは長文モードでの分割に使うため、本文はそのまま残します。
+type Markdown struct{}
+
+// Extract は ports.TextExtractor の実装です。
+func (Markdown) Extract(_ context.Context, data []byte) (*ports.ExtractedText, error) {
+	text := normalizeNewlines(string(bytes.TrimPrefix(data, utf8BOM)))
+	frontMatter, body := splitFrontMatter(text)
+	title := frontMatterTitle(frontMatter)
+	if title == "" {
+		title = firstHeading(body)
+	}
+	return &ports.ExtractedText{Title: title, Text: strings.TrimSpace(body)}, nil
+}
+
+// splitFrontMatter は text を front matter と本文に分けます。front matter が無い場合は空文字列と text を返します。
+func splitFrontMatter(text string) (frontMatter, body string) {
+	trimmed := strings.TrimLeft(text, "\n")
+	for _, fence := range []string{"---", "+++"} {
+		if !strings.HasPrefix(trimmed, fence+"\n") {
+			continue
+		}
+		rest := trimmed[len(fence)+1:]
+		if strings.HasPrefix(rest, fence+"\n") || rest == fence {
+			return "", strings.TrimPrefix(rest, fence)
+		}
+		if end := strings.Index(rest, "\n"+fence+"\n"); end >= 0 {
+			return rest[:end], rest[end+len(fence)+2:]
+		}
+		if strings.HasSuffix(rest, "\n"+fence) {
+			return strings.TrimSuffix(rest, "\n"+fence), ""
+		}
+	}
+	return "", text
+}
+
+// frontMatterTitle は front matter の title（YAML の "title: ..."、TOML の "title = ..."）を返します。
+func frontMatterTitle(frontMatter string) string {
+	for line := range strings.Lines(frontMatter) {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || strings.Contains(key, "=") {
+			key, value, ok = strings.Cut(line, "=")
+		}
+		if !ok || strings.TrimSpace(key) != "title" {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		return value
+	}
+	return ""
+}
+
+// firstHeading は、コードブロックの外にある最初のレベル1の見出しを返します。
+func firstHeading(body string) string {
+	inFence := false
+	for line := range strings.Lines(body) {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if !inFence && strings.HasPrefix(trimmed, "# ") {
+			return strings.TrimSpace(strings.TrimRight(trimmed[2:], "#"))
+		}
+	}
+	return ""
+}
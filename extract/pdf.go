@@ -0,0 +1,983 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+const (
+	// maxPDFDepth は、参照・ページツリー・フォーム XObject をたどる最大の深さです。
+	maxPDFDepth = 32
+	// maxCMapRange は、ToUnicode CMap の bfrange 1つで展開する最大のコード数です。
+	maxCMapRange = 0x10000
+	// tjSpaceThreshold は、TJ の字送りの調整値（1/1000 em）のうち、単語の区切りとみなす値です。
+	tjSpaceThreshold = -250
+	// DefaultPDFDecodeLimit は、1つの PDF で展開するストリームの合計バイト数の既定の上限です。
+	DefaultPDFDecodeLimit = 64 << 20
+)
+
+// ErrPDFDecodeLimit は、PDF のストリームを展開したサイズの合計が上限を超えたことを表します。
+// 少ないバイト数から巨大なデータに展開される PDF（圧縮爆弾）で抽出を打ち切る際に返します。
+var ErrPDFDecodeLimit = errors.New("PDF のストリームの展開サイズが上限を超えました")
+
+var (
+	// objHeaderRegex は間接オブジェクトの開始（"12 0 obj"）です。
+	objHeaderRegex = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	// infoRegex はトレーラーの文書情報辞書への参照です。
+	infoRegex = regexp.MustCompile(`/Info\s+(\d+)\s+\d+\s+R`)
+)
+
+// PDF は PDF からテキストを抽出する、外部コマンドや cgo を使わない抽出器です。
+// ページツリーの順にコンテンツストリーム（フォーム XObject を含む）のテキスト描画命令を読み、
+// フォントの ToUnicode CMap で文字コードを Unicode に変換します。FlateDecode・ASCIIHexDecode・
+// ASCII85Decode の圧縮とオブジェクトストリームに対応しています。暗号化された PDF や、
+// 画像のみの PDF（スキャンしたもの）からはテキストを抽出できません。タイトルは文書情報辞書の Title です。
+type PDF struct {
+	// DecodeLimit は、1つの PDF で展開するストリームの合計バイト数の上限です。
+	// 0 以下の場合は DefaultPDFDecodeLimit です。上限を超えた場合は ErrPDFDecodeLimit を返します。
+	DecodeLimit int64
+}
+
+// Extract は ports.TextExtractor の実装です。
+func (p PDF) Extract(_ context.Context, data []byte) (*ports.ExtractedText, error) {
+	if !bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-")) {
+		return nil, errors.New("PDF のヘッダーがありません")
+	}
+	limit := p.DecodeLimit
+	if limit <= 0 {
+		limit = DefaultPDFDecodeLimit
+	}
+	doc := parsePDF(data, limit)
+	if doc.encrypted {
+		return nil, errors.New("暗号化された PDF からはテキストを抽出できません")
+	}
+	text := doc.text()
+	if doc.err != nil {
+		return nil, doc.err
+	}
+	if text == "" {
+		return nil, errors.New("PDF からテキストを抽出できませんでした（画像のみの PDF の可能性があります）")
+	}
+	return &ports.ExtractedText{Title: doc.title(), Text: text}, nil
+}
+
+// PDF のオブジェクトの型です。数値は float64、真偽値・null・演算子は pdfKeyword で表します。
+type (
+	pdfName    string
+	pdfKeyword string
+	pdfString  []byte
+	pdfArray   []any
+	pdfDict    map[pdfName]any
+	pdfRef     int
+	pdfStream  struct {
+		dict pdfDict
+		raw  []byte
+	}
+)
+
+// pdfDoc は解析した PDF です。
+type pdfDoc struct {
+	objects   map[int]any
+	data      []byte
+	encrypted bool
+	// cmaps はフォントのオブジェクトごとの文字コードの変換表です。
+	cmaps map[any]*cmap
+	// decodeBudget は、ストリームの展開に使える残りのバイト数です。
+	decodeBudget int64
+	// err は展開の上限を超えたなど、抽出を打ち切った理由です。
+	err error
+}
+
+// parsePDF は data に含まれる間接オブジェクトを読み込みます。壊れたオブジェクトは読み飛ばします。
+// decodeLimit は、ストリームを展開したサイズの合計の上限です。
+func parsePDF(data []byte, decodeLimit int64) *pdfDoc {
+	doc := &pdfDoc{objects: make(map[int]any), data: data, cmaps: make(map[any]*cmap), decodeBudget: decodeLimit}
+	var objStreams []*pdfStream
+	pos := 0
+	for {
+		loc := objHeaderRegex.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(data[pos+loc[2] : pos+loc[3]]))
+		lx := &lexer{data: data, pos: pos + loc[1]}
+		value, err := lx.value()
+		if err != nil {
+			pos += loc[1]
+			continue
+		}
+		if dict, ok := value.(pdfDict); ok {
+			if stream, end, ok := readStream(data, lx.pos, dict); ok {
+				value = stream
+				lx.pos = end
+				if dict["Type"] == pdfName("ObjStm") {
+					objStreams = append(objStreams, stream)
+				}
+			}
+			if dict["Type"] == pdfName("XRef") && dict["Encrypt"] != nil {
+				doc.encrypted = true
+			}
+		}
+		doc.objects[num] = value
+		// 字句解析は終端を越えないが、壊れた入力でも data の範囲外を参照しないよう位置を制限します。
+		pos = min(max(lx.pos, pos+loc[1]), len(data))
+	}
+	for _, stream := range objStreams {
+		doc.loadObjectStream(stream)
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) && trailerHasEncrypt(data) {
+		doc.encrypted = true
+	}
+	return doc
+}
+
+// trailerHasEncrypt は、トレーラーに暗号化辞書への参照があるかを返します。
+func trailerHasEncrypt(data []byte) bool {
+	idx := bytes.LastIndex(data, []byte("trailer"))
+	if idx < 0 {
+		return false
+	}
+	lx := &lexer{data: data, pos: idx + len("trailer")}
+	value, err := lx.value()
+	if err != nil {
+		return false
+	}
+	dict, ok := value.(pdfDict)
+	return ok && dict["Encrypt"] != nil
+}
+
+// readStream は、辞書の直後にストリームがあればその内容と終端の位置を返します。
+func readStream(data []byte, pos int, dict pdfDict) (*pdfStream, int, bool) {
+	lx := &lexer{data: data, pos: min(pos, len(data))}
+	lx.skipSpace()
+	if !bytes.HasPrefix(data[lx.pos:], []byte("stream")) {
+		return nil, 0, false
+	}
+	start := lx.pos + len("stream")
+	if bytes.HasPrefix(data[start:], []byte("\r\n")) {
+		start += 2
+	} else if start < len(data) && (data[start] == '\n' || data[start] == '\r') {
+		start++
+	}
+	if length, ok := dict["Length"].(float64); ok {
+		end := start + int(length)
+		if end <= len(data) && end >= start {
+			rest := bytes.TrimLeft(data[end:min(len(data), end+32)], "\r\n \t")
+			if bytes.HasPrefix(rest, []byte("endstream")) {
+				return &pdfStream{dict: dict, raw: data[start:end]}, end, true
+			}
+		}
+	}
+	idx := bytes.Index(data[start:], []byte("endstream"))
+	if idx < 0 {
+		return &pdfStream{dict: dict, raw: data[start:]}, len(data), true
+	}
+	end := start + idx
+	return &pdfStream{dict: dict, raw: bytes.TrimRight(data[start:end], "\r\n")}, end + len("endstream"), true
+}
+
+// loadObjectStream は、オブジェクトストリームに含まれるオブジェクトを読み込みます。
+// 通常の間接オブジェクトとして定義済みの番号は上書きしません。
+func (d *pdfDoc) loadObjectStream(stream *pdfStream) {
+	content, ok := d.decodeStream(stream)
+	if !ok {
+		return
+	}
+	n, _ := stream.dict["N"].(float64)
+	first, _ := stream.dict["First"].(float64)
+	header := &lexer{data: content}
+	for range int(n) {
+		num, err1 := header.value()
+		offset, err2 := header.value()
+		numF, ok1 := num.(float64)
+		offsetF, ok2 := offset.(float64)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 {
+			return
+		}
+		pos := int(first) + int(offsetF)
+		if pos < 0 || pos >= len(content) {
+			continue
+		}
+		if _, exists := d.objects[int(numF)]; exists {
+			continue
+		}
+		if value, err := (&lexer{data: content, pos: pos}).value(); err == nil {
+			d.objects[int(numF)] = value
+		}
+	}
+}
+
+// resolve は参照をたどって実体を返します。
+func (d *pdfDoc) resolve(v any) any {
+	for range maxPDFDepth {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = d.objects[int(ref)]
+	}
+	return nil
+}
+
+// dict は v を辞書として返します。ストリームの場合はその辞書を返します。
+func (d *pdfDoc) dict(v any) pdfDict {
+	switch v := d.resolve(v).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// title は文書情報辞書の Title を返します。
+func (d *pdfDoc) title() string {
+	var info pdfDict
+	if m := infoRegex.FindAllSubmatch(d.data, -1); len(m) > 0 {
+		num, _ := strconv.Atoi(string(m[len(m)-1][1]))
+		info = d.dict(pdfRef(num))
+	}
+	if info == nil {
+		return ""
+	}
+	s, ok := d.resolve(info["Title"]).(pdfString)
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(decodeTextString(s))
+}
+
+// pages はページツリーの順にページの辞書と、継承したリソースを返します。
+// ページツリーをたどれない場合は、ページのオブジェクトを番号順に返します。
+func (d *pdfDoc) pages() []pdfPage {
+	var pages []pdfPage
+	visited := make(map[pdfRef]bool)
+	var walk func(node any, resources pdfDict, depth int)
+	walk = func(node any, resources pdfDict, depth int) {
+		if depth > maxPDFDepth {
+			return
+		}
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref] {
+				return
+			}
+			visited[ref] = true
+		}
+		dict := d.dict(node)
+		if dict == nil {
+			return
+		}
+		if r := d.dict(dict["Resources"]); r != nil {
+			resources = r
+		}
+		if kids, ok := d.resolve(dict["Kids"]).(pdfArray); ok {
+			for _, kid := range kids {
+				walk(kid, resources, depth+1)
+			}
+			return
+		}
+		if dict["Type"] == pdfName("Page") || dict["Contents"] != nil {
+			pages = append(pages, pdfPage{dict: dict, resources: resources})
+		}
+	}
+	for _, num := range d.sortedObjectNumbers() {
+		if dict := d.dict(pdfRef(num)); dict["Type"] == pdfName("Catalog") {
+			walk(dict["Pages"], nil, 0)
+			break
+		}
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+	for _, num := range d.sortedObjectNumbers() {
+		if dict := d.dict(pdfRef(num)); dict["Type"] == pdfName("Page") {
+			pages = append(pages, pdfPage{dict: dict, resources: d.dict(dict["Resources"])})
+		}
+	}
+	return pages
+}
+
+func (d *pdfDoc) sortedObjectNumbers() []int {
+	nums := make([]int, 0, len(d.objects))
+	for num := range d.objects {
+		nums = append(nums, num)
+	}
+	slices.Sort(nums)
+	return nums
+}
+
+// pdfPage は1ページの辞書と、適用するリソースです。
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// text はすべてのページのテキストを、ページの間に空行を入れて返します。
+func (d *pdfDoc) text() string {
+	var pages []string
+	for _, page := range d.pages() {
+		if d.err != nil {
+			return ""
+		}
+		var content []byte
+		contents := d.resolve(page.dict["Contents"])
+		if arr, ok := contents.(pdfArray); ok {
+			for _, c := range arr {
+				if s, ok := d.resolve(c).(*pdfStream); ok {
+					if decoded, ok := d.decodeStream(s); ok {
+						content = append(append(content, decoded...), '\n')
+					}
+				}
+			}
+		} else if s, ok := contents.(*pdfStream); ok {
+			content, _ = d.decodeStream(s)
+		}
+		w := &pdfTextWriter{}
+		d.interpret(content, page.resources, w, 0)
+		if t := w.String(); t != "" {
+			pages = append(pages, t)
+		}
+	}
+	return strings.Join(pages, "\n\n")
+}
+
+// interpret はコンテンツストリームのテキスト描画命令を解釈して w に書き込みます。
+func (d *pdfDoc) interpret(content []byte, resources pdfDict, w *pdfTextWriter, depth int) {
+	if depth > maxPDFDepth {
+		return
+	}
+	fonts := d.dict(resources["Font"])
+	var font *cmap
+	var operands []any
+	lastY, hasY := 0.0, false
+	lx := &lexer{data: content}
+	for {
+		tok, err := lx.value()
+		if err != nil || d.err != nil {
+			break
+		}
+		op, ok := tok.(pdfKeyword)
+		if !ok {
+			operands = append(operands, tok)
+			continue
+		}
+		switch op {
+		case "BT":
+			hasY = false
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[0].(pdfName); ok {
+					font = d.fontCMap(fonts[name])
+				}
+			}
+		case "Tj":
+			if len(operands) >= 1 {
+				w.write(font.decode(operands[len(operands)-1]))
+			}
+		case "'", "\"":
+			w.newline()
+			if len(operands) >= 1 {
+				w.write(font.decode(operands[len(operands)-1]))
+			}
+		case "TJ":
+			if len(operands) >= 1 {
+				arr, _ := operands[len(operands)-1].(pdfArray)
+				for _, item := range arr {
+					if n, ok := item.(float64); ok {
+						if n < tjSpaceThreshold {
+							w.space()
+						}
+						continue
+					}
+					w.write(font.decode(item))
+				}
+			}
+		case "T*":
+			w.newline()
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				if ty, ok := operands[len(operands)-1].(float64); ok && ty != 0 {
+					w.newline()
+				}
+			}
+		case "Tm":
+			if len(operands) >= 6 {
+				if y, ok := operands[len(operands)-1].(float64); ok {
+					if hasY && y != lastY {
+						w.newline()
+					}
+					lastY, hasY = y, true
+				}
+			}
+		case "ET":
+			w.newline()
+		case "BI":
+			lx.skipInlineImage()
+		case "Do":
+			if len(operands) >= 1 {
+				name, _ := operands[len(operands)-1].(pdfName)
+				if form, ok := d.resolve(d.dict(resources["XObject"])[name]).(*pdfStream); ok && form.dict["Subtype"] == pdfName("Form") {
+					formResources := d.dict(form.dict["Resources"])
+					if formResources == nil {
+						formResources = resources
+					}
+					if decoded, ok := d.decodeStream(form); ok {
+						d.interpret(decoded, formResources, w, depth+1)
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// fontCMap はフォントの文字コードの変換表を返します。ToUnicode CMap が無いフォントは、
+// 1バイトの文字コードをそのまま Latin-1 として扱います（2バイトの Identity 符号化は変換できません）。
+func (d *pdfDoc) fontCMap(fontRef any) *cmap {
+	key := fontRef
+	if _, ok := key.(pdfRef); !ok {
+		key = fmt.Sprintf("%p", d.dict(fontRef))
+	}
+	if c, ok := d.cmaps[key]; ok {
+		return c
+	}
+	font := d.dict(fontRef)
+	c := &cmap{codeLen: 1}
+	if s, ok := d.resolve(font["ToUnicode"]).(*pdfStream); ok {
+		if decoded, ok := d.decodeStream(s); ok {
+			c = parseCMap(decoded)
+		}
+	} else if enc, ok := d.resolve(font["Encoding"]).(pdfName); ok && strings.HasPrefix(string(enc), "Identity") {
+		c = &cmap{codeLen: 2, m: map[uint32]string{}}
+	}
+	d.cmaps[key] = c
+	return c
+}
+
+// decodeStream はストリームの圧縮を展開します。対応していない圧縮形式の場合は ok が false です。
+// FlateDecode で展開したサイズは decodeBudget から差し引き、上限を超えた場合は d.err に
+// ErrPDFDecodeLimit を設定して以降の展開をすべて打ち切ります。
+func (d *pdfDoc) decodeStream(s *pdfStream) ([]byte, bool) {
+	if d.err != nil {
+		return nil, false
+	}
+	var filters []any
+	switch f := s.dict["Filter"].(type) {
+	case pdfName:
+		filters = []any{f}
+	case pdfArray:
+		filters = f
+	}
+	data := s.raw
+	for _, f := range filters {
+		switch f {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			zr, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, false
+			}
+			// 末尾が壊れたストリームでも、展開できた分は使います。
+			decoded, err := io.ReadAll(io.LimitReader(zr, d.decodeBudget+1))
+			if int64(len(decoded)) > d.decodeBudget {
+				d.err = fmt.Errorf("%w（%d バイト）", ErrPDFDecodeLimit, d.decodeBudget)
+				return nil, false
+			}
+			d.decodeBudget -= int64(len(decoded))
+			if err != nil && len(decoded) == 0 {
+				return nil, false
+			}
+			data = decoded
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			data = decodeHex(data)
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			src := bytes.TrimSpace(data)
+			src = bytes.TrimPrefix(src, []byte("<~"))
+			if i := bytes.Index(src, []byte("~>")); i >= 0 {
+				src = src[:i]
+			}
+			dst := make([]byte, len(src)*4/5+4)
+			n, _, err := ascii85.Decode(dst, src, true)
+			if err != nil {
+				return nil, false
+			}
+			data = dst[:n]
+		default:
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+// decodeHex は空白を含む16進数の文字列をバイト列に変換します。桁数が奇数の場合は末尾に 0 を補います。
+func decodeHex(src []byte) []byte {
+	digits := make([]byte, 0, len(src))
+	for _, c := range src {
+		if c == '>' {
+			break
+		}
+		if (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	_, _ = hex.Decode(out, digits)
+	return out
+}
+
+// decodeTextString は文書情報辞書などのテキスト文字列を変換します。UTF-16BE（BOM 付き）と UTF-8（BOM 付き）に
+// 対応し、それ以外は PDFDocEncoding を Latin-1 として扱います。
+func decodeTextString(s []byte) string {
+	switch {
+	case bytes.HasPrefix(s, []byte{0xfe, 0xff}):
+		return decodeUTF16BE(s[2:])
+	case bytes.HasPrefix(s, utf8BOM):
+		return string(s[3:])
+	}
+	return latin1(s)
+}
+
+func decodeUTF16BE(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+func latin1(b []byte) string {
+	runes := make([]rune, 0, len(b))
+	for _, c := range b {
+		if c >= 0x20 || c == '\t' {
+			runes = append(runes, rune(c))
+		}
+	}
+	return string(runes)
+}
+
+// cmap はフォントの文字コードから Unicode への変換表です。
+type cmap struct {
+	// codeLen は文字コードのバイト数です。
+	codeLen int
+	// m は文字コードから Unicode の文字列への対応です。nil の場合は Latin-1 として変換します。
+	m map[uint32]string
+}
+
+// decode は文字列の文字コードを Unicode に変換します。c が nil の場合は Latin-1 として扱います。
+func (c *cmap) decode(v any) string {
+	s, ok := v.(pdfString)
+	if !ok {
+		return ""
+	}
+	if c == nil || c.m == nil {
+		return latin1(s)
+	}
+	var b strings.Builder
+	for i := 0; i+c.codeLen <= len(s); i += c.codeLen {
+		var code uint32
+		for _, x := range s[i : i+c.codeLen] {
+			code = code<<8 | uint32(x)
+		}
+		b.WriteString(c.m[code])
+	}
+	return b.String()
+}
+
+// parseCMap は ToUnicode CMap の codespacerange・bfchar・bfrange を読み込みます。
+func parseCMap(data []byte) *cmap {
+	c := &cmap{codeLen: 0, m: make(map[uint32]string)}
+	lx := &lexer{data: data}
+	var operands []any
+	section := pdfKeyword("")
+	for {
+		tok, err := lx.value()
+		if err != nil {
+			break
+		}
+		kw, ok := tok.(pdfKeyword)
+		if !ok {
+			operands = append(operands, tok)
+			continue
+		}
+		switch kw {
+		case "begincodespacerange", "beginbfchar", "beginbfrange":
+			section = kw
+			operands = operands[:0]
+			continue
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				if lo, ok := operands[i].(pdfString); ok && c.codeLen == 0 {
+					c.codeLen = len(lo)
+				}
+			}
+			section = ""
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(pdfString)
+				dst, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 {
+					c.setCodeLen(len(src))
+					c.m[codeOf(src)] = decodeUTF16BE(dst)
+				}
+			}
+			section = ""
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 {
+					continue
+				}
+				c.setCodeLen(len(lo))
+				start, end := codeOf(lo), codeOf(hi)
+				if end < start || end-start >= maxCMapRange {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case pdfString:
+					units := make([]byte, len(dst))
+					copy(units, dst)
+					for code := start; code <= end; code++ {
+						c.m[code] = decodeUTF16BE(units)
+						incrementLast(units)
+					}
+				case pdfArray:
+					for j, item := range dst {
+						if s, ok := item.(pdfString); ok && start+uint32(j) <= end {
+							c.m[start+uint32(j)] = decodeUTF16BE(s)
+						}
+					}
+				}
+			}
+			section = ""
+		}
+		if section == "" {
+			operands = operands[:0]
+		}
+	}
+	if c.codeLen == 0 {
+		c.codeLen = 1
+	}
+	return c
+}
+
+func (c *cmap) setCodeLen(n int) {
+	if c.codeLen == 0 && n > 0 {
+		c.codeLen = n
+	}
+}
+
+func codeOf(b []byte) uint32 {
+	var code uint32
+	for _, x := range b {
+		code = code<<8 | uint32(x)
+	}
+	return code
+}
+
+// incrementLast は UTF-16BE の最後の符号単位に 1 を加えます（bfrange の連番の展開に使います）。
+func incrementLast(units []byte) {
+	for i := len(units) - 1; i >= 0; i-- {
+		units[i]++
+		if units[i] != 0 || i%2 == 0 {
+			return
+		}
+	}
+}
+
+// pdfTextWriter は抽出したテキストを行ごとにまとめます。
+type pdfTextWriter struct {
+	b strings.Builder
+}
+
+func (w *pdfTextWriter) write(s string) {
+	w.b.WriteString(s)
+}
+
+func (w *pdfTextWriter) space() {
+	if s := w.b.String(); s != "" && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+		w.b.WriteByte(' ')
+	}
+}
+
+func (w *pdfTextWriter) newline() {
+	if s := w.b.String(); s != "" && !strings.HasSuffix(s, "\n") {
+		w.b.WriteByte('\n')
+	}
+}
+
+// String は各行の前後の空白と空行を取り除いたテキストを返します。
+func (w *pdfTextWriter) String() string {
+	var lines []string
+	for line := range strings.Lines(w.b.String()) {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// errEndOfData はこれ以上の字句が無いことを表します。
+var errEndOfData = errors.New("end of data")
+
+// lexer は PDF のオブジェクトとコンテンツストリームの字句解析器です。
+type lexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// skipSpace は空白とコメントを読み飛ばします。
+func (lx *lexer) skipSpace() {
+	for lx.pos < len(lx.data) {
+		c := lx.data[lx.pos]
+		switch {
+		case isPDFSpace(c):
+			lx.pos++
+		case c == '%':
+			for lx.pos < len(lx.data) && lx.data[lx.pos] != '\n' && lx.data[lx.pos] != '\r' {
+				lx.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// value は次のオブジェクトを読み込みます。"N G R" は参照として読み込みます。
+func (lx *lexer) value() (any, error) {
+	tok, err := lx.token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case pdfKeyword("["):
+		var arr pdfArray
+		for {
+			item, err := lx.value()
+			if err != nil {
+				return nil, err
+			}
+			if item == pdfKeyword("]") {
+				return arr, nil
+			}
+			arr = append(arr, item)
+		}
+	case pdfKeyword("<<"):
+		dict := make(pdfDict)
+		for {
+			key, err := lx.value()
+			if err != nil {
+				return nil, err
+			}
+			if key == pdfKeyword(">>") {
+				return dict, nil
+			}
+			name, ok := key.(pdfName)
+			if !ok {
+				continue
+			}
+			item, err := lx.value()
+			if err != nil {
+				return nil, err
+			}
+			if item == pdfKeyword(">>") {
+				return dict, nil
+			}
+			dict[name] = item
+		}
+	}
+	if n, ok := tok.(float64); ok && n == float64(int(n)) && n >= 0 {
+		save := lx.pos
+		if gen, err := lx.token(); err == nil {
+			if _, ok := gen.(float64); ok {
+				if r, err := lx.token(); err == nil && r == pdfKeyword("R") {
+					return pdfRef(int(n)), nil
+				}
+			}
+		}
+		lx.pos = save
+	}
+	return tok, nil
+}
+
+// token は次の字句を読み込みます。
+func (lx *lexer) token() (any, error) {
+	lx.skipSpace()
+	if lx.pos >= len(lx.data) {
+		return nil, errEndOfData
+	}
+	c := lx.data[lx.pos]
+	switch {
+	case c == '/':
+		lx.pos++
+		start := lx.pos
+		for lx.pos < len(lx.data) && !isPDFSpace(lx.data[lx.pos]) && !isPDFDelimiter(lx.data[lx.pos]) {
+			lx.pos++
+		}
+		return pdfName(unescapeName(lx.data[start:lx.pos])), nil
+	case c == '(':
+		return lx.literalString()
+	case c == '<':
+		if lx.pos+1 < len(lx.data) && lx.data[lx.pos+1] == '<' {
+			lx.pos += 2
+			return pdfKeyword("<<"), nil
+		}
+		lx.pos++
+		start := lx.pos
+		for lx.pos < len(lx.data) && lx.data[lx.pos] != '>' {
+			lx.pos++
+		}
+		if lx.pos >= len(lx.data) {
+			return nil, errEndOfData
+		}
+		s := decodeHex(lx.data[start:lx.pos])
+		lx.pos++
+		return pdfString(s), nil
+	case c == '>':
+		if lx.pos+1 < len(lx.data) && lx.data[lx.pos+1] == '>' {
+			lx.pos += 2
+			return pdfKeyword(">>"), nil
+		}
+		lx.pos++
+		return pdfKeyword(">"), nil
+	case c == '[' || c == ']' || c == '{' || c == '}':
+		lx.pos++
+		return pdfKeyword(string(c)), nil
+	case c == ')':
+		lx.pos++
+		return pdfKeyword(")"), nil
+	}
+	start := lx.pos
+	for lx.pos < len(lx.data) && !isPDFSpace(lx.data[lx.pos]) && !isPDFDelimiter(lx.data[lx.pos]) {
+		lx.pos++
+	}
+	word := string(lx.data[start:lx.pos])
+	if n, err := strconv.ParseFloat(word, 64); err == nil {
+		return n, nil
+	}
+	return pdfKeyword(word), nil
+}
+
+// literalString は括弧で囲まれた文字列を、エスケープを解釈して読み込みます。
+// 閉じ括弧の前に終端に達した場合は errEndOfData を返します。
+func (lx *lexer) literalString() (pdfString, error) {
+	lx.pos++ // (
+	var out []byte
+	depth := 1
+	for lx.pos < len(lx.data) {
+		c := lx.data[lx.pos]
+		lx.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out, nil
+			}
+		case '\\':
+			if lx.pos >= len(lx.data) {
+				return nil, errEndOfData
+			}
+			e := lx.data[lx.pos]
+			lx.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				if lx.pos < len(lx.data) && lx.data[lx.pos] == '\n' {
+					lx.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for range 2 {
+						if lx.pos < len(lx.data) && lx.data[lx.pos] >= '0' && lx.data[lx.pos] <= '7' {
+							v = v*8 + int(lx.data[lx.pos]-'0')
+							lx.pos++
+						}
+					}
+					out = append(out, byte(v))
+				} else {
+					out = append(out, e)
+				}
+			}
+			continue
+		}
+		out = append(out, c)
+	}
+	return nil, errEndOfData
+}
+
+// skipInlineImage はインライン画像（BI ... ID データ EI）のデータを読み飛ばします。
+func (lx *lexer) skipInlineImage() {
+	idx := bytes.Index(lx.data[lx.pos:], []byte("ID"))
+	if idx < 0 {
+		lx.pos = len(lx.data)
+		return
+	}
+	lx.pos += idx + 2
+	for lx.pos < len(lx.data) {
+		idx := bytes.Index(lx.data[lx.pos:], []byte("EI"))
+		if idx < 0 {
+			lx.pos = len(lx.data)
+			return
+		}
+		end := lx.pos + idx
+		lx.pos = end + 2
+		if end > 0 && isPDFSpace(lx.data[end-1]) && (lx.pos >= len(lx.data) || isPDFSpace(lx.data[lx.pos])) {
+			return
+		}
+	}
+}
+
+// unescapeName は名前の "#xx" 形式のエスケープを解釈します。
+func unescapeName(b []byte) string {
+	if !bytes.Contains(b, []byte("#")) {
+		return string(b)
+	}
+	var out []byte
+	for i := 0; i < len(b); i++ {
+		if b[i] == '#' && i+2 < len(b) {
+			if v, err := strconv.ParseUint(string(b[i+1:i+3]), 16, 8); err == nil {
+				out = append(out, byte(v))
+				i += 2
+				continue
+			}
+		}
+		out = append(out, b[i])
+	}
+	return string(out)
+}
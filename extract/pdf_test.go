@@ -0,0 +1,150 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF は objects を番号順（1 始まり）の間接オブジェクトとして並べた PDF を作成します。
+// 値が []byte の場合は FlateDecode で圧縮したストリームとして書き込みます。
+func buildPDF(t *testing.T, trailer string, objects ...any) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	for i, obj := range objects {
+		fmt.Fprintf(&b, "%d 0 obj\n", i+1)
+		switch obj := obj.(type) {
+		case string:
+			b.WriteString(obj)
+		case []byte:
+			var z bytes.Buffer
+			zw := zlib.NewWriter(&z)
+			if _, err := zw.Write(obj); err != nil {
+				t.Fatalf("compress failed: %v", err)
+			}
+			if err := zw.Close(); err != nil {
+				t.Fatalf("compress failed: %v", err)
+			}
+			fmt.Fprintf(&b, "<< /Length %d /Filter /FlateDecode >>\nstream\n", z.Len())
+			b.Write(z.Bytes())
+			b.WriteString("\nendstream")
+		}
+		b.WriteString("\nendobj\n")
+	}
+	fmt.Fprintf(&b, "trailer\n%s\n%%%%EOF\n", trailer)
+	return b.Bytes()
+}
+
+const toUnicode = `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+2 beginbfchar
+<0001> <305A>
+<0002> <3093>
+endbfchar
+1 beginbfrange
+<0003> <0004> <3060>
+endbfrange
+endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end`
+
+func TestPDF_Extract(t *testing.T) {
+	data := buildPDF(t, "<< /Root 1 0 R /Info 9 0 R /Size 10 >>",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 7 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /Contents [8 0 R] >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Type /Font /Subtype /Type0 /Encoding /Identity-H /ToUnicode 10 0 R >>",
+		[]byte("BT /F1 12 Tf 72 720 Td (Hello) Tj [(W) 30 (orld) -300 (\\(PDF\\))] TJ 0 -14 Td (Line\\0402) Tj ET"),
+		[]byte("BT /F2 12 Tf 1 0 0 1 72 720 Tm <0001000200030004> Tj ET\nBI /W 1 /H 1 ID \x00EI\x01 EI\nBT /F2 12 Tf (\x00\x01) Tj ET"),
+		"<< /Title <FEFF305A30933060> /Producer (test) >>",
+		[]byte(toUnicode),
+	)
+
+	got, err := PDF{}.Extract(context.Background(), data)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if got.Title != "ずんだ" {
+		t.Errorf("Title = %q, want %q", got.Title, "ずんだ")
+	}
+	want := "HelloWorld (PDF)\nLine 2\n\nずんだち\nず"
+	if got.Text != want {
+		t.Errorf("Text = %q, want %q", got.Text, want)
+	}
+}
+
+func TestPDF_ExtractErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"not a pdf", []byte("hello"), "ヘッダー"},
+		{"encrypted", buildPDF(t, "<< /Root 1 0 R /Encrypt 3 0 R >>",
+			"<< /Type /Catalog /Pages 2 0 R >>", "<< /Type /Pages /Kids [] >>", "<< /Filter /Standard >>"), "暗号化"},
+		{"no text", buildPDF(t, "<< /Root 1 0 R >>",
+			"<< /Type /Catalog /Pages 2 0 R >>", "<< /Type /Pages /Kids [3 0 R] >>",
+			"<< /Type /Page /Contents 4 0 R >>", []byte("q 100 0 0 100 0 0 cm /Im1 Do Q")), "画像のみ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := PDF{}.Extract(context.Background(), tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Extract error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPDF_ExtractDecodeLimit(t *testing.T) {
+	// 少ないバイト数から大きく展開されるストリームを、合計の展開サイズで打ち切ります。
+	content := []byte("BT /F1 12 Tf (Hello) Tj ET\n" + strings.Repeat(" ", 1<<20))
+	data := buildPDF(t, "<< /Root 1 0 R >>",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Resources << /Font << /F1 5 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents [4 0 R 4 0 R] >>",
+		content,
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+
+	_, err := PDF{DecodeLimit: 1 << 20}.Extract(context.Background(), data)
+	if !errors.Is(err, ErrPDFDecodeLimit) {
+		t.Errorf("Extract error = %v, want ErrPDFDecodeLimit", err)
+	}
+
+	// 上限内であれば抽出できます。
+	got, err := PDF{DecodeLimit: 4 << 20}.Extract(context.Background(), data)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if !strings.HasPrefix(got.Text, "Hello") {
+		t.Errorf("Text = %q, want it to start with Hello", got.Text[:min(len(got.Text), 20)])
+	}
+}
+
+func FuzzPDF(f *testing.F) {
+	f.Add([]byte("%PDF-00000 0 obj<"))
+	f.Add([]byte("%PDF-1 0 obj(abc"))
+	f.Add([]byte("%PDF-1 0 obj<< /Length 5 >>stream\nab"))
+	f.Add([]byte("%PDF-1.7\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n" +
+		"2 0 obj\n<< /Type /Pages /Kids [3 0 R] >>\nendobj\n" +
+		"3 0 obj\n<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>\nendobj\n" +
+		"4 0 obj\n<< /Length 26 >>\nstream\nBT /F1 12 Tf (Hello) Tj ET\nendstream\nendobj\n" +
+		"trailer\n<< /Root 1 0 R >>\n%%EOF\n"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// 壊れた入力でも panic せず、エラーまたは抽出結果を返すことを確認します。
+		_, _ = PDF{DecodeLimit: 1 << 20}.Extract(context.Background(), data)
+	})
+}
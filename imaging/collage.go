@@ -0,0 +1,97 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+)
+
+const (
+	defaultCollageCell = 512
+	collageLabelScale  = 4
+	collagePadding     = 8
+)
+
+// Tile はコラージュの1区画に配置する画像とラベルです。
+type Tile struct {
+	Image image.Image
+	// Label は区画の上部に描画する文字列です。組み込みフォントは英数字と一部の記号のみに対応します。
+	Label string
+}
+
+// Collage は tiles を格子状に並べ、各区画の上部にラベルを描画した PNG 画像を生成します。
+// cell は1区画の一辺のピクセル数で、0 以下の場合は 512 です。各画像はアスペクト比を保って
+// 区画内に縮小・中央配置されます。
+func Collage(tiles []Tile, cell int) (*Result, error) {
+	if len(tiles) == 0 {
+		return nil, errors.New("コラージュに配置する画像がありません")
+	}
+	if cell <= 0 {
+		cell = defaultCollageCell
+	}
+	labelHeight := glyphHeight*collageLabelScale + collagePadding*2
+	if cell <= labelHeight+collagePadding*2 {
+		return nil, fmt.Errorf("コラージュの区画が小さすぎます: %d", cell)
+	}
+
+	cols := CollageColumns(len(tiles))
+	rows := (len(tiles) + cols - 1) / cols
+	canvas := image.NewRGBA(image.Rect(0, 0, cols*cell, rows*cell))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	for i, tile := range tiles {
+		origin := image.Pt((i%cols)*cell, (i/cols)*cell)
+
+		// 区画の境界線
+		border := image.NewUniform(color.Gray{Y: 0xc0})
+		draw.Draw(canvas, image.Rect(origin.X, origin.Y, origin.X+cell, origin.Y+1), border, image.Point{}, draw.Src)
+		draw.Draw(canvas, image.Rect(origin.X, origin.Y, origin.X+1, origin.Y+cell), border, image.Point{}, draw.Src)
+
+		label := tile.Label
+		maxChars := (cell - collagePadding*2 + collageLabelScale) / ((glyphWidth + 1) * collageLabelScale)
+		if r := []rune(label); len(r) > maxChars {
+			label = string(r[:maxChars])
+		}
+		drawText(canvas, origin.X+(cell-textWidth(label, collageLabelScale))/2, origin.Y+collagePadding, label, collageLabelScale, color.Black)
+
+		if tile.Image == nil {
+			continue
+		}
+		area := image.Rect(0, 0, cell-collagePadding*2, cell-labelHeight-collagePadding)
+		img := fitInto(flatten(tile.Image, color.White), area.Dx(), area.Dy())
+		b := img.Bounds()
+		offset := origin.Add(image.Pt(collagePadding+(area.Dx()-b.Dx())/2, labelHeight+(area.Dy()-b.Dy())/2))
+		draw.Draw(canvas, b.Add(offset), img, image.Point{}, draw.Src)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, canvas); err != nil {
+		return nil, fmt.Errorf("コラージュのエンコードに失敗しました: %w", err)
+	}
+	b := canvas.Bounds()
+	return &Result{Data: buf.Bytes(), MIMEType: "image/png", Width: b.Dx(), Height: b.Dy()}, nil
+}
+
+// CollageColumns は n 枚のタイルを並べるコラージュの列数を返します。
+// タイルは左上から行優先で並ぶため、i 番目（0 始まり）は i/列数 行・i%列数 列に配置されます。
+func CollageColumns(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	return int(math.Ceil(math.Sqrt(float64(n))))
+}
+
+// fitInto はアスペクト比を保って w×h に収まるよう縮小します（拡大はしません）。
+func fitInto(img *image.RGBA, w, h int) *image.RGBA {
+	sw, sh := img.Bounds().Dx(), img.Bounds().Dy()
+	if sw <= w && sh <= h {
+		return img
+	}
+	scale := math.Min(float64(w)/float64(sw), float64(h)/float64(sh))
+	return Resize(img, max(1, int(float64(sw)*scale)), max(1, int(float64(sh)*scale)))
+}
@@ -0,0 +1,53 @@
+package imaging
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestCollage(t *testing.T) {
+	tiles := []Tile{
+		{Image: solid(300, 100, color.RGBA{R: 0xff, A: 0xff}), Label: "#1 zundamon"},
+		{Image: solid(100, 300, color.RGBA{B: 0xff, A: 0xff}), Label: "#2 panel 3"},
+		{Image: solid(50, 50, color.RGBA{G: 0xff, A: 0xff}), Label: "#3 ずんだもん"},
+	}
+	res, err := Collage(tiles, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 3枚は 2 列 × 2 行の格子に並びます。
+	if res.Width != 400 || res.Height != 400 || res.MIMEType != "image/png" {
+		t.Fatalf("Collage = %dx%d %s, want 400x400 image/png", res.Width, res.Height, res.MIMEType)
+	}
+
+	img := decode(t, res)
+	// 各区画の中央付近には縮小した画像が描画されます。
+	for i, want := range []color.RGBA{{R: 0xff, A: 0xff}, {B: 0xff, A: 0xff}, {G: 0xff, A: 0xff}} {
+		x, y := (i%2)*200+100, (i/2)*200+120
+		r, g, b, _ := img.At(x, y).RGBA()
+		if uint8(r>>8) != want.R || uint8(g>>8) != want.G || uint8(b>>8) != want.B {
+			t.Errorf("tile %d center = %v, want %v", i+1, img.At(x, y), want)
+		}
+	}
+	// ラベルの帯には黒い文字が描画されます。
+	var dark int
+	for y := 8; y < 8+glyphHeight*collageLabelScale; y++ {
+		for x := range 200 {
+			if r, _, _, _ := img.At(x, y).RGBA(); r == 0 {
+				dark++
+			}
+		}
+	}
+	if dark == 0 {
+		t.Error("label band of the first tile has no text")
+	}
+}
+
+func TestCollage_Errors(t *testing.T) {
+	if _, err := Collage(nil, 0); err == nil {
+		t.Error("Collage(nil) should fail")
+	}
+	if _, err := Collage([]Tile{{Label: "x"}}, 40); err == nil {
+		t.Error("Collage with a cell smaller than the label band should fail")
+	}
+}
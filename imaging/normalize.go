@@ -0,0 +1,208 @@
+// Package imaging は、参照画像のアップロード前処理（縮小・アスペクト比の調整・再エンコード）など、
+// 外部依存の無い画像処理を提供します。
+package imaging
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // 入力形式として登録
+	"image/jpeg"
+	"image/png"
+	"strconv"
+	"strings"
+)
+
+// Fit はアスペクト比を合わせる方法です。
+type Fit string
+
+const (
+	// FitPad は画像全体を残し、不足する領域を背景色で埋めます。
+	FitPad Fit = "pad"
+	// FitCrop は中央を基準に、はみ出す領域を切り取ります。
+	FitCrop Fit = "crop"
+)
+
+// Format は出力形式です。
+type Format string
+
+const (
+	FormatPNG  Format = "png"
+	FormatJPEG Format = "jpeg"
+)
+
+const defaultJPEGQuality = 90
+
+// Options は Normalize の設定です。ゼロ値の項目は処理しません。
+type Options struct {
+	// MaxEdge は長辺の最大ピクセル数です。超える場合はアスペクト比を保って縮小します。
+	MaxEdge int
+	// AspectRatio は "16:9" 形式の目標アスペクト比です。空の場合は元の比率を保ちます。
+	AspectRatio string
+	// Fit はアスペクト比の合わせ方です。既定は FitPad です。
+	Fit Fit
+	// Format は出力形式です。既定は FormatPNG です。
+	Format Format
+	// JPEGQuality は FormatJPEG の品質（1〜100）です。既定は 90 です。
+	JPEGQuality int
+	// Background は透過部分と余白の色です。既定は白です。
+	Background color.Color
+}
+
+// Key は出力結果に影響する設定を表す文字列です。処理結果のキャッシュキーに使います。
+func (o Options) Key() string {
+	o = o.withDefaults()
+	r, g, b, a := o.Background.RGBA()
+	return fmt.Sprintf("edge=%d;aspect=%s;fit=%s;format=%s;q=%d;bg=%04x%04x%04x%04x",
+		o.MaxEdge, o.AspectRatio, o.Fit, o.Format, o.JPEGQuality, r, g, b, a)
+}
+
+func (o Options) withDefaults() Options {
+	if o.Fit == "" {
+		o.Fit = FitPad
+	}
+	if o.Format == "" {
+		o.Format = FormatPNG
+	}
+	if o.JPEGQuality <= 0 || o.JPEGQuality > 100 {
+		o.JPEGQuality = defaultJPEGQuality
+	}
+	if o.Background == nil {
+		o.Background = color.White
+	}
+	return o
+}
+
+// Result は Normalize の結果です。
+type Result struct {
+	Data     []byte
+	MIMEType string
+	Width    int
+	Height   int
+}
+
+// Extension は出力形式に対応するファイル拡張子（"." 付き）を返します。
+func (r *Result) Extension() string {
+	if r.MIMEType == "image/jpeg" {
+		return ".jpg"
+	}
+	return ".png"
+}
+
+// Normalize は画像をデコードし、EXIF の向きを反映したうえで、透過を背景色で塗りつぶし、
+// アスペクト比の調整と縮小を行って再エンコードします。再エンコードにより EXIF 等の
+// メタデータは取り除かれます。対応する入力形式は PNG・JPEG・GIF です。
+func Normalize(data []byte, opts Options) (*Result, error) {
+	opts = opts.withDefaults()
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("画像のデコードに失敗しました: %w", err)
+	}
+	img := flatten(applyOrientation(src, jpegOrientation(data)), opts.Background)
+
+	if opts.AspectRatio != "" {
+		aw, ah, err := ParseAspectRatio(opts.AspectRatio)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Fit == FitCrop {
+			img = cropToAspect(img, aw, ah)
+		} else {
+			img = padToAspect(img, aw, ah, opts.Background)
+		}
+	}
+	if opts.MaxEdge > 0 {
+		img = fitWithin(img, opts.MaxEdge)
+	}
+
+	var buf bytes.Buffer
+	mimeType := "image/png"
+	switch opts.Format {
+	case FormatJPEG:
+		mimeType = "image/jpeg"
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: opts.JPEGQuality})
+	case FormatPNG:
+		err = png.Encode(&buf, img)
+	default:
+		return nil, fmt.Errorf("未対応の出力形式です: %s", opts.Format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("画像のエンコードに失敗しました: %w", err)
+	}
+
+	b := img.Bounds()
+	return &Result{Data: buf.Bytes(), MIMEType: mimeType, Width: b.Dx(), Height: b.Dy()}, nil
+}
+
+// ContentKey は、入力画像の内容と設定から処理結果を一意に識別するキー（SHA-256）を返します。
+func ContentKey(data []byte, opts Options) string {
+	h := sha256.New()
+	h.Write(data)
+	h.Write([]byte{0})
+	h.Write([]byte(opts.Key()))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ParseAspectRatio は "16:9" 形式のアスペクト比を解析します。
+func ParseAspectRatio(s string) (int, int, error) {
+	ws, hs, ok := strings.Cut(s, ":")
+	w, errW := strconv.Atoi(strings.TrimSpace(ws))
+	h, errH := strconv.Atoi(strings.TrimSpace(hs))
+	if !ok || errW != nil || errH != nil || w <= 0 || h <= 0 {
+		return 0, 0, fmt.Errorf("アスペクト比の形式が不正です: %q", s)
+	}
+	return w, h, nil
+}
+
+// flatten は img を背景色の上に合成した不透明な RGBA 画像を返します。
+func flatten(img image.Image, bg color.Color) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+	return dst
+}
+
+// cropToAspect は中央を基準に aw:ah となるよう切り取ります。
+func cropToAspect(img *image.RGBA, aw, ah int) *image.RGBA {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	cw, ch := w, w*ah/aw
+	if ch > h {
+		cw, ch = h*aw/ah, h
+	}
+	if cw == w && ch == h {
+		return img
+	}
+	x0, y0 := (w-cw)/2, (h-ch)/2
+	return SubImage(img, image.Rect(x0, y0, x0+cw, y0+ch))
+}
+
+// padToAspect は背景色の余白を加えて aw:ah となるよう拡張し、元の画像を中央に配置します。
+func padToAspect(img *image.RGBA, aw, ah int, bg color.Color) *image.RGBA {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	pw, ph := w, (w*ah+aw-1)/aw
+	if ph < h {
+		pw, ph = (h*aw+ah-1)/ah, h
+	}
+	if pw == w && ph == h {
+		return img
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, pw, ph))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	off := image.Pt((pw-w)/2, (ph-h)/2)
+	draw.Draw(dst, img.Bounds().Add(off), img, img.Bounds().Min, draw.Src)
+	return dst
+}
+
+// SubImage は r の範囲を複製した、原点が (0, 0) の RGBA 画像を返します。
+func SubImage(img image.Image, r image.Rectangle) *image.RGBA {
+	r = r.Intersect(img.Bounds())
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, r.Min, draw.Src)
+	return dst
+}
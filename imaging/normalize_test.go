@@ -0,0 +1,144 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func solid(w, h int, c color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func decode(t *testing.T, res *Result) image.Image {
+	t.Helper()
+	img, _, err := image.Decode(bytes.NewReader(res.Data))
+	if err != nil {
+		t.Fatalf("decode result: %v", err)
+	}
+	return img
+}
+
+func TestNormalize_DownscalesToMaxEdge(t *testing.T) {
+	res, err := Normalize(encodePNG(t, solid(400, 200, color.Black)), Options{MaxEdge: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Width != 100 || res.Height != 50 || res.MIMEType != "image/png" {
+		t.Errorf("result = %dx%d %s, want 100x50 image/png", res.Width, res.Height, res.MIMEType)
+	}
+	if b := decode(t, res).Bounds(); b.Dx() != 100 || b.Dy() != 50 {
+		t.Errorf("encoded size = %v", b)
+	}
+}
+
+func TestNormalize_AspectRatio(t *testing.T) {
+	src := encodePNG(t, solid(100, 100, color.Black))
+
+	padded, err := Normalize(src, Options{AspectRatio: "16:9"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if padded.Width != 178 || padded.Height != 100 {
+		t.Errorf("padded = %dx%d, want 178x100", padded.Width, padded.Height)
+	}
+	img := decode(t, padded)
+	if r, _, _, _ := img.At(0, 50).RGBA(); r != 0xffff {
+		t.Error("padding should use the white background")
+	}
+	if r, _, _, _ := img.At(89, 50).RGBA(); r != 0 {
+		t.Error("original content should be centered")
+	}
+
+	cropped, err := Normalize(src, Options{AspectRatio: "3:4", Fit: FitCrop})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cropped.Width != 75 || cropped.Height != 100 {
+		t.Errorf("cropped = %dx%d, want 75x100", cropped.Width, cropped.Height)
+	}
+
+	if _, err := Normalize(src, Options{AspectRatio: "wide"}); err == nil {
+		t.Error("expected an error for an invalid aspect ratio")
+	}
+}
+
+func TestNormalize_FlattensAlphaAndEncodesJPEG(t *testing.T) {
+	res, err := Normalize(encodePNG(t, solid(10, 10, color.NRGBA{})), Options{Format: FormatJPEG})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.MIMEType != "image/jpeg" || res.Extension() != ".jpg" {
+		t.Errorf("result = %s %s", res.MIMEType, res.Extension())
+	}
+	if r, g, b, _ := decode(t, res).At(5, 5).RGBA(); r < 0xf000 || g < 0xf000 || b < 0xf000 {
+		t.Errorf("transparent pixels = (%x, %x, %x), want white", r, g, b)
+	}
+}
+
+// withOrientation は JPEG の SOI 直後に Orientation を含む EXIF（APP1）を挿入します。
+func withOrientation(jpg []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:], 0x0112)
+	binary.BigEndian.PutUint16(entry[2:], 3)
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	tiff = append(tiff, entry...)
+	tiff = append(tiff, 0, 0, 0, 0)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, segment...)
+	return append(out, jpg[2:]...)
+}
+
+func TestNormalize_AppliesEXIFOrientation(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, solid(40, 20, color.Black), nil); err != nil {
+		t.Fatal(err)
+	}
+	res, err := Normalize(withOrientation(buf.Bytes(), 6), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Width != 20 || res.Height != 40 {
+		t.Errorf("result = %dx%d, want 20x40 after rotating", res.Width, res.Height)
+	}
+}
+
+func TestContentKey(t *testing.T) {
+	data := []byte("image")
+	if ContentKey(data, Options{}) != ContentKey(data, Options{Format: FormatPNG, Fit: FitPad}) {
+		t.Error("explicit defaults must produce the same key")
+	}
+	if ContentKey(data, Options{}) == ContentKey(data, Options{MaxEdge: 512}) {
+		t.Error("different options must produce different keys")
+	}
+	if ContentKey(data, Options{}) == ContentKey([]byte("other"), Options{}) {
+		t.Error("different content must produce different keys")
+	}
+}
@@ -0,0 +1,64 @@
+package imaging
+
+import (
+	"image"
+)
+
+// fitWithin は長辺が maxEdge を超える場合に、アスペクト比を保って縮小します。
+func fitWithin(img *image.RGBA, maxEdge int) *image.RGBA {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if w <= maxEdge && h <= maxEdge {
+		return img
+	}
+	if w >= h {
+		return Resize(img, maxEdge, max(1, h*maxEdge/w))
+	}
+	return Resize(img, max(1, w*maxEdge/h), maxEdge)
+}
+
+// Resize は img を w×h に縮小・拡大します。縮小時は対応する元画素の平均（エリア平均）を、
+// 拡大時は最近傍の画素を使います。
+func Resize(img image.Image, w, h int) *image.RGBA {
+	src := toRGBA(img)
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if sw == 0 || sh == 0 || w == 0 || h == 0 {
+		return dst
+	}
+
+	for dy := range h {
+		sy0 := dy * sh / h
+		sy1 := max((dy+1)*sh/h, sy0+1)
+		for dx := range w {
+			sx0 := dx * sw / w
+			sx1 := max((dx+1)*sw/w, sx0+1)
+
+			var r, g, b, a, n uint32
+			for sy := sy0; sy < sy1; sy++ {
+				off := src.PixOffset(sx0, sy)
+				for sx := sx0; sx < sx1; sx++ {
+					r += uint32(src.Pix[off])
+					g += uint32(src.Pix[off+1])
+					b += uint32(src.Pix[off+2])
+					a += uint32(src.Pix[off+3])
+					off += 4
+					n++
+				}
+			}
+			doff := dst.PixOffset(dx, dy)
+			dst.Pix[doff] = uint8(r / n)
+			dst.Pix[doff+1] = uint8(g / n)
+			dst.Pix[doff+2] = uint8(b / n)
+			dst.Pix[doff+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// toRGBA は img を原点が (0, 0) の RGBA 画像に変換します。既に該当する場合はそのまま返します。
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	return SubImage(img, img.Bounds())
+}
@@ -0,0 +1,129 @@
+package imaging
+
+import (
+	"image"
+)
+
+// SplitOptions は DetectFigures の設定です。ゼロ値の項目は画像の大きさから決まる既定値を使います。
+type SplitOptions struct {
+	// BackgroundThreshold は背景とみなす明るさの下限です。R・G・B がすべてこの値以上の画素、
+	// または透明な画素を背景として扱います。既定は 235 です。
+	BackgroundThreshold uint8
+	// MinGap は図を区切る背景列の最小幅（ピクセル）です。これより狭い隙間は同じ図の一部
+	// （腕と胴の間など）として結合します。既定は画像幅の 1%（最小 4）です。
+	MinGap int
+	// MinWidth・MinHeight は図とみなす最小の幅・高さです。これより小さい領域はノイズとして
+	// 捨てます。既定は画像幅の 4%（最小 8）・画像高さの 10% です。
+	MinWidth  int
+	MinHeight int
+	// Margin は検出した図の周囲に残す余白です。既定は画像幅の 1%（最小 4）です。
+	Margin int
+}
+
+func (o SplitOptions) withDefaults(w, h int) SplitOptions {
+	if o.BackgroundThreshold == 0 {
+		o.BackgroundThreshold = 235
+	}
+	if o.MinGap <= 0 {
+		o.MinGap = max(4, w/100)
+	}
+	if o.MinWidth <= 0 {
+		o.MinWidth = max(8, w/25)
+	}
+	if o.MinHeight <= 0 {
+		o.MinHeight = max(1, h/10)
+	}
+	if o.Margin <= 0 {
+		o.Margin = max(4, w/100)
+	}
+	return o
+}
+
+// DetectFigures は、白い無地背景の上に横に並んだ図（3面図の各ビュー等）を列方向の射影で検出し、
+// 左から順に各図の範囲を返します。背景が均一で照明がフラットであることを前提とします。
+func DetectFigures(img image.Image, opts SplitOptions) []image.Rectangle {
+	src := toRGBA(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if w == 0 || h == 0 {
+		return nil
+	}
+	opts = opts.withDefaults(w, h)
+	mask := foregroundMask(src, opts.BackgroundThreshold)
+
+	// 1. 列ごとの前景画素数から、図を含む列の連続区間を求めます。
+	// アンチエイリアスや圧縮ノイズを無視するため、高さの 0.5% 未満の列は背景として扱います。
+	colNoise := max(2, h/200)
+	var runs [][2]int
+	start := -1
+	for x := 0; x <= w; x++ {
+		content := false
+		if x < w {
+			n := 0
+			for y := range h {
+				if mask[y*w+x] {
+					n++
+				}
+			}
+			content = n >= colNoise
+		}
+		switch {
+		case content && start < 0:
+			start = x
+		case !content && start >= 0:
+			if len(runs) > 0 && start-runs[len(runs)-1][1] < opts.MinGap {
+				runs[len(runs)-1][1] = x
+			} else {
+				runs = append(runs, [2]int{start, x})
+			}
+			start = -1
+		}
+	}
+
+	// 2. 各区間の上下端を行方向の射影で求め、小さすぎる領域を除いて余白を加えます。
+	var figures []image.Rectangle
+	for _, run := range runs {
+		if run[1]-run[0] < opts.MinWidth {
+			continue
+		}
+		rowNoise := max(1, (run[1]-run[0])/200)
+		top, bottom := -1, -1
+		for y := range h {
+			n := 0
+			for x := run[0]; x < run[1]; x++ {
+				if mask[y*w+x] {
+					n++
+				}
+			}
+			if n >= rowNoise {
+				if top < 0 {
+					top = y
+				}
+				bottom = y + 1
+			}
+		}
+		if top < 0 || bottom-top < opts.MinHeight {
+			continue
+		}
+		r := image.Rect(run[0]-opts.Margin, top-opts.Margin, run[1]+opts.Margin, bottom+opts.Margin)
+		figures = append(figures, r.Intersect(image.Rect(0, 0, w, h)))
+	}
+	return figures
+}
+
+// foregroundMask は背景でない画素を true とするマスクを返します。
+func foregroundMask(img *image.RGBA, threshold uint8) []bool {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	mask := make([]bool, w*h)
+	for y := range h {
+		off := img.PixOffset(0, y)
+		for x := range w {
+			p := img.Pix[off : off+4]
+			off += 4
+			if p[3] < 16 {
+				continue
+			}
+			mask[y*w+x] = p[0] < threshold || p[1] < threshold || p[2] < threshold
+		}
+	}
+	return mask
+}
@@ -0,0 +1,55 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// turnaround は白背景に幅 40 の図を3つ並べた 300x120 の画像を返します。
+// 中央の図は幅 2 の隙間で左右に分かれています（腕と胴の間の隙間を模しています）。
+func turnaround() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 300, 120))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	ink := image.NewUniform(color.RGBA{R: 0x30, G: 0x60, B: 0x90, A: 0xff})
+	for _, r := range []image.Rectangle{
+		image.Rect(20, 10, 60, 110),
+		image.Rect(130, 15, 148, 105), image.Rect(150, 15, 170, 105),
+		image.Rect(240, 20, 280, 100),
+	} {
+		draw.Draw(img, r, ink, image.Point{}, draw.Src)
+	}
+	// 背景のノイズ（1画素）は無視されます。
+	img.Set(100, 60, color.Black)
+	return img
+}
+
+func TestDetectFigures(t *testing.T) {
+	got := DetectFigures(turnaround(), SplitOptions{Margin: 2})
+	want := []image.Rectangle{
+		image.Rect(18, 8, 62, 112),
+		image.Rect(128, 13, 172, 107),
+		image.Rect(238, 18, 282, 102),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("DetectFigures = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("figure %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDetectFigures_BlankAndTransparent(t *testing.T) {
+	blank := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	draw.Draw(blank, blank.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	if got := DetectFigures(blank, SplitOptions{}); len(got) != 0 {
+		t.Errorf("DetectFigures(blank) = %v, want none", got)
+	}
+	// 透明な画素は背景として扱います。
+	if got := DetectFigures(image.NewNRGBA(image.Rect(0, 0, 100, 50)), SplitOptions{}); len(got) != 0 {
+		t.Errorf("DetectFigures(transparent) = %v, want none", got)
+	}
+}
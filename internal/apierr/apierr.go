@@ -0,0 +1,79 @@
+// Package apierr は、Gemini API や画像生成クライアントが返すエラーを ports のエラー分類へ変換します。
+package apierr
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/shouni/go-gemini-client/gemini"
+	"google.golang.org/genai"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// finishReasonRegex は gemini-image-kit が返す "generation failed with FinishReason: X" から理由を抽出します。
+var finishReasonRegex = regexp.MustCompile(`FinishReason: (\w+)`)
+
+// blockedReasonRegex は go-gemini-client が返す「生成がブロックされました（理由: X）」から理由を抽出します。
+var blockedReasonRegex = regexp.MustCompile(`ブロックされました（理由: ([^）]+)）`)
+
+// blockedFinishReasons は、安全性・ポリシーによるブロックとして扱う FinishReason です。
+// MAX_TOKENS・OTHER・NO_IMAGE などは再試行で解消し得るため、分類せずにそのまま返します。
+var blockedFinishReasons = map[genai.FinishReason]bool{
+	genai.FinishReasonSafety:            true,
+	genai.FinishReasonProhibitedContent: true,
+	genai.FinishReasonBlocklist:         true,
+	genai.FinishReasonSPII:              true,
+	genai.FinishReasonImageSafety:       true,
+	genai.FinishReasonRecitation:        true,
+}
+
+// Classify は err を ports.RateLimitError / ports.ContentBlockedError でラップして返します。
+// どちらにも該当しない場合、または既に分類済みの場合は err をそのまま返します。
+func Classify(err error) error {
+	if err == nil || errors.Is(err, ports.ErrRateLimited) || errors.Is(err, ports.ErrContentBlocked) {
+		return err
+	}
+
+	if isRateLimited(err) {
+		return &ports.RateLimitError{Err: err}
+	}
+	if reason, ok := blockedReason(err); ok {
+		return &ports.ContentBlockedError{Reason: string(reason), Err: err}
+	}
+	return err
+}
+
+// blockedReason は err がブロックによるものであれば、その FinishReason を返します。
+// go-gemini-client・gemini-image-kit は genai.FinishReason を型として公開していないため、
+// エラーメッセージから取り出した理由を genai.FinishReason として照合します。
+func blockedReason(err error) (genai.FinishReason, bool) {
+	var m []string
+	var respErr *gemini.APIResponseError
+	if errors.As(err, &respErr) {
+		m = blockedReasonRegex.FindStringSubmatch(respErr.Error())
+	}
+	if m == nil {
+		m = finishReasonRegex.FindStringSubmatch(err.Error())
+	}
+	if m == nil {
+		return "", false
+	}
+	reason := genai.FinishReason(strings.TrimSpace(m[1]))
+	return reason, blockedFinishReasons[reason]
+}
+
+// isRateLimited は err が API のクォータ・レート制限によるものかを判定します。
+func isRateLimited(err error) bool {
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == http.StatusTooManyRequests || strings.EqualFold(apiErr.Status, "RESOURCE_EXHAUSTED")
+	}
+	var apiErrPtr *genai.APIError
+	if errors.As(err, &apiErrPtr) && apiErrPtr != nil {
+		return apiErrPtr.Code == http.StatusTooManyRequests || strings.EqualFold(apiErrPtr.Status, "RESOURCE_EXHAUSTED")
+	}
+	return false
+}
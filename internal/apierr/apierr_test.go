@@ -0,0 +1,76 @@
+package apierr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/genai"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantTarget error
+		wantReason string
+	}{
+		{
+			name:       "HTTP 429",
+			err:        fmt.Errorf("generate: %w", genai.APIError{Code: 429, Message: "quota"}),
+			wantTarget: ports.ErrRateLimited,
+		},
+		{
+			name:       "RESOURCE_EXHAUSTED",
+			err:        &genai.APIError{Code: 400, Status: "RESOURCE_EXHAUSTED"},
+			wantTarget: ports.ErrRateLimited,
+		},
+		{
+			name:       "image FinishReason",
+			err:        fmt.Errorf("wrapped: %w", errors.New("generation failed with FinishReason: IMAGE_SAFETY")),
+			wantTarget: ports.ErrContentBlocked,
+			wantReason: "IMAGE_SAFETY",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Classify(tt.err)
+			if !errors.Is(got, tt.wantTarget) {
+				t.Fatalf("Classify(%v) = %v, want %v", tt.err, got, tt.wantTarget)
+			}
+			if !errors.Is(got, tt.err) {
+				t.Error("classified error should wrap the original")
+			}
+			var blocked *ports.ContentBlockedError
+			if errors.As(got, &blocked) && blocked.Reason != tt.wantReason {
+				t.Errorf("Reason = %q, want %q", blocked.Reason, tt.wantReason)
+			}
+		})
+	}
+
+	plain := errors.New("connection reset")
+	if got := Classify(plain); got != plain {
+		t.Errorf("Classify(plain) = %v, want the error unchanged", got)
+	}
+	if ports.IsRetryable(Classify(errors.New("generation failed with FinishReason: SAFETY"))) {
+		t.Error("blocked content should not be retryable")
+	}
+}
+
+func TestClassify_NonBlockingFinishReasons(t *testing.T) {
+	for _, reason := range []string{"MAX_TOKENS", "OTHER", "NO_IMAGE", "MALFORMED_FUNCTION_CALL"} {
+		err := fmt.Errorf("generation failed with FinishReason: %s", reason)
+		got := Classify(err)
+		if got != err {
+			t.Errorf("Classify(%s) = %v, want the error unchanged", reason, got)
+		}
+		if errors.Is(got, ports.ErrContentBlocked) {
+			t.Errorf("%s should not be classified as blocked", reason)
+		}
+	}
+	if !ports.IsRetryable(Classify(errors.New("generation failed with FinishReason: MAX_TOKENS"))) {
+		t.Error("MAX_TOKENS should stay retryable")
+	}
+}
@@ -0,0 +1,28 @@
+// Package deadline は、AI 呼び出しやアップロード1回ごとに期限を設定するための補助関数を提供します。
+package deadline
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// Call は timeout を期限として call を実行します。timeout が 0 以下の場合は期限を設定しません。
+// 設定した期限を超えて call が失敗した場合は *ports.TimeoutError を返します。
+// 呼び出し元の ctx 自体がキャンセル・期限切れになった場合は call のエラーをそのまま返します。
+func Call[T any](ctx context.Context, stage string, timeout time.Duration, call func(ctx context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		return call(ctx)
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	res, err := call(callCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		return res, &ports.TimeoutError{Stage: stage, Timeout: timeout, Err: err}
+	}
+	return res, err
+}
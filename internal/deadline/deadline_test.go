@@ -0,0 +1,52 @@
+package deadline
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+func TestCall(t *testing.T) {
+	hang := func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+
+	t.Run("per-call deadline surfaces as TimeoutError", func(t *testing.T) {
+		_, err := Call(context.Background(), ports.StagePanel, 10*time.Millisecond, hang)
+		if !errors.Is(err, ports.ErrRequestTimeout) {
+			t.Fatalf("err = %v, want ErrRequestTimeout", err)
+		}
+		var te *ports.TimeoutError
+		if !errors.As(err, &te) || te.Stage != ports.StagePanel || te.Timeout != 10*time.Millisecond {
+			t.Errorf("err = %#v", err)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Error("TimeoutError should wrap the underlying error")
+		}
+	})
+
+	t.Run("caller cancellation is not a timeout", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := Call(ctx, ports.StagePanel, time.Minute, hang)
+		if errors.Is(err, ports.ErrRequestTimeout) || !errors.Is(err, context.Canceled) {
+			t.Errorf("err = %v, want context.Canceled", err)
+		}
+	})
+
+	t.Run("zero timeout disables the deadline", func(t *testing.T) {
+		res, err := Call(context.Background(), ports.StagePage, 0, func(ctx context.Context) (string, error) {
+			if _, ok := ctx.Deadline(); ok {
+				t.Error("unexpected deadline")
+			}
+			return "ok", nil
+		})
+		if err != nil || res != "ok" {
+			t.Errorf("Call = (%q, %v)", res, err)
+		}
+	})
+}
@@ -0,0 +1,88 @@
+// Package telemetry は、go-manga-kit 内部で共有する OpenTelemetry のトレーサーと
+// スパン属性の定義を提供します。
+//
+// トレーサーはグローバルの TracerProvider（otel.SetTracerProvider）から取得するため、
+// 呼び出し元が OpenTelemetry を設定していない場合は何も記録しません。
+package telemetry
+
+import (
+	"context"
+	"io"
+
+	"github.com/shouni/go-remote-io/remoteio"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName は OpenTelemetry の計装スコープ名です。
+const instrumentationName = "github.com/shouni/go-manga-kit"
+
+// スパン属性のキーです。
+const (
+	AttrModel            = attribute.Key("gen_ai.request.model")
+	AttrStage            = attribute.Key("manga.stage")
+	AttrSeed             = attribute.Key("manga.seed")
+	AttrAspectRatio      = attribute.Key("manga.aspect_ratio")
+	AttrImageSize        = attribute.Key("manga.image_size")
+	AttrAssetCount       = attribute.Key("manga.asset_count")
+	AttrRetryCount       = attribute.Key("manga.retry_count")
+	AttrPanelIndex       = attribute.Key("manga.panel_index")
+	AttrPageIndex        = attribute.Key("manga.page_index")
+	AttrCharacterID      = attribute.Key("manga.character_id")
+	AttrResourceType     = attribute.Key("manga.resource_type")
+	AttrReferenceURL     = attribute.Key("manga.reference_url")
+	AttrCacheHit         = attribute.Key("manga.asset.cache_hit")
+	AttrSingleflightHit  = attribute.Key("manga.asset.singleflight_shared")
+	AttrStoragePath      = attribute.Key("manga.storage.path")
+	AttrStorageBytes     = attribute.Key("manga.storage.bytes")
+	AttrStorageMediaType = attribute.Key("manga.storage.content_type")
+)
+
+// Start は go-manga-kit のトレーサーでスパンを開始します。
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Annotate は ctx の現在のスパンに属性を追加します。
+func Annotate(ctx context.Context, attrs ...attribute.KeyValue) {
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+}
+
+// End は err があればスパンに記録したうえでスパンを終了します。
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Write は、ストレージへの書き込み1回をスパンで計測しながら writer.Write を呼び出します。
+// contentType は remoteio.WithContentType として opts の先頭に追加されます。
+func Write(ctx context.Context, writer remoteio.Writer, path, contentType string, content io.Reader, opts ...remoteio.WriteOption) (err error) {
+	ctx, span := Start(ctx, "manga.storage.write",
+		AttrStoragePath.String(path),
+		AttrStorageMediaType.String(contentType),
+	)
+	defer func() { End(span, err) }()
+
+	counter := &countingReader{r: content}
+	opts = append([]remoteio.WriteOption{remoteio.WithContentType(contentType)}, opts...)
+	err = writer.Write(ctx, path, counter, opts...)
+	span.SetAttributes(AttrStorageBytes.Int64(counter.n))
+	return err
+}
+
+// countingReader は読み出されたバイト数を数える io.Reader です。
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
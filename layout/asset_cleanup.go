@@ -0,0 +1,270 @@
+package layout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// UploadedAsset は MangaComposer が File API にアップロードしたアセット1件の記録です。
+type UploadedAsset struct {
+	FileAPIURI   string
+	ReferenceURL string
+	// ResourceType は "character" または "panel" です。
+	ResourceType string
+	UploadedAt   time.Time
+	// ExpiresAt は File API URI の有効期限です。
+	ExpiresAt time.Time
+	// Persistent はアセットレジストリに記録されていることを表します。
+	// 再起動後のプロセスが再利用できるよう、Close では削除されません。
+	Persistent bool
+}
+
+// expiring は、now から margin 以内に期限切れとなる場合に true を返します。
+func (u UploadedAsset) expiring(now time.Time, margin time.Duration) bool {
+	return !u.ExpiresAt.IsZero() && !now.Add(margin).Before(u.ExpiresAt)
+}
+
+// pendingDeletion は、削除待ちのアセットです。epoch 以前に取得されたリースが
+// すべて解放されるまで削除されません。
+type pendingDeletion struct {
+	asset UploadedAsset
+	epoch uint64
+}
+
+// Uploads は、現在追跡しているアップロード済みアセットの一覧を返します。
+func (mc *MangaComposer) Uploads() []UploadedAsset {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+	uploads := make([]UploadedAsset, 0, len(mc.uploads))
+	for _, u := range mc.uploads {
+		uploads = append(uploads, u)
+	}
+	return uploads
+}
+
+// Lease は、アップロード済みアセットの URI を参照する処理の開始を宣言します。
+// 返された関数を呼び出すまで、その時点で参照され得るアセットは Sweep・Close によって
+// 削除されません。PanelGenerator・PageGenerator は Execute の間リースを保持します。
+func (mc *MangaComposer) Lease() (release func()) {
+	mc.leaseMu.Lock()
+	epoch := mc.epoch
+	mc.leases[epoch]++
+	mc.leaseMu.Unlock()
+
+	var released bool
+	return func() {
+		mc.leaseMu.Lock()
+		defer mc.leaseMu.Unlock()
+		if released {
+			return
+		}
+		released = true
+		if mc.leases[epoch]--; mc.leases[epoch] == 0 {
+			delete(mc.leases, epoch)
+		}
+		select {
+		case mc.leaseReleased <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Sweep は、アップロードから maxAge 以上経過したアセットを追跡対象から外し、
+// それらを参照し得るリースがすべて解放されていれば File API から削除します。
+// 参照中のアセットは削除待ちとして保持され、次回の Sweep または Close で削除されます。
+// レジストリに記録されたアセットも対象となり、削除したものはレジストリからも取り除きます。
+// 戻り値は削除に成功した件数です。
+func (mc *MangaComposer) Sweep(ctx context.Context, maxAge time.Duration) (int, error) {
+	threshold := time.Now().Add(-maxAge)
+	mc.retire(func(u UploadedAsset) bool { return !u.UploadedAt.After(threshold) })
+	return mc.deletePending(ctx)
+}
+
+// Close は、追跡しているアセットを File API から削除し、スイーパーを停止します。
+// アセットレジストリに記録されたアセットは、次のプロセスが再利用できるよう削除しません。
+// 実行中のリースが解放されるまで待機します。ctx が終了した場合は、その時点で削除できた
+// アセットのみを削除して ctx のエラーを返します。
+func (mc *MangaComposer) Close(ctx context.Context) error {
+	mc.stopSweeper()
+	mc.retire(func(u UploadedAsset) bool { return !u.Persistent })
+
+	var deleteErrs []error
+	for {
+		_, err := mc.deletePending(ctx)
+		if err != nil {
+			deleteErrs = append(deleteErrs, err)
+		}
+
+		mc.leaseMu.Lock()
+		remaining := len(mc.pending)
+		mc.leaseMu.Unlock()
+		if remaining == 0 {
+			return errors.Join(deleteErrs...)
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(append(deleteErrs, fmt.Errorf("%d 件のアセットが参照中のため削除できませんでした: %w", remaining, ctx.Err()))...)
+		case <-mc.leaseReleased:
+		}
+	}
+}
+
+// StartSweeper は、interval ごとに Sweep(maxAge) を実行するバックグラウンド処理を開始します。
+// 既に開始している場合は何もしません。スイーパーは Close で停止します。
+func (mc *MangaComposer) StartSweeper(interval, maxAge time.Duration) {
+	if interval <= 0 || maxAge <= 0 {
+		return
+	}
+
+	mc.leaseMu.Lock()
+	defer mc.leaseMu.Unlock()
+	if mc.sweeperStop != nil {
+		return
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	mc.sweeperStop, mc.sweeperDone = stop, done
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				n, err := mc.Sweep(ctx, maxAge)
+				cancel()
+				if n > 0 {
+					slog.Info("Swept expired File API assets", "deleted", n)
+				}
+				if err != nil {
+					slog.Warn("Failed to sweep some File API assets", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// stopSweeper はスイーパーを停止し、実行中の Sweep の完了を待ちます。
+func (mc *MangaComposer) stopSweeper() {
+	mc.leaseMu.Lock()
+	stop, done := mc.sweeperStop, mc.sweeperDone
+	mc.sweeperStop, mc.sweeperDone = nil, nil
+	mc.leaseMu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// trackUpload はアップロード済みアセットを追跡対象に加えます。
+// 同じ URI が削除待ちになっていた場合（AssetManager 側のキャッシュから同じ URI が
+// 返された場合）は、削除待ちを取り消します。呼び出し元は mc.mu の書き込みロックを保持します。
+func (mc *MangaComposer) trackUpload(asset UploadedAsset) {
+	mc.uploads[asset.FileAPIURI] = asset
+
+	mc.leaseMu.Lock()
+	defer mc.leaseMu.Unlock()
+	kept := mc.pending[:0]
+	for _, p := range mc.pending {
+		if p.asset.FileAPIURI != asset.FileAPIURI {
+			kept = append(kept, p)
+		}
+	}
+	mc.pending = kept
+}
+
+// retire は expired が true を返すアセットを追跡対象と URI キャッシュから外し、削除待ちにします。
+// 以降のリクエストは同じ ReferenceURL を再アップロードします。
+func (mc *MangaComposer) retire(expired func(UploadedAsset) bool) {
+	mc.mu.Lock()
+	var retired []UploadedAsset
+	for uri, u := range mc.uploads {
+		if !expired(u) {
+			continue
+		}
+		delete(mc.uploads, uri)
+		for _, m := range []map[string]string{mc.resourceMap.character, mc.resourceMap.panel, mc.variants} {
+			for key, cached := range m {
+				if cached == uri {
+					delete(m, key)
+				}
+			}
+		}
+		retired = append(retired, u)
+	}
+	mc.mu.Unlock()
+
+	if len(retired) == 0 {
+		return
+	}
+
+	// URI キャッシュから外した後でエポックを進めるため、この時点より前に取得された
+	// リースだけが、削除待ちのアセットを参照し得ます。
+	mc.leaseMu.Lock()
+	for _, u := range retired {
+		mc.pending = append(mc.pending, pendingDeletion{asset: u, epoch: mc.epoch})
+	}
+	mc.epoch++
+	mc.leaseMu.Unlock()
+}
+
+// deletePending は、参照し得るリースが残っていない削除待ちのアセットを削除します。
+// 削除に失敗したアセットはログに記録して追跡対象から外します（File API のファイルは
+// いずれ期限切れで自動削除されるため、再試行はしません）。
+func (mc *MangaComposer) deletePending(ctx context.Context) (int, error) {
+	mc.leaseMu.Lock()
+	oldest, hasLease := mc.oldestLeaseEpoch()
+	var deletable []UploadedAsset
+	kept := mc.pending[:0]
+	for _, p := range mc.pending {
+		if hasLease && p.epoch >= oldest {
+			kept = append(kept, p)
+			continue
+		}
+		deletable = append(deletable, p.asset)
+	}
+	mc.pending = kept
+	mc.leaseMu.Unlock()
+
+	var errs []error
+	deleted := 0
+	for _, u := range deletable {
+		if err := mc.AssetManager.DeleteFile(ctx, u.FileAPIURI); err != nil {
+			slog.WarnContext(ctx, "Failed to delete File API asset",
+				"file_uri", u.FileAPIURI,
+				"reference_url", u.ReferenceURL,
+				"error", err,
+			)
+			errs = append(errs, &ports.AssetError{ResourceType: u.ResourceType, ReferenceURL: u.ReferenceURL, Err: err})
+			continue
+		}
+		if u.Persistent {
+			mc.forgetRegistry(ctx, u)
+		}
+		deleted++
+	}
+	return deleted, errors.Join(errs...)
+}
+
+// oldestLeaseEpoch は実行中のリースのうち最も古いエポックを返します。呼び出し元は leaseMu を保持します。
+func (mc *MangaComposer) oldestLeaseEpoch() (uint64, bool) {
+	var oldest uint64
+	found := false
+	for epoch := range mc.leases {
+		if !found || epoch < oldest {
+			oldest, found = epoch, true
+		}
+	}
+	return oldest, found
+}
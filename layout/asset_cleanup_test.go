@@ -0,0 +1,139 @@
+package layout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+var cleanupTestCharacters = []ports.Character{
+	{ID: "zundamon", Name: "ずんだもん", ReferenceURL: "https://example.com/zunda.png", IsDefault: true},
+	{ID: "metan", Name: "めたん", ReferenceURL: "https://example.com/metan.png"},
+}
+
+func TestMangaComposer_CloseDeletesUploads(t *testing.T) {
+	ctx := context.Background()
+	mc, assets := newTestComposer(t, cleanupTestCharacters)
+
+	panels := []ports.Panel{{SpeakerID: "zundamon"}, {SpeakerID: "metan"}}
+	if err := mc.PrepareCharacterResources(ctx, panels); err != nil {
+		t.Fatal(err)
+	}
+	if err := mc.PreparePanelResources(ctx, []ports.Panel{{ReferenceURL: "https://example.com/panel1.png"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(mc.Uploads()); got != 3 {
+		t.Fatalf("Uploads() = %d, want 3", got)
+	}
+
+	if err := mc.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if got := len(assets.Deletes()); got != 3 {
+		t.Errorf("Deletes() = %v, want 3 deletions", assets.Deletes())
+	}
+	if got := len(mc.Uploads()); got != 0 {
+		t.Errorf("Uploads() after Close = %d, want 0", got)
+	}
+}
+
+func TestMangaComposer_SweepWaitsForLeases(t *testing.T) {
+	ctx := context.Background()
+	mc, assets := newTestComposer(t, cleanupTestCharacters)
+	panels := []ports.Panel{{SpeakerID: "zundamon"}}
+
+	release := mc.Lease()
+	if err := mc.PrepareCharacterResources(ctx, panels); err != nil {
+		t.Fatal(err)
+	}
+	uri := mc.GetCharacterResourceURI("zundamon")
+
+	// リース中は期限切れでも削除されず、URI キャッシュからのみ外れます。
+	n, err := mc.Sweep(ctx, 0)
+	if err != nil || n != 0 {
+		t.Fatalf("Sweep during lease = (%d, %v), want (0, nil)", n, err)
+	}
+	if len(assets.Deletes()) != 0 {
+		t.Fatalf("asset deleted while leased: %v", assets.Deletes())
+	}
+	if got := mc.GetCharacterResourceURI("zundamon"); got != "" {
+		t.Errorf("retired URI is still served: %q", got)
+	}
+
+	// リース解放後の Sweep で削除されます。
+	release()
+	if n, err := mc.Sweep(ctx, time.Hour); err != nil || n != 1 {
+		t.Fatalf("Sweep after release = (%d, %v), want (1, nil)", n, err)
+	}
+	if got := assets.Deletes(); len(got) != 1 || got[0] != uri {
+		t.Errorf("Deletes() = %v, want [%s]", got, uri)
+	}
+
+	// 新しいリクエストは再アップロードします。
+	if err := mc.PrepareCharacterResources(ctx, panels); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(assets.Uploads()); got != 2 {
+		t.Errorf("Uploads() = %d, want a re-upload after sweeping", got)
+	}
+}
+
+func TestMangaComposer_SweepKeepsFreshUploads(t *testing.T) {
+	ctx := context.Background()
+	mc, assets := newTestComposer(t, cleanupTestCharacters)
+	if err := mc.PrepareCharacterResources(ctx, []ports.Panel{{SpeakerID: "zundamon"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := mc.Sweep(ctx, time.Hour); err != nil || n != 0 {
+		t.Fatalf("Sweep = (%d, %v), want (0, nil)", n, err)
+	}
+	if len(assets.Deletes()) != 0 || len(mc.Uploads()) != 1 {
+		t.Errorf("fresh upload was swept: deletes=%v uploads=%v", assets.Deletes(), mc.Uploads())
+	}
+}
+
+func TestMangaComposer_CloseWaitsForInFlightLease(t *testing.T) {
+	mc, assets := newTestComposer(t, cleanupTestCharacters)
+	release := mc.Lease()
+	if err := mc.PrepareCharacterResources(context.Background(), []ports.Panel{{SpeakerID: "zundamon"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- mc.Close(context.Background()) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Close returned before the lease was released: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if len(assets.Deletes()) != 0 {
+		t.Fatal("asset deleted while leased")
+	}
+
+	release()
+	if err := <-done; err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if len(assets.Deletes()) != 1 {
+		t.Errorf("Deletes() = %v, want 1 deletion", assets.Deletes())
+	}
+}
+
+func TestMangaComposer_CloseRespectsContext(t *testing.T) {
+	mc, _ := newTestComposer(t, cleanupTestCharacters)
+	release := mc.Lease()
+	defer release()
+	if err := mc.PrepareCharacterResources(context.Background(), []ports.Panel{{SpeakerID: "zundamon"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := mc.Close(ctx); err == nil {
+		t.Error("expected Close to fail while a lease is held past the deadline")
+	}
+}
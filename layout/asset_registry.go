@@ -0,0 +1,151 @@
+package layout
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// DefaultAssetRefreshMargin は、File API URI の期限切れに先立って再アップロードを始める余裕です。
+// 長時間のページ生成の途中で URI が失効しないよう、期限の数時間前から新しい URI に切り替えます。
+const DefaultAssetRefreshMargin = 6 * time.Hour
+
+// cachedURI は resourceMap にキャッシュされた URI を返します。
+// 期限切れが近い URI はキャッシュミスとして扱い、再アップロードさせます。
+func (mc *MangaComposer) cachedURI(resourceMap map[string]string, key string) (string, bool) {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+	uri, ok := resourceMap[key]
+	if !ok || uri == "" {
+		return uri, ok
+	}
+	if u, tracked := mc.uploads[uri]; tracked && u.expiring(time.Now(), mc.refreshMargin) {
+		return "", false
+	}
+	return uri, true
+}
+
+// storeUpload は asset を resourceMap[key] に記録し、追跡対象に加えます。
+// 置き換えられる URI が期限切れ間近であれば、削除は試みずに追跡対象から外します。
+func (mc *MangaComposer) storeUpload(resourceMap map[string]string, key string, asset UploadedAsset) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if prev, ok := resourceMap[key]; ok && prev != asset.FileAPIURI {
+		if u, tracked := mc.uploads[prev]; tracked && u.expiring(time.Now(), mc.refreshMargin) {
+			delete(mc.uploads, prev)
+		}
+	}
+	resourceMap[key] = asset.FileAPIURI
+	mc.trackUpload(asset)
+}
+
+// contentHash は参照画像の SHA-256 を返します。読み込み済みの data があればそれを使い、
+// なければ（前処理の上限を超える場合など）読み込み元から算出します。レジストリまたは
+// 読み込み元が未設定の場合、および読み込みに失敗した場合は空文字列を返します（その場合、
+// 内容の変化は検出されません）。
+func (mc *MangaComposer) contentHash(ctx context.Context, referenceURL string, data []byte) string {
+	if mc.registry == nil || mc.source == nil {
+		return ""
+	}
+	if data != nil {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:])
+	}
+	hash, err := hashContent(ctx, mc.source, referenceURL)
+	if err != nil {
+		slog.WarnContext(ctx, "参照画像のハッシュ算出に失敗しました", "reference_url", referenceURL, "error", err)
+		return ""
+	}
+	return hash
+}
+
+func hashContent(ctx context.Context, reader ports.ContentReader, referenceURL string) (string, error) {
+	rc, err := reader.Open(ctx, referenceURL)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return "", fmt.Errorf("読み込みに失敗しました: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// registryKey は、resourceType の参照画像 referenceURL のレジストリのキーを返します。
+// 前処理の設定が種類ごとに異なるため、同じ URL でもキャラクターとパネルは別に記録します。
+func registryKey(resourceType, referenceURL string) string {
+	return resourceType + "|" + referenceURL
+}
+
+// registryVariant は、resourceType の参照画像をアップロードする際の前処理の設定のキーを返します。
+// 前処理が無効な場合は空文字列です。
+func (mc *MangaComposer) registryVariant(resourceType string) string {
+	if mc.preprocess == nil || mc.source == nil {
+		return ""
+	}
+	return mc.preprocess.options(resourceType).Key()
+}
+
+// lookupRegistry は、再利用できるレジストリの記録を返します。期限切れが近い記録や、
+// 参照画像の内容・前処理の設定が変わった記録は再利用しません。レジストリのエラーはログに記録し、
+// アップロードで代替します。
+func (mc *MangaComposer) lookupRegistry(ctx context.Context, resourceType, referenceURL, contentHash string) (ports.AssetRecord, bool) {
+	if mc.registry == nil {
+		return ports.AssetRecord{}, false
+	}
+	rec, ok, err := mc.registry.Get(ctx, registryKey(resourceType, referenceURL))
+	if err != nil {
+		slog.WarnContext(ctx, "アセットレジストリの参照に失敗しました", "reference_url", referenceURL, "error", err)
+		return ports.AssetRecord{}, false
+	}
+	if !ok || rec.FileAPIURI == "" {
+		return ports.AssetRecord{}, false
+	}
+	if rec.Expired(time.Now(), mc.refreshMargin) {
+		slog.DebugContext(ctx, "File API URI の期限が近いため再アップロードします", "reference_url", referenceURL, "expires_at", rec.ExpiresAt)
+		return ports.AssetRecord{}, false
+	}
+	if contentHash != "" && rec.ContentHash != "" && contentHash != rec.ContentHash {
+		slog.InfoContext(ctx, "参照画像が更新されたため再アップロードします", "reference_url", referenceURL)
+		return ports.AssetRecord{}, false
+	}
+	if variant := mc.registryVariant(resourceType); rec.Variant != variant {
+		slog.InfoContext(ctx, "前処理の設定が変わったため再アップロードします", "reference_url", referenceURL, "variant", variant)
+		return ports.AssetRecord{}, false
+	}
+	return rec, true
+}
+
+// putRegistry はアップロード結果をレジストリに記録します。失敗はログに記録するのみです。
+func (mc *MangaComposer) putRegistry(ctx context.Context, rec ports.AssetRecord) {
+	if mc.registry == nil {
+		return
+	}
+	if err := mc.registry.Put(ctx, rec); err != nil {
+		slog.WarnContext(ctx, "アセットレジストリの更新に失敗しました", "reference_url", rec.ReferenceURL, "error", err)
+	}
+}
+
+// forgetRegistry は、削除したアセットを指しているレジストリの記録を取り除きます。
+// 他のプロセスが既に新しい URI を記録している場合は何もしません。
+func (mc *MangaComposer) forgetRegistry(ctx context.Context, asset UploadedAsset) {
+	if mc.registry == nil {
+		return
+	}
+	key := registryKey(asset.ResourceType, asset.ReferenceURL)
+	rec, ok, err := mc.registry.Get(ctx, key)
+	if err == nil && ok && rec.FileAPIURI == asset.FileAPIURI {
+		err = mc.registry.Delete(ctx, key)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "アセットレジストリの更新に失敗しました", "reference_url", asset.ReferenceURL, "error", err)
+	}
+}
@@ -0,0 +1,221 @@
+package layout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shouni/go-manga-kit/asset"
+	"github.com/shouni/go-manga-kit/imaging"
+	"github.com/shouni/go-manga-kit/mangakittest"
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+const registryTestURL = "https://example.com/zunda.png"
+
+var registryTestCharacters = []ports.Character{
+	{ID: "zundamon", Name: "ずんだもん", ReferenceURL: registryTestURL, IsDefault: true},
+}
+
+func TestMangaComposer_RegistryReusedAcrossRestarts(t *testing.T) {
+	ctx := context.Background()
+	registry := asset.NewMemoryRegistry()
+	source := &mangakittest.ContentReader{}
+	source.SetFile(registryTestURL, []byte("v1"))
+	panels := []ports.Panel{{SpeakerID: "zundamon"}}
+
+	first, firstAssets := newTestComposer(t, registryTestCharacters, WithAssetRegistry(registry), WithAssetSource(source))
+	if err := first.PrepareCharacterResources(ctx, panels); err != nil {
+		t.Fatal(err)
+	}
+	uri := first.GetCharacterResourceURI("zundamon")
+	if len(firstAssets.Uploads()) != 1 {
+		t.Fatalf("first Uploads() = %v, want 1 upload", firstAssets.Uploads())
+	}
+
+	// レジストリに記録されたアセットは Close で削除されません。
+	if err := first.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(firstAssets.Deletes()) != 0 {
+		t.Errorf("Close deleted registry-backed assets: %v", firstAssets.Deletes())
+	}
+
+	second, secondAssets := newTestComposer(t, registryTestCharacters, WithAssetRegistry(registry), WithAssetSource(source))
+	if err := second.PrepareCharacterResources(ctx, panels); err != nil {
+		t.Fatal(err)
+	}
+	if len(secondAssets.Uploads()) != 0 {
+		t.Errorf("restarted composer re-uploaded: %v", secondAssets.Uploads())
+	}
+	if got := second.GetCharacterResourceURI("zundamon"); got != uri {
+		t.Errorf("URI = %q, want the registered %q", got, uri)
+	}
+}
+
+func TestMangaComposer_RegistryReuploadsChangedSource(t *testing.T) {
+	ctx := context.Background()
+	registry := asset.NewMemoryRegistry()
+	source := &mangakittest.ContentReader{}
+	source.SetFile(registryTestURL, []byte("v1"))
+	panels := []ports.Panel{{SpeakerID: "zundamon"}}
+
+	first, _ := newTestComposer(t, registryTestCharacters, WithAssetRegistry(registry), WithAssetSource(source))
+	if err := first.PrepareCharacterResources(ctx, panels); err != nil {
+		t.Fatal(err)
+	}
+	before, _, _ := registry.Get(ctx, registryKey("character", registryTestURL))
+
+	source.SetFile(registryTestURL, []byte("v2"))
+	second, secondAssets := newTestComposer(t, registryTestCharacters, WithAssetRegistry(registry), WithAssetSource(source))
+	if err := second.PrepareCharacterResources(ctx, panels); err != nil {
+		t.Fatal(err)
+	}
+	if len(secondAssets.Uploads()) != 1 {
+		t.Fatalf("Uploads() = %v, want a re-upload after the source changed", secondAssets.Uploads())
+	}
+	after, _, _ := registry.Get(ctx, registryKey("character", registryTestURL))
+	if after.ContentHash == before.ContentHash || after.ContentHash == "" {
+		t.Errorf("ContentHash = %q, want a new hash (was %q)", after.ContentHash, before.ContentHash)
+	}
+}
+
+func TestMangaComposer_RegistrySeparatesResourceTypes(t *testing.T) {
+	ctx := context.Background()
+	registry := asset.NewMemoryRegistry()
+	source := &mangakittest.ContentReader{}
+	source.SetFile(registryTestURL, pngBytes(t, 400, 200))
+	preprocess := ReferencePreprocessing{
+		Character:  imaging.Options{MaxEdge: 100, AspectRatio: "1:1"},
+		Panel:      imaging.Options{MaxEdge: 200, AspectRatio: "16:9"},
+		Writer:     &mangakittest.Writer{},
+		StagingDir: "gs://staging/refs",
+	}
+	// 同じ URL をキャラクターとパネルの両方の参照画像に使います。
+	panels := []ports.Panel{{SpeakerID: "zundamon", ReferenceURL: registryTestURL}}
+
+	first, _ := newTestComposer(t, registryTestCharacters, WithAssetRegistry(registry), WithAssetSource(source), WithReferencePreprocessing(preprocess))
+	if err := first.PrepareCharacterResources(ctx, panels); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.PreparePanelResources(ctx, panels); err != nil {
+		t.Fatal(err)
+	}
+	charURI := first.GetCharacterResourceURI("zundamon")
+	panelURI := first.GetPanelResourceURI(registryTestURL)
+	if charURI == panelURI {
+		t.Fatalf("character and panel share %q, want separate variants", charURI)
+	}
+
+	second, secondAssets := newTestComposer(t, registryTestCharacters, WithAssetRegistry(registry), WithAssetSource(source), WithReferencePreprocessing(preprocess))
+	if err := second.PrepareCharacterResources(ctx, panels); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.PreparePanelResources(ctx, panels); err != nil {
+		t.Fatal(err)
+	}
+	if len(secondAssets.Uploads()) != 0 {
+		t.Errorf("restarted composer re-uploaded: %v", secondAssets.Uploads())
+	}
+	if second.GetCharacterResourceURI("zundamon") != charURI || second.GetPanelResourceURI(registryTestURL) != panelURI {
+		t.Errorf("URIs = %q/%q, want %q/%q", second.GetCharacterResourceURI("zundamon"),
+			second.GetPanelResourceURI(registryTestURL), charURI, panelURI)
+	}
+}
+
+func TestMangaComposer_RegistryReuploadsChangedPreprocessing(t *testing.T) {
+	ctx := context.Background()
+	registry := asset.NewMemoryRegistry()
+	source := &mangakittest.ContentReader{}
+	source.SetFile(registryTestURL, pngBytes(t, 400, 200))
+	panels := []ports.Panel{{SpeakerID: "zundamon"}}
+	preprocess := func(maxEdge int) ComposerOption {
+		return WithReferencePreprocessing(ReferencePreprocessing{
+			Character:  imaging.Options{MaxEdge: maxEdge},
+			Writer:     &mangakittest.Writer{},
+			StagingDir: "gs://staging/refs",
+		})
+	}
+
+	first, _ := newTestComposer(t, registryTestCharacters, WithAssetRegistry(registry), WithAssetSource(source), preprocess(100))
+	if err := first.PrepareCharacterResources(ctx, panels); err != nil {
+		t.Fatal(err)
+	}
+
+	// 前処理の設定を変えて再起動した場合は、以前の設定の画像を再利用しません。
+	second, secondAssets := newTestComposer(t, registryTestCharacters, WithAssetRegistry(registry), WithAssetSource(source), preprocess(200))
+	if err := second.PrepareCharacterResources(ctx, panels); err != nil {
+		t.Fatal(err)
+	}
+	if len(secondAssets.Uploads()) != 1 {
+		t.Fatalf("Uploads() = %v, want a re-upload after the preprocessing changed", secondAssets.Uploads())
+	}
+	rec, _, _ := registry.Get(ctx, registryKey("character", registryTestURL))
+	if want := (imaging.Options{MaxEdge: 200}).Key(); rec.Variant != want {
+		t.Errorf("Variant = %q, want %q", rec.Variant, want)
+	}
+
+	// 前処理を無効にした場合も、前処理した画像を再利用しません。
+	third, thirdAssets := newTestComposer(t, registryTestCharacters, WithAssetRegistry(registry), WithAssetSource(source))
+	if err := third.PrepareCharacterResources(ctx, panels); err != nil {
+		t.Fatal(err)
+	}
+	if len(thirdAssets.Uploads()) != 1 || thirdAssets.Uploads()[0] != registryTestURL {
+		t.Errorf("Uploads() = %v, want the original reference", thirdAssets.Uploads())
+	}
+}
+
+func TestMangaComposer_ReuploadsNearExpiry(t *testing.T) {
+	ctx := context.Background()
+	registry := asset.NewMemoryRegistry()
+	panels := []ports.Panel{{SpeakerID: "zundamon"}}
+
+	// 期限切れ間近の記録はレジストリから再利用しません。
+	stale := ports.AssetRecord{
+		ReferenceURL: registryKey("character", registryTestURL),
+		FileAPIURI:   "https://file-api/stale",
+		UploadedAt:   time.Now().Add(-47 * time.Hour),
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}
+	if err := registry.Put(ctx, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	// TTL が余裕より短いため、プロセス内のキャッシュも毎回期限切れ間近として扱われます。
+	mc, assets := newTestComposer(t, registryTestCharacters, WithAssetRegistry(registry), WithAssetTTL(time.Hour), WithAssetRefreshMargin(2*time.Hour))
+	for range 2 {
+		if err := mc.PrepareCharacterResources(ctx, panels); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(assets.Uploads()); got != 2 {
+		t.Errorf("Uploads() = %d, want 2 (each lookup must refresh an expiring URI)", got)
+	}
+	if got := len(mc.Uploads()); got != 1 {
+		t.Errorf("tracked uploads = %d, want the replaced URI to be dropped", got)
+	}
+	rec, _, _ := registry.Get(ctx, registryKey("character", registryTestURL))
+	if rec.FileAPIURI == stale.FileAPIURI {
+		t.Error("registry still points to the stale URI")
+	}
+}
+
+func TestMangaComposer_SweepForgetsRegistryRecords(t *testing.T) {
+	ctx := context.Background()
+	registry := asset.NewMemoryRegistry()
+	mc, assets := newTestComposer(t, registryTestCharacters, WithAssetRegistry(registry))
+	if err := mc.PrepareCharacterResources(ctx, []ports.Panel{{SpeakerID: "zundamon"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := mc.Sweep(ctx, 0)
+	if err != nil || n != 1 {
+		t.Fatalf("Sweep = (%d, %v), want (1, nil)", n, err)
+	}
+	if len(assets.Deletes()) != 1 {
+		t.Errorf("Deletes() = %v, want 1", assets.Deletes())
+	}
+	if _, ok, _ := registry.Get(ctx, registryKey("character", registryTestURL)); ok {
+		t.Error("swept asset is still in the registry")
+	}
+}
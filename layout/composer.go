@@ -5,11 +5,15 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	imagePorts "github.com/shouni/gemini-image-kit/ports"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/sync/singleflight"
 
+	"github.com/shouni/go-manga-kit/internal/apierr"
+	"github.com/shouni/go-manga-kit/internal/deadline"
+	"github.com/shouni/go-manga-kit/internal/telemetry"
 	"github.com/shouni/go-manga-kit/ports"
 )
 
@@ -21,6 +25,39 @@ type MangaComposer struct {
 	resourceMap     resourceMap
 	mu              sync.RWMutex
 	uploadGroup     singleflight.Group
+	metrics         ports.MetricsRecorder
+	uploadTimeout   time.Duration
+
+	// registry はプロセスを跨いでアップロード結果を再利用するための記録です（任意）。
+	registry ports.AssetRegistry
+	// source は参照画像の内容ハッシュの算出と事前検査に使う読み込み元です（任意）。
+	source ports.ContentReader
+	// preflightEnabled が true の場合、Panel/PageGenerator は生成前に Preflight を実行します。
+	preflightEnabled bool
+	preflightLimits  PreflightLimits
+	// referencePolicy はパネルの参照画像（台本由来の URL）に適用する方針です。
+	referencePolicy ports.ReferencePolicy
+	// preprocess は参照画像のアップロード前処理の設定です（nil の場合は前処理しません）。
+	// variants は前処理結果のキー -> File API URI で、mu で保護します。
+	preprocess *ReferencePreprocessing
+	variants   map[string]string
+	// variantGroup は、参照 URL が異なっても前処理結果が同じ画像の同時アップロードを1つに集約します。
+	variantGroup singleflight.Group
+	// assetTTL は File API URI の有効期間、refreshMargin は期限切れ前に再アップロードを始める余裕です。
+	assetTTL      time.Duration
+	refreshMargin time.Duration
+
+	// uploads は File API にアップロードしたアセットの記録です（キーは File API URI）。mu で保護します。
+	uploads map[string]UploadedAsset
+
+	// 以下は削除とリースの管理用で、leaseMu で保護します。
+	leaseMu       sync.Mutex
+	epoch         uint64
+	leases        map[uint64]int
+	pending       []pendingDeletion
+	leaseReleased chan struct{}
+	sweeperStop   chan struct{}
+	sweeperDone   chan struct{}
 }
 
 type resourceMap struct {
@@ -33,6 +70,7 @@ func NewMangaComposer(
 	assetMgr imagePorts.AssetManager,
 	backend imagePorts.Backend,
 	cm *ports.Characters,
+	opts ...ComposerOption,
 ) (*MangaComposer, error) {
 	if assetMgr == nil {
 		return nil, fmt.Errorf("assetMgr is required")
@@ -41,7 +79,7 @@ func NewMangaComposer(
 		return nil, fmt.Errorf("backend is required")
 	}
 
-	return &MangaComposer{
+	mc := &MangaComposer{
 		AssetManager:    assetMgr,
 		BackendProvider: backend,
 		CharactersMap:   cm,
@@ -49,7 +87,20 @@ func NewMangaComposer(
 			character: make(map[string]string),
 			panel:     make(map[string]string),
 		},
-	}, nil
+		metrics:         ports.NopMetrics{},
+		assetTTL:        ports.DefaultFileAPITTL,
+		refreshMargin:   DefaultAssetRefreshMargin,
+		preflightLimits: DefaultPreflightLimits(),
+		referencePolicy: ports.DefaultReferencePolicy(),
+		uploads:         make(map[string]UploadedAsset),
+		variants:        make(map[string]string),
+		leases:          make(map[uint64]int),
+		leaseReleased:   make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(mc)
+	}
+	return mc, nil
 }
 
 // GetCharacterResourceURI はキャラクターの既定参照画像（ReferenceURL）の画像URIを取得します。
@@ -83,6 +134,22 @@ func (mc *MangaComposer) getReferenceResourceURI(referenceURL string) string {
 func (mc *MangaComposer) GetPanelResourceURI(referenceURL string) string {
 	mc.mu.RLock()
 	defer mc.mu.RUnlock()
+	return mc.panelURILocked(referenceURL)
+}
+
+// characterURILocked はキャラクターの既定参照画像の画像URIを返します。呼び出し側で mc.mu を
+// 保持している必要があります。RWMutex の読み取りロックは再入できない（待機中の書き込みがあると
+// 2回目の RLock がブロックする）ため、ロック中はこちらを使ってください。
+func (mc *MangaComposer) characterURILocked(charID string) string {
+	char := mc.CharactersMap.GetCharacterWithDefault(charID)
+	if char == nil {
+		return ""
+	}
+	return mc.resourceMap.character[char.ReferenceURL]
+}
+
+// panelURILocked はパネルの画像URIを返します。呼び出し側で mc.mu を保持している必要があります。
+func (mc *MangaComposer) panelURILocked(referenceURL string) string {
 	return mc.resourceMap.panel[referenceURL]
 }
 
@@ -90,17 +157,17 @@ func (mc *MangaComposer) GetPanelResourceURI(referenceURL string) string {
 // します。各キャラクターの ReferenceURL（既定のフォールバック）と ReferenceURLs（アスペクト比別）の
 // 両方に含まれる参照画像URLをすべて対象にします。
 func (mc *MangaComposer) PrepareCharacterResources(ctx context.Context, panels []ports.Panel) error {
-	targets := make(map[string]string)
+	targets := make(map[string]string) // ReferenceURL -> CharacterID
 	addCharacterURLs := func(char *ports.Character) {
 		if char == nil {
 			return
 		}
 		if char.ReferenceURL != "" {
-			targets[char.ReferenceURL] = char.ReferenceURL
+			targets[char.ReferenceURL] = char.ID
 		}
 		for _, url := range char.ReferenceURLs {
 			if url != "" {
-				targets[url] = url
+				targets[url] = char.ID
 			}
 		}
 	}
@@ -117,6 +184,8 @@ func (mc *MangaComposer) PrepareCharacterResources(ctx context.Context, panels [
 }
 
 // PreparePanelResources は各パネル固有の ReferenceURL を事前アップロードします。
+// ReferenceURL は台本（AI の出力を含む）に由来するため、取得の前に参照方針
+// （WithReferencePolicy）で検査し、違反する URL は取得せずにエラーとします。
 func (mc *MangaComposer) PreparePanelResources(ctx context.Context, panels []ports.Panel) error {
 	targets := make(map[string]string)
 
@@ -124,38 +193,54 @@ func (mc *MangaComposer) PreparePanelResources(ctx context.Context, panels []por
 		if panel.ReferenceURL == "" {
 			continue
 		}
-		targets[panel.ReferenceURL] = panel.ReferenceURL
+		targets[panel.ReferenceURL] = ""
 	}
 
-	return mc.prepareResources(ctx, targets, func(ctx context.Context, key, _ string) (string, error) {
-		return mc.getOrUploadPanelAsset(ctx, key)
+	return mc.prepareResources(ctx, targets, func(ctx context.Context, referenceURL string) (string, error) {
+		if err := mc.referencePolicy.Check(ctx, referenceURL); err != nil {
+			return "", err
+		}
+		return mc.getOrUploadPanelAsset(ctx, referenceURL)
 	}, "panel")
 }
 
 // getOrUploadAsset はキャラクター用アセットをキャッシュ制御しつつ取得またはアップロードします。
-func (mc *MangaComposer) getOrUploadAsset(ctx context.Context, charID, referenceURL string) (string, error) {
-	return mc.getOrUploadResource(ctx, charID, referenceURL, mc.resourceMap.character)
+func (mc *MangaComposer) getOrUploadAsset(ctx context.Context, referenceURL string) (string, error) {
+	return mc.getOrUploadResource(ctx, referenceURL, referenceURL, mc.resourceMap.character, "character")
 }
 
 // getOrUploadPanelAsset はパネル用参照URLをキャッシュ制御しつつ取得またはアップロードします。
 func (mc *MangaComposer) getOrUploadPanelAsset(ctx context.Context, referenceURL string) (string, error) {
 	// パネルアセットの場合、検索キーとソースURLは同一です。
-	return mc.getOrUploadResource(ctx, referenceURL, referenceURL, mc.resourceMap.panel)
+	return mc.getOrUploadResource(ctx, referenceURL, referenceURL, mc.resourceMap.panel, "panel")
 }
 
 // prepareResources は指定されたリソースを事前アップロードします。
+// targets は ReferenceURL から、その画像を持つキャラクターのID（パネル画像の場合は空）への対応です。
+// 失敗した場合は *ports.AssetError を返します。
 func (mc *MangaComposer) prepareResources(
 	ctx context.Context,
 	targets map[string]string,
-	upload func(context.Context, string, string) (string, error),
+	upload func(context.Context, string) (string, error),
 	resourceType string,
-) error {
+) (err error) {
+	ctx, span := telemetry.Start(ctx, "manga.assets.prepare",
+		telemetry.AttrResourceType.String(resourceType),
+		telemetry.AttrAssetCount.Int(len(targets)),
+	)
+	defer func() { telemetry.End(span, err) }()
+
 	eg, egCtx := errgroup.WithContext(ctx)
 
-	for key, referenceURL := range targets {
+	for referenceURL, charID := range targets {
 		eg.Go(func() error {
-			if _, err := upload(egCtx, key, referenceURL); err != nil {
-				return fmt.Errorf("%s resource preparation failed for '%s': %w", resourceType, key, err)
+			if _, err := upload(egCtx, referenceURL); err != nil {
+				return &ports.AssetError{
+					ResourceType: resourceType,
+					ReferenceURL: referenceURL,
+					CharacterID:  charID,
+					Err:          apierr.Classify(err),
+				}
 			}
 			return nil
 		})
@@ -165,7 +250,10 @@ func (mc *MangaComposer) prepareResources(
 }
 
 // getOrUploadResource は二重チェックロッキングと singleflight を用いてアセットアップロードの共通ロジックを提供します。
-func (mc *MangaComposer) getOrUploadResource(ctx context.Context, key, referenceURL string, resourceMap map[string]string) (string, error) {
+func (mc *MangaComposer) getOrUploadResource(ctx context.Context, key, referenceURL string, resourceMap map[string]string, resourceType string) (_ string, err error) {
+	ctx, span := telemetry.Start(ctx, "manga.asset.upload", telemetry.AttrReferenceURL.String(referenceURL))
+	defer func() { telemetry.End(span, err) }()
+
 	// Vertex AI モード時は Cloud Storage (gs://) を直接参照可能なため、
 	// File API へのアップロード処理をバイパスし、転送コストを削減します。
 	if mc.BackendProvider.IsVertexAI() && IsGCSURI(referenceURL) {
@@ -178,37 +266,83 @@ func (mc *MangaComposer) getOrUploadResource(ctx context.Context, key, reference
 			resourceMap[key] = ""
 			mc.mu.Unlock()
 		}
+		span.SetAttributes(telemetry.AttrCacheHit.Bool(true))
 		return "", nil
 	}
 
 	// 最初のチェック: ロックを最小限にするための RLock
-	mc.mu.RLock()
-	uri, ok := resourceMap[key]
-	mc.mu.RUnlock()
+	uri, ok := mc.cachedURI(resourceMap, key)
+	span.SetAttributes(telemetry.AttrCacheHit.Bool(ok))
+	mc.metrics.IncCacheLookup(ports.CacheAsset, ok)
 	if ok {
 		return uri, nil
 	}
 
 	// 同一キーに対する同時リクエストを1つに集約（HTTP URL等の場合のみ）
-	val, err, _ := mc.uploadGroup.Do(key, func() (interface{}, error) {
-		mc.mu.RLock()
-		existingURI, ok := resourceMap[key]
-		mc.mu.RUnlock()
-		if ok {
+	val, err, shared := mc.uploadGroup.Do(key, func() (interface{}, error) {
+		if existingURI, ok := mc.cachedURI(resourceMap, key); ok {
 			return existingURI, nil
 		}
 
-		// ここで実際に File API (Google AI Studio) へアップロードされる
-		uploadedURI, uploadErr := mc.AssetManager.UploadFile(ctx, referenceURL)
+		// 参照画像は1度だけ読み込み、ハッシュの算出と前処理で共有します。
+		data := mc.loadReference(ctx, referenceURL)
+
+		// レジストリに有効な記録があれば、アップロードせずに再利用します。
+		contentHash := mc.contentHash(ctx, referenceURL, data)
+		if rec, ok := mc.lookupRegistry(ctx, resourceType, referenceURL, contentHash); ok {
+			mc.storeUpload(resourceMap, key, UploadedAsset{
+				FileAPIURI:   rec.FileAPIURI,
+				ReferenceURL: referenceURL,
+				ResourceType: resourceType,
+				UploadedAt:   rec.UploadedAt,
+				ExpiresAt:    rec.ExpiresAt,
+				Persistent:   true,
+			})
+			return rec.FileAPIURI, nil
+		}
+
+		// 前処理が有効な場合は正規化した画像をアップロードします。同じ内容の画像が
+		// アップロード済みであれば、その URI を共有します。
+		variantKey, reused := mc.preprocessReference(resourceType, data)
+		if reused != nil {
+			shared := *reused
+			shared.ReferenceURL, shared.ResourceType = referenceURL, resourceType
+			mc.storeUpload(resourceMap, key, shared)
+			return shared.FileAPIURI, nil
+		}
+
+		uploadedURI, preprocessed, uploadErr := mc.uploadReference(ctx, referenceURL, resourceType, variantKey, data)
 		if uploadErr != nil {
 			return nil, uploadErr
 		}
 
-		mc.mu.Lock()
-		resourceMap[key] = uploadedURI
-		mc.mu.Unlock()
+		uploaded := UploadedAsset{
+			FileAPIURI:   uploadedURI,
+			ReferenceURL: referenceURL,
+			ResourceType: resourceType,
+			UploadedAt:   time.Now(),
+			Persistent:   mc.registry != nil,
+		}
+		uploaded.ExpiresAt = uploaded.UploadedAt.Add(mc.assetTTL)
+
+		mc.storeUpload(resourceMap, key, uploaded)
+
+		// 前処理に失敗して元の画像をアップロードした場合は、設定のキーを記録しません。
+		var variant string
+		if preprocessed {
+			variant = mc.registryVariant(resourceType)
+		}
+		mc.putRegistry(ctx, ports.AssetRecord{
+			ReferenceURL: registryKey(resourceType, referenceURL),
+			ContentHash:  contentHash,
+			Variant:      variant,
+			FileAPIURI:   uploadedURI,
+			UploadedAt:   uploaded.UploadedAt,
+			ExpiresAt:    uploaded.ExpiresAt,
+		})
 		return uploadedURI, nil
 	})
+	span.SetAttributes(telemetry.AttrSingleflightHit.Bool(shared))
 
 	if err != nil {
 		return "", err
@@ -216,3 +350,65 @@ func (mc *MangaComposer) getOrUploadResource(ctx context.Context, key, reference
 
 	return val.(string), nil
 }
+
+// uploadReference は参照画像を File API にアップロードします。variantKey が設定されている場合は、
+// 同じ前処理結果の同時アップロードを1つに集約し、アップロード済みであればその URI を返します。
+// 未アップロードであれば data を正規化して保存し、保存先をアップロードしてから削除します。
+// preprocessed は前処理した画像の URI であるかどうかで、正規化に失敗した場合は元の参照画像を
+// アップロードして false を返します。
+func (mc *MangaComposer) uploadReference(ctx context.Context, referenceURL, resourceType, variantKey string, data []byte) (uri string, preprocessed bool, err error) {
+	if variantKey == "" {
+		uri, err = mc.uploadFile(ctx, referenceURL, referenceURL, resourceType)
+		return uri, false, err
+	}
+
+	type variantUpload struct {
+		uri          string
+		preprocessed bool
+	}
+	val, err, _ := mc.variantGroup.Do(variantKey, func() (interface{}, error) {
+		if uri, ok := mc.uploadedVariant(variantKey); ok {
+			return variantUpload{uri: uri, preprocessed: true}, nil
+		}
+		staged, ok := mc.stageVariant(ctx, referenceURL, resourceType, variantKey, data)
+		if !ok {
+			uri, err := mc.uploadFile(ctx, referenceURL, referenceURL, resourceType)
+			if err != nil {
+				return nil, err
+			}
+			return variantUpload{uri: uri}, nil
+		}
+		defer mc.removeStaged(ctx, staged)
+
+		uri, err := mc.uploadFile(ctx, staged, referenceURL, resourceType)
+		if err != nil {
+			return nil, err
+		}
+		mc.rememberVariant(variantKey, uri)
+		return variantUpload{uri: uri, preprocessed: true}, nil
+	})
+	if err != nil {
+		return "", false, err
+	}
+	result := val.(variantUpload)
+	return result.uri, result.preprocessed, nil
+}
+
+// uploadFile は source を File API にアップロードします。referenceURL は進捗の通知に使う元の参照画像です。
+func (mc *MangaComposer) uploadFile(ctx context.Context, source, referenceURL, resourceType string) (string, error) {
+	// ここで実際に File API (Google AI Studio) へアップロードされる
+	startTime := time.Now()
+	uri, err := deadline.Call(ctx, ports.StageUpload, mc.uploadTimeout, func(ctx context.Context) (string, error) {
+		return mc.AssetManager.UploadFile(ctx, source)
+	})
+	if err != nil {
+		return "", err
+	}
+	mc.metrics.IncAssetUpload(resourceType)
+	emitProgress(ctx, ports.ProgressEvent{
+		Kind:         ports.ProgressAssetUploaded,
+		ReferenceURL: referenceURL,
+		Duration:     time.Since(startTime),
+	})
+	return uri, nil
+}
@@ -2,46 +2,18 @@ package layout
 
 import (
 	"context"
-	"sync/atomic"
+	"errors"
 	"testing"
 
 	characterkit "github.com/shouni/go-character-kit/character"
+	"github.com/shouni/go-manga-kit/mangakittest"
 	"github.com/shouni/go-manga-kit/ports"
 )
 
-// --- Mocks ---
-
-type mockAssetManager struct {
-	uploadCount int32
-	deleteCount int32
-	uploadFunc  func(ctx context.Context, refURL string) (string, error)
-}
-
-func (m *mockAssetManager) UploadFile(ctx context.Context, refURL string) (string, error) {
-	atomic.AddInt32(&m.uploadCount, 1)
-	if m.uploadFunc != nil {
-		return m.uploadFunc(ctx, refURL)
-	}
-	return "https://file-api.google.com/" + refURL, nil
-}
-
-func (m *mockAssetManager) DeleteFile(_ context.Context, _ string) error {
-	atomic.AddInt32(&m.deleteCount, 1)
-	return nil
-}
-
-type mockBackend struct {
-	isVertex bool
-}
-
-func (m *mockBackend) IsVertexAI() bool { return m.isVertex }
-
-// --- Tests ---
-
 func TestMangaComposer_PrepareCharacterResources(t *testing.T) {
 	ctx := context.Background()
-	assetMgr := &mockAssetManager{}
-	backend := &mockBackend{isVertex: false}
+	assetMgr := &mangakittest.AssetManager{}
+	backend := &mangakittest.Backend{VertexAI: false}
 
 	cm, err := characterkit.NewCharacters([]ports.Character{
 		{
@@ -81,8 +53,8 @@ func TestMangaComposer_PrepareCharacterResources(t *testing.T) {
 		t.Error("default character (metan) resource not cached")
 	}
 
-	if assetMgr.uploadCount != 2 {
-		t.Errorf("Expected 2 uploads, got %d", assetMgr.uploadCount)
+	if len(assetMgr.Uploads()) != 2 {
+		t.Errorf("Expected 2 uploads, got %d", len(assetMgr.Uploads()))
 	}
 }
 
@@ -91,8 +63,8 @@ func TestMangaComposer_PrepareCharacterResources(t *testing.T) {
 // GetCharacterResourceURIFor resolves the aspect-ratio-specific variant when present.
 func TestMangaComposer_PrepareCharacterResourcesUploadsAllAspectRatioVariants(t *testing.T) {
 	ctx := context.Background()
-	assetMgr := &mockAssetManager{}
-	backend := &mockBackend{isVertex: false}
+	assetMgr := &mangakittest.AssetManager{}
+	backend := &mangakittest.Backend{VertexAI: false}
 
 	cm, err := characterkit.NewCharacters([]ports.Character{
 		{
@@ -124,7 +96,32 @@ func TestMangaComposer_PrepareCharacterResourcesUploadsAllAspectRatioVariants(t
 		t.Error("GetCharacterResourceURIFor should fall back to ReferenceURL when no 9:16 entry exists")
 	}
 
-	if assetMgr.uploadCount != 2 {
-		t.Errorf("Expected 2 uploads (default + 1:1 variant), got %d", assetMgr.uploadCount)
+	if len(assetMgr.Uploads()) != 2 {
+		t.Errorf("Expected 2 uploads (default + 1:1 variant), got %d", len(assetMgr.Uploads()))
+	}
+}
+
+func TestMangaComposer_PrepareCharacterResourcesAssetError(t *testing.T) {
+	cm, err := characterkit.NewCharacters([]ports.Character{
+		{ID: "zundamon", Name: "ずんだもん", ReferenceURL: "https://example.com/zunda.png", IsDefault: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploadErr := errors.New("upload refused")
+	assetMgr := &mangakittest.AssetManager{UploadErr: uploadErr}
+	mc, _ := NewMangaComposer(assetMgr, &mangakittest.Backend{}, cm)
+
+	err = mc.PrepareCharacterResources(context.Background(), []ports.Panel{{SpeakerID: "zundamon"}})
+
+	var assetErr *ports.AssetError
+	if !errors.As(err, &assetErr) {
+		t.Fatalf("err = %v, want *ports.AssetError", err)
+	}
+	if assetErr.CharacterID != "zundamon" || assetErr.ReferenceURL != "https://example.com/zunda.png" || assetErr.ResourceType != "character" {
+		t.Errorf("AssetError = %+v", assetErr)
+	}
+	if !errors.Is(err, ports.ErrAssetPreparation) || !errors.Is(err, uploadErr) {
+		t.Errorf("err = %v, want ErrAssetPreparation wrapping the upload error", err)
 	}
 }
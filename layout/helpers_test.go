@@ -0,0 +1,53 @@
+package layout
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	characterkit "github.com/shouni/go-character-kit/character"
+
+	"github.com/shouni/go-manga-kit/mangakittest"
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// newTestComposer は、chars を登録し、フェイクの AssetManager・Backend と名前解決を行わない
+// 参照方針を使う MangaComposer を作成します。opts は既定の設定の後に適用されます。
+func newTestComposer(t *testing.T, chars []ports.Character, opts ...ComposerOption) (*MangaComposer, *mangakittest.AssetManager) {
+	t.Helper()
+	cm, err := characterkit.NewCharacters(chars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assets := &mangakittest.AssetManager{}
+	opts = append([]ComposerOption{WithReferencePolicy(offlineReferencePolicy())}, opts...)
+	mc, err := NewMangaComposer(assets, &mangakittest.Backend{}, cm, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mc, assets
+}
+
+// offlineReferencePolicy は、名前解決を行わない既定の参照方針を返します。
+func offlineReferencePolicy() ports.ReferencePolicy {
+	policy := ports.DefaultReferencePolicy()
+	policy.Resolver = &mangakittest.Resolver{
+		Hosts: map[string][]string{"internal.example.com": {"10.0.0.5"}},
+	}
+	return policy
+}
+
+// pngBytes は w×h の透明な PNG 画像を返します。
+func pngBytes(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func ptrInt64(v int64) *int64 {
+	return &v
+}
@@ -0,0 +1,52 @@
+package layout
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// waiter はレートリミッターの待機を抽象化します。
+type waiter interface {
+	Wait(ctx context.Context) error
+}
+
+// NewRateLimiter は、PanelGenerator・PageGenerator の既定と同じバースト数で interval 間隔の
+// レートリミッターを作成します。interval が 0 以下の場合は既定の間隔を使います。
+// 同じモデルを呼び出す生成処理に WithPanelRateLimiter・WithPageRateLimiter などで共有して渡すと、
+// API のレート制限を工程をまたいで守れます。
+func NewRateLimiter(interval time.Duration) *rate.Limiter {
+	if interval <= 0 {
+		interval = defaultRateInterval
+	}
+	return rate.NewLimiter(rate.Every(interval), defaultRateBurst)
+}
+
+// meteredLimiter は、Wait で待機した時間をメトリクスに記録するレートリミッターです。
+type meteredLimiter struct {
+	limiter *rate.Limiter
+	stage   string
+	metrics ports.MetricsRecorder
+}
+
+// Wait は waiter の実装です。
+func (l meteredLimiter) Wait(ctx context.Context) error {
+	start := time.Now()
+	err := l.limiter.Wait(ctx)
+	l.metrics.ObserveRateLimitWait(l.stage, time.Since(start))
+	return err
+}
+
+// observeGeneration は call の実行中を in-flight として計上し、所要時間を記録します。
+func observeGeneration[T any](metrics ports.MetricsRecorder, stage, model string, call func() (T, error)) (T, error) {
+	metrics.AddInFlight(stage, 1)
+	defer metrics.AddInFlight(stage, -1)
+
+	start := time.Now()
+	res, err := call()
+	metrics.ObserveGeneration(stage, model, time.Since(start))
+	return res, err
+}
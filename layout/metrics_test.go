@@ -0,0 +1,85 @@
+package layout
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	characterkit "github.com/shouni/go-character-kit/character"
+
+	"github.com/shouni/go-manga-kit/mangakittest"
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+func TestPanelGenerator_Metrics(t *testing.T) {
+	cm, err := characterkit.NewCharacters([]ports.Character{
+		{ID: "zundamon", Name: "ずんだもん", ReferenceURL: "https://example.com/zunda.png", IsDefault: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := &mangakittest.Metrics{}
+	composer, _ := NewMangaComposer(&mangakittest.AssetManager{}, &mangakittest.Backend{}, cm,
+		WithComposerMetrics(metrics),
+	)
+	generator := NewPanelGenerator(composer, &mangakittest.ImageGenerator{}, &mangakittest.ImagePrompt{}, "model",
+		WithPanelRateInterval(time.Microsecond),
+		WithPanelRateBurst(100),
+		WithPanelMetrics(metrics),
+	)
+
+	panels := []ports.Panel{{SpeakerID: "zundamon"}, {SpeakerID: "zundamon"}}
+	if _, err := generator.Execute(context.Background(), panels); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	// 2回目の Execute ではアップロード済みの URI がキャッシュから返されます。
+	if _, err := generator.Execute(context.Background(), panels); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if got := metrics.Generations(ports.StagePanel, "model"); got != 4 {
+		t.Errorf("Generations = %d, want 4", got)
+	}
+	if got := metrics.RateLimitWaits(ports.StagePanel); got != 4 {
+		t.Errorf("RateLimitWaits = %d, want 4", got)
+	}
+	if got := metrics.AssetUploads("character"); got != 1 {
+		t.Errorf("AssetUploads = %d, want 1", got)
+	}
+	if hits, misses := metrics.CacheLookups(ports.CacheAsset); hits != 1 || misses != 1 {
+		t.Errorf("CacheLookups = (%d, %d), want (1, 1)", hits, misses)
+	}
+	if got := metrics.InFlight(ports.StagePanel); got != 0 {
+		t.Errorf("InFlight after Execute = %d, want 0", got)
+	}
+	if got := metrics.MaxInFlight(ports.StagePanel); got != 1 {
+		t.Errorf("MaxInFlight = %d, want 1 with the default concurrency", got)
+	}
+}
+
+func TestPageGenerator_FailureMetrics(t *testing.T) {
+	cm, err := characterkit.NewCharacters([]ports.Character{
+		{ID: "zundamon", Name: "ずんだもん", IsDefault: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	composer, _ := NewMangaComposer(&mangakittest.AssetManager{}, &mangakittest.Backend{}, cm)
+
+	metrics := &mangakittest.Metrics{}
+	gen := &mangakittest.ImageGenerator{Err: context.DeadlineExceeded}
+	generator := NewPageGenerator(composer, gen, &mangakittest.ImagePrompt{}, "model",
+		WithPageRateInterval(time.Microsecond),
+		WithPageMetrics(metrics),
+	)
+
+	manga := &ports.MangaResponse{Panels: []ports.Panel{{SpeakerID: "zundamon"}}}
+	if _, err := generator.Execute(context.Background(), manga); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Execute err = %v, want deadline exceeded", err)
+	}
+	if got := metrics.Failures(ports.StagePage, ports.ErrorClassTimeout); got != 1 {
+		t.Errorf("Failures(timeout) = %d, want 1", got)
+	}
+}
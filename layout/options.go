@@ -1,6 +1,12 @@
 package layout
 
-import "time"
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
 
 // --- PanelGenerator Options ---
 
@@ -34,6 +40,50 @@ func WithPanelRateBurst(value int) PanelOption {
 	}
 }
 
+// WithPanelRateLimiter は、パネル生成が使うレートリミッターを設定します。
+// 設定した場合、WithPanelRateInterval・WithPanelRateBurst は無視されます。
+func WithPanelRateLimiter(l *rate.Limiter) PanelOption {
+	return func(g *PanelGenerator) {
+		if l != nil {
+			g.limiter = l
+		}
+	}
+}
+
+// WithPanelMaxAttempts は、1パネルあたりの最大試行回数（初回を含む）を設定します。
+// 既定は 1 で、パネル単位の再試行は行いません。
+func WithPanelMaxAttempts(value int) PanelOption {
+	return func(g *PanelGenerator) {
+		if value > 0 {
+			g.maxAttempts = value
+		}
+	}
+}
+
+// WithPanelProgressObserver は、パネル生成の進捗イベントを受け取る observer を設定します。
+func WithPanelProgressObserver(observer ports.ProgressObserver) PanelOption {
+	return func(g *PanelGenerator) {
+		g.observer = observer
+	}
+}
+
+// WithPanelMetrics は、パネル生成のメトリクスを記録する recorder を設定します。
+func WithPanelMetrics(m ports.MetricsRecorder) PanelOption {
+	return func(g *PanelGenerator) {
+		g.metrics = ports.MetricsOrNop(m)
+	}
+}
+
+// WithPanelRequestTimeout は、パネル1枚の生成呼び出し1回あたりの期限を設定します。
+// 期限を超えた呼び出しは ports.ErrRequestTimeout として失敗し、再試行の対象になります。
+func WithPanelRequestTimeout(d time.Duration) PanelOption {
+	return func(g *PanelGenerator) {
+		if d > 0 {
+			g.requestTimeout = d
+		}
+	}
+}
+
 // --- PageGenerator Options ---
 
 // PageOption は PageGenerator の設定を適用する関数型です。
@@ -66,6 +116,16 @@ func WithPageRateBurst(value int) PageOption {
 	}
 }
 
+// WithPageRateLimiter は、ページ生成が使うレートリミッターを設定します。
+// 設定した場合、WithPageRateInterval・WithPageRateBurst は無視されます。
+func WithPageRateLimiter(l *rate.Limiter) PageOption {
+	return func(g *PageGenerator) {
+		if l != nil {
+			g.limiter = l
+		}
+	}
+}
+
 // WithMaxPanelsPerPage は、1ページあたりの最大パネル数を設定します。
 func WithMaxPanelsPerPage(value int) PageOption {
 	return func(g *PageGenerator) {
@@ -74,3 +134,148 @@ func WithMaxPanelsPerPage(value int) PageOption {
 		}
 	}
 }
+
+// WithPageMaxAttempts は、1ページあたりの最大試行回数（初回を含む）を設定します。
+// 既定は 1 で、ページ単位の再試行は行いません。
+func WithPageMaxAttempts(value int) PageOption {
+	return func(g *PageGenerator) {
+		if value > 0 {
+			g.maxAttempts = value
+		}
+	}
+}
+
+// WithPageProgressObserver は、ページ生成の進捗イベントを受け取る observer を設定します。
+func WithPageProgressObserver(observer ports.ProgressObserver) PageOption {
+	return func(g *PageGenerator) {
+		g.observer = observer
+	}
+}
+
+// WithPageMetrics は、ページ生成のメトリクスを記録する recorder を設定します。
+func WithPageMetrics(m ports.MetricsRecorder) PageOption {
+	return func(g *PageGenerator) {
+		g.metrics = ports.MetricsOrNop(m)
+	}
+}
+
+// WithPageRequestTimeout は、ページ1枚の生成呼び出し1回あたりの期限を設定します。
+// 期限を超えた呼び出しは ports.ErrRequestTimeout として失敗し、再試行の対象になります。
+func WithPageRequestTimeout(d time.Duration) PageOption {
+	return func(g *PageGenerator) {
+		if d > 0 {
+			g.requestTimeout = d
+		}
+	}
+}
+
+// WithPageAssetBudget は、ページ1枚の生成リクエストに含める参照画像の上限を設定します。
+// 上限を超える場合は、キャラクターの参照画像を優先し、ページ内で参照の少ないものから除外します。
+func WithPageAssetBudget(value int) PageOption {
+	return func(g *PageGenerator) {
+		if value > 0 {
+			g.assetBudget = value
+		}
+	}
+}
+
+// WithReferenceCollage は、上限を超えた参照画像を除外する代わりに、1枚のラベル付き
+// コラージュにまとめて渡すよう設定します。Writer と StagingDir が未設定の場合は無効です。
+// コラージュの作成には MangaComposer の WithAssetSource が必要です。
+func WithReferenceCollage(c ReferenceCollage) PageOption {
+	return func(g *PageGenerator) {
+		if c.Writer != nil && c.StagingDir != "" {
+			g.collage = &c
+		}
+	}
+}
+
+// --- MangaComposer Options ---
+
+// ComposerOption は MangaComposer の設定を適用する関数型です。
+type ComposerOption func(*MangaComposer)
+
+// WithComposerMetrics は、アセットのアップロード数とキャッシュヒット数を記録する recorder を設定します。
+func WithComposerMetrics(m ports.MetricsRecorder) ComposerOption {
+	return func(mc *MangaComposer) {
+		mc.metrics = ports.MetricsOrNop(m)
+	}
+}
+
+// WithUploadTimeout は、AssetManager.UploadFile 1回あたりの期限を設定します。
+func WithUploadTimeout(d time.Duration) ComposerOption {
+	return func(mc *MangaComposer) {
+		if d > 0 {
+			mc.uploadTimeout = d
+		}
+	}
+}
+
+// WithAssetRegistry は、アップロード結果を記録するレジストリを設定します。
+// レジストリに有効な記録がある参照画像は、プロセスの再起動後も再アップロードせずに再利用します。
+func WithAssetRegistry(r ports.AssetRegistry) ComposerOption {
+	return func(mc *MangaComposer) {
+		mc.registry = r
+	}
+}
+
+// WithAssetSource は、参照画像を読み込むための ContentReader を設定します。
+// Preflight に必要です。レジストリと併用すると、記録と内容が異なる参照画像を再アップロードします。
+func WithAssetSource(r ports.ContentReader) ComposerOption {
+	return func(mc *MangaComposer) {
+		mc.source = r
+	}
+}
+
+// WithAssetTTL は、アップロードした File API URI の有効期間を設定します（既定は48時間）。
+func WithAssetTTL(d time.Duration) ComposerOption {
+	return func(mc *MangaComposer) {
+		if d > 0 {
+			mc.assetTTL = d
+		}
+	}
+}
+
+// WithAssetRefreshMargin は、有効期限のどれだけ前から再アップロードするかを設定します（既定は6時間）。
+func WithAssetRefreshMargin(d time.Duration) ComposerOption {
+	return func(mc *MangaComposer) {
+		if d >= 0 {
+			mc.refreshMargin = d
+		}
+	}
+}
+
+// WithAssetPreflight を true にすると、PanelGenerator・PageGenerator が生成の前に
+// Preflight で参照画像を検査し、問題があれば生成を始めずにレポートをエラーとして返します。
+// WithAssetSource の設定が必要です。
+func WithAssetPreflight(enabled bool) ComposerOption {
+	return func(mc *MangaComposer) {
+		mc.preflightEnabled = enabled
+	}
+}
+
+// WithPreflightLimits は Preflight の検査基準を設定します（既定は DefaultPreflightLimits）。
+func WithPreflightLimits(limits PreflightLimits) ComposerOption {
+	return func(mc *MangaComposer) {
+		mc.preflightLimits = limits
+	}
+}
+
+// WithReferencePolicy は、パネルの参照画像の URL に適用する方針を設定します
+// （既定は ports.DefaultReferencePolicy）。
+func WithReferencePolicy(p ports.ReferencePolicy) ComposerOption {
+	return func(mc *MangaComposer) {
+		mc.referencePolicy = p
+	}
+}
+
+// WithReferencePreprocessing は、参照画像をアップロード前に縮小・アスペクト比の調整・
+// 再エンコードする前処理を有効にします。WithAssetSource の設定が必要です。
+// Vertex AI モードで gs:// を直接参照する参照画像は前処理しません。
+func WithReferencePreprocessing(p ReferencePreprocessing) ComposerOption {
+	return func(mc *MangaComposer) {
+		if p.Writer != nil && p.StagingDir != "" {
+			mc.preprocess = &p
+		}
+	}
+}
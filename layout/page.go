@@ -4,12 +4,17 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	imagePorts "github.com/shouni/gemini-image-kit/ports"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
 	"golang.org/x/time/rate"
 
+	"github.com/shouni/go-manga-kit/internal/apierr"
+	"github.com/shouni/go-manga-kit/internal/deadline"
+	"github.com/shouni/go-manga-kit/internal/telemetry"
 	"github.com/shouni/go-manga-kit/ports"
 )
 
@@ -27,6 +32,18 @@ type PageGenerator struct {
 	rateInterval     time.Duration
 	rateBurst        int
 	maxPanelsPerPage int
+	maxAttempts      int
+	observer         ports.ProgressObserver
+	metrics          ports.MetricsRecorder
+	requestTimeout   time.Duration
+	assetBudget      int
+	collage          *ReferenceCollage
+
+	// collages は保存済みのコラージュ（キー -> 保存先）で collageMu で保護します。
+	// collageGroup は同じコラージュの同時の生成を1つに集約します。
+	collageMu    sync.Mutex
+	collages     map[string]string
+	collageGroup singleflight.Group
 }
 
 // PageImageGenerator は、複数パネルを1枚の画像へ合成生成するインターフェースです。
@@ -51,23 +68,62 @@ func NewPageGenerator(
 		rateInterval:     defaultRateInterval,
 		rateBurst:        defaultRateBurst,
 		maxPanelsPerPage: defaultMaxPanelsPerPage,
+		maxAttempts:      defaultMaxAttempts,
+		metrics:          ports.NopMetrics{},
+		assetBudget:      DefaultAssetBudget,
+		collages:         make(map[string]string),
 	}
 
 	for _, opt := range opts {
 		opt(g)
 	}
 
-	g.limiter = rate.NewLimiter(rate.Every(g.rateInterval), g.rateBurst)
+	if g.limiter == nil {
+		g.limiter = rate.NewLimiter(rate.Every(g.rateInterval), g.rateBurst)
+	}
 
 	return g
 }
 
 // Execute は、errgroupの制限機能を使用して並列数を制御しながらページ画像を生成します。
+// 進捗イベントは WithPageProgressObserver と ports.WithProgressObserver で設定した observer に通知されます。
 func (g *PageGenerator) Execute(ctx context.Context, manga *ports.MangaResponse) ([]*imagePorts.ImageResponse, error) {
 	if manga == nil || len(manga.Panels) == 0 {
 		return nil, nil
 	}
 
+	defer g.composer.Lease()()
+
+	panelGroups := chunkPanels(manga.Panels, g.maxPanelsPerPage)
+	ctx, span := telemetry.Start(ctx, "manga.pages.execute",
+		telemetry.AttrStage.String(ports.StagePage),
+		telemetry.AttrModel.String(g.model),
+	)
+	reporter := newProgressReporter(ctx, ports.StagePage, len(panelGroups), g.observer)
+	ctx = ports.WithProgressObserver(ctx, reporter)
+
+	stageStart := time.Now()
+	reporter.OnProgress(ports.ProgressEvent{Kind: ports.ProgressStageStarted})
+	responses, err := g.execute(ctx, manga, panelGroups, reporter)
+	reporter.OnProgress(ports.ProgressEvent{
+		Kind:     ports.ProgressStageFinished,
+		Duration: time.Since(stageStart),
+		Err:      err,
+	})
+	telemetry.End(span, err)
+	return responses, err
+}
+
+// execute は Execute の本体です。
+func (g *PageGenerator) execute(
+	ctx context.Context,
+	manga *ports.MangaResponse,
+	panelGroups [][]ports.Panel,
+	reporter *progressReporter,
+) ([]*imagePorts.ImageResponse, error) {
+	if err := g.composer.preflight(ctx, manga.Panels); err != nil {
+		return nil, err
+	}
 	if err := g.composer.PrepareCharacterResources(ctx, manga.Panels); err != nil {
 		return nil, fmt.Errorf("failed to prepare character resources: %w", err)
 	}
@@ -75,22 +131,29 @@ func (g *PageGenerator) Execute(ctx context.Context, manga *ports.MangaResponse)
 		return nil, fmt.Errorf("failed to prepare panel resources: %w", err)
 	}
 
-	maxPanels := g.maxPanelsPerPage
-	panelGroups := chunkPanels(manga.Panels, maxPanels)
 	totalPages := len(panelGroups)
 	allResponses := make([]*imagePorts.ImageResponse, totalPages)
 
 	eg, egCtx := errgroup.WithContext(ctx)
 	eg.SetLimit(int(g.maxConcurrency))
 
+	for i := range panelGroups {
+		reporter.OnProgress(ports.ProgressEvent{Kind: ports.ProgressQueued, Index: i + 1})
+	}
+
 	for i, group := range panelGroups {
 		seed := g.determineDefaultSeed(group)
 		currentPageNum := i + 1
 
-		eg.Go(func() error {
-			if err := g.limiter.Wait(egCtx); err != nil {
-				return err
-			}
+		eg.Go(func() (err error) {
+			ctx, span := telemetry.Start(egCtx, "manga.page.generate",
+				telemetry.AttrPageIndex.Int(currentPageNum),
+				telemetry.AttrModel.String(g.model),
+				telemetry.AttrSeed.Int64(seed),
+				telemetry.AttrAspectRatio.String(PageAspectRatio),
+				telemetry.AttrImageSize.String(ImageSize2K),
+			)
+			defer func() { telemetry.End(span, err) }()
 
 			subManga := ports.MangaResponse{
 				Title:       fmt.Sprintf("%s (Page %d/%d)", manga.Title, currentPageNum, totalPages),
@@ -104,15 +167,41 @@ func (g *PageGenerator) Execute(ctx context.Context, manga *ports.MangaResponse)
 				"panels", len(group),
 				"seed", seed,
 			)
-			logger.Info("Starting manga page generation")
 
 			startTime := time.Now()
-			res, err := g.generateMangaPage(egCtx, subManga, seed)
+			limiter := meteredLimiter{limiter: g.limiter, stage: ports.StagePage, metrics: g.metrics}
+			res, attempts, err := generateWithAttempts(ctx, limiter, g.maxAttempts,
+				func(attempt int, err error) {
+					logger.Warn("Manga page generation failed, retrying", "attempt", attempt, "error", err)
+					reporter.OnProgress(ports.ProgressEvent{
+						Kind: ports.ProgressRetried, Index: currentPageNum, Attempt: attempt, Seed: seed, Err: err,
+					})
+				},
+				func(ctx context.Context, attempt int) (*imagePorts.ImageResponse, error) {
+					if attempt == 1 {
+						logger.Info("Starting manga page generation")
+						reporter.OnProgress(ports.ProgressEvent{
+							Kind: ports.ProgressStarted, Index: currentPageNum, Attempt: attempt, Seed: seed,
+						})
+					}
+					return g.generateMangaPage(ctx, subManga, seed)
+				},
+			)
+			duration := time.Since(startTime)
+			span.SetAttributes(telemetry.AttrRetryCount.Int(attempts - 1))
 			if err != nil {
-				return fmt.Errorf("failed to generate page %d: %w", currentPageNum, err)
+				g.metrics.IncFailure(ports.StagePage, ports.ClassifyError(err))
+				err = &ports.GenerationError{Stage: ports.StagePage, Index: currentPageNum, Attempts: attempts, Err: err}
+				reporter.OnProgress(ports.ProgressEvent{
+					Kind: ports.ProgressFailed, Index: currentPageNum, Attempt: attempts, Seed: seed, Duration: duration, Err: err,
+				})
+				return err
 			}
 
-			logger.Info("Manga page generation completed", "duration", time.Since(startTime).Round(time.Second))
+			logger.Info("Manga page generation completed", "duration", duration.Round(time.Second))
+			reporter.OnProgress(ports.ProgressEvent{
+				Kind: ports.ProgressCompleted, Index: currentPageNum, Attempt: attempts, Seed: seed, Duration: duration,
+			})
 			allResponses[i] = res
 			return nil
 		})
@@ -127,10 +216,13 @@ func (g *PageGenerator) Execute(ctx context.Context, manga *ports.MangaResponse)
 // generateMangaPage は、提供されたマンガレスポンスとAIベースの画像生成用のシードを使用して、マンガページの画像を生成します。
 func (g *PageGenerator) generateMangaPage(ctx context.Context, manga ports.MangaResponse, seed int64) (*imagePorts.ImageResponse, error) {
 	// 1. リソース収集とインデックスマッピングの作成
-	resMap := g.collectResources(manga.Panels)
+	resMap, collageNote := g.collectResources(ctx, manga.Panels)
 
 	// 2. プロンプト構築
 	userPrompt, systemPrompt := g.pb.BuildPage(manga.Panels, resMap)
+	if collageNote != "" {
+		userPrompt += "\n\n" + collageNote
+	}
 
 	// 3. ImageURI 構造体のスライスを作成
 	req := imagePorts.ImageFusionRequest{
@@ -146,24 +238,19 @@ func (g *PageGenerator) generateMangaPage(ctx context.Context, manga ports.Manga
 		Images: resMap.OrderedAssets,
 	}
 
+	telemetry.Annotate(ctx, telemetry.AttrAssetCount.Int(len(resMap.OrderedAssets)))
 	slog.Info("Requesting AI image generation",
 		"title", manga.Title,
 		"seed", seed,
 		"total_assets", len(resMap.OrderedAssets),
 	)
 
-	return g.generator.GenerateFusedImage(ctx, req)
-}
-
-// collectResources は、ページ内のキャラクター立ち絵とパネル参照画像を整理し、インデックスを割り振ります。
-func (g *PageGenerator) collectResources(panels []ports.Panel) *ports.ResourceMap {
-	g.composer.mu.RLock()
-	defer g.composer.mu.RUnlock()
-
-	collector := newPageResourceCollector(g.composer)
-	collector.addCharacterAssets(panels)
-	collector.addPanelAssets(panels)
-	return collector.resourceMap
+	return observeGeneration(g.metrics, ports.StagePage, g.model, func() (*imagePorts.ImageResponse, error) {
+		return deadline.Call(ctx, ports.StagePage, g.requestTimeout, func(ctx context.Context) (*imagePorts.ImageResponse, error) {
+			resp, err := g.generator.GenerateFusedImage(ctx, req)
+			return resp, apierr.Classify(err)
+		})
+	})
 }
 
 // determineDefaultSeed はキャラクターデータを基にページ生成時のデフォルトシード値を決定します。
@@ -0,0 +1,193 @@
+package layout
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"log/slog"
+	"sort"
+	"strings"
+
+	imagePorts "github.com/shouni/gemini-image-kit/ports"
+	"github.com/shouni/go-remote-io/remoteio"
+
+	"github.com/shouni/go-manga-kit/asset"
+	"github.com/shouni/go-manga-kit/imaging"
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// DefaultAssetBudget は1回のページ生成リクエストに含める参照画像の既定の上限です。
+const DefaultAssetBudget = 14
+
+// ReferenceCollage は、参照画像の上限を超えた分を1枚のラベル付きコラージュ画像にまとめる設定です。
+// コラージュは StagingDir に保存され、パネルの参照画像と同様にアップロードされます。
+type ReferenceCollage struct {
+	// Writer はコラージュ画像の保存に使います。
+	Writer remoteio.Writer
+	// StagingDir はコラージュ画像の保存先ディレクトリ（GCS/ローカル）です。
+	StagingDir string
+	// CellSize はコラージュの1区画の一辺のピクセル数です。0 の場合は 512 です。
+	CellSize int
+}
+
+// collectResources は、ページ内のキャラクター立ち絵とパネル参照画像を整理し、インデックスを割り振ります。
+// 参照画像が上限を超える場合は優先度の低いものを外し、コラージュが有効であれば外したものを
+// 1枚のコラージュにまとめて追加します。コラージュを追加した場合は、その説明を note として返します。
+func (g *PageGenerator) collectResources(ctx context.Context, panels []ports.Panel) (*ports.ResourceMap, string) {
+	reserve := 0
+	if g.collage != nil {
+		reserve = 1
+	}
+
+	g.composer.mu.RLock()
+	collector := newPageResourceCollector(g.composer)
+	collector.addCharacterAssets(panels)
+	collector.addPanelAssets(panels)
+	overflow := collector.applyBudget(panels, g.assetBudget, reserve)
+	g.composer.mu.RUnlock()
+
+	resMap := collector.resourceMap
+	if len(overflow) == 0 {
+		return resMap, ""
+	}
+	if g.collage != nil {
+		note, err := g.attachCollage(ctx, resMap, overflow)
+		if err == nil {
+			return resMap, note
+		}
+		slog.WarnContext(ctx, "参照画像のコラージュを作成できませんでした", "error", err)
+	}
+
+	dropped := make([]string, len(overflow))
+	for i, o := range overflow {
+		dropped[i] = o.asset.ReferenceURL
+	}
+	slog.WarnContext(ctx, "参照画像が上限を超えたため一部を除外しました",
+		"budget", g.assetBudget,
+		"dropped", dropped,
+	)
+	return resMap, ""
+}
+
+// attachCollage は overflow の参照画像を1枚のコラージュにまとめ、resMap の末尾に追加します。
+// 各参照画像の持ち主（キャラクター・パネル）はコラージュのインデックスに対応付けます。
+func (g *PageGenerator) attachCollage(ctx context.Context, resMap *ports.ResourceMap, overflow []overflowAsset) (string, error) {
+	collageURL, err := g.stageCollage(ctx, overflow)
+	if err != nil {
+		return "", err
+	}
+
+	fileURI, err := g.composer.getOrUploadPanelAsset(ctx, collageURL)
+	if err != nil {
+		return "", fmt.Errorf("コラージュのアップロードに失敗しました: %w", err)
+	}
+
+	idx := len(resMap.OrderedAssets)
+	resMap.OrderedAssets = append(resMap.OrderedAssets, imagePorts.ImageURI{
+		ReferenceURL: collageURL,
+		FileAPIURI:   fileURI,
+	})
+	tiles := make([]string, len(overflow))
+	for i, o := range overflow {
+		for _, id := range o.characterIDs {
+			resMap.CharacterFiles[id] = idx
+		}
+		for _, url := range o.panelURLs {
+			resMap.PanelFiles[url] = idx
+		}
+		tiles[i] = fmt.Sprintf("tile %q is the %s", collageTileLabel(i, o), o.description)
+	}
+
+	slog.InfoContext(ctx, "上限を超えた参照画像をコラージュにまとめました",
+		"budget", g.assetBudget,
+		"tiles", len(overflow),
+		"collage", collageURL,
+	)
+	note := fmt.Sprintf("Reference image #%d is a collage of labeled reference tiles: %s. Use each tile only as the reference for its label.",
+		idx+1, strings.Join(tiles, "; "))
+	return note, nil
+}
+
+// stageCollage はコラージュ画像を生成して StagingDir に保存し、保存先を返します。
+// 同じ参照画像の組み合わせのコラージュは1度だけ生成します。生成と保存はロックの外で行い、
+// 同じ組み合わせの同時の要求は1つに集約します。
+func (g *PageGenerator) stageCollage(ctx context.Context, overflow []overflowAsset) (string, error) {
+	if g.composer.source == nil {
+		return "", errors.New("参照画像の読み込み元が設定されていません")
+	}
+
+	urls := make([]string, len(overflow))
+	for i, o := range overflow {
+		urls[i] = o.asset.ReferenceURL + "\x00" + o.label
+	}
+	sort.Strings(urls)
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d\x00%s", g.collage.CellSize, strings.Join(urls, "\x00"))))
+	key := hex.EncodeToString(sum[:])
+
+	if staged, ok := g.stagedCollage(key); ok {
+		return staged, nil
+	}
+	val, err, _ := g.collageGroup.Do(key, func() (interface{}, error) {
+		if staged, ok := g.stagedCollage(key); ok {
+			return staged, nil
+		}
+		staged, err := g.writeCollage(ctx, key, overflow)
+		if err != nil {
+			return nil, err
+		}
+		g.collageMu.Lock()
+		g.collages[key] = staged
+		g.collageMu.Unlock()
+		return staged, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return val.(string), nil
+}
+
+// stagedCollage は key のコラージュが保存済みであればその保存先を返します。
+func (g *PageGenerator) stagedCollage(key string) (string, bool) {
+	g.collageMu.Lock()
+	defer g.collageMu.Unlock()
+	staged, ok := g.collages[key]
+	return staged, ok
+}
+
+// writeCollage は overflow の参照画像を読み込んでコラージュを作成し、StagingDir に保存します。
+func (g *PageGenerator) writeCollage(ctx context.Context, key string, overflow []overflowAsset) (string, error) {
+	tiles := make([]imaging.Tile, len(overflow))
+	for i, o := range overflow {
+		data, err := g.composer.readReference(ctx, o.asset.ReferenceURL)
+		if err != nil {
+			return "", fmt.Errorf("参照画像 %s の読み込みに失敗しました: %w", o.asset.ReferenceURL, err)
+		}
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return "", fmt.Errorf("参照画像 %s のデコードに失敗しました: %w", o.asset.ReferenceURL, err)
+		}
+		tiles[i] = imaging.Tile{Image: img, Label: collageTileLabel(i, o)}
+	}
+
+	result, err := imaging.Collage(tiles, g.collage.CellSize)
+	if err != nil {
+		return "", err
+	}
+	staged, err := asset.ResolveOutputPath(g.collage.StagingDir, "collage-"+key[:32]+result.Extension())
+	if err != nil {
+		return "", fmt.Errorf("コラージュの保存先を解決できませんでした: %w", err)
+	}
+	if err := g.collage.Writer.Write(ctx, staged, bytes.NewReader(result.Data), remoteio.WithContentType(result.MIMEType)); err != nil {
+		return "", fmt.Errorf("コラージュの保存に失敗しました: %w", err)
+	}
+	return staged, nil
+}
+
+// collageTileLabel はコラージュの i 番目の区画に描画するラベルです。
+func collageTileLabel(i int, o overflowAsset) string {
+	return fmt.Sprintf("#%d %s", i+1, o.label)
+}
@@ -0,0 +1,346 @@
+package layout
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	imagePorts "github.com/shouni/gemini-image-kit/ports"
+	characterkit "github.com/shouni/go-character-kit/character"
+
+	"github.com/shouni/go-manga-kit/mangakittest"
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+func newBudgetTestCharacters(t *testing.T) *characterkit.Characters {
+	t.Helper()
+	cm, err := characterkit.NewCharacters([]ports.Character{
+		{ID: "zundamon", Name: "ずんだもん", ReferenceURL: "gs://bucket/zunda.png", IsDefault: true},
+		{ID: "metan", Name: "めたん", ReferenceURL: "gs://bucket/metan.png"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cm
+}
+
+func TestPageResourceCollector_ApplyBudget(t *testing.T) {
+	composer, err := NewMangaComposer(&mangakittest.AssetManager{}, &mangakittest.Backend{VertexAI: true}, newBudgetTestCharacters(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	panels := []ports.Panel{
+		{SpeakerID: "metan", ReferenceURL: "gs://bucket/a.png"},
+		{SpeakerID: "zundamon", ReferenceURL: "gs://bucket/b.png"},
+		{SpeakerID: "zundamon", ReferenceURL: "gs://bucket/b.png"},
+		{SpeakerID: "zundamon", ReferenceURL: "gs://bucket/c.png"},
+	}
+
+	collector := newPageResourceCollector(composer)
+	collector.addCharacterAssets(panels)
+	collector.addPanelAssets(panels)
+	// OrderedAssets: metan, zunda, a, b, c
+	overflow := collector.applyBudget(panels, 3, 0)
+
+	// キャラクター2枚と、パネル参照画像のうち最も多く参照される b が残ります。
+	rm := collector.resourceMap
+	var kept []string
+	for _, asset := range rm.OrderedAssets {
+		kept = append(kept, asset.ReferenceURL)
+	}
+	if want := "gs://bucket/metan.png gs://bucket/zunda.png gs://bucket/b.png"; strings.Join(kept, " ") != want {
+		t.Errorf("kept = %v, want %s", kept, want)
+	}
+	if rm.CharacterFiles["metan"] != 0 || rm.CharacterFiles["zundamon"] != 1 || rm.PanelFiles["gs://bucket/b.png"] != 2 {
+		t.Errorf("indexes were not remapped: characters=%v panels=%v", rm.CharacterFiles, rm.PanelFiles)
+	}
+	if _, ok := rm.PanelFiles["gs://bucket/a.png"]; ok {
+		t.Error("dropped reference should be removed from PanelFiles")
+	}
+
+	if len(overflow) != 2 {
+		t.Fatalf("overflow = %+v, want 2 entries", overflow)
+	}
+	if overflow[0].asset.ReferenceURL != "gs://bucket/a.png" || overflow[0].label != "panel 1" {
+		t.Errorf("overflow[0] = %+v", overflow[0])
+	}
+	if overflow[1].asset.ReferenceURL != "gs://bucket/c.png" || overflow[1].label != "panel 4" {
+		t.Errorf("overflow[1] = %+v", overflow[1])
+	}
+
+	// 上限内であれば何も除外しません。
+	if got := collector.applyBudget(panels, 3, 0); got != nil {
+		t.Errorf("applyBudget within budget = %+v, want nil", got)
+	}
+}
+
+func TestPageResourceCollector_ApplyBudgetCountsAllSpeakers(t *testing.T) {
+	composer, err := NewMangaComposer(&mangakittest.AssetManager{}, &mangakittest.Backend{VertexAI: true}, newBudgetTestCharacters(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// zundamon は SpeakerID には現れませんが、2つのパネルで話しています。
+	panels := []ports.Panel{
+		{SpeakerID: "metan", Lines: []ports.DialogueLine{{SpeakerID: "metan", Text: "a"}, {SpeakerID: "zundamon", Text: "b"}}},
+		{Lines: []ports.DialogueLine{{SpeakerID: "zundamon", Text: "c"}, {SpeakerID: "zundamon", Text: "d"}}},
+	}
+
+	collector := newPageResourceCollector(composer)
+	collector.addCharacterAssets(panels)
+	// OrderedAssets: metan, zunda
+	overflow := collector.applyBudget(panels, 1, 0)
+
+	rm := collector.resourceMap
+	if len(rm.OrderedAssets) != 1 || rm.OrderedAssets[0].ReferenceURL != "gs://bucket/zunda.png" {
+		t.Errorf("kept = %+v, want only zundamon", rm.OrderedAssets)
+	}
+	if len(overflow) != 1 || overflow[0].label != "metan" {
+		t.Errorf("overflow = %+v, want metan", overflow)
+	}
+}
+
+func TestPageGenerator_CollagesOverflowReferences(t *testing.T) {
+	ctx := context.Background()
+	source := &mangakittest.ContentReader{}
+	for _, url := range []string{"gs://bucket/zunda.png", "gs://bucket/metan.png", "gs://bucket/a.png", "gs://bucket/b.png"} {
+		source.SetFile(url, pngBytes(t, 64, 64))
+	}
+	assets := &mangakittest.AssetManager{}
+	composer, err := NewMangaComposer(assets, &mangakittest.Backend{}, newBudgetTestCharacters(t), WithAssetSource(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	staging := &mangakittest.Writer{}
+
+	var (
+		mu   sync.Mutex
+		reqs []imagePorts.ImageFusionRequest
+	)
+	gen := &mangakittest.ImageGenerator{
+		FusedFunc: func(_ context.Context, req imagePorts.ImageFusionRequest) (*imagePorts.ImageResponse, error) {
+			mu.Lock()
+			reqs = append(reqs, req)
+			mu.Unlock()
+			return &imagePorts.ImageResponse{Data: []byte("page")}, nil
+		},
+	}
+	prompt := &mangakittest.ImagePrompt{}
+	g := NewPageGenerator(composer, gen, prompt, "model",
+		WithPageRateInterval(time.Microsecond),
+		WithPageAssetBudget(3),
+		WithReferenceCollage(ReferenceCollage{Writer: staging, StagingDir: "gs://staging/collages", CellSize: 128}),
+	)
+
+	manga := &ports.MangaResponse{Panels: []ports.Panel{
+		{SpeakerID: "zundamon", ReferenceURL: "gs://bucket/a.png"},
+		{SpeakerID: "metan", ReferenceURL: "gs://bucket/b.png"},
+	}}
+	if _, err := g.Execute(ctx, manga); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reqs) != 1 {
+		t.Fatalf("GenerateFusedImage calls = %d, want 1", len(reqs))
+	}
+	req := reqs[0]
+	// キャラクター2枚とコラージュ1枚で上限の3枚に収まります。
+	if len(req.Images) != 3 {
+		t.Fatalf("Images = %+v, want 3", req.Images)
+	}
+	collage := req.Images[2]
+	if !strings.HasPrefix(collage.ReferenceURL, "gs://staging/collages/collage-") || collage.FileAPIURI == "" {
+		t.Errorf("collage asset = %+v", collage)
+	}
+	if _, ok := staging.File(collage.ReferenceURL); !ok {
+		t.Errorf("collage %s was not written", collage.ReferenceURL)
+	}
+
+	rm := prompt.ResourceMaps()[0]
+	if rm.PanelFiles["gs://bucket/a.png"] != 2 || rm.PanelFiles["gs://bucket/b.png"] != 2 {
+		t.Errorf("PanelFiles = %v, want both panels mapped to the collage", rm.PanelFiles)
+	}
+	if !strings.Contains(req.Prompt, "Reference image #3 is a collage") ||
+		!strings.Contains(req.Prompt, `"#1 panel 1"`) || !strings.Contains(req.Prompt, `"#2 panel 2"`) {
+		t.Errorf("prompt does not describe the collage: %q", req.Prompt)
+	}
+}
+
+// 複数のページを並列に生成しても、参照画像の収集（読み取りロック）とコラージュのアップロード
+// （書き込みロック）が互いにブロックしないことを確認します。-race 付きでも実行してください。
+func TestPageGenerator_CollagesConcurrentPages(t *testing.T) {
+	ctx := context.Background()
+	source := &mangakittest.ContentReader{}
+	refs := []string{"gs://bucket/a.png", "gs://bucket/b.png", "gs://bucket/c.png", "gs://bucket/d.png"}
+	for _, url := range append([]string{"gs://bucket/zunda.png", "gs://bucket/metan.png"}, refs...) {
+		source.SetFile(url, pngBytes(t, 64, 64))
+	}
+	composer, err := NewMangaComposer(&mangakittest.AssetManager{}, &mangakittest.Backend{}, newBudgetTestCharacters(t), WithAssetSource(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen := &mangakittest.ImageGenerator{
+		FusedFunc: func(_ context.Context, _ imagePorts.ImageFusionRequest) (*imagePorts.ImageResponse, error) {
+			return &imagePorts.ImageResponse{Data: []byte("page")}, nil
+		},
+	}
+	g := NewPageGenerator(composer, gen, &mangakittest.ImagePrompt{}, "model",
+		WithPageRateInterval(time.Microsecond),
+		WithPageRateBurst(2),
+		WithPageMaxConcurrency(2),
+		WithMaxPanelsPerPage(2),
+		WithPageAssetBudget(3),
+		WithReferenceCollage(ReferenceCollage{Writer: &mangakittest.Writer{}, StagingDir: "gs://staging/collages", CellSize: 64}),
+	)
+
+	manga := &ports.MangaResponse{Panels: []ports.Panel{
+		{SpeakerID: "zundamon", ReferenceURL: refs[0]},
+		{SpeakerID: "metan", ReferenceURL: refs[1]},
+		{SpeakerID: "zundamon", ReferenceURL: refs[2]},
+		{SpeakerID: "metan", ReferenceURL: refs[3]},
+	}}
+
+	// 収集中に書き込みロックを待つ処理（Sweep）を並行して繰り返し、再入した読み取りロックが
+	// ブロックする状況を起こりやすくします。
+	stop := make(chan struct{})
+	var sweeper sync.WaitGroup
+	sweeper.Go(func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_, _ = composer.Sweep(ctx, time.Hour)
+			}
+		}
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := g.Execute(ctx, manga)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		close(stop)
+		sweeper.Wait()
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Execute did not finish; resource collection and collage upload deadlocked")
+	}
+
+	uploads := 0
+	for _, u := range composer.Uploads() {
+		if strings.HasPrefix(u.ReferenceURL, "gs://staging/collages/collage-") {
+			uploads++
+		}
+	}
+	if uploads != 2 {
+		t.Errorf("collage uploads = %d, want 2 (one per page)", uploads)
+	}
+}
+
+func TestPageGenerator_DropsOverflowWithoutCollage(t *testing.T) {
+	ctx := context.Background()
+	composer, err := NewMangaComposer(&mangakittest.AssetManager{}, &mangakittest.Backend{VertexAI: true}, newBudgetTestCharacters(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var images []imagePorts.ImageURI
+	gen := &mangakittest.ImageGenerator{
+		FusedFunc: func(_ context.Context, req imagePorts.ImageFusionRequest) (*imagePorts.ImageResponse, error) {
+			images = req.Images
+			return &imagePorts.ImageResponse{Data: []byte("page")}, nil
+		},
+	}
+	g := NewPageGenerator(composer, gen, &mangakittest.ImagePrompt{}, "model",
+		WithPageRateInterval(time.Microsecond),
+		WithPageAssetBudget(2),
+	)
+
+	manga := &ports.MangaResponse{Panels: []ports.Panel{
+		{SpeakerID: "zundamon", ReferenceURL: "gs://bucket/a.png"},
+		{SpeakerID: "metan", ReferenceURL: "gs://bucket/b.png"},
+	}}
+	if _, err := g.Execute(ctx, manga); err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 2 || images[0].ReferenceURL != "gs://bucket/metan.png" || images[1].ReferenceURL != "gs://bucket/zunda.png" {
+		t.Errorf("Images = %+v, want only the two character references", images)
+	}
+}
+
+func TestPageGenerator_CollagesDoNotSerializePages(t *testing.T) {
+	ctx := context.Background()
+	refs := []string{"gs://bucket/a.png", "gs://bucket/b.png", "gs://bucket/c.png", "gs://bucket/d.png"}
+	images := make(map[string][]byte, len(refs))
+	for _, url := range refs {
+		images[url] = pngBytes(t, 64, 64)
+	}
+
+	// 1ページ目のコラージュの読み込みは、2ページ目のコラージュが保存されるまで戻りません。
+	// コラージュの生成を1つのロックで直列化していると、2ページ目が保存できずに行き詰まります。
+	var (
+		firstStarted  = make(chan struct{})
+		secondWritten = make(chan struct{})
+		startOnce     sync.Once
+		writeOnce     sync.Once
+	)
+	source := &mangakittest.ContentReader{
+		OpenFunc: func(ctx context.Context, uri string) (io.ReadCloser, error) {
+			switch uri {
+			case refs[0], refs[1]:
+				startOnce.Do(func() { close(firstStarted) })
+				select {
+				case <-secondWritten:
+				case <-time.After(5 * time.Second):
+					return nil, errors.New("the second page's collage was never written")
+				}
+			case refs[2], refs[3]:
+				select {
+				case <-firstStarted:
+				case <-time.After(5 * time.Second):
+					return nil, errors.New("the first page's collage was never started")
+				}
+			}
+			return io.NopCloser(bytes.NewReader(images[uri])), nil
+		},
+	}
+	staging := &mangakittest.Writer{
+		WriteFunc: func(_ context.Context, _ string, _ []byte) error {
+			writeOnce.Do(func() { close(secondWritten) })
+			return nil
+		},
+	}
+	composer, err := NewMangaComposer(&mangakittest.AssetManager{}, &mangakittest.Backend{VertexAI: true}, newBudgetTestCharacters(t), WithAssetSource(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewPageGenerator(composer, &mangakittest.ImageGenerator{}, &mangakittest.ImagePrompt{}, "model",
+		WithPageRateInterval(time.Microsecond),
+		WithPageRateBurst(2),
+		WithPageMaxConcurrency(2),
+		WithMaxPanelsPerPage(2),
+		WithPageAssetBudget(3),
+		WithReferenceCollage(ReferenceCollage{Writer: staging, StagingDir: "gs://staging/collages", CellSize: 64}),
+	)
+
+	manga := &ports.MangaResponse{Panels: []ports.Panel{
+		{SpeakerID: "zundamon", ReferenceURL: refs[0]},
+		{SpeakerID: "metan", ReferenceURL: refs[1]},
+		{SpeakerID: "zundamon", ReferenceURL: refs[2]},
+		{SpeakerID: "metan", ReferenceURL: refs[3]},
+	}}
+	if _, err := g.Execute(ctx, manga); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(staging.Paths()); n != 2 {
+		t.Errorf("staged collages = %d, want 2 (one per page)", n)
+	}
+}
@@ -1,12 +1,16 @@
 package layout
 
 import (
+	"fmt"
 	"sort"
+	"strings"
 
 	imagePorts "github.com/shouni/gemini-image-kit/ports"
 	"github.com/shouni/go-manga-kit/ports"
 )
 
+// pageResourceCollector はページ生成に使う参照画像を集めます。
+// composer の画像URIを直接参照するため、使用中は呼び出し側で composer.mu を保持してください。
 type pageResourceCollector struct {
 	composer    *MangaComposer
 	resourceMap *ports.ResourceMap
@@ -36,7 +40,7 @@ func (c *pageResourceCollector) addCharacterAssets(panels []ports.Panel) {
 			continue
 		}
 
-		fileURI := c.composer.GetCharacterResourceURI(char.ID)
+		fileURI := c.composer.characterURILocked(char.ID)
 		if !c.canRegister(fileURI, char.ReferenceURL) {
 			continue
 		}
@@ -79,7 +83,7 @@ func (c *pageResourceCollector) sortedPanelAssets(panels []ports.Panel) []imageP
 			continue
 		}
 
-		fileURI := c.composer.GetPanelResourceURI(panel.ReferenceURL)
+		fileURI := c.composer.panelURILocked(panel.ReferenceURL)
 		if !c.canRegister(fileURI, panel.ReferenceURL) {
 			continue
 		}
@@ -121,3 +125,136 @@ func (c *pageResourceCollector) canRegister(fileURI, referenceURL string) bool {
 	// Vertex AI モード かつ GCS URI なら OK (File API URI が空でも許容)
 	return c.isVertex && IsGCSURI(referenceURL)
 }
+
+// overflowAsset は予算を超えたために OrderedAssets から外された参照画像です。
+type overflowAsset struct {
+	asset        imagePorts.ImageURI
+	characterIDs []string
+	panelURLs    []string
+	label        string
+	description  string
+}
+
+// applyBudget は OrderedAssets を budget 件以内に絞り込み、外した参照画像を返します。
+// キャラクターの参照画像をパネルの参照画像より優先し、同じ種類の中ではページ内で
+// 参照するパネル（キャラクターはいずれかのセリフの話者となるパネル）の多いものを
+// 優先します。残した参照画像は元の順序を保ち、CharacterFiles・PanelFiles の
+// インデックスを振り直します。外した参照画像の持ち主はマップから削除します。reserve は後から追加する画像（コラージュ）のために空けておく枠数です。
+func (c *pageResourceCollector) applyBudget(panels []ports.Panel, budget, reserve int) []overflowAsset {
+	rm := c.resourceMap
+	if budget <= 0 || len(rm.OrderedAssets) <= budget {
+		return nil
+	}
+	keep := max(budget-reserve, 0)
+
+	type candidate struct {
+		index     int
+		character bool
+		usage     int
+	}
+	candidates := make([]candidate, len(rm.OrderedAssets))
+	owners := make([]overflowAsset, len(rm.OrderedAssets))
+	for i, asset := range rm.OrderedAssets {
+		candidates[i].index = i
+		owners[i].asset = asset
+	}
+	for _, id := range sortedKeys(rm.CharacterFiles) {
+		idx := rm.CharacterFiles[id]
+		candidates[idx].character = true
+		owners[idx].characterIDs = append(owners[idx].characterIDs, id)
+	}
+	for _, url := range sortedKeys(rm.PanelFiles) {
+		idx := rm.PanelFiles[url]
+		owners[idx].panelURLs = append(owners[idx].panelURLs, url)
+	}
+	for _, panel := range panels {
+		// 話者は SpeakerID だけでなく Lines の全話者から数えます（同じパネルでは1回）。
+		for _, id := range ports.Panels([]ports.Panel{panel}).UniqueSpeakerIDs() {
+			if idx, ok := rm.CharacterFiles[id]; ok {
+				candidates[idx].usage++
+			}
+		}
+		if idx, ok := rm.PanelFiles[panel.ReferenceURL]; ok && panel.ReferenceURL != "" {
+			candidates[idx].usage++
+		}
+	}
+
+	ranked := append([]candidate(nil), candidates...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].character != ranked[j].character {
+			return ranked[i].character
+		}
+		return ranked[i].usage > ranked[j].usage
+	})
+	kept := make([]bool, len(candidates))
+	for _, cand := range ranked[:keep] {
+		kept[cand.index] = true
+	}
+
+	remap := make(map[int]int, keep)
+	ordered := make([]imagePorts.ImageURI, 0, keep)
+	var overflow []overflowAsset
+	for i, asset := range rm.OrderedAssets {
+		if kept[i] {
+			remap[i] = len(ordered)
+			ordered = append(ordered, asset)
+			continue
+		}
+		overflow = append(overflow, c.describe(owners[i], panels))
+	}
+	rm.OrderedAssets = ordered
+	remapIndexes(rm.CharacterFiles, remap)
+	remapIndexes(rm.PanelFiles, remap)
+
+	c.addedByURL = make(map[string]int, len(ordered))
+	for i, asset := range ordered {
+		c.addedByURL[asset.ReferenceURL] = i
+	}
+	return overflow
+}
+
+// describe は外した参照画像のラベル（コラージュに描画する英数字）と、プロンプト用の説明を設定します。
+func (c *pageResourceCollector) describe(o overflowAsset, panels []ports.Panel) overflowAsset {
+	var labels, descriptions []string
+	for _, id := range o.characterIDs {
+		labels = append(labels, id)
+		name := id
+		if char := c.composer.CharactersMap.GetCharacter(id); char != nil && char.Name != "" {
+			name = fmt.Sprintf("%s (%s)", char.Name, id)
+		}
+		descriptions = append(descriptions, "character "+name)
+	}
+	for _, url := range o.panelURLs {
+		for i, panel := range panels {
+			if panel.ReferenceURL == url {
+				labels = append(labels, fmt.Sprintf("panel %d", i+1))
+				descriptions = append(descriptions, fmt.Sprintf("reference for panel %d", i+1))
+				break
+			}
+		}
+	}
+	o.label = strings.Join(labels, " ")
+	o.description = strings.Join(descriptions, ", ")
+	return o
+}
+
+// remapIndexes は m のインデックスを remap に従って振り直し、remap に無いものを削除します。
+func remapIndexes(m map[string]int, remap map[int]int) {
+	for key, idx := range m {
+		if newIdx, ok := remap[idx]; ok {
+			m[key] = newIdx
+		} else {
+			delete(m, key)
+		}
+	}
+}
+
+// sortedKeys は m のキーを昇順で返します。
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
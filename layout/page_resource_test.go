@@ -4,12 +4,13 @@ import (
 	"testing"
 
 	characterkit "github.com/shouni/go-character-kit/character"
+	"github.com/shouni/go-manga-kit/mangakittest"
 	"github.com/shouni/go-manga-kit/ports"
 )
 
 func TestPageResourceCollector(t *testing.T) {
 	// 共通のセットアップ
-	assetMgr := &mockAssetManager{}
+	assetMgr := &mangakittest.AssetManager{}
 	cm, err := characterkit.NewCharacters([]ports.Character{
 		{ID: "zundamon", Name: "ずんだもん", ReferenceURL: "gs://bucket/zunda.png", VisualCues: []string{"green hair"}},
 		{ID: "metan", Name: "めたん", ReferenceURL: "https://example.com/metan.png", VisualCues: []string{"purple hair"}},
@@ -19,7 +20,7 @@ func TestPageResourceCollector(t *testing.T) {
 	}
 
 	t.Run("Standard Asset Collection", func(t *testing.T) {
-		backend := &mockBackend{isVertex: false} // AI Studio モード (File API必須)
+		backend := &mangakittest.Backend{VertexAI: false} // AI Studio モード (File API必須)
 		composer, _ := NewMangaComposer(assetMgr, backend, cm)
 
 		// 偽のキャッシュをセット（本来は PrepareCharacterResources / PreparePanelResources で入るもの）
@@ -64,7 +65,7 @@ func TestPageResourceCollector(t *testing.T) {
 	})
 
 	t.Run("Vertex AI Mode Bypass", func(t *testing.T) {
-		backend := &mockBackend{isVertex: true} // Vertex AI モード (GCS直参照OK)
+		backend := &mangakittest.Backend{VertexAI: true} // Vertex AI モード (GCS直参照OK)
 		composer, _ := NewMangaComposer(assetMgr, backend, cm)
 
 		// File API URI が空（アップロードしていない）状態をシミュレート
@@ -88,7 +89,7 @@ func TestPageResourceCollector(t *testing.T) {
 	})
 
 	t.Run("Invalid/Missing Assets", func(t *testing.T) {
-		backend := &mockBackend{isVertex: false}
+		backend := &mangakittest.Backend{VertexAI: false}
 		composer, _ := NewMangaComposer(assetMgr, backend, cm)
 		collector := newPageResourceCollector(composer)
 
@@ -105,7 +106,7 @@ func TestPageResourceCollector(t *testing.T) {
 	})
 
 	t.Run("Sorted Panel Assets", func(t *testing.T) {
-		backend := &mockBackend{isVertex: true}
+		backend := &mangakittest.Backend{VertexAI: true}
 		composer, _ := NewMangaComposer(assetMgr, backend, cm)
 		collector := newPageResourceCollector(composer)
 
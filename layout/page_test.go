@@ -2,59 +2,21 @@ package layout
 
 import (
 	"context"
-	"sync"
 	"testing"
 	"time"
 
 	imagePorts "github.com/shouni/gemini-image-kit/ports"
 	characterkit "github.com/shouni/go-character-kit/character"
+	"github.com/shouni/go-manga-kit/mangakittest"
 	"github.com/shouni/go-manga-kit/ports"
 )
 
-// --- Mocks ---
-
-type mockPageImageGenerator struct {
-	mu            sync.Mutex
-	generateCount int
-	generateFunc  func(ctx context.Context, req imagePorts.ImageFusionRequest) (*imagePorts.ImageResponse, error)
-}
-
-func (m *mockPageImageGenerator) GenerateFusedImage(ctx context.Context, req imagePorts.ImageFusionRequest) (*imagePorts.ImageResponse, error) {
-	m.mu.Lock()
-	m.generateCount++
-	m.mu.Unlock()
-	if m.generateFunc != nil {
-		return m.generateFunc(ctx, req)
-	}
-	var s int64
-	if req.Seed != nil {
-		s = *req.Seed
-	}
-	return &imagePorts.ImageResponse{Data: []byte("fake-image"), UsedSeed: s}, nil
-}
-
-func ptrInt64(v int64) *int64 {
-	return &v
-}
-
-type mockImagePrompt struct{}
-
-func (m *mockImagePrompt) BuildPanel(_ ports.Panel, _ *ports.Character) (string, string) {
-	return "user-prompt", "system-prompt"
-}
-
-func (m *mockImagePrompt) BuildPage(_ []ports.Panel, _ *ports.ResourceMap) (string, string) {
-	return "page-user-prompt", "page-system-prompt"
-}
-
-// --- Tests ---
-
 func TestPageGenerator_Execute(t *testing.T) {
 	ctx := context.Background()
 
 	// 1. 依存関係のセットアップ
-	assetMgr := &mockAssetManager{}
-	backend := &mockBackend{isVertex: false}
+	assetMgr := &mangakittest.AssetManager{}
+	backend := &mangakittest.Backend{VertexAI: false}
 
 	cm, err := characterkit.NewCharacters([]ports.Character{
 		{
@@ -70,8 +32,8 @@ func TestPageGenerator_Execute(t *testing.T) {
 	}
 	composer, _ := NewMangaComposer(assetMgr, backend, cm)
 
-	genMock := &mockPageImageGenerator{}
-	pbMock := &mockImagePrompt{}
+	genMock := &mangakittest.ImageGenerator{}
+	pbMock := &mangakittest.ImagePrompt{}
 
 	// 2. Generator の作成 (テスト高速化設定を注入)
 	maxPanels := 2
@@ -123,7 +85,6 @@ func TestPageGenerator_Execute(t *testing.T) {
 	})
 
 	t.Run("Seed Determination Logic", func(t *testing.T) {
-		genMock.generateCount = 0
 		manga := &ports.MangaResponse{
 			Title: "Seed Test",
 			Panels: []ports.Panel{
@@ -132,7 +93,7 @@ func TestPageGenerator_Execute(t *testing.T) {
 		}
 
 		var capturedSeed int64
-		genMock.generateFunc = func(_ context.Context, req imagePorts.ImageFusionRequest) (*imagePorts.ImageResponse, error) {
+		genMock.FusedFunc = func(_ context.Context, req imagePorts.ImageFusionRequest) (*imagePorts.ImageResponse, error) {
 			if req.Seed != nil {
 				capturedSeed = *req.Seed
 			}
@@ -161,3 +122,34 @@ func TestChunkPanels(t *testing.T) {
 		t.Errorf("Expected last chunk size 1, got %d", len(got[2]))
 	}
 }
+
+func TestPageGenerator_SharedRateLimiter(t *testing.T) {
+	cm, err := characterkit.NewCharacters([]ports.Character{
+		{ID: "zundamon", Name: "ずんだもん", IsDefault: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	composer, _ := NewMangaComposer(&mangakittest.AssetManager{}, &mangakittest.Backend{VertexAI: true}, cm)
+
+	// 他の工程（キャラクターデザインなど）が共有のリミッターの枠を使い切った状態を再現します。
+	limiter := NewRateLimiter(time.Hour)
+	if !limiter.Allow() {
+		t.Fatal("limiter should allow the first request")
+	}
+
+	gen := &mangakittest.ImageGenerator{}
+	g := NewPageGenerator(composer, gen, &mangakittest.ImagePrompt{}, "model",
+		WithPageRateLimiter(limiter),
+		WithPageRateInterval(time.Microsecond), // 共有のリミッターが優先されます
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := g.Execute(ctx, &ports.MangaResponse{Panels: []ports.Panel{{SpeakerID: "zundamon"}}}); err == nil {
+		t.Fatal("Execute succeeded, want the shared limiter to block until the deadline")
+	}
+	if n := len(gen.FusedRequests()); n != 0 {
+		t.Errorf("GenerateFusedImage calls = %d, want 0", n)
+	}
+}
@@ -2,7 +2,6 @@ package layout
 
 import (
 	"context"
-	"fmt"
 	"log/slog"
 	"time"
 
@@ -10,6 +9,9 @@ import (
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/time/rate"
 
+	"github.com/shouni/go-manga-kit/internal/apierr"
+	"github.com/shouni/go-manga-kit/internal/deadline"
+	"github.com/shouni/go-manga-kit/internal/telemetry"
 	"github.com/shouni/go-manga-kit/ports"
 )
 
@@ -26,6 +28,10 @@ type PanelGenerator struct {
 	maxConcurrency int
 	rateInterval   time.Duration
 	rateBurst      int
+	maxAttempts    int
+	observer       ports.ProgressObserver
+	metrics        ports.MetricsRecorder
+	requestTimeout time.Duration
 }
 
 // PanelImageGenerator は、単一パネルの画像を生成するインターフェースです。
@@ -49,23 +55,54 @@ func NewPanelGenerator(
 		maxConcurrency: ports.DefaultMaxConcurrency,
 		rateInterval:   defaultRateInterval,
 		rateBurst:      defaultRateBurst,
+		maxAttempts:    defaultMaxAttempts,
+		metrics:        ports.NopMetrics{},
 	}
 
 	for _, opt := range opts {
 		opt(g)
 	}
 
-	g.limiter = rate.NewLimiter(rate.Every(g.rateInterval), g.rateBurst)
+	if g.limiter == nil {
+		g.limiter = rate.NewLimiter(rate.Every(g.rateInterval), g.rateBurst)
+	}
 
 	return g
 }
 
 // Execute は、errgroupの制限機能を使用して同時実行数を制限しながらパネルを並列生成します。
+// 進捗イベントは WithPanelProgressObserver と ports.WithProgressObserver で設定した observer に通知されます。
 func (g *PanelGenerator) Execute(ctx context.Context, panels []ports.Panel) ([]*imagePorts.ImageResponse, error) {
 	if len(panels) == 0 {
 		return nil, nil
 	}
 
+	defer g.composer.Lease()()
+
+	ctx, span := telemetry.Start(ctx, "manga.panels.execute",
+		telemetry.AttrStage.String(ports.StagePanel),
+		telemetry.AttrModel.String(g.model),
+	)
+	reporter := newProgressReporter(ctx, ports.StagePanel, len(panels), g.observer)
+	ctx = ports.WithProgressObserver(ctx, reporter)
+
+	stageStart := time.Now()
+	reporter.OnProgress(ports.ProgressEvent{Kind: ports.ProgressStageStarted})
+	images, err := g.execute(ctx, panels, reporter)
+	reporter.OnProgress(ports.ProgressEvent{
+		Kind:     ports.ProgressStageFinished,
+		Duration: time.Since(stageStart),
+		Err:      err,
+	})
+	telemetry.End(span, err)
+	return images, err
+}
+
+// execute は Execute の本体です。
+func (g *PanelGenerator) execute(ctx context.Context, panels []ports.Panel, reporter *progressReporter) ([]*imagePorts.ImageResponse, error) {
+	if err := g.composer.preflight(ctx, panels); err != nil {
+		return nil, err
+	}
 	if err := g.composer.PrepareCharacterResources(ctx, panels); err != nil {
 		return nil, err
 	}
@@ -76,18 +113,42 @@ func (g *PanelGenerator) Execute(ctx context.Context, panels []ports.Panel) ([]*
 
 	cm := g.composer.CharactersMap
 
+	for i := range panels {
+		reporter.OnProgress(ports.ProgressEvent{Kind: ports.ProgressQueued, Index: i + 1})
+	}
+
 	for i, panel := range panels {
-		eg.Go(func() error {
-			if err := g.limiter.Wait(egCtx); err != nil {
-				return err
-			}
+		eg.Go(func() (err error) {
+			ctx, span := telemetry.Start(egCtx, "manga.panel.generate",
+				telemetry.AttrPanelIndex.Int(i+1),
+				telemetry.AttrModel.String(g.model),
+				telemetry.AttrAspectRatio.String(PanelAspectRatio),
+				telemetry.AttrImageSize.String(ImageSize1K),
+			)
+			defer func() { telemetry.End(span, err) }()
 
 			char := cm.GetCharacterWithDefault(panel.SpeakerID)
 			if char == nil {
-				return fmt.Errorf("character not found for speaker ID '%s'", panel.SpeakerID)
+				err := &ports.GenerationError{
+					Stage: ports.StagePanel,
+					Index: i + 1,
+					Err:   &ports.CharacterNotFoundError{CharacterIDs: []string{panel.SpeakerID}},
+				}
+				reporter.OnProgress(ports.ProgressEvent{Kind: ports.ProgressFailed, Index: i + 1, Err: err})
+				return err
 			}
 			userPrompt, systemPrompt := g.pb.BuildPanel(panel, char)
 			fileURI := g.composer.GetCharacterResourceURI(char.ID)
+			seed := imagePorts.DereferenceSeed(char.Seed)
+			assetCount := 0
+			if fileURI != "" || char.ReferenceURL != "" {
+				assetCount = 1
+			}
+			span.SetAttributes(
+				telemetry.AttrCharacterID.String(char.ID),
+				telemetry.AttrSeed.Int64(seed),
+				telemetry.AttrAssetCount.Int(assetCount),
+			)
 
 			var seedVal any
 			if char.Seed != nil {
@@ -101,31 +162,64 @@ func (g *PanelGenerator) Execute(ctx context.Context, panels []ports.Panel) ([]*
 				"seed", seedVal,
 				"use_file_api", fileURI != "",
 			)
-			logger.Info("Starting panel generation")
 
 			startTime := time.Now()
-			resp, err := g.generator.GenerateSingleImage(egCtx, imagePorts.SingleImageRequest{
-				GenerationOptions: imagePorts.GenerationOptions{
-					Model:          g.model,
-					Prompt:         userPrompt,
-					SystemPrompt:   systemPrompt,
-					NegativePrompt: negativePanelPrompt,
-					AspectRatio:    PanelAspectRatio,
-					ImageSize:      ImageSize1K,
-					Seed:           char.Seed,
+			limiter := meteredLimiter{limiter: g.limiter, stage: ports.StagePanel, metrics: g.metrics}
+			resp, attempts, err := generateWithAttempts(ctx, limiter, g.maxAttempts,
+				func(attempt int, err error) {
+					logger.Warn("Panel generation failed, retrying", "attempt", attempt, "error", err)
+					reporter.OnProgress(ports.ProgressEvent{
+						Kind: ports.ProgressRetried, Index: i + 1, Attempt: attempt, Seed: seed, Err: err,
+					})
 				},
-				Image: imagePorts.ImageURI{
-					FileAPIURI:   fileURI,
-					ReferenceURL: char.ReferenceURL,
+				func(ctx context.Context, attempt int) (*imagePorts.ImageResponse, error) {
+					if attempt == 1 {
+						logger.Info("Starting panel generation")
+						reporter.OnProgress(ports.ProgressEvent{
+							Kind: ports.ProgressStarted, Index: i + 1, Attempt: attempt, Seed: seed,
+						})
+					}
+					return observeGeneration(g.metrics, ports.StagePanel, g.model, func() (*imagePorts.ImageResponse, error) {
+						return deadline.Call(ctx, ports.StagePanel, g.requestTimeout, func(ctx context.Context) (*imagePorts.ImageResponse, error) {
+							resp, err := g.generator.GenerateSingleImage(ctx, imagePorts.SingleImageRequest{
+								GenerationOptions: imagePorts.GenerationOptions{
+									Model:          g.model,
+									Prompt:         userPrompt,
+									SystemPrompt:   systemPrompt,
+									NegativePrompt: negativePanelPrompt,
+									AspectRatio:    PanelAspectRatio,
+									ImageSize:      ImageSize1K,
+									Seed:           char.Seed,
+								},
+								Image: imagePorts.ImageURI{
+									FileAPIURI:   fileURI,
+									ReferenceURL: char.ReferenceURL,
+								},
+							})
+							return resp, apierr.Classify(err)
+						})
+					})
 				},
-			})
+			)
+			duration := time.Since(startTime)
+			span.SetAttributes(telemetry.AttrRetryCount.Int(attempts - 1))
 			if err != nil {
-				return fmt.Errorf("panel %d (character_id: %s) generation failed: %w", i+1, char.ID, err)
+				g.metrics.IncFailure(ports.StagePanel, ports.ClassifyError(err))
+				err = &ports.GenerationError{
+					Stage: ports.StagePanel, Index: i + 1, CharacterID: char.ID, Attempts: attempts, Err: err,
+				}
+				reporter.OnProgress(ports.ProgressEvent{
+					Kind: ports.ProgressFailed, Index: i + 1, Attempt: attempts, Seed: seed, Duration: duration, Err: err,
+				})
+				return err
 			}
 
 			logger.Info("Panel generation completed",
-				"duration", time.Since(startTime).Round(time.Second),
+				"duration", duration.Round(time.Second),
 			)
+			reporter.OnProgress(ports.ProgressEvent{
+				Kind: ports.ProgressCompleted, Index: i + 1, Attempt: attempts, Seed: resp.UsedSeed, Duration: duration,
+			})
 			images[i] = resp
 			return nil
 		})
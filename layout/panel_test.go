@@ -2,45 +2,22 @@ package layout
 
 import (
 	"context"
-	"sync"
+	"errors"
 	"testing"
 	"time"
 
 	imagePorts "github.com/shouni/gemini-image-kit/ports"
 	characterkit "github.com/shouni/go-character-kit/character"
+	"github.com/shouni/go-manga-kit/mangakittest"
 	"github.com/shouni/go-manga-kit/ports"
 )
 
-// --- Mocks ---
-
-type mockPanelImageGenerator struct {
-	mu            sync.Mutex
-	generateCount int
-	generateFunc  func(ctx context.Context, req imagePorts.SingleImageRequest) (*imagePorts.ImageResponse, error)
-}
-
-func (m *mockPanelImageGenerator) GenerateSingleImage(ctx context.Context, req imagePorts.SingleImageRequest) (*imagePorts.ImageResponse, error) {
-	m.mu.Lock()
-	m.generateCount++
-	m.mu.Unlock()
-	if m.generateFunc != nil {
-		return m.generateFunc(ctx, req)
-	}
-	var s int64
-	if req.Seed != nil {
-		s = *req.Seed
-	}
-	return &imagePorts.ImageResponse{Data: []byte("fake-panel-image"), UsedSeed: s}, nil
-}
-
-// --- Tests ---
-
 func TestPanelGenerator_Execute(t *testing.T) {
 	ctx := context.Background()
 
 	// 1. 依存関係のセットアップ
-	assetMgr := &mockAssetManager{}
-	backend := &mockBackend{isVertex: false}
+	assetMgr := &mangakittest.AssetManager{}
+	backend := &mangakittest.Backend{VertexAI: false}
 
 	// 異なる Seed 値を持つキャラクターを用意
 	cm, err := characterkit.NewCharacters([]ports.Character{
@@ -65,8 +42,8 @@ func TestPanelGenerator_Execute(t *testing.T) {
 	}
 	composer, _ := NewMangaComposer(assetMgr, backend, cm)
 
-	genMock := &mockPanelImageGenerator{}
-	pbMock := &mockImagePrompt{} // page_test.go で定義したものを使用
+	genMock := &mangakittest.ImageGenerator{}
+	pbMock := &mangakittest.ImagePrompt{} // page_test.go で定義したものを使用
 
 	// 2. Generator の作成 (高速化設定)
 	generator := NewPanelGenerator(
@@ -90,7 +67,7 @@ func TestPanelGenerator_Execute(t *testing.T) {
 
 		// リクエストされた Seed を記録するためのスライス
 		capturedSeeds := make([]int64, len(panels))
-		genMock.generateFunc = func(_ context.Context, req imagePorts.SingleImageRequest) (*imagePorts.ImageResponse, error) {
+		genMock.SingleFunc = func(_ context.Context, req imagePorts.SingleImageRequest) (*imagePorts.ImageResponse, error) {
 			// どのパネルのリクエストか特定するのが難しいため、
 			// 呼ばれた順ではなく最終的な Seed 値を検証
 			return &imagePorts.ImageResponse{UsedSeed: *req.Seed}, nil
@@ -133,8 +110,8 @@ func TestPanelGenerator_Execute(t *testing.T) {
 
 	t.Run("Vertex AI Bypass in Panel Generation", func(t *testing.T) {
 		// Vertex モードでは File API へのアップロードをスキップして直接生成に回る
-		backend.isVertex = true
-		genMock.generateCount = 0
+		backend.VertexAI = true
+		before := len(genMock.SingleRequests())
 
 		panels := []ports.Panel{{SpeakerID: "zundamon"}}
 
@@ -143,10 +120,87 @@ func TestPanelGenerator_Execute(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		if genMock.generateCount != 1 {
-			t.Errorf("Expected 1 generation call, got %d", genMock.generateCount)
+		if got := len(genMock.SingleRequests()) - before; got != 1 {
+			t.Errorf("Expected 1 generation call, got %d", got)
 		}
-		// mockAssetManager.uploadCount が増えていないことを確認したいが、
+		// AssetManager のアップロードが増えていないことを確認したいが、
 		// PrepareCharacterResources 内の挙動に依存するためここでは生成が成功することを確認
 	})
 }
+
+func TestPanelGenerator_RequestTimeoutIsRetried(t *testing.T) {
+	cm, err := characterkit.NewCharacters([]ports.Character{
+		{ID: "zundamon", Name: "ずんだもん", IsDefault: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	composer, _ := NewMangaComposer(&mangakittest.AssetManager{}, &mangakittest.Backend{}, cm)
+
+	var attempts int
+	mockGen := &mangakittest.ImageGenerator{
+		SingleFunc: func(ctx context.Context, _ imagePorts.SingleImageRequest) (*imagePorts.ImageResponse, error) {
+			attempts++
+			if attempts == 1 {
+				// 1回目はハングした呼び出しを再現し、期限切れまで戻りません。
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			return &imagePorts.ImageResponse{}, nil
+		},
+	}
+
+	observer := &recordingObserver{}
+	gen := NewPanelGenerator(composer, mockGen, &mangakittest.ImagePrompt{}, "test-model",
+		WithPanelRateInterval(time.Microsecond),
+		WithPanelMaxAttempts(2),
+		WithPanelRequestTimeout(20*time.Millisecond),
+		WithPanelProgressObserver(observer),
+	)
+
+	if _, err := gen.Execute(context.Background(), []ports.Panel{{SpeakerID: "zundamon"}}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+	for _, e := range observer.events {
+		if e.Kind == ports.ProgressRetried && !errors.Is(e.Err, ports.ErrRequestTimeout) {
+			t.Errorf("retried event err = %v, want ErrRequestTimeout", e.Err)
+		}
+	}
+}
+
+func TestPanelGenerator_BlockedContentIsNotRetried(t *testing.T) {
+	cm, err := characterkit.NewCharacters([]ports.Character{
+		{ID: "zundamon", Name: "ずんだもん", IsDefault: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	composer, _ := NewMangaComposer(&mangakittest.AssetManager{}, &mangakittest.Backend{}, cm)
+
+	mockGen := &mangakittest.ImageGenerator{
+		SingleFunc: func(context.Context, imagePorts.SingleImageRequest) (*imagePorts.ImageResponse, error) {
+			return nil, errors.New("generation failed with FinishReason: PROHIBITED_CONTENT")
+		},
+	}
+	gen := NewPanelGenerator(composer, mockGen, &mangakittest.ImagePrompt{}, "test-model",
+		WithPanelRateInterval(time.Microsecond),
+		WithPanelMaxAttempts(3),
+	)
+
+	_, err = gen.Execute(context.Background(), []ports.Panel{{SpeakerID: "zundamon"}})
+
+	var genErr *ports.GenerationError
+	if !errors.As(err, &genErr) || genErr.Stage != ports.StagePanel || genErr.Index != 1 || genErr.CharacterID != "zundamon" {
+		t.Fatalf("err = %v, want a *GenerationError for panel 1", err)
+	}
+	var blocked *ports.ContentBlockedError
+	if !errors.As(err, &blocked) || blocked.Reason != "PROHIBITED_CONTENT" {
+		t.Errorf("err = %v, want *ContentBlockedError with the finish reason", err)
+	}
+	if got := len(mockGen.SingleRequests()); got != 1 {
+		t.Errorf("GenerateSingleImage calls = %d, blocked content should not be retried", got)
+	}
+}
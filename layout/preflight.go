@@ -0,0 +1,318 @@
+package layout
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // image.DecodeConfig 用
+	_ "image/jpeg" // image.DecodeConfig 用
+	_ "image/png"  // image.DecodeConfig 用
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/shouni/go-manga-kit/internal/telemetry"
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// preflightConcurrency は事前検査で同時に読み込む参照画像の数です。
+const preflightConcurrency = 8
+
+// PreflightLimits は参照画像の事前検査の基準です。0 の項目は検査しません。
+type PreflightLimits struct {
+	// MaxBytes は参照画像1枚あたりの最大サイズです。
+	MaxBytes int64
+	// MinDimension・MaxDimension は幅・高さそれぞれの下限・上限（ピクセル）です。
+	MinDimension int
+	MaxDimension int
+	// AllowedMIMETypes は許可する MIME タイプです。空の場合は検査しません。
+	AllowedMIMETypes []string
+}
+
+// DefaultPreflightLimits は、Gemini の画像入力の制約に合わせた既定の検査基準を返します。
+func DefaultPreflightLimits() PreflightLimits {
+	return PreflightLimits{
+		MaxBytes:         20 * 1024 * 1024,
+		MinDimension:     64,
+		MaxDimension:     8192,
+		AllowedMIMETypes: []string{"image/png", "image/jpeg", "image/webp"},
+	}
+}
+
+// PreflightIssue は、事前検査で見つかった参照画像1枚の問題です。
+type PreflightIssue struct {
+	ReferenceURL string
+	// CharacterIDs はこの画像を参照するキャラクターのIDです。
+	CharacterIDs []string
+	// PanelIndexes はこの画像を ReferenceURL に持つパネルの番号（1始まり）です。
+	PanelIndexes []int
+	// Problem は問題の内容です。
+	Problem string
+	// Err は読み込み失敗などの元のエラーです（無い場合は nil）。
+	Err error
+}
+
+func (i PreflightIssue) String() string {
+	var owners []string
+	if len(i.CharacterIDs) > 0 {
+		owners = append(owners, "character_id: "+strings.Join(i.CharacterIDs, ", "))
+	}
+	if len(i.PanelIndexes) > 0 {
+		indexes := make([]string, len(i.PanelIndexes))
+		for n, idx := range i.PanelIndexes {
+			indexes[n] = strconv.Itoa(idx)
+		}
+		owners = append(owners, "panel: "+strings.Join(indexes, ", "))
+	}
+	s := fmt.Sprintf("'%s' (%s): %s", i.ReferenceURL, strings.Join(owners, "; "), i.Problem)
+	if i.Err != nil {
+		s += ": " + i.Err.Error()
+	}
+	return s
+}
+
+// PreflightReport は事前検査の結果です。
+type PreflightReport struct {
+	// Checked は検査した参照画像の数です（URL の重複は除きます）。
+	Checked int
+	Issues  []PreflightIssue
+}
+
+// OK は問題が見つからなかった場合に true を返します。
+func (r *PreflightReport) OK() bool { return r == nil || len(r.Issues) == 0 }
+
+// Err は、問題が見つかった場合にレポート自体をエラーとして返します。
+// 返されるエラーは errors.Is で ports.ErrInvalidAsset と判定できます。
+func (r *PreflightReport) Err() error {
+	if r.OK() {
+		return nil
+	}
+	return r
+}
+
+func (r *PreflightReport) Error() string {
+	lines := make([]string, 0, len(r.Issues)+1)
+	lines = append(lines, fmt.Sprintf("%d of %d reference assets failed preflight:", len(r.Issues), r.Checked))
+	for _, issue := range r.Issues {
+		lines = append(lines, "  - "+issue.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Is は ports.ErrInvalidAsset との比較を可能にします。
+func (r *PreflightReport) Is(target error) bool { return target == ports.ErrInvalidAsset }
+
+// preflightTarget は検査対象の参照画像と、それを参照する所有者です。
+type preflightTarget struct {
+	referenceURL string
+	characterIDs []string
+	panelIndexes []int
+}
+
+// Preflight は、生成を始める前に、panels が使用するキャラクター（既定キャラクターを含む）と
+// パネルの参照画像をすべて ContentReader（WithAssetSource）から読み込み、取得可否・MIME タイプ・
+// デコード可否・寸法・サイズを検査します。見つかった問題はすべてレポートにまとめて返します。
+// 読み込み元が設定されていない場合、および ctx が終了した場合はエラーを返します。
+func (mc *MangaComposer) Preflight(ctx context.Context, panels []ports.Panel) (_ *PreflightReport, err error) {
+	if mc.source == nil {
+		return nil, errors.New("preflight requires an asset source (WithAssetSource)")
+	}
+
+	targets := mc.preflightTargets(panels)
+	ctx, span := telemetry.Start(ctx, "manga.assets.preflight", telemetry.AttrAssetCount.Int(len(targets)))
+	defer func() { telemetry.End(span, err) }()
+
+	report := &PreflightReport{Checked: len(targets)}
+	var mu sync.Mutex
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(preflightConcurrency)
+	for _, target := range targets {
+		eg.Go(func() error {
+			problem, cause := mc.inspectTarget(egCtx, target)
+			if problem == "" {
+				return nil
+			}
+			if egCtx.Err() != nil {
+				return egCtx.Err()
+			}
+			mu.Lock()
+			report.Issues = append(report.Issues, PreflightIssue{
+				ReferenceURL: target.referenceURL,
+				CharacterIDs: target.characterIDs,
+				PanelIndexes: target.panelIndexes,
+				Problem:      problem,
+				Err:          cause,
+			})
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(report.Issues, func(i, j int) bool { return report.Issues[i].ReferenceURL < report.Issues[j].ReferenceURL })
+	return report, nil
+}
+
+// preflight は WithAssetPreflight が有効な場合に Preflight を実行し、問題があればエラーを返します。
+func (mc *MangaComposer) preflight(ctx context.Context, panels []ports.Panel) error {
+	if !mc.preflightEnabled {
+		return nil
+	}
+	report, err := mc.Preflight(ctx, panels)
+	if err != nil {
+		return err
+	}
+	return report.Err()
+}
+
+// preflightTargets は検査対象の参照画像を URL ごとにまとめて返します。
+func (mc *MangaComposer) preflightTargets(panels []ports.Panel) []*preflightTarget {
+	byURL := make(map[string]*preflightTarget)
+	var order []*preflightTarget
+	get := func(url string) *preflightTarget {
+		t, ok := byURL[url]
+		if !ok {
+			t = &preflightTarget{referenceURL: url}
+			byURL[url] = t
+			order = append(order, t)
+		}
+		return t
+	}
+	addCharacter := func(char *ports.Character) {
+		if char == nil {
+			return
+		}
+		urls := []string{char.ReferenceURL}
+		for _, url := range char.ReferenceURLs {
+			urls = append(urls, url)
+		}
+		for _, url := range urls {
+			if url == "" {
+				continue
+			}
+			t := get(url)
+			if !slices.Contains(t.characterIDs, char.ID) {
+				t.characterIDs = append(t.characterIDs, char.ID)
+			}
+		}
+	}
+
+	addCharacter(mc.CharactersMap.GetDefault())
+	for _, id := range ports.Panels(panels).UniqueSpeakerIDs() {
+		addCharacter(mc.CharactersMap.GetCharacterWithDefault(id))
+	}
+	for i, panel := range panels {
+		if panel.ReferenceURL != "" {
+			t := get(panel.ReferenceURL)
+			t.panelIndexes = append(t.panelIndexes, i+1)
+		}
+	}
+	return order
+}
+
+// inspectTarget は、パネルの参照画像であれば参照方針を検査したうえで、参照画像を検査します。
+// 方針に違反する URL は読み込みません。
+func (mc *MangaComposer) inspectTarget(ctx context.Context, target *preflightTarget) (string, error) {
+	if len(target.panelIndexes) > 0 {
+		if err := mc.referencePolicy.Check(ctx, target.referenceURL); err != nil {
+			return "is not allowed by the reference policy", err
+		}
+	}
+	return mc.inspectReference(ctx, target.referenceURL)
+}
+
+// inspectReference は参照画像1枚を検査し、問題があればその内容と原因を返します。
+func (mc *MangaComposer) inspectReference(ctx context.Context, referenceURL string) (string, error) {
+	limits := mc.preflightLimits
+
+	rc, err := mc.source.Open(ctx, referenceURL)
+	if err != nil {
+		return "could not be fetched", err
+	}
+	defer rc.Close()
+
+	var r io.Reader = rc
+	if limits.MaxBytes > 0 {
+		r = io.LimitReader(rc, limits.MaxBytes+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "could not be read", err
+	}
+	if limits.MaxBytes > 0 && int64(len(data)) > limits.MaxBytes {
+		return fmt.Sprintf("exceeds the size limit of %d bytes", limits.MaxBytes), nil
+	}
+	if len(data) == 0 {
+		return "is empty", nil
+	}
+
+	mimeType := http.DetectContentType(data)
+	if len(limits.AllowedMIMETypes) > 0 && !slices.Contains(limits.AllowedMIMETypes, mimeType) {
+		return fmt.Sprintf("has unsupported MIME type %s", mimeType), nil
+	}
+
+	width, height, err := imageDimensions(data, mimeType)
+	if err != nil {
+		return "could not be decoded as an image", err
+	}
+	switch {
+	case limits.MinDimension > 0 && (width < limits.MinDimension || height < limits.MinDimension):
+		return fmt.Sprintf("is %dx%d, smaller than the minimum of %dpx", width, height, limits.MinDimension), nil
+	case limits.MaxDimension > 0 && (width > limits.MaxDimension || height > limits.MaxDimension):
+		return fmt.Sprintf("is %dx%d, larger than the maximum of %dpx", width, height, limits.MaxDimension), nil
+	}
+	return "", nil
+}
+
+// imageDimensions は画像ヘッダーから幅と高さを読み取ります。
+// WebP は標準ライブラリにデコーダーが無いため、RIFF ヘッダーを直接解析します。
+func imageDimensions(data []byte, mimeType string) (int, int, error) {
+	if mimeType == "image/webp" {
+		return webpDimensions(data)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, err
+	}
+	return cfg.Width, cfg.Height, nil
+}
+
+// webpDimensions は WebP（VP8・VP8L・VP8X）の先頭チャンクから幅と高さを読み取ります。
+func webpDimensions(data []byte) (int, int, error) {
+	if len(data) < 30 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 0, 0, errors.New("invalid WebP header")
+	}
+	chunk := data[20:]
+	switch string(data[12:16]) {
+	case "VP8 ":
+		if chunk[3] != 0x9d || chunk[4] != 0x01 || chunk[5] != 0x2a {
+			return 0, 0, errors.New("invalid VP8 start code")
+		}
+		w := int(binary.LittleEndian.Uint16(chunk[6:8]) & 0x3fff)
+		h := int(binary.LittleEndian.Uint16(chunk[8:10]) & 0x3fff)
+		return w, h, nil
+	case "VP8L":
+		if chunk[0] != 0x2f {
+			return 0, 0, errors.New("invalid VP8L signature")
+		}
+		bits := binary.LittleEndian.Uint32(chunk[1:5])
+		return int(bits&0x3fff) + 1, int((bits>>14)&0x3fff) + 1, nil
+	case "VP8X":
+		w := int(uint32(chunk[4])|uint32(chunk[5])<<8|uint32(chunk[6])<<16) + 1
+		h := int(uint32(chunk[7])|uint32(chunk[8])<<8|uint32(chunk[9])<<16) + 1
+		return w, h, nil
+	default:
+		return 0, 0, fmt.Errorf("unsupported WebP chunk %q", data[12:16])
+	}
+}
@@ -0,0 +1,100 @@
+package layout
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/shouni/go-manga-kit/mangakittest"
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+var preflightTestCharacters = []ports.Character{
+	{ID: "zundamon", Name: "ずんだもん", ReferenceURL: "https://example.com/zunda.png", IsDefault: true},
+	{ID: "metan", Name: "めたん", ReferenceURL: "https://example.com/missing.png"},
+	{ID: "tsumugi", Name: "つむぎ", ReferenceURL: "https://example.com/tiny.png"},
+}
+
+func TestMangaComposer_PreflightReportsEveryProblem(t *testing.T) {
+	source := &mangakittest.ContentReader{}
+	source.SetFile("https://example.com/zunda.png", pngBytes(t, 256, 256))
+	source.SetFile("https://example.com/tiny.png", pngBytes(t, 16, 16))
+	source.SetFile("https://example.com/error.html", []byte("<!DOCTYPE html><html><body>404 Not Found</body></html>"))
+	source.SetFile("https://example.com/huge.png", append(pngBytes(t, 128, 128), make([]byte, 8192)...))
+	mc, _ := newTestComposer(t, preflightTestCharacters, WithAssetSource(source), WithPreflightLimits(PreflightLimits{
+		MaxBytes:         4096,
+		MinDimension:     64,
+		AllowedMIMETypes: []string{"image/png"},
+	}))
+
+	panels := []ports.Panel{
+		{SpeakerID: "zundamon"},
+		{SpeakerID: "metan", ReferenceURL: "https://example.com/error.html"},
+		{SpeakerID: "tsumugi", ReferenceURL: "https://example.com/error.html"},
+		{SpeakerID: "zundamon", ReferenceURL: "https://example.com/huge.png"},
+	}
+	report, err := mc.Preflight(context.Background(), panels)
+	if err != nil {
+		t.Fatalf("Preflight failed: %v", err)
+	}
+	if report.Checked != 5 {
+		t.Errorf("Checked = %d, want 5", report.Checked)
+	}
+
+	byURL := map[string]PreflightIssue{}
+	for _, issue := range report.Issues {
+		byURL[issue.ReferenceURL] = issue
+	}
+	if len(byURL) != 4 {
+		t.Fatalf("issues = %v, want 4", report.Issues)
+	}
+	if issue := byURL["https://example.com/missing.png"]; !slices.Equal(issue.CharacterIDs, []string{"metan"}) || issue.Err == nil {
+		t.Errorf("missing reference issue = %+v, want owner metan with the fetch error", issue)
+	}
+	if issue := byURL["https://example.com/error.html"]; !slices.Equal(issue.PanelIndexes, []int{2, 3}) {
+		t.Errorf("HTML reference issue panels = %v, want [2 3]", issue.PanelIndexes)
+	}
+	if issue := byURL["https://example.com/tiny.png"]; !slices.Equal(issue.CharacterIDs, []string{"tsumugi"}) {
+		t.Errorf("tiny reference issue = %+v, want owner tsumugi", issue)
+	}
+	if _, ok := byURL["https://example.com/huge.png"]; !ok {
+		t.Error("oversized reference was not reported")
+	}
+
+	if err := report.Err(); !errors.Is(err, ports.ErrInvalidAsset) {
+		t.Errorf("report.Err() = %v, want ErrInvalidAsset", err)
+	}
+	if ports.IsRetryable(report.Err()) {
+		t.Error("preflight failures must not be retryable")
+	}
+}
+
+func TestMangaComposer_PreflightAcceptsWebP(t *testing.T) {
+	// 100x80 の VP8L（ロスレス）ヘッダー。
+	bits := uint32(100-1) | uint32(80-1)<<14
+	webp := append([]byte("RIFF\x00\x00\x00\x00WEBPVP8L\x00\x00\x00\x00\x2f"),
+		byte(bits), byte(bits>>8), byte(bits>>16), byte(bits>>24))
+	webp = append(webp, make([]byte, 16)...)
+
+	w, h, err := imageDimensions(webp, "image/webp")
+	if err != nil || w != 100 || h != 80 {
+		t.Fatalf("imageDimensions = (%d, %d, %v), want (100, 80, nil)", w, h, err)
+	}
+}
+
+func TestPanelGenerator_PreflightBlocksGeneration(t *testing.T) {
+	source := &mangakittest.ContentReader{}
+	source.SetFile("https://example.com/zunda.png", pngBytes(t, 256, 256))
+	mc, assets := newTestComposer(t, preflightTestCharacters, WithAssetSource(source), WithAssetPreflight(true))
+	images := &mangakittest.ImageGenerator{}
+	generator := NewPanelGenerator(mc, images, &mangakittest.ImagePrompt{}, "model")
+
+	_, err := generator.Execute(context.Background(), []ports.Panel{{SpeakerID: "zundamon"}, {SpeakerID: "metan"}})
+	if !errors.Is(err, ports.ErrInvalidAsset) {
+		t.Fatalf("Execute error = %v, want ErrInvalidAsset", err)
+	}
+	if len(assets.Uploads()) != 0 || len(images.SingleRequests()) != 0 {
+		t.Errorf("uploads = %v, generations = %d; want nothing after a failed preflight", assets.Uploads(), len(images.SingleRequests()))
+	}
+}
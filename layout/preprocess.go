@@ -0,0 +1,162 @@
+package layout
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/shouni/go-remote-io/remoteio"
+
+	"github.com/shouni/go-manga-kit/asset"
+	"github.com/shouni/go-manga-kit/imaging"
+)
+
+// ReferencePreprocessing は、参照画像をアップロード前に正規化する設定です。
+// 正規化した画像は StagingDir に保存され、AssetManager はその保存先をアップロードします。
+// 同じ内容・設定の画像は1度だけ正規化・アップロードされます。
+type ReferencePreprocessing struct {
+	// Character・Panel はキャラクター・パネルの参照画像それぞれの正規化設定です。
+	Character imaging.Options
+	Panel     imaging.Options
+	// Writer は正規化した画像の保存に使います。remoteio.Remover を実装していれば、
+	// 保存した画像はアップロード後（失敗した場合も含む）に削除されます。
+	Writer remoteio.Writer
+	// StagingDir は正規化した画像の保存先ディレクトリ（GCS/ローカル）です。
+	// Writer が削除に対応していない場合は、ライフサイクルルール等で期限を設定してください。
+	StagingDir string
+}
+
+// options は resourceType の正規化設定を返します。
+func (p *ReferencePreprocessing) options(resourceType string) imaging.Options {
+	if resourceType == "panel" {
+		return p.Panel
+	}
+	return p.Character
+}
+
+// preprocessReference は、前処理が有効な場合に data の前処理結果のキーを返します。
+// 同じ内容・設定の画像が既にアップロード済みであれば、そのアセットを reuse として返します。
+// 前処理が無効な場合、または data を読み込めなかった場合は空のキーを返します。
+func (mc *MangaComposer) preprocessReference(resourceType string, data []byte) (variantKey string, reuse *UploadedAsset) {
+	if mc.preprocess == nil || data == nil {
+		return "", nil
+	}
+	variantKey = imaging.ContentKey(data, mc.preprocess.options(resourceType))
+
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+	uri, ok := mc.variants[variantKey]
+	if !ok {
+		return variantKey, nil
+	}
+	existing, ok := mc.uploads[uri]
+	if !ok || existing.expiring(time.Now(), mc.refreshMargin) {
+		return variantKey, nil
+	}
+	return variantKey, &existing
+}
+
+// stageVariant は data を正規化して StagingDir に保存し、保存先を返します。
+// 正規化・保存に失敗した場合はログに記録し、false を返します。
+func (mc *MangaComposer) stageVariant(ctx context.Context, referenceURL, resourceType, variantKey string, data []byte) (string, bool) {
+	result, err := imaging.Normalize(data, mc.preprocess.options(resourceType))
+	if err != nil {
+		slog.WarnContext(ctx, "参照画像の前処理をスキップしました", "reference_url", referenceURL, "error", err)
+		return "", false
+	}
+	staged, err := asset.ResolveOutputPath(mc.preprocess.StagingDir, variantKey[:32]+result.Extension())
+	if err != nil {
+		slog.WarnContext(ctx, "前処理した参照画像の保存先を解決できませんでした", "staging_dir", mc.preprocess.StagingDir, "error", err)
+		return "", false
+	}
+	if err := mc.preprocess.Writer.Write(ctx, staged, bytes.NewReader(result.Data), remoteio.WithContentType(result.MIMEType)); err != nil {
+		slog.WarnContext(ctx, "前処理した参照画像の保存に失敗しました", "reference_url", referenceURL, "path", staged, "error", err)
+		return "", false
+	}
+	slog.DebugContext(ctx, "参照画像を前処理しました",
+		"reference_url", referenceURL,
+		"staged", staged,
+		"original_bytes", len(data),
+		"bytes", len(result.Data),
+		"width", result.Width,
+		"height", result.Height,
+	)
+	return staged, true
+}
+
+// removeStaged は、アップロードを終えた前処理済みの画像を削除します。
+// Writer が remoteio.Remover を実装していない場合は何もしません。呼び出し元の
+// コンテキストがキャンセルされていても削除できるよう、キャンセルを引き継ぎません。
+func (mc *MangaComposer) removeStaged(ctx context.Context, staged string) {
+	remover, ok := mc.preprocess.Writer.(remoteio.Remover)
+	if !ok {
+		return
+	}
+	if err := remover.Delete(context.WithoutCancel(ctx), staged); err != nil {
+		slog.WarnContext(ctx, "前処理した参照画像の削除に失敗しました", "path", staged, "error", err)
+	}
+}
+
+// rememberVariant は、前処理した画像のアップロード結果を記録します。
+func (mc *MangaComposer) rememberVariant(variantKey, fileAPIURI string) {
+	if variantKey == "" {
+		return
+	}
+	mc.mu.Lock()
+	mc.variants[variantKey] = fileAPIURI
+	mc.mu.Unlock()
+}
+
+// uploadedVariant は、前処理結果 variantKey のアップロード済みで期限の迫っていない File API URI を返します。
+func (mc *MangaComposer) uploadedVariant(variantKey string) (string, bool) {
+	if variantKey == "" {
+		return "", false
+	}
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+	uri, ok := mc.variants[variantKey]
+	if !ok {
+		return "", false
+	}
+	if existing, ok := mc.uploads[uri]; ok && existing.expiring(time.Now(), mc.refreshMargin) {
+		return "", false
+	}
+	return uri, true
+}
+
+// loadReference は、レジストリのハッシュまたは前処理に使う参照画像を1度だけ読み込みます。
+// いずれも不要な場合、および読み込みに失敗した場合は nil を返します。
+func (mc *MangaComposer) loadReference(ctx context.Context, referenceURL string) []byte {
+	if mc.source == nil || (mc.registry == nil && mc.preprocess == nil) {
+		return nil
+	}
+	data, err := mc.readReference(ctx, referenceURL)
+	if err != nil {
+		slog.WarnContext(ctx, "参照画像を読み込めなかったため、前処理をスキップします", "reference_url", referenceURL, "error", err)
+		return nil
+	}
+	return data
+}
+
+// readReference は参照画像を読み込みます。
+func (mc *MangaComposer) readReference(ctx context.Context, referenceURL string) ([]byte, error) {
+	rc, err := mc.source.Open(ctx, referenceURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxPreprocessBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxPreprocessBytes {
+		return nil, fmt.Errorf("参照画像が前処理の上限（%d バイト）を超えています", maxPreprocessBytes)
+	}
+	return data, nil
+}
+
+// maxPreprocessBytes は前処理のために読み込む参照画像の上限です。
+const maxPreprocessBytes = 64 * 1024 * 1024
@@ -0,0 +1,143 @@
+package layout
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	characterkit "github.com/shouni/go-character-kit/character"
+
+	"github.com/shouni/go-manga-kit/asset"
+	"github.com/shouni/go-manga-kit/imaging"
+	"github.com/shouni/go-manga-kit/mangakittest"
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+func TestMangaComposer_PreprocessesReferencesBeforeUpload(t *testing.T) {
+	ctx := context.Background()
+	cm, err := characterkit.NewCharacters([]ports.Character{
+		{ID: "zundamon", Name: "ずんだもん", ReferenceURL: "https://example.com/zunda.png", IsDefault: true},
+		{ID: "copy", Name: "コピー", ReferenceURL: "https://example.com/zunda-copy.png"},
+		{ID: "broken", Name: "ブローク", ReferenceURL: "https://example.com/broken.webp"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := &mangakittest.ContentReader{}
+	source.SetFile("https://example.com/zunda.png", pngBytes(t, 400, 200))
+	source.SetFile("https://example.com/zunda-copy.png", pngBytes(t, 400, 200))
+	source.SetFile("https://example.com/broken.webp", []byte("RIFF....WEBP"))
+	staging := &mangakittest.Writer{}
+	// 保存した画像はアップロード後に削除されるため、アップロードの時点の内容を控えます。
+	uploaded := make(map[string][]byte)
+	assets := &mangakittest.AssetManager{
+		UploadFunc: func(_ context.Context, fileURI string) (string, error) {
+			if data, ok := staging.File(fileURI); ok {
+				uploaded[fileURI] = data
+			}
+			return mangakittest.FileAPIURIPrefix + fileURI, nil
+		},
+	}
+
+	mc, err := NewMangaComposer(assets, &mangakittest.Backend{}, cm,
+		WithAssetSource(source),
+		WithReferencePreprocessing(ReferencePreprocessing{
+			Character:  imaging.Options{MaxEdge: 100, AspectRatio: "1:1"},
+			Writer:     staging,
+			StagingDir: "gs://staging/refs",
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	panels := []ports.Panel{{SpeakerID: "zundamon"}, {SpeakerID: "copy"}, {SpeakerID: "broken"}}
+	if err := mc.PrepareCharacterResources(ctx, panels); err != nil {
+		t.Fatal(err)
+	}
+
+	// 同じ内容の2枚は1度だけ正規化・アップロードされ、デコードできない画像は元の URL のまま
+	// アップロードされます。
+	uploads := assets.Uploads()
+	if len(uploads) != 2 {
+		t.Fatalf("Uploads() = %v, want 2", uploads)
+	}
+	var stagedUpload string
+	for _, u := range uploads {
+		if strings.HasPrefix(u, "gs://staging/refs/") {
+			stagedUpload = u
+		} else if u != "https://example.com/broken.webp" {
+			t.Errorf("unexpected upload source %q", u)
+		}
+	}
+	if stagedUpload == "" {
+		t.Fatalf("no staged upload in %v", uploads)
+	}
+	if mc.GetCharacterResourceURI("zundamon") != mc.GetCharacterResourceURI("copy") {
+		t.Error("identical references should share one File API URI")
+	}
+
+	data, ok := uploaded[stagedUpload]
+	if !ok {
+		t.Fatalf("staged file %s was not written before upload", stagedUpload)
+	}
+	if _, ok := staging.File(stagedUpload); ok {
+		t.Errorf("staged file %s should be removed after upload", stagedUpload)
+	}
+	// 参照画像はそれぞれ1度だけ読み込まれます。
+	if opens := source.Opens(); len(opens) != 3 {
+		t.Errorf("Opens() = %v, want each reference once", opens)
+	}
+	res, err := imaging.Normalize(data, imaging.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Width != 100 || res.Height != 100 {
+		t.Errorf("staged image = %dx%d, want 100x100", res.Width, res.Height)
+	}
+}
+
+func TestMangaComposer_RemovesStagedReferenceOnUploadError(t *testing.T) {
+	ctx := context.Background()
+	cm, err := characterkit.NewCharacters([]ports.Character{
+		{ID: "zundamon", Name: "ずんだもん", ReferenceURL: "https://example.com/zunda.png", IsDefault: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := &mangakittest.ContentReader{}
+	source.SetFile("https://example.com/zunda.png", pngBytes(t, 400, 200))
+	staging := &mangakittest.Writer{}
+	assets := &mangakittest.AssetManager{UploadErr: errors.New("upload failed")}
+	registry := asset.NewMemoryRegistry()
+
+	mc, err := NewMangaComposer(assets, &mangakittest.Backend{}, cm,
+		WithAssetSource(source),
+		WithAssetRegistry(registry),
+		WithReferencePreprocessing(ReferencePreprocessing{
+			Character:  imaging.Options{MaxEdge: 100},
+			Writer:     staging,
+			StagingDir: "gs://staging/refs",
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mc.PrepareCharacterResources(ctx, []ports.Panel{{SpeakerID: "zundamon"}}); err == nil {
+		t.Fatal("PrepareCharacterResources() error = nil, want upload error")
+	}
+	if writes := staging.Writes(); len(writes) != 1 {
+		t.Fatalf("Writes() = %v, want 1 staged file", writes)
+	}
+	if paths := staging.Paths(); len(paths) != 0 {
+		t.Errorf("staged files %v should be removed after a failed upload", paths)
+	}
+	// ハッシュの算出と前処理は同じ読み込みを共有します。
+	if opens := source.Opens(); len(opens) != 1 {
+		t.Errorf("Opens() = %v, want 1", opens)
+	}
+}
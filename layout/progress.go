@@ -0,0 +1,59 @@
+package layout
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// progressReporter は、1回の Execute で発生した進捗イベントにステージ情報を付与し、
+// Runner のオプションと ctx の双方で設定された observer へ直列に通知します。
+type progressReporter struct {
+	mu        sync.Mutex
+	stage     string
+	total     int
+	observers []ports.ProgressObserver
+}
+
+// newProgressReporter は ctx の observer と generator に設定された observer を束ねた reporter を生成します。
+func newProgressReporter(ctx context.Context, stage string, total int, configured ports.ProgressObserver) *progressReporter {
+	r := &progressReporter{stage: stage, total: total}
+	if configured != nil {
+		r.observers = append(r.observers, configured)
+	}
+	if fromCtx := ports.ProgressObserverFromContext(ctx); fromCtx != nil {
+		r.observers = append(r.observers, fromCtx)
+	}
+	return r
+}
+
+// OnProgress は ports.ProgressObserver の実装です。
+func (r *progressReporter) OnProgress(event ports.ProgressEvent) {
+	if len(r.observers) == 0 {
+		return
+	}
+	if event.Stage == "" {
+		event.Stage = r.stage
+	}
+	if event.Total == 0 {
+		event.Total = r.total
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, observer := range r.observers {
+		observer.OnProgress(event)
+	}
+}
+
+// emitProgress は ctx に設定された observer へイベントを通知します。
+func emitProgress(ctx context.Context, event ports.ProgressEvent) {
+	if observer := ports.ProgressObserverFromContext(ctx); observer != nil {
+		observer.OnProgress(event)
+	}
+}
@@ -0,0 +1,184 @@
+package layout
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	imagePorts "github.com/shouni/gemini-image-kit/ports"
+	characterkit "github.com/shouni/go-character-kit/character"
+
+	"github.com/shouni/go-manga-kit/mangakittest"
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+type recordingObserver struct {
+	mu     sync.Mutex
+	events []ports.ProgressEvent
+}
+
+func (o *recordingObserver) OnProgress(event ports.ProgressEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, event)
+}
+
+func (o *recordingObserver) kinds() []ports.ProgressEventKind {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	kinds := make([]ports.ProgressEventKind, len(o.events))
+	for i, e := range o.events {
+		kinds[i] = e.Kind
+	}
+	return kinds
+}
+
+func countKind(kinds []ports.ProgressEventKind, kind ports.ProgressEventKind) int {
+	n := 0
+	for _, k := range kinds {
+		if k == kind {
+			n++
+		}
+	}
+	return n
+}
+
+func TestPanelGenerator_ProgressEventsWithRetry(t *testing.T) {
+	cm, err := characterkit.NewCharacters([]ports.Character{
+		{ID: "zundamon", Name: "ずんだもん", Seed: ptrInt64(7), ReferenceURL: "https://example.com/zunda.png", IsDefault: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	composer, _ := NewMangaComposer(&mangakittest.AssetManager{}, &mangakittest.Backend{}, cm)
+
+	var calls int
+	var mu sync.Mutex
+	gen := &mangakittest.ImageGenerator{
+		SingleFunc: func(_ context.Context, req imagePorts.SingleImageRequest) (*imagePorts.ImageResponse, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			if calls == 1 {
+				return nil, errors.New("transient failure")
+			}
+			return &imagePorts.ImageResponse{UsedSeed: *req.Seed}, nil
+		},
+	}
+
+	configured := &recordingObserver{}
+	perCall := &recordingObserver{}
+	generator := NewPanelGenerator(composer, gen, &mangakittest.ImagePrompt{}, "model",
+		WithPanelRateInterval(time.Microsecond),
+		WithPanelRateBurst(100),
+		WithPanelMaxAttempts(2),
+		WithPanelProgressObserver(configured),
+	)
+
+	ctx := ports.WithProgressObserver(context.Background(), perCall)
+	if _, err := generator.Execute(ctx, []ports.Panel{{SpeakerID: "zundamon"}}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	want := []ports.ProgressEventKind{
+		ports.ProgressStageStarted,
+		ports.ProgressAssetUploaded,
+		ports.ProgressQueued,
+		ports.ProgressStarted,
+		ports.ProgressRetried,
+		ports.ProgressCompleted,
+		ports.ProgressStageFinished,
+	}
+	for _, observer := range []*recordingObserver{configured, perCall} {
+		got := observer.kinds()
+		if len(got) != len(want) {
+			t.Fatalf("events = %v, want %v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("event[%d] = %s, want %s", i, got[i], want[i])
+			}
+		}
+	}
+
+	completed := configured.events[5]
+	if completed.Stage != ports.StagePanel || completed.Index != 1 || completed.Attempt != 2 || completed.Seed != 7 {
+		t.Errorf("completed event = %+v", completed)
+	}
+}
+
+func TestPageGenerator_ProgressEventsOnFailure(t *testing.T) {
+	cm, err := characterkit.NewCharacters([]ports.Character{
+		{ID: "zundamon", Name: "ずんだもん", ReferenceURL: "https://example.com/zunda.png", IsDefault: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	composer, _ := NewMangaComposer(&mangakittest.AssetManager{}, &mangakittest.Backend{}, cm)
+
+	gen := &mangakittest.ImageGenerator{Err: errors.New("blocked")}
+	observer := &recordingObserver{}
+	generator := NewPageGenerator(composer, gen, &mangakittest.ImagePrompt{}, "model",
+		WithPageRateInterval(time.Microsecond),
+		WithPageRateBurst(100),
+		WithPageProgressObserver(observer),
+	)
+
+	manga := &ports.MangaResponse{Panels: []ports.Panel{{SpeakerID: "zundamon"}}}
+	if _, err := generator.Execute(context.Background(), manga); err == nil {
+		t.Fatal("expected Execute to fail")
+	}
+
+	kinds := observer.kinds()
+	if countKind(kinds, ports.ProgressFailed) != 1 {
+		t.Errorf("events = %v, want exactly one failed event", kinds)
+	}
+	if countKind(kinds, ports.ProgressRetried) != 0 {
+		t.Errorf("events = %v, want no retries with the default attempt count", kinds)
+	}
+	last := observer.events[len(observer.events)-1]
+	if last.Kind != ports.ProgressStageFinished || last.Err == nil || last.Stage != ports.StagePage {
+		t.Errorf("last event = %+v, want a failed page stage_finished", last)
+	}
+}
+
+func TestPanelGenerator_ChannelObserverDoesNotBlock(t *testing.T) {
+	cm, err := characterkit.NewCharacters([]ports.Character{
+		{ID: "zundamon", Name: "ずんだもん", ReferenceURL: "https://example.com/zunda.png", IsDefault: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	composer, _ := NewMangaComposer(&mangakittest.AssetManager{}, &mangakittest.Backend{}, cm)
+
+	// 誰も受信しないバッファ1のチャネルでも、生成処理は止まらずイベントを破棄します。
+	ch := make(chan ports.ProgressEvent, 1)
+	observer := ports.ChannelObserver(ch)
+	generator := NewPanelGenerator(composer, &mangakittest.ImageGenerator{}, &mangakittest.ImagePrompt{}, "model",
+		WithPanelRateInterval(time.Microsecond),
+		WithPanelProgressObserver(observer),
+	)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := generator.Execute(context.Background(), []ports.Panel{{SpeakerID: "zundamon"}})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Execute blocked on a full progress channel")
+	}
+
+	if first := <-ch; first.Kind != ports.ProgressStageStarted {
+		t.Errorf("first event = %s, want %s", first.Kind, ports.ProgressStageStarted)
+	}
+	if observer.Dropped() == 0 {
+		t.Error("Dropped() = 0, want the events that did not fit in the channel")
+	}
+}
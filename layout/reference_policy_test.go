@@ -0,0 +1,76 @@
+package layout
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shouni/go-manga-kit/mangakittest"
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+func TestMangaComposer_PreparePanelResourcesEnforcesPolicy(t *testing.T) {
+	policy := offlineReferencePolicy()
+	policy.AllowedGCSPrefixes = []string{"gs://assets/panels/"}
+
+	tests := []struct {
+		name    string
+		url     string
+		allowed bool
+	}{
+		{"PublicHTTPS", "https://cdn.example.com/panel.png", true},
+		{"AllowedBucket", "gs://assets/panels/p1.png", true},
+		{"PlainHTTP", "http://cdn.example.com/panel.png", true},
+		{"S3", "s3://assets/panels/p1.png", true},
+		{"LocalPath", "images/panel.png", true},
+		{"LocalTraversal", "images/../../secrets/panel.png", false},
+		{"WindowsTraversal", `images\..\secrets\panel.png`, false},
+		{"FileScheme", "file:///etc/passwd", false},
+		{"PrivateHTTP", "http://10.0.0.5/panel.png", false},
+		{"Loopback", "https://127.0.0.1/panel.png", false},
+		{"MetadataServer", "https://169.254.169.254/computeMetadata/v1/", false},
+		{"ResolvesToPrivate", "https://internal.example.com/panel.png", false},
+		{"OtherBucket", "gs://other/panels/p1.png", false},
+		{"BucketTraversal", "gs://assets/panels/../private/p1.png", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mc, assets := newTestComposer(t, cleanupTestCharacters, WithReferencePolicy(policy))
+
+			err := mc.PreparePanelResources(context.Background(), []ports.Panel{{ReferenceURL: tt.url}})
+			if tt.allowed {
+				if err != nil {
+					t.Fatalf("PreparePanelResources(%q) failed: %v", tt.url, err)
+				}
+				return
+			}
+			if !errors.Is(err, ports.ErrReferenceURLNotAllowed) || !errors.Is(err, ports.ErrAssetPreparation) {
+				t.Fatalf("PreparePanelResources(%q) error = %v, want ErrReferenceURLNotAllowed", tt.url, err)
+			}
+			if len(assets.Uploads()) != 0 {
+				t.Errorf("disallowed URL was uploaded: %v", assets.Uploads())
+			}
+		})
+	}
+}
+
+func TestMangaComposer_PreflightReportsPolicyViolations(t *testing.T) {
+	source := &mangakittest.ContentReader{}
+	source.SetFile("https://example.com/zunda.png", pngBytes(t, 256, 256))
+	mc, _ := newTestComposer(t, preflightTestCharacters, WithAssetSource(source))
+
+	report, err := mc.Preflight(context.Background(), []ports.Panel{
+		{SpeakerID: "zundamon", ReferenceURL: "https://internal.example.com/panel.png"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Issues) != 1 || !errors.Is(report.Issues[0].Err, ports.ErrReferenceURLNotAllowed) {
+		t.Fatalf("issues = %v, want one policy violation", report.Issues)
+	}
+	for _, opened := range source.Opens() {
+		if opened == "https://internal.example.com/panel.png" {
+			t.Error("preflight fetched a URL rejected by the policy")
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package layout
+
+import (
+	"context"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// generateWithAttempts は、レート制限を待機したうえで generate を最大 maxAttempts 回試行します。
+// パネル・ページ単位の再試行は既定では行わず（defaultMaxAttempts）、WithPanelMaxAttempts・
+// WithPageMaxAttempts で有効にした場合のみ、AI クライアント自体の再試行とは別に行います。
+// 失敗した試行の後に再試行する場合は onRetry を呼び出します。ctx がキャンセルされた場合と、
+// ports.IsRetryable が false を返すエラー（コンテンツのブロック等）の場合は再試行しません。
+// 戻り値の int は実際の試行回数です。
+func generateWithAttempts[T any](
+	ctx context.Context,
+	limiter waiter,
+	maxAttempts int,
+	onRetry func(attempt int, err error),
+	generate func(ctx context.Context, attempt int) (T, error),
+) (T, int, error) {
+	var zero T
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err := limiter.Wait(ctx); err != nil {
+			return zero, attempt, err
+		}
+
+		res, err := generate(ctx, attempt)
+		if err == nil {
+			return res, attempt, nil
+		}
+		lastErr = err
+
+		if ctx.Err() != nil || attempt == maxAttempts || !ports.IsRetryable(err) {
+			return zero, attempt, lastErr
+		}
+		onRetry(attempt, err)
+	}
+	return zero, maxAttempts, lastErr
+}
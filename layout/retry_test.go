@@ -0,0 +1,51 @@
+package layout
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"golang.org/x/time/rate"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+func TestGenerateWithAttempts(t *testing.T) {
+	ctx := context.Background()
+	limiter := rate.NewLimiter(rate.Inf, 1)
+	transient := errors.New("transient failure")
+
+	tests := []struct {
+		name         string
+		maxAttempts  int
+		errs         []error
+		wantAttempts int
+		wantRetries  int
+		wantErr      error
+	}{
+		{name: "does not retry by default", maxAttempts: defaultMaxAttempts, errs: []error{transient}, wantAttempts: 1, wantErr: transient},
+		{name: "retries until success", maxAttempts: 3, errs: []error{transient, transient, nil}, wantAttempts: 3, wantRetries: 2},
+		{name: "stops at the attempt limit", maxAttempts: 2, errs: []error{transient, transient, nil}, wantAttempts: 2, wantRetries: 1, wantErr: transient},
+		{name: "does not retry blocked content", maxAttempts: 3, errs: []error{ports.ErrContentBlocked, nil}, wantAttempts: 1, wantErr: ports.ErrContentBlocked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls, retries int
+			_, attempts, err := generateWithAttempts(ctx, limiter, tt.maxAttempts,
+				func(int, error) { retries++ },
+				func(context.Context, int) (string, error) {
+					err := tt.errs[calls]
+					calls++
+					return "ok", err
+				},
+			)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts || calls != tt.wantAttempts || retries != tt.wantRetries {
+				t.Errorf("attempts = %d, calls = %d, retries = %d, want %d attempts and %d retries",
+					attempts, calls, retries, tt.wantAttempts, tt.wantRetries)
+			}
+		})
+	}
+}
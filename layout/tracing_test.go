@@ -0,0 +1,74 @@
+package layout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	characterkit "github.com/shouni/go-character-kit/character"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/shouni/go-manga-kit/mangakittest"
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+func TestPanelGenerator_Spans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	cm, err := characterkit.NewCharacters([]ports.Character{
+		{ID: "zundamon", Name: "ずんだもん", Seed: ptrInt64(7), ReferenceURL: "https://example.com/zunda.png", IsDefault: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	composer, _ := NewMangaComposer(&mangakittest.AssetManager{}, &mangakittest.Backend{}, cm)
+	generator := NewPanelGenerator(composer, &mangakittest.ImageGenerator{}, &mangakittest.ImagePrompt{}, "model",
+		WithPanelRateInterval(time.Microsecond),
+		WithPanelRateBurst(100),
+	)
+
+	if _, err := generator.Execute(context.Background(), []ports.Panel{{SpeakerID: "zundamon"}, {SpeakerID: "zundamon"}}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	spans := map[string][]sdktrace.ReadOnlySpan{}
+	for _, s := range recorder.Ended() {
+		spans[s.Name()] = append(spans[s.Name()], s)
+	}
+	if len(spans["manga.panels.execute"]) != 1 {
+		t.Fatalf("stage spans = %d, want 1", len(spans["manga.panels.execute"]))
+	}
+	if len(spans["manga.panel.generate"]) != 2 {
+		t.Fatalf("panel spans = %d, want 2", len(spans["manga.panel.generate"]))
+	}
+
+	stage := spans["manga.panels.execute"][0]
+	for _, s := range spans["manga.panel.generate"] {
+		if s.Parent().SpanID() != stage.SpanContext().SpanID() {
+			t.Errorf("panel span parent = %v, want the stage span", s.Parent().SpanID())
+		}
+		attrs := attribute.NewSet(s.Attributes()...)
+		if v, ok := attrs.Value("gen_ai.request.model"); !ok || v.AsString() != "model" {
+			t.Errorf("model attribute = %v", v)
+		}
+		if v, ok := attrs.Value("manga.seed"); !ok || v.AsInt64() != 7 {
+			t.Errorf("seed attribute = %v", v)
+		}
+	}
+
+	// 2つのパネルが同じ参照画像を使うため、アップロードは1回だけ行われます。
+	uploads := spans["manga.asset.upload"]
+	if len(uploads) != 1 {
+		t.Fatalf("upload spans = %d, want 1", len(uploads))
+	}
+	uploadAttrs := attribute.NewSet(uploads[0].Attributes()...)
+	if v, ok := uploadAttrs.Value("manga.asset.cache_hit"); !ok || v.AsBool() {
+		t.Errorf("cache_hit attribute = %v, want false for the first upload", v)
+	}
+}
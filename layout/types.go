@@ -28,6 +28,8 @@ const (
 	defaultRateBurst = 1
 	// DefaultRateInterval は、リクエスト間のデフォルトの待機間隔です。
 	defaultRateInterval = 60 * time.Second
+	// defaultMaxAttempts は、1パネル・1ページあたりの既定の試行回数です（再試行なし）。
+	defaultMaxAttempts = 1
 )
 
 // IsGCSURI は、指定されたURIがGCS（Google Cloud Storage）のストレージURIであるかどうかを判定します。
@@ -42,6 +44,11 @@ func IsGCSURI(uri string) bool {
 // 用意できるようにするための選択肢で、ap-comp の coverArtAspectRatios と揃えています。
 var designAspectRatios = []string{"1:1", "9:16", "16:9"}
 
+// DesignAspectRatios は、デザインシート生成でサポート対象のアスペクト比を返します。
+func DesignAspectRatios() []string {
+	return append([]string(nil), designAspectRatios...)
+}
+
 // IsDesignAspectRatio は、value がデザインシート生成でサポート対象のアスペクト比かどうかを
 // 判定します。
 func IsDesignAspectRatio(value string) bool {
@@ -0,0 +1,180 @@
+package mangakittest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"sync"
+
+	"github.com/shouni/go-remote-io/remoteio"
+)
+
+// ErrNotFound は、フェイクのストアに存在しないパスを開こうとした場合に返されます。
+// 実際のストアと同様に errors.Is(err, fs.ErrNotExist) で判定できます。
+var ErrNotFound = fmt.Errorf("file not found: %w", fs.ErrNotExist)
+
+// --- ContentReader ---
+
+// ContentReader は ports.ContentReader のフェイクです。
+// Files に登録された内容を返し、登録されていない URI には ErrNotFound を返します。
+type ContentReader struct {
+	// Files は URI から内容へのマップです。SetFile を使うと同時実行下でも安全に登録できます。
+	Files map[string][]byte
+	// OpenFunc が設定されている場合、Open はその戻り値を返します。
+	OpenFunc func(ctx context.Context, uri string) (io.ReadCloser, error)
+	// Errors は URI ごとに Open が返すエラーです。
+	Errors map[string]error
+	// Err が設定されている場合、Open は常にこのエラーを返します。
+	Err error
+
+	mu    sync.Mutex
+	opens []string
+}
+
+// SetFile は uri の内容を登録します。
+func (f *ContentReader) SetFile(uri string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Files == nil {
+		f.Files = make(map[string][]byte)
+	}
+	f.Files[uri] = data
+}
+
+// Open は ports.ContentReader の実装です。
+func (f *ContentReader) Open(ctx context.Context, uri string) (io.ReadCloser, error) {
+	f.mu.Lock()
+	f.opens = append(f.opens, uri)
+	data, ok := f.Files[uri]
+	injected := f.Errors[uri]
+	f.mu.Unlock()
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if injected != nil {
+		return nil, injected
+	}
+	if f.OpenFunc != nil {
+		return f.OpenFunc(ctx, uri)
+	}
+	if !ok {
+		return nil, notFoundError(uri)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Opens は Open に渡された URI を呼び出し順に返します。
+func (f *ContentReader) Opens() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.opens...)
+}
+
+// --- Writer ---
+
+// Writer は remoteio.Writer のフェイクとなるインメモリのファイルシステムです。
+// 書き込んだ内容は Open（ports.ContentReader）・Exists・Delete からも参照できるため、
+// 保存と再読み込みを伴う処理を1つのフェイクで検証できます。
+type Writer struct {
+	// WriteFunc が設定されている場合、Write は内容を保存した後にその戻り値を返します。
+	WriteFunc func(ctx context.Context, path string, data []byte) error
+	// Errors はパスごとに Write が返すエラーです。エラーを返した書き込みは保存されません。
+	Errors map[string]error
+	// Err が設定されている場合、Write は常にこのエラーを返します。
+	Err error
+
+	mu     sync.Mutex
+	files  map[string][]byte
+	writes []string
+}
+
+// Write は remoteio.Writer の実装です。
+func (f *Writer) Write(ctx context.Context, path string, contentReader io.Reader, _ ...remoteio.WriteOption) error {
+	f.mu.Lock()
+	f.writes = append(f.writes, path)
+	injected := f.Errors[path]
+	f.mu.Unlock()
+
+	if f.Err != nil {
+		return f.Err
+	}
+	if injected != nil {
+		return injected
+	}
+
+	data, err := io.ReadAll(contentReader)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	if f.files == nil {
+		f.files = make(map[string][]byte)
+	}
+	f.files[path] = data
+	f.mu.Unlock()
+
+	if f.WriteFunc != nil {
+		return f.WriteFunc(ctx, path, data)
+	}
+	return nil
+}
+
+// Open は書き込み済みの内容を返します。ports.ContentReader を満たします。
+func (f *Writer) Open(_ context.Context, path string) (io.ReadCloser, error) {
+	data, ok := f.File(path)
+	if !ok {
+		return nil, notFoundError(path)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Exists は path に書き込み済みの内容があるかを返します。remoteio.Exister を満たします。
+func (f *Writer) Exists(_ context.Context, path string) (bool, error) {
+	_, ok := f.File(path)
+	return ok, nil
+}
+
+// Delete は path の内容を削除します。remoteio.Remover を満たします。
+func (f *Writer) Delete(_ context.Context, path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.files, path)
+	return nil
+}
+
+// File は path に書き込まれた内容を返します。
+func (f *Writer) File(path string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.files[path]
+	return data, ok
+}
+
+// Paths は書き込み済みのパスを昇順で返します。
+func (f *Writer) Paths() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	paths := make([]string, 0, len(f.files))
+	for p := range f.files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Writes は Write に渡されたパスを呼び出し順に返します（失敗した書き込みも含みます）。
+func (f *Writer) Writes() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.writes...)
+}
+
+// notFoundError は、フェイクのストアに存在しないパスを開こうとした場合のエラーを生成します。
+func notFoundError(uri string) error {
+	return fmt.Errorf("mangakittest: %s: %w", uri, ErrNotFound)
+}
@@ -0,0 +1,170 @@
+// Package mangakittest は、go-manga-kit の各ポートを満たすテスト用のフェイク実装を提供します。
+//
+// 各フェイクは呼び出しを記録し、Err フィールドまたは *Func フィールドでエラーや応答を
+// 差し込めます。ネットワークにアクセスせずに go-manga-kit との結合をテストするために
+// 使用してください。すべてのフェイクは複数の goroutine から同時に呼び出しても安全です。
+package mangakittest
+
+import (
+	"context"
+	"sync"
+
+	imagePorts "github.com/shouni/gemini-image-kit/ports"
+)
+
+const (
+	// FileAPIURIPrefix は AssetManager が既定で返す File API URI の接頭辞です。
+	FileAPIURIPrefix = "https://generativelanguage.googleapis.com/v1beta/files/"
+	// DefaultImageData は画像生成フェイクが既定で返す画像データです。
+	DefaultImageData = "fake-image"
+	// DefaultImageMimeType は画像生成フェイクが既定で返す MIME タイプです。
+	DefaultImageMimeType = "image/png"
+)
+
+// --- AssetManager ---
+
+// AssetManager は imagePorts.AssetManager のフェイクです。
+// 既定では FileAPIURIPrefix に参照URLを連結した URI を返します。
+type AssetManager struct {
+	// UploadFunc が設定されている場合、UploadFile はその戻り値を返します。
+	UploadFunc func(ctx context.Context, fileURI string) (string, error)
+	// DeleteFunc が設定されている場合、DeleteFile はその戻り値を返します。
+	DeleteFunc func(ctx context.Context, fileURI string) error
+	// UploadErr が設定されている場合、UploadFile は常にこのエラーを返します。
+	UploadErr error
+	// DeleteErr が設定されている場合、DeleteFile は常にこのエラーを返します。
+	DeleteErr error
+
+	mu      sync.Mutex
+	uploads []string
+	deletes []string
+}
+
+// UploadFile は imagePorts.AssetManager の実装です。
+func (f *AssetManager) UploadFile(ctx context.Context, fileURI string) (string, error) {
+	f.mu.Lock()
+	f.uploads = append(f.uploads, fileURI)
+	f.mu.Unlock()
+
+	if f.UploadErr != nil {
+		return "", f.UploadErr
+	}
+	if f.UploadFunc != nil {
+		return f.UploadFunc(ctx, fileURI)
+	}
+	return FileAPIURIPrefix + fileURI, nil
+}
+
+// DeleteFile は imagePorts.AssetManager の実装です。
+func (f *AssetManager) DeleteFile(ctx context.Context, fileURI string) error {
+	f.mu.Lock()
+	f.deletes = append(f.deletes, fileURI)
+	f.mu.Unlock()
+
+	if f.DeleteErr != nil {
+		return f.DeleteErr
+	}
+	if f.DeleteFunc != nil {
+		return f.DeleteFunc(ctx, fileURI)
+	}
+	return nil
+}
+
+// Uploads は UploadFile に渡された参照URLを呼び出し順に返します。
+func (f *AssetManager) Uploads() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.uploads...)
+}
+
+// Deletes は DeleteFile に渡された URI を呼び出し順に返します。
+func (f *AssetManager) Deletes() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.deletes...)
+}
+
+// --- Backend ---
+
+// Backend は imagePorts.Backend のフェイクです。
+type Backend struct {
+	VertexAI bool
+}
+
+// IsVertexAI は imagePorts.Backend の実装です。
+func (f *Backend) IsVertexAI() bool { return f.VertexAI }
+
+// --- ImageGenerator ---
+
+// ImageGenerator は imagePorts.ImageGenerator のフェイクです。
+// layout.PanelImageGenerator・layout.PageImageGenerator・runner.DesignImageGenerator も満たします。
+// 既定ではリクエストの Seed をそのまま UsedSeed に設定した DefaultImageData を返します。
+type ImageGenerator struct {
+	// SingleFunc が設定されている場合、GenerateSingleImage はその戻り値を返します。
+	SingleFunc func(ctx context.Context, req imagePorts.SingleImageRequest) (*imagePorts.ImageResponse, error)
+	// FusedFunc が設定されている場合、GenerateFusedImage はその戻り値を返します。
+	FusedFunc func(ctx context.Context, req imagePorts.ImageFusionRequest) (*imagePorts.ImageResponse, error)
+	// Err が設定されている場合、すべての生成呼び出しはこのエラーを返します。
+	Err error
+	// VertexAI は IsVertexAI の戻り値です。
+	VertexAI bool
+
+	mu             sync.Mutex
+	singleRequests []imagePorts.SingleImageRequest
+	fusedRequests  []imagePorts.ImageFusionRequest
+}
+
+// GenerateSingleImage は imagePorts.ImageGenerator の実装です。
+func (f *ImageGenerator) GenerateSingleImage(ctx context.Context, req imagePorts.SingleImageRequest) (*imagePorts.ImageResponse, error) {
+	f.mu.Lock()
+	f.singleRequests = append(f.singleRequests, req)
+	f.mu.Unlock()
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if f.SingleFunc != nil {
+		return f.SingleFunc(ctx, req)
+	}
+	return newImageResponse(req.Seed), nil
+}
+
+// GenerateFusedImage は imagePorts.ImageGenerator の実装です。
+func (f *ImageGenerator) GenerateFusedImage(ctx context.Context, req imagePorts.ImageFusionRequest) (*imagePorts.ImageResponse, error) {
+	f.mu.Lock()
+	f.fusedRequests = append(f.fusedRequests, req)
+	f.mu.Unlock()
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if f.FusedFunc != nil {
+		return f.FusedFunc(ctx, req)
+	}
+	return newImageResponse(req.Seed), nil
+}
+
+// IsVertexAI は imagePorts.Backend の実装です。
+func (f *ImageGenerator) IsVertexAI() bool { return f.VertexAI }
+
+// SingleRequests は GenerateSingleImage に渡されたリクエストを呼び出し順に返します。
+func (f *ImageGenerator) SingleRequests() []imagePorts.SingleImageRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]imagePorts.SingleImageRequest(nil), f.singleRequests...)
+}
+
+// FusedRequests は GenerateFusedImage に渡されたリクエストを呼び出し順に返します。
+func (f *ImageGenerator) FusedRequests() []imagePorts.ImageFusionRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]imagePorts.ImageFusionRequest(nil), f.fusedRequests...)
+}
+
+func newImageResponse(seed *int64) *imagePorts.ImageResponse {
+	return &imagePorts.ImageResponse{
+		Data:     []byte(DefaultImageData),
+		MimeType: DefaultImageMimeType,
+		UsedSeed: imagePorts.DereferenceSeed(seed),
+	}
+}
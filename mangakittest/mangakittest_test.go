@@ -0,0 +1,251 @@
+package mangakittest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	imagePorts "github.com/shouni/gemini-image-kit/ports"
+	"github.com/shouni/go-gemini-client/gemini"
+	md "github.com/shouni/go-prompt-kit/md/ports"
+	"github.com/shouni/go-remote-io/remoteio"
+	"google.golang.org/genai"
+
+	"github.com/shouni/go-manga-kit/mangakittest"
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// 各フェイクが対応するポートを満たしていることをコンパイル時に検証します。
+var (
+	_ imagePorts.AssetManager   = (*mangakittest.AssetManager)(nil)
+	_ imagePorts.Backend        = (*mangakittest.Backend)(nil)
+	_ imagePorts.ImageGenerator = (*mangakittest.ImageGenerator)(nil)
+	_ ports.ContentReader       = (*mangakittest.ContentReader)(nil)
+	_ ports.ContentReader       = (*mangakittest.Writer)(nil)
+	_ remoteio.Writer           = (*mangakittest.Writer)(nil)
+	_ remoteio.Remover          = (*mangakittest.Writer)(nil)
+	_ remoteio.Exister          = (*mangakittest.Writer)(nil)
+	_ ports.ScriptPrompt        = (*mangakittest.ScriptPrompt)(nil)
+	_ ports.ImagePrompt         = (*mangakittest.ImagePrompt)(nil)
+	_ gemini.Generator          = (*mangakittest.ContentGenerator)(nil)
+	_ ports.StructuredGenerator = (*mangakittest.ContentGenerator)(nil)
+	_ ports.MetricsRecorder     = (*mangakittest.Metrics)(nil)
+	_ md.Runner                 = (*mangakittest.MarkdownRunner)(nil)
+)
+
+func TestAssetManager(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("records uploads and returns a File API URI", func(t *testing.T) {
+		am := &mangakittest.AssetManager{}
+		uri, err := am.UploadFile(ctx, "gs://bucket/a.png")
+		if err != nil {
+			t.Fatalf("UploadFile failed: %v", err)
+		}
+		if uri != mangakittest.FileAPIURIPrefix+"gs://bucket/a.png" {
+			t.Errorf("uri = %q", uri)
+		}
+		if err := am.DeleteFile(ctx, uri); err != nil {
+			t.Fatalf("DeleteFile failed: %v", err)
+		}
+		if got := am.Uploads(); len(got) != 1 || got[0] != "gs://bucket/a.png" {
+			t.Errorf("Uploads() = %v", got)
+		}
+		if got := am.Deletes(); len(got) != 1 || got[0] != uri {
+			t.Errorf("Deletes() = %v", got)
+		}
+	})
+
+	t.Run("injects errors", func(t *testing.T) {
+		wantErr := errors.New("quota exceeded")
+		am := &mangakittest.AssetManager{UploadErr: wantErr}
+		if _, err := am.UploadFile(ctx, "gs://bucket/a.png"); !errors.Is(err, wantErr) {
+			t.Errorf("UploadFile err = %v, want %v", err, wantErr)
+		}
+		if len(am.Uploads()) != 1 {
+			t.Error("failed uploads should still be recorded")
+		}
+	})
+}
+
+func TestImageGenerator(t *testing.T) {
+	ctx := context.Background()
+	seed := int64(42)
+
+	gen := &mangakittest.ImageGenerator{}
+	resp, err := gen.GenerateFusedImage(ctx, imagePorts.ImageFusionRequest{
+		GenerationOptions: imagePorts.GenerationOptions{Seed: &seed},
+	})
+	if err != nil {
+		t.Fatalf("GenerateFusedImage failed: %v", err)
+	}
+	if resp.UsedSeed != seed || string(resp.Data) != mangakittest.DefaultImageData {
+		t.Errorf("resp = %+v, want default image with seed %d", resp, seed)
+	}
+	if len(gen.FusedRequests()) != 1 || len(gen.SingleRequests()) != 0 {
+		t.Errorf("recorded requests: fused=%d single=%d", len(gen.FusedRequests()), len(gen.SingleRequests()))
+	}
+
+	gen.Err = errors.New("blocked")
+	if _, err := gen.GenerateSingleImage(ctx, imagePorts.SingleImageRequest{}); !errors.Is(err, gen.Err) {
+		t.Errorf("GenerateSingleImage err = %v, want injected error", err)
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	w := &mangakittest.Writer{}
+
+	if err := w.Write(ctx, "out/plot.json", bytes.NewReader([]byte(`{}`))); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	rc, err := w.Open(ctx, "out/plot.json")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	if string(data) != `{}` {
+		t.Errorf("Open returned %q", data)
+	}
+
+	if _, err := w.Open(ctx, "out/missing.json"); !errors.Is(err, mangakittest.ErrNotFound) {
+		t.Errorf("Open(missing) err = %v, want ErrNotFound", err)
+	}
+
+	w.Errors = map[string]error{"out/fail.png": errors.New("disk full")}
+	if err := w.Write(ctx, "out/fail.png", bytes.NewReader(nil)); err == nil {
+		t.Error("expected injected write error")
+	}
+	if got := w.Paths(); len(got) != 1 || got[0] != "out/plot.json" {
+		t.Errorf("Paths() = %v, want only the successful write", got)
+	}
+	if got := w.Writes(); len(got) != 2 {
+		t.Errorf("Writes() = %v, want both attempts recorded", got)
+	}
+}
+
+func TestContentGeneratorResponsesInOrder(t *testing.T) {
+	ctx := context.Background()
+	ai := &mangakittest.ContentGenerator{Responses: []string{"first", "second"}, Text: "fallback"}
+
+	for _, want := range []string{"first", "second", "fallback"} {
+		resp, err := ai.GenerateContent(ctx, "model", "prompt")
+		if err != nil {
+			t.Fatalf("GenerateContent failed: %v", err)
+		}
+		if resp.Text != want {
+			t.Errorf("resp.Text = %q, want %q", resp.Text, want)
+		}
+	}
+	if len(ai.Calls()) != 3 {
+		t.Errorf("Calls() = %d, want 3", len(ai.Calls()))
+	}
+}
+
+func TestContentGeneratorSharesResponsesAcrossMethods(t *testing.T) {
+	ctx := context.Background()
+	ai := &mangakittest.ContentGenerator{Responses: []string{"content", "parts"}, Text: "structured", VertexAI: true}
+
+	resp, err := ai.GenerateContent(ctx, "model", "prompt")
+	if err != nil || resp.Text != "content" {
+		t.Fatalf("GenerateContent = %v, %v", resp, err)
+	}
+	parts := []*genai.Part{genai.NewPartFromText("a"), genai.NewPartFromURI("gs://bucket/a.png", "image/png"), genai.NewPartFromText("b")}
+	resp, err = ai.GenerateWithParts(ctx, "model", parts, gemini.GenerateOptions{ResponseMIMEType: "application/json"})
+	if err != nil || resp.Text != "parts" {
+		t.Fatalf("GenerateWithParts = %v, %v", resp, err)
+	}
+	schema := map[string]any{"type": "object"}
+	text, err := ai.GenerateStructured(ctx, "model", "structured prompt", schema)
+	if err != nil || text != "structured" {
+		t.Fatalf("GenerateStructured = %q, %v", text, err)
+	}
+	if !ai.IsVertexAI() {
+		t.Error("IsVertexAI() = false, want true")
+	}
+
+	calls := ai.Calls()
+	if len(calls) != 3 {
+		t.Fatalf("Calls() = %d, want 3", len(calls))
+	}
+	if calls[1].Prompt != "a\nb" || len(calls[1].Parts) != 3 || calls[1].Options.ResponseMIMEType != "application/json" {
+		t.Errorf("GenerateWithParts call = %+v", calls[1])
+	}
+	if calls[2].Prompt != "structured prompt" || calls[2].Schema["type"] != "object" {
+		t.Errorf("GenerateStructured call = %+v", calls[2])
+	}
+}
+
+func TestContentGeneratorErrors(t *testing.T) {
+	ctx := context.Background()
+	wantErr := errors.New("boom")
+	ai := &mangakittest.ContentGenerator{Err: wantErr}
+
+	if _, err := ai.GenerateContent(ctx, "model", "prompt"); !errors.Is(err, wantErr) {
+		t.Errorf("GenerateContent err = %v, want %v", err, wantErr)
+	}
+	if _, err := ai.GenerateWithParts(ctx, "model", nil, gemini.GenerateOptions{}); !errors.Is(err, wantErr) {
+		t.Errorf("GenerateWithParts err = %v, want %v", err, wantErr)
+	}
+	if _, err := ai.GenerateStructured(ctx, "model", "prompt", nil); !errors.Is(err, wantErr) {
+		t.Errorf("GenerateStructured err = %v, want %v", err, wantErr)
+	}
+	if len(ai.Calls()) != 3 {
+		t.Errorf("Calls() = %d, want failed calls recorded", len(ai.Calls()))
+	}
+
+	ai = &mangakittest.ContentGenerator{
+		PartsFunc: func(context.Context, string, []*genai.Part, gemini.GenerateOptions) (*gemini.Response, error) {
+			return nil, wantErr
+		},
+		StructuredFunc: func(context.Context, string, string, map[string]any) (string, error) {
+			return "", wantErr
+		},
+	}
+	if _, err := ai.GenerateWithParts(ctx, "model", nil, gemini.GenerateOptions{}); !errors.Is(err, wantErr) {
+		t.Errorf("PartsFunc err = %v, want %v", err, wantErr)
+	}
+	if _, err := ai.GenerateStructured(ctx, "model", "prompt", nil); !errors.Is(err, wantErr) {
+		t.Errorf("StructuredFunc err = %v, want %v", err, wantErr)
+	}
+}
+
+func TestMarkdownRunner(t *testing.T) {
+	runner := &mangakittest.MarkdownRunner{}
+	buf, err := runner.Run("タイトル", []byte("# 本文"))
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "<title>タイトル</title>") || !strings.Contains(buf.String(), "# 本文") {
+		t.Errorf("Run returned %q", buf.String())
+	}
+	if calls := runner.Calls(); len(calls) != 1 || calls[0].Title != "タイトル" || calls[0].Markdown != "# 本文" {
+		t.Errorf("Calls() = %+v", calls)
+	}
+}
+
+func TestContentReader(t *testing.T) {
+	ctx := context.Background()
+	r := &mangakittest.ContentReader{}
+	r.SetFile("https://example.com/a.txt", []byte("hello"))
+	r.Errors = map[string]error{"https://example.com/b.txt": errors.New("403")}
+
+	rc, err := r.Open(ctx, "https://example.com/a.txt")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	if string(data) != "hello" {
+		t.Errorf("Open returned %q", data)
+	}
+	if _, err := r.Open(ctx, "https://example.com/b.txt"); err == nil {
+		t.Error("expected injected error")
+	}
+	if len(r.Opens()) != 2 {
+		t.Errorf("Opens() = %v", r.Opens())
+	}
+}
@@ -0,0 +1,130 @@
+package mangakittest
+
+import (
+	"sync"
+	"time"
+)
+
+// Metrics は ports.MetricsRecorder のフェイクです。記録された値をラベルごとに集計します。
+type Metrics struct {
+	mu            sync.Mutex
+	generations   map[string]int
+	failures      map[string]int
+	rateLimitWait map[string]int
+	assetUploads  map[string]int
+	cacheHits     map[string]int
+	cacheMisses   map[string]int
+	inFlight      map[string]int
+	maxInFlight   map[string]int
+}
+
+func (m *Metrics) init() {
+	if m.generations == nil {
+		m.generations = map[string]int{}
+		m.failures = map[string]int{}
+		m.rateLimitWait = map[string]int{}
+		m.assetUploads = map[string]int{}
+		m.cacheHits = map[string]int{}
+		m.cacheMisses = map[string]int{}
+		m.inFlight = map[string]int{}
+		m.maxInFlight = map[string]int{}
+	}
+}
+
+// ObserveGeneration は ports.MetricsRecorder の実装です。
+func (m *Metrics) ObserveGeneration(stage, model string, _ time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.generations[stage+"/"+model]++
+}
+
+// IncFailure は ports.MetricsRecorder の実装です。
+func (m *Metrics) IncFailure(stage, class string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.failures[stage+"/"+class]++
+}
+
+// ObserveRateLimitWait は ports.MetricsRecorder の実装です。
+func (m *Metrics) ObserveRateLimitWait(stage string, _ time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.rateLimitWait[stage]++
+}
+
+// IncAssetUpload は ports.MetricsRecorder の実装です。
+func (m *Metrics) IncAssetUpload(resourceType string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.assetUploads[resourceType]++
+}
+
+// IncCacheLookup は ports.MetricsRecorder の実装です。
+func (m *Metrics) IncCacheLookup(cache string, hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	if hit {
+		m.cacheHits[cache]++
+	} else {
+		m.cacheMisses[cache]++
+	}
+}
+
+// AddInFlight は ports.MetricsRecorder の実装です。
+func (m *Metrics) AddInFlight(stage string, delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.inFlight[stage] += delta
+	m.maxInFlight[stage] = max(m.maxInFlight[stage], m.inFlight[stage])
+}
+
+// Generations は stage と model の組で記録された生成回数を返します。
+func (m *Metrics) Generations(stage, model string) int {
+	return m.get(func() int { return m.generations[stage+"/"+model] })
+}
+
+// Failures は stage と失敗分類の組で記録された失敗数を返します。
+func (m *Metrics) Failures(stage, class string) int {
+	return m.get(func() int { return m.failures[stage+"/"+class] })
+}
+
+// RateLimitWaits は stage で記録されたレート制限の待機回数を返します。
+func (m *Metrics) RateLimitWaits(stage string) int {
+	return m.get(func() int { return m.rateLimitWait[stage] })
+}
+
+// AssetUploads は resourceType で記録されたアップロード数を返します。
+func (m *Metrics) AssetUploads(resourceType string) int {
+	return m.get(func() int { return m.assetUploads[resourceType] })
+}
+
+// CacheLookups は cache で記録されたヒット数とミス数を返します。
+func (m *Metrics) CacheLookups(cache string) (hits, misses int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	return m.cacheHits[cache], m.cacheMisses[cache]
+}
+
+// InFlight は stage の現在の in-flight 数を返します。
+func (m *Metrics) InFlight(stage string) int {
+	return m.get(func() int { return m.inFlight[stage] })
+}
+
+// MaxInFlight は stage の in-flight 数の最大値を返します。
+func (m *Metrics) MaxInFlight(stage string) int {
+	return m.get(func() int { return m.maxInFlight[stage] })
+}
+
+func (m *Metrics) get(f func() int) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	return f()
+}
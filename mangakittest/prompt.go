@@ -0,0 +1,277 @@
+package mangakittest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+
+	"github.com/shouni/go-gemini-client/gemini"
+	"google.golang.org/genai"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// --- ScriptPrompt ---
+
+// ScriptPromptCall は ScriptPrompt.Build の1回の呼び出しです。
+type ScriptPromptCall struct {
+	Mode string
+	Data ports.TemplateData
+}
+
+// ScriptPrompt は ports.ScriptPrompt のフェイクです。
+// 既定では入力テキストをそのままプロンプトとして返します。
+type ScriptPrompt struct {
+	// BuildFunc が設定されている場合、Build はその戻り値を返します。
+	BuildFunc func(mode string, data *ports.TemplateData) (string, error)
+	// Err が設定されている場合、Build は常にこのエラーを返します。
+	Err error
+
+	mu    sync.Mutex
+	calls []ScriptPromptCall
+}
+
+// Build は ports.ScriptPrompt の実装です。
+func (f *ScriptPrompt) Build(mode string, data *ports.TemplateData) (string, error) {
+	f.mu.Lock()
+	call := ScriptPromptCall{Mode: mode}
+	if data != nil {
+		call.Data = *data
+	}
+	f.calls = append(f.calls, call)
+	f.mu.Unlock()
+
+	if f.Err != nil {
+		return "", f.Err
+	}
+	if f.BuildFunc != nil {
+		return f.BuildFunc(mode, data)
+	}
+	return call.Data.InputText, nil
+}
+
+// Calls は Build の呼び出しを呼び出し順に返します。
+func (f *ScriptPrompt) Calls() []ScriptPromptCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]ScriptPromptCall(nil), f.calls...)
+}
+
+// --- ImagePrompt ---
+
+// ImagePrompt は ports.ImagePrompt のフェイクです。
+// 既定では固定のユーザープロンプトとシステムプロンプトを返します。
+type ImagePrompt struct {
+	// PanelFunc が設定されている場合、BuildPanel はその戻り値を返します。
+	PanelFunc func(panel ports.Panel, char *ports.Character) (string, string)
+	// PageFunc が設定されている場合、BuildPage はその戻り値を返します。
+	PageFunc func(panels []ports.Panel, rm *ports.ResourceMap) (string, string)
+
+	mu         sync.Mutex
+	panelCalls []ports.Panel
+	pageCalls  [][]ports.Panel
+	resources  []*ports.ResourceMap
+}
+
+// BuildPanel は ports.ImagePrompt の実装です。
+func (f *ImagePrompt) BuildPanel(panel ports.Panel, char *ports.Character) (string, string) {
+	f.mu.Lock()
+	f.panelCalls = append(f.panelCalls, panel)
+	f.mu.Unlock()
+
+	if f.PanelFunc != nil {
+		return f.PanelFunc(panel, char)
+	}
+	return "panel-user-prompt", "panel-system-prompt"
+}
+
+// BuildPage は ports.ImagePrompt の実装です。
+func (f *ImagePrompt) BuildPage(panels []ports.Panel, rm *ports.ResourceMap) (string, string) {
+	f.mu.Lock()
+	f.pageCalls = append(f.pageCalls, append([]ports.Panel(nil), panels...))
+	f.resources = append(f.resources, rm)
+	f.mu.Unlock()
+
+	if f.PageFunc != nil {
+		return f.PageFunc(panels, rm)
+	}
+	return "page-user-prompt", "page-system-prompt"
+}
+
+// PanelCalls は BuildPanel に渡されたパネルを呼び出し順に返します。
+func (f *ImagePrompt) PanelCalls() []ports.Panel {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]ports.Panel(nil), f.panelCalls...)
+}
+
+// PageCalls は BuildPage に渡されたパネル群を呼び出し順に返します。
+func (f *ImagePrompt) PageCalls() [][]ports.Panel {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]ports.Panel(nil), f.pageCalls...)
+}
+
+// ResourceMaps は BuildPage に渡された ResourceMap を呼び出し順に返します。
+func (f *ImagePrompt) ResourceMaps() []*ports.ResourceMap {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*ports.ResourceMap(nil), f.resources...)
+}
+
+// --- ContentGenerator ---
+
+// ContentGeneratorCall は ContentGenerator の1回の呼び出しです。
+// GenerateWithParts の呼び出しでは、Prompt はテキストのパーツを改行で連結したものです。
+type ContentGeneratorCall struct {
+	Model  string
+	Prompt string
+	// Parts・Options は GenerateWithParts に渡された値です。
+	Parts   []*genai.Part
+	Options gemini.GenerateOptions
+	// Schema は GenerateStructured に渡されたスキーマです。
+	Schema map[string]any
+}
+
+// ContentGenerator は gemini.Generator と ports.StructuredGenerator のフェイクです。
+// いずれのメソッドも Responses に登録した応答テキストを呼び出し順に先頭から返し、
+// 使い切った後は Text を返します。
+type ContentGenerator struct {
+	// Responses は呼び出しごとに順番に返す応答テキストです。
+	Responses []string
+	// Text は Responses を使い切った後に返す応答テキストです。
+	Text string
+	// GenerateFunc が設定されている場合、GenerateContent はその戻り値を返します。
+	GenerateFunc func(ctx context.Context, model, prompt string) (*gemini.Response, error)
+	// PartsFunc が設定されている場合、GenerateWithParts はその戻り値を返します。
+	PartsFunc func(ctx context.Context, model string, parts []*genai.Part, opts gemini.GenerateOptions) (*gemini.Response, error)
+	// StructuredFunc が設定されている場合、GenerateStructured はその戻り値を返します。
+	StructuredFunc func(ctx context.Context, model, prompt string, schema map[string]any) (string, error)
+	// Err が設定されている場合、すべての生成メソッドは常にこのエラーを返します。
+	Err error
+	// VertexAI は IsVertexAI の戻り値です。
+	VertexAI bool
+
+	mu    sync.Mutex
+	calls []ContentGeneratorCall
+}
+
+// record は call を記録し、既定の応答テキストを返します。
+func (f *ContentGenerator) record(call ContentGeneratorCall) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := len(f.calls)
+	f.calls = append(f.calls, call)
+	if n < len(f.Responses) {
+		return f.Responses[n]
+	}
+	return f.Text
+}
+
+// GenerateContent は gemini.ContentGenerator の実装です。
+func (f *ContentGenerator) GenerateContent(ctx context.Context, model string, prompt string) (*gemini.Response, error) {
+	text := f.record(ContentGeneratorCall{Model: model, Prompt: prompt})
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if f.GenerateFunc != nil {
+		return f.GenerateFunc(ctx, model, prompt)
+	}
+	return &gemini.Response{Text: text}, nil
+}
+
+// GenerateWithParts は gemini.Generator の実装です。
+func (f *ContentGenerator) GenerateWithParts(ctx context.Context, model string, parts []*genai.Part, opts gemini.GenerateOptions) (*gemini.Response, error) {
+	var texts []string
+	for _, part := range parts {
+		if part != nil && part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	text := f.record(ContentGeneratorCall{
+		Model:   model,
+		Prompt:  strings.Join(texts, "\n"),
+		Parts:   append([]*genai.Part(nil), parts...),
+		Options: opts,
+	})
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if f.PartsFunc != nil {
+		return f.PartsFunc(ctx, model, parts, opts)
+	}
+	return &gemini.Response{Text: text}, nil
+}
+
+// GenerateStructured は ports.StructuredGenerator の実装です。
+func (f *ContentGenerator) GenerateStructured(ctx context.Context, model, prompt string, schema map[string]any) (string, error) {
+	text := f.record(ContentGeneratorCall{Model: model, Prompt: prompt, Schema: schema})
+
+	if f.Err != nil {
+		return "", f.Err
+	}
+	if f.StructuredFunc != nil {
+		return f.StructuredFunc(ctx, model, prompt, schema)
+	}
+	return text, nil
+}
+
+// IsVertexAI は gemini.Generator の実装です。
+func (f *ContentGenerator) IsVertexAI() bool {
+	return f.VertexAI
+}
+
+// Calls はすべての生成メソッドの呼び出しを呼び出し順に返します。
+func (f *ContentGenerator) Calls() []ContentGeneratorCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]ContentGeneratorCall(nil), f.calls...)
+}
+
+// --- MarkdownRunner ---
+
+// MarkdownRunCall は MarkdownRunner.Run の1回の呼び出しです。
+type MarkdownRunCall struct {
+	Title    string
+	Markdown string
+}
+
+// MarkdownRunner は Markdown を HTML に変換する md.Runner（go-prompt-kit）のフェイクです。
+// 既定ではタイトルと Markdown をそのまま埋め込んだ簡易的な HTML を返します。
+type MarkdownRunner struct {
+	// RunFunc が設定されている場合、Run はその戻り値を返します。
+	RunFunc func(title string, markdown []byte) (*bytes.Buffer, error)
+	// Err が設定されている場合、Run は常にこのエラーを返します。
+	Err error
+
+	mu    sync.Mutex
+	calls []MarkdownRunCall
+}
+
+// Run は md.Runner の実装です。
+func (f *MarkdownRunner) Run(title string, markdown []byte) (*bytes.Buffer, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, MarkdownRunCall{Title: title, Markdown: string(markdown)})
+	f.mu.Unlock()
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if f.RunFunc != nil {
+		return f.RunFunc(title, markdown)
+	}
+	return bytes.NewBufferString(fmt.Sprintf("<html><head><title>%s</title></head><body>%s</body></html>",
+		html.EscapeString(title), markdown)), nil
+}
+
+// Calls は Run の呼び出しを呼び出し順に返します。
+func (f *MarkdownRunner) Calls() []MarkdownRunCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]MarkdownRunCall(nil), f.calls...)
+}
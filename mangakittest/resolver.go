@@ -0,0 +1,54 @@
+package mangakittest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// PublicAddr は Resolver が既定で返す公開アドレス（文書用アドレス帯）です。
+const PublicAddr = "203.0.113.10"
+
+// Resolver は ports.HostResolver のフェイクです。
+// Hosts に登録されたアドレスを返し、登録されていないホストには PublicAddr を返します。
+type Resolver struct {
+	// Hosts はホスト名から解決先アドレスへのマップです。
+	Hosts map[string][]string
+	// Err が設定されている場合、LookupIPAddr は常にこのエラーを返します。
+	Err error
+
+	mu      sync.Mutex
+	lookups []string
+}
+
+// LookupIPAddr は ports.HostResolver の実装です。
+func (f *Resolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	f.mu.Lock()
+	f.lookups = append(f.lookups, host)
+	f.mu.Unlock()
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	addrs, ok := f.Hosts[host]
+	if !ok {
+		addrs = []string{PublicAddr}
+	}
+	result := make([]net.IPAddr, 0, len(addrs))
+	for _, a := range addrs {
+		ip := net.ParseIP(a)
+		if ip == nil {
+			return nil, fmt.Errorf("mangakittest: invalid address %q for %s", a, host)
+		}
+		result = append(result, net.IPAddr{IP: ip})
+	}
+	return result, nil
+}
+
+// Lookups は LookupIPAddr に渡されたホスト名を呼び出し順に返します。
+func (f *Resolver) Lookups() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.lookups...)
+}
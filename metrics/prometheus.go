@@ -0,0 +1,135 @@
+// Package metrics は、ports.MetricsRecorder の Prometheus 実装を提供します。
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// Namespace は go-manga-kit が登録するメトリクス名の接頭辞です。
+const Namespace = "manga_kit"
+
+// generationBuckets は画像生成の所要時間向けのバケットです（秒）。
+// テキスト生成の数秒から、高解像度ページ生成の数分までを想定しています。
+var generationBuckets = []float64{1, 2.5, 5, 10, 20, 30, 45, 60, 90, 120, 180, 300}
+
+// Prometheus は ports.MetricsRecorder を Prometheus のコレクタとして実装します。
+type Prometheus struct {
+	generationDuration *prometheus.HistogramVec
+	failures           *prometheus.CounterVec
+	rateLimitWait      *prometheus.HistogramVec
+	assetUploads       *prometheus.CounterVec
+	cacheLookups       *prometheus.CounterVec
+	inFlight           *prometheus.GaugeVec
+}
+
+// NewPrometheus は各コレクタを生成し、reg に登録します。
+// reg が nil の場合は prometheus.DefaultRegisterer に登録します。
+// 同じ reg に登録済みのコレクタは再利用するため、1つの reg から複数のマネージャーを作成しても、
+// 返り値はすべて同じメトリクスに記録します。同名で種類やラベルの異なるメトリクスが登録済みの場合はエラーです。
+func NewPrometheus(reg prometheus.Registerer) (*Prometheus, error) {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+
+	p := &Prometheus{
+		generationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "generation_duration_seconds",
+			Help:      "AI 呼び出し1回あたりの所要時間（ステージ・モデル別）。",
+			Buckets:   generationBuckets,
+		}, []string{"stage", "model"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "generation_failures_total",
+			Help:      "生成の失敗数（ステージ・失敗分類別）。",
+		}, []string{"stage", "class"}),
+		rateLimitWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "rate_limit_wait_seconds",
+			Help:      "レートリミッターの Wait で待機した時間（ステージ別）。",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 4, 8),
+		}, []string{"stage"}),
+		assetUploads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "asset_uploads_total",
+			Help:      "File API への参照画像のアップロード数（リソース種別別）。",
+		}, []string{"resource_type"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "cache_lookups_total",
+			Help:      "キャッシュの参照数（キャッシュ種別・ヒット有無別）。",
+		}, []string{"cache", "hit"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "in_flight_requests",
+			Help:      "実行中の AI 呼び出し数（ステージ別）。",
+		}, []string{"stage"}),
+	}
+
+	if err := errors.Join(
+		register(reg, &p.generationDuration),
+		register(reg, &p.failures),
+		register(reg, &p.rateLimitWait),
+		register(reg, &p.assetUploads),
+		register(reg, &p.cacheLookups),
+		register(reg, &p.inFlight),
+	); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// register は *c を reg に登録します。同じコレクタが登録済みの場合は、登録済みのコレクタを *c に設定します。
+func register[T prometheus.Collector](reg prometheus.Registerer, c *T) error {
+	err := reg.Register(*c)
+	if err == nil {
+		return nil
+	}
+	var already prometheus.AlreadyRegisteredError
+	if errors.As(err, &already) {
+		if existing, ok := already.ExistingCollector.(T); ok {
+			*c = existing
+			return nil
+		}
+	}
+	return fmt.Errorf("メトリクスの登録に失敗しました: %w", err)
+}
+
+var _ ports.MetricsRecorder = (*Prometheus)(nil)
+
+// ObserveGeneration は ports.MetricsRecorder の実装です。
+func (p *Prometheus) ObserveGeneration(stage, model string, d time.Duration) {
+	p.generationDuration.WithLabelValues(stage, model).Observe(d.Seconds())
+}
+
+// IncFailure は ports.MetricsRecorder の実装です。
+func (p *Prometheus) IncFailure(stage, class string) {
+	p.failures.WithLabelValues(stage, class).Inc()
+}
+
+// ObserveRateLimitWait は ports.MetricsRecorder の実装です。
+func (p *Prometheus) ObserveRateLimitWait(stage string, d time.Duration) {
+	p.rateLimitWait.WithLabelValues(stage).Observe(d.Seconds())
+}
+
+// IncAssetUpload は ports.MetricsRecorder の実装です。
+func (p *Prometheus) IncAssetUpload(resourceType string) {
+	p.assetUploads.WithLabelValues(resourceType).Inc()
+}
+
+// IncCacheLookup は ports.MetricsRecorder の実装です。
+func (p *Prometheus) IncCacheLookup(cache string, hit bool) {
+	p.cacheLookups.WithLabelValues(cache, strconv.FormatBool(hit)).Inc()
+}
+
+// AddInFlight は ports.MetricsRecorder の実装です。
+func (p *Prometheus) AddInFlight(stage string, delta int) {
+	p.inFlight.WithLabelValues(stage).Add(float64(delta))
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+func TestPrometheus(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	p, err := NewPrometheus(reg)
+	if err != nil {
+		t.Fatalf("NewPrometheus failed: %v", err)
+	}
+
+	p.ObserveGeneration(ports.StagePanel, "model", 3*time.Second)
+	p.IncFailure(ports.StagePage, ports.ErrorClassTimeout)
+	p.ObserveRateLimitWait(ports.StagePanel, 50*time.Millisecond)
+	p.IncAssetUpload("character")
+	p.IncCacheLookup(ports.CacheAsset, true)
+	p.AddInFlight(ports.StagePage, 2)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	got := map[string]bool{}
+	for _, f := range families {
+		got[f.GetName()] = true
+	}
+	for _, name := range []string{
+		"manga_kit_generation_duration_seconds",
+		"manga_kit_generation_failures_total",
+		"manga_kit_rate_limit_wait_seconds",
+		"manga_kit_asset_uploads_total",
+		"manga_kit_cache_lookups_total",
+		"manga_kit_in_flight_requests",
+	} {
+		if !got[name] {
+			t.Errorf("metric %s is not registered", name)
+		}
+	}
+
+	// 同じレジストリから作成した2つ目の recorder は、登録済みのコレクタを共有します。
+	second, err := NewPrometheus(reg)
+	if err != nil {
+		t.Fatalf("second NewPrometheus failed: %v", err)
+	}
+	second.IncAssetUpload("character")
+	families, err = reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	for _, f := range families {
+		if f.GetName() != "manga_kit_asset_uploads_total" {
+			continue
+		}
+		if got := f.GetMetric()[0].GetCounter().GetValue(); got != 2 {
+			t.Errorf("asset uploads = %v, want 2 (shared between recorders)", got)
+		}
+	}
+}
+
+func TestPrometheus_ConflictingRegistration(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	// 同名でラベルの異なるメトリクスが登録済みの場合は再利用できません。
+	reg.MustRegister(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "asset_uploads_total",
+		Help:      "別のライブラリのメトリクス。",
+	}, []string{"kind"}))
+
+	if _, err := NewPrometheus(reg); err == nil {
+		t.Error("expected conflicting registration to fail")
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"log/slog"
 
 	"github.com/shouni/go-manga-kit/ports"
+	"github.com/shouni/go-manga-kit/speaker"
+	"github.com/shouni/go-manga-kit/validate"
 )
 
 // Parser は解析するためのインターフェースを定義します。
@@ -18,15 +20,48 @@ type Parser interface {
 // MangaResponseParser は JSON 形式の台本を解析する構造体です。
 type MangaResponseParser struct {
 	reader ports.ContentReader
+	// validation が true の場合、解析した台本を characters と rules で検証します。
+	validation bool
+	characters *ports.Characters
+	rules      validate.Rules
+	// speakers を設定すると、解析した台本の SpeakerID をキャラクターIDに解決します。
+	speakers *speaker.Resolver
+}
+
+// Option は MangaResponseParser の設定を適用する関数型です。
+type Option func(*MangaResponseParser)
+
+// WithValidation は、解析した台本を rules で検証し、重大度 error の指摘があれば
+// *ports.ScriptValidationError を返すようにします。cm が nil の場合、話者IDは検証しません。
+func WithValidation(cm *ports.Characters, rules validate.Rules) Option {
+	return func(p *MangaResponseParser) {
+		p.validation = true
+		p.characters = cm
+		p.rules = rules
+	}
+}
+
+// WithSpeakerResolver は、解析した台本の SpeakerID を resolver でキャラクターIDに書き換えるようにします。
+// 解決できなかった話者があれば *ports.ScriptValidationError を返します。
+func WithSpeakerResolver(resolver *speaker.Resolver) Option {
+	return func(p *MangaResponseParser) {
+		p.speakers = resolver
+	}
 }
 
 // NewMangaResponseParser は新しい MangaResponseParser インスタンスを生成します。
-func NewMangaResponseParser(r ports.ContentReader) *MangaResponseParser {
-	return &MangaResponseParser{reader: r}
+func NewMangaResponseParser(r ports.ContentReader, opts ...Option) *MangaResponseParser {
+	p := &MangaResponseParser{reader: r}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 // ParseFromPath は指定された GCS URIやローカルファイルパスなどから
 // コンテンツを読み込み、解析して domain.MangaResponse を返します。
+// dialogue・speaker_id だけの旧形式のプロットも読み込めます。lines だけで書かれたパネルは、
+// 最初の話者を SpeakerID に補います。
 func (p *MangaResponseParser) ParseFromPath(ctx context.Context, plotFile string) (*ports.MangaResponse, error) {
 	slog.InfoContext(ctx, "プロットファイルを読み込んでいます", "path", plotFile)
 	rc, err := p.reader.Open(ctx, plotFile)
@@ -37,7 +72,24 @@ func (p *MangaResponseParser) ParseFromPath(ctx context.Context, plotFile string
 
 	manga := &ports.MangaResponse{}
 	if err := json.NewDecoder(rc).Decode(manga); err != nil {
-		return nil, fmt.Errorf("プロットJSONのパースに失敗しました: %w", err)
+		return nil, fmt.Errorf("プロットJSONのパースに失敗しました: %w", &ports.ScriptParseError{Err: err})
+	}
+	if p.speakers != nil {
+		report := p.speakers.Apply(manga)
+		for _, rw := range report.Rewrites {
+			slog.InfoContext(ctx, "話者をキャラクターIDに解決しました",
+				"panel_index", rw.PanelIndex, "from", rw.From, "to", rw.To, "method", rw.Method)
+		}
+		if len(report.Unresolved) > 0 {
+			return nil, fmt.Errorf("プロットの話者を解決できませんでした (%s): %w", plotFile,
+				&ports.ScriptValidationError{Issues: report.Issues()})
+		}
+	}
+	manga.Normalize()
+	if p.validation {
+		if err := validate.Gate(ctx, manga, p.characters, p.rules); err != nil {
+			return nil, fmt.Errorf("プロットの検証に失敗しました (%s): %w", plotFile, err)
+		}
 	}
 
 	return manga, nil
@@ -2,31 +2,18 @@ package parser
 
 import (
 	"context"
+	"errors"
 	"io"
 	"strings"
 	"testing"
-)
-
-// --- Mocks ---
-
-// mockReader は ports.ContentReader インターフェースを満たすテスト用モックです。
-type mockReader struct {
-	openFunc func(ctx context.Context, path string) (io.ReadCloser, error)
-}
-
-// Open は ports.ContentReader インターフェースの実装です。
-func (m *mockReader) Open(ctx context.Context, path string) (io.ReadCloser, error) {
-	return m.openFunc(ctx, path)
-}
-
-// stringReadCloser は文字列を io.ReadCloser として扱うためのヘルパーです。
-type stringReadCloser struct {
-	*strings.Reader
-}
 
-func (s *stringReadCloser) Close() error { return nil }
+	characterkit "github.com/shouni/go-character-kit/character"
 
-// --- Tests ---
+	"github.com/shouni/go-manga-kit/mangakittest"
+	"github.com/shouni/go-manga-kit/ports"
+	"github.com/shouni/go-manga-kit/speaker"
+	"github.com/shouni/go-manga-kit/validate"
+)
 
 func TestMangaResponseParser_ParseFromPath(t *testing.T) {
 	ctx := context.Background()
@@ -41,10 +28,8 @@ func TestMangaResponseParser_ParseFromPath(t *testing.T) {
 			]
 		}`
 
-		mReader := &mockReader{
-			openFunc: func(_ context.Context, _ string) (io.ReadCloser, error) {
-				return &stringReadCloser{strings.NewReader(validJSON)}, nil
-			},
+		mReader := &mangakittest.ContentReader{
+			Files: map[string][]byte{"gs://bucket/plot.json": []byte(validJSON)},
 		}
 
 		// Reader を受け取る Parser の初期化
@@ -69,10 +54,8 @@ func TestMangaResponseParser_ParseFromPath(t *testing.T) {
 	t.Run("Failure with Invalid JSON", func(t *testing.T) {
 		invalidJSON := `{ "title": "incomplete json...`
 
-		mReader := &mockReader{
-			openFunc: func(_ context.Context, _ string) (io.ReadCloser, error) {
-				return &stringReadCloser{strings.NewReader(invalidJSON)}, nil
-			},
+		mReader := &mangakittest.ContentReader{
+			Files: map[string][]byte{"invalid.json": []byte(invalidJSON)},
 		}
 
 		p := NewMangaResponseParser(mReader)
@@ -87,10 +70,8 @@ func TestMangaResponseParser_ParseFromPath(t *testing.T) {
 	})
 
 	t.Run("Failure when File Open Fails", func(t *testing.T) {
-		mReader := &mockReader{
-			openFunc: func(_ context.Context, _ string) (io.ReadCloser, error) {
-				return nil, io.ErrUnexpectedEOF // オープン失敗をシミュレート
-			},
+		mReader := &mangakittest.ContentReader{
+			Err: io.ErrUnexpectedEOF, // オープン失敗をシミュレート
 		}
 
 		p := NewMangaResponseParser(mReader)
@@ -104,3 +85,86 @@ func TestMangaResponseParser_ParseFromPath(t *testing.T) {
 		}
 	})
 }
+
+func TestMangaResponseParser_WithValidation(t *testing.T) {
+	plot := `{"title":"t","panels":[
+		{"page":1,"speaker_id":"zundamon","visual_anchor":"教室","dialogue":"a"},
+		{"page":1,"speaker_id":"ghost","visual_anchor":"教室","dialogue":"b"}]}`
+	mReader := &mangakittest.ContentReader{
+		Files: map[string][]byte{"plot.json": []byte(plot)},
+	}
+	cm, err := characterkit.NewCharacters([]ports.Character{{ID: "zundamon", Name: "ずんだもん", IsDefault: true}})
+	if err != nil {
+		t.Fatalf("NewCharacters failed: %v", err)
+	}
+
+	if _, err := NewMangaResponseParser(mReader).ParseFromPath(context.Background(), "plot.json"); err != nil {
+		t.Fatalf("ParseFromPath without validation failed: %v", err)
+	}
+
+	p := NewMangaResponseParser(mReader, WithValidation(cm, validate.DefaultRules()))
+	_, err = p.ParseFromPath(context.Background(), "plot.json")
+	var verr *ports.ScriptValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("err = %v, want *ScriptValidationError", err)
+	}
+	if len(verr.Issues) != 1 || verr.Issues[0].RuleID != validate.RuleUnknownSpeaker || verr.Issues[0].PanelIndex != 2 {
+		t.Errorf("Issues = %+v, want unknown-speaker on panel 2", verr.Issues)
+	}
+}
+
+func TestMangaResponseParser_WithSpeakerResolver(t *testing.T) {
+	plot := `{"title":"t","panels":[{"speaker_id":"ずんだもん"},{"speaker_id":"ghost"}]}`
+	mReader := &mangakittest.ContentReader{
+		Files: map[string][]byte{"plot.json": []byte(plot)},
+	}
+	resolver, err := speaker.NewResolver([]ports.Character{{ID: "zundamon", Name: "ずんだもん", IsDefault: true}})
+	if err != nil {
+		t.Fatalf("NewResolver failed: %v", err)
+	}
+
+	_, err = NewMangaResponseParser(mReader, WithSpeakerResolver(resolver)).ParseFromPath(context.Background(), "plot.json")
+	var verr *ports.ScriptValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("err = %v, want *ScriptValidationError", err)
+	}
+	if len(verr.Issues) != 1 || verr.Issues[0].PanelIndex != 2 {
+		t.Errorf("Issues = %+v, want the unresolved speaker on panel 2", verr.Issues)
+	}
+}
+
+func TestMangaResponseParser_ReadsLegacyAndExtendedPanels(t *testing.T) {
+	plot := `{"title":"t","description":"","panels":[
+		{"page":1,"visual_anchor":"教室","dialogue":"旧形式","speaker_id":"zundamon"},
+		{"page":1,"visual_anchor":"廊下","speaker_id":"","shot":"close_up","scene_id":"s1",
+		 "lines":[{"speaker_id":"metan","text":"新形式","kind":"whisper"},{"speaker_id":"zundamon","text":"なのだ"}],
+		 "narration":["その頃"],"sfx":["ドン"],"emotions":[{"character_id":"metan","emotion":"驚き"}]}
+	]}`
+	mReader := &mangakittest.ContentReader{
+		Files: map[string][]byte{"plot.json": []byte(plot)},
+	}
+
+	res, err := NewMangaResponseParser(mReader).ParseFromPath(context.Background(), "plot.json")
+	if err != nil {
+		t.Fatalf("ParseFromPath failed: %v", err)
+	}
+	legacy := res.Panels[0].DialogueLines()
+	if len(legacy) != 1 || legacy[0] != (ports.DialogueLine{SpeakerID: "zundamon", Text: "旧形式"}) {
+		t.Errorf("legacy DialogueLines = %+v", legacy)
+	}
+	extended := res.Panels[1]
+	if extended.SpeakerID != "metan" {
+		t.Errorf("SpeakerID = %q, want it filled from the first line", extended.SpeakerID)
+	}
+	// Dialogue だけを読む ImagePrompt の実装にもセリフが渡るよう、Lines の本文で補います。
+	if extended.Dialogue != "新形式\nなのだ" {
+		t.Errorf("Dialogue = %q, want the joined lines", extended.Dialogue)
+	}
+	if got := extended.DialogueLines(); len(got) != 2 {
+		t.Errorf("DialogueLines = %+v, want the original lines", got)
+	}
+	if extended.Shot != ports.ShotCloseUp || extended.SceneID != "s1" || len(extended.Narration) != 1 ||
+		len(extended.SFX) != 1 || len(extended.Emotions) != 1 || extended.Lines[0].Kind != ports.DialogueWhisper {
+		t.Errorf("extended panel = %+v", extended)
+	}
+}
@@ -0,0 +1,42 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultFileAPITTL は Gemini File API にアップロードしたファイルの保持期間です。
+// File API のファイルはアップロードから約48時間で自動的に削除されます。
+const DefaultFileAPITTL = 48 * time.Hour
+
+// AssetRecord は、参照画像と File API にアップロードしたファイルの対応です。
+type AssetRecord struct {
+	// ReferenceURL はレジストリのキーです。MangaComposer は同じ URL をキャラクターとパネルの両方の
+	// 参照画像に使えるよう、"character|<URL>"・"panel|<URL>" の形式で記録します。
+	ReferenceURL string `json:"reference_url"`
+	// ContentHash はアップロード時点の参照画像の SHA-256（16進数）です。算出できなかった場合は空です。
+	ContentHash string `json:"content_hash,omitempty"`
+	// Variant はアップロードした画像の前処理の設定（imaging.Options.Key）です。
+	// 前処理をせずに元の画像をアップロードした場合は空です。
+	Variant    string    `json:"variant,omitempty"`
+	FileAPIURI string    `json:"file_api_uri"`
+	UploadedAt time.Time `json:"uploaded_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Expired は、now から margin 以内に期限切れとなる場合に true を返します。
+func (r AssetRecord) Expired(now time.Time, margin time.Duration) bool {
+	return !r.ExpiresAt.IsZero() && !now.Add(margin).Before(r.ExpiresAt)
+}
+
+// AssetRegistry は、アップロード済みアセットの記録を保持するレジストリです。
+// プロセスの再起動を跨いで File API URI を再利用するために使われます。
+// 実装は複数の goroutine から同時に呼び出されるため、並行安全である必要があります。
+type AssetRegistry interface {
+	// Get は referenceURL の記録を返します。記録が無い場合は false を返します。
+	Get(ctx context.Context, referenceURL string) (AssetRecord, bool, error)
+	// Put は記録を追加または上書きします。
+	Put(ctx context.Context, record AssetRecord) error
+	// Delete は referenceURL の記録を削除します。記録が無い場合も nil を返します。
+	Delete(ctx context.Context, referenceURL string) error
+}
@@ -6,11 +6,14 @@ import (
 
 // デフォルト値の定義
 const (
-	DefaultGeminiModel        = "gemini-3-flash-preview"
-	DefaultImageStandardModel = "gemini-3-pro-image-preview"
-	DefaultImageQualityModel  = "gemini-3-pro-image-preview"
-	DefaultMaxConcurrency     = 1
-	DefaultStyleSuffix        = "Japanese anime style, official art, cel-shaded, clean line art, high-quality manga coloring, expressive eyes, vibrant colors, cinematic lighting, masterpiece, ultra-detailed, flat shading, clear character features, no 3D effect, high resolution"
+	DefaultGeminiModel         = "gemini-3-flash-preview"
+	DefaultImageStandardModel  = "gemini-3-pro-image-preview"
+	DefaultImageQualityModel   = "gemini-3-pro-image-preview"
+	DefaultMaxConcurrency      = 1
+	DefaultRequestTimeout      = 5 * time.Minute
+	DefaultAssetCleanupTimeout = 30 * time.Second
+	DefaultReferenceMaxEdge    = 1536
+	DefaultStyleSuffix         = "Japanese anime style, official art, cel-shaded, clean line art, high-quality manga coloring, expressive eyes, vibrant colors, cinematic lighting, masterpiece, ultra-detailed, flat shading, clear character features, no 3D effect, high resolution"
 )
 
 // Config は Go Manga Kit の各 Runner を動作させるための基本設定です。
@@ -28,8 +31,100 @@ type Config struct {
 	// --- Layout Settings ---
 	MaxPanelsPerPage int
 
+	// --- Long Document ---
+	// LongDocumentChunkTokens を設定すると台本生成の長文モードを有効にし、推定トークン数がこれを超える
+	// 入力を分割して要約・章ごとに台本を生成します。0 の場合は入力を1つのプロンプトで処理します。
+	LongDocumentChunkTokens int
+
+	// --- Script Validation ---
+	// ValidateScripts を true にすると、台本生成の結果と、パネル・ページ生成に渡す台本を検証し、
+	// 未定義の話者や空の描写などの問題があれば生成を始めずに ErrInvalidScript のエラーを返します。
+	// 長すぎるセリフやパネルの多すぎるページは警告としてログに出力します。
+	ValidateScripts bool
+	// MaxDialogueLength はパネル1つのセリフの最大文字数です。未設定の場合は validate.DefaultMaxDialogueLength です。
+	MaxDialogueLength int
+	// SpeakerAliases は話者の別名からキャラクターIDへの対応です（例: "ずんだ" → "zundamon"）。
+	// 台本の話者の解決（PromptDeps.CharacterList の設定が必要）で、表示名・表記ゆれに加えて使われます。
+	SpeakerAliases map[string]string
+
 	// --- Timeout & Retries ---
-	RequestTimeout time.Duration
+	// RequestTimeout は AI 呼び出し・アセットアップロード1回あたりの期限です。0 の場合は期限を設けません。
+	// 以下のステージ別の値が設定されている場合はそちらが優先されます。
+	// DefaultRequestTimeout は ApplyDefaults では適用されない推奨値で、期限を設ける場合に明示的に設定します。
+	RequestTimeout       time.Duration
+	ScriptRequestTimeout time.Duration
+	DesignRequestTimeout time.Duration
+	PanelRequestTimeout  time.Duration
+	PageRequestTimeout   time.Duration // 4K ページなど生成に時間が掛かる場合に延長します
+	UploadRequestTimeout time.Duration
+	// ScriptRepairAttempts は、AI が出力した台本を解析できない、または内容に問題がある場合に、
+	// 問題の一覧を添えて修正を依頼する最大回数です。0 の場合は修正を依頼しません。
+	ScriptRepairAttempts int
+
+	// --- Asset Cleanup ---
+	// AssetMaxAge を設定すると、アップロードから AssetMaxAge 以上経過した File API の
+	// アセットをバックグラウンドで削除します。0 の場合は Workflows.Close 時にのみ削除します。
+	AssetMaxAge time.Duration
+	// AssetSweepInterval はバックグラウンド削除の実行間隔です。未設定の場合は AssetMaxAge の半分です。
+	AssetSweepInterval time.Duration
+	// AssetCleanupTimeout は Workflows.Close 時のアセット削除の待機上限です。
+	AssetCleanupTimeout time.Duration
+
+	// --- Asset Preflight ---
+	// PreflightAssets を true にすると、パネル・ページの生成前に全参照画像の取得可否・形式・寸法・
+	// サイズを検査し、問題があれば生成を始めずにすべての問題をまとめたエラーを返します。
+	PreflightAssets bool
+
+	// --- Reference URL Policy ---
+	// ReferencePolicy はパネルの参照画像として取得を許可する URL の方針です。
+	// 未設定の場合は DefaultReferencePolicy（https・http・gs://・s3://・ローカルパスを許可し、
+	// AI が生成した ReferenceURL は除去）です。
+	ReferencePolicy *ReferencePolicy
+
+	// --- Reference Preprocessing ---
+	// ReferenceStagingDir を設定すると、参照画像をアップロード前に正規化（縮小・アスペクト比の調整・
+	// EXIF と透過の除去・再エンコード）し、このディレクトリに保存したものをアップロードします。
+	ReferenceStagingDir string
+	// ReferenceMaxEdge は正規化後の長辺の最大ピクセル数です。未設定の場合は DefaultReferenceMaxEdge です。
+	ReferenceMaxEdge int
+	// ReferenceFormat は正規化後の形式（"png" または "jpeg"）です。未設定の場合は "png" です。
+	ReferenceFormat string
+	// CharacterReferenceAspectRatio・PanelReferenceAspectRatio は正規化後のアスペクト比（"16:9" 等）で、
+	// 余白を加えて合わせます。未設定の場合は元の比率を保ちます。
+	CharacterReferenceAspectRatio string
+	PanelReferenceAspectRatio     string
+
+	// --- Reference Budget ---
+	// MaxReferenceImages はページ1枚の生成リクエストに含める参照画像の上限です。
+	// 未設定の場合は layout.DefaultAssetBudget です。
+	MaxReferenceImages int
+	// ReferenceCollage を true にすると、上限を超えた参照画像を除外する代わりに1枚の
+	// ラベル付きコラージュにまとめて渡します。コラージュは ReferenceStagingDir に保存されるため、
+	// ReferenceStagingDir の設定が必要です。
+	ReferenceCollage bool
+}
+
+// TimeoutFor は stage（StageScript/StageDesign/StagePanel/StagePage/StageUpload）の
+// 呼び出し1回あたりの期限を返します。ステージ別の値が未設定の場合は RequestTimeout を返します。
+// 0 は期限なしを表します。
+func (c *Config) TimeoutFor(stage string) time.Duration {
+	var override time.Duration
+	switch stage {
+	case StageScript:
+		override = c.ScriptRequestTimeout
+	case StageDesign:
+		override = c.DesignRequestTimeout
+	case StagePanel:
+		override = c.PanelRequestTimeout
+	case StagePage:
+		override = c.PageRequestTimeout
+	case StageUpload:
+		override = c.UploadRequestTimeout
+	}
+	if override > 0 {
+		return override
+	}
+	return c.RequestTimeout
 }
 
 // ApplyDefaults は未設定（ゼロ値）の項目にデフォルト値を適用します。
@@ -46,7 +141,20 @@ func (c *Config) ApplyDefaults() {
 	if c.MaxConcurrency <= 0 {
 		c.MaxConcurrency = DefaultMaxConcurrency
 	}
+	if c.AssetMaxAge > 0 && c.AssetSweepInterval <= 0 {
+		c.AssetSweepInterval = c.AssetMaxAge / 2
+	}
+	if c.AssetCleanupTimeout <= 0 {
+		c.AssetCleanupTimeout = DefaultAssetCleanupTimeout
+	}
 	if c.StyleSuffix == "" {
 		c.StyleSuffix = DefaultStyleSuffix
 	}
+	if c.ReferenceStagingDir != "" && c.ReferenceMaxEdge <= 0 {
+		c.ReferenceMaxEdge = DefaultReferenceMaxEdge
+	}
+	if c.ReferencePolicy == nil {
+		policy := DefaultReferencePolicy()
+		c.ReferencePolicy = &policy
+	}
 }
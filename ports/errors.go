@@ -0,0 +1,234 @@
+package ports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrRequestTimeout は、AI 呼び出しやアセットアップロード1回が Config.RequestTimeout
+// （またはステージ別の期限）を超えたことを表します。errors.Is で判定できます。
+var ErrRequestTimeout = errors.New("request timed out")
+
+// TimeoutError は、呼び出し1回の期限切れを表す型付きエラーです。
+// 呼び出し元の ctx がキャンセル・期限切れになった場合には使われません。
+type TimeoutError struct {
+	// Stage は期限切れになった呼び出しのステージ（StagePanel 等）です。
+	Stage   string
+	Timeout time.Duration
+	// Err は呼び出しが返した元のエラーです。
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s request timed out after %s: %v", e.Stage, e.Timeout, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error { return e.Err }
+
+// Is は ErrRequestTimeout との比較を可能にします。
+func (e *TimeoutError) Is(target error) bool { return target == ErrRequestTimeout }
+
+// 生成パイプラインのエラー分類です。各 Runner・Generator が返すエラーは、
+// 該当する場合これらのいずれかを errors.Is で判定できるようにラップされています。
+var (
+	// ErrCharacterNotFound は、話者IDやデザイン対象のキャラクターIDが定義に存在しないことを表します。
+	ErrCharacterNotFound = errors.New("character not found")
+	// ErrAssetPreparation は、参照画像の File API へのアップロード等、アセットの準備に失敗したことを表します。
+	ErrAssetPreparation = errors.New("asset preparation failed")
+	// ErrInvalidAsset は、参照画像が取得できない・画像として解釈できない・制限を超えている等、
+	// 事前検査（プリフライト）で不適格と判定されたことを表します。
+	ErrInvalidAsset = errors.New("invalid reference asset")
+	// ErrRateLimited は、API のクォータ・レート制限（HTTP 429 / RESOURCE_EXHAUSTED）を表します。
+	ErrRateLimited = errors.New("rate limited")
+	// ErrContentBlocked は、安全フィルター等により生成がブロックされたことを表します。
+	ErrContentBlocked = errors.New("content blocked")
+	// ErrScriptParse は、AI 応答やプロットファイルの JSON を台本として解析できなかったことを表します。
+	ErrScriptParse = errors.New("script parse failed")
+	// ErrCountMismatch は、生成結果の件数が期待値と一致しないことを表します。
+	ErrCountMismatch = errors.New("count mismatch")
+	// ErrInvalidScript は、台本を JSON として解析できても、未定義の話者や空の描写など内容に問題があることを表します。
+	ErrInvalidScript = errors.New("invalid script")
+	// ErrInvalidDesignOverride は、デザインシートの上書き指定が対象のキャラクターと対応しないことを表します。
+	ErrInvalidDesignOverride = errors.New("invalid design override")
+)
+
+// CharacterNotFoundError は、見つからなかったキャラクターIDを保持する ErrCharacterNotFound です。
+type CharacterNotFoundError struct {
+	CharacterIDs []string
+}
+
+func (e *CharacterNotFoundError) Error() string {
+	return fmt.Sprintf("character not found: %s", strings.Join(e.CharacterIDs, ", "))
+}
+
+// Is は ErrCharacterNotFound との比較を可能にします。
+func (e *CharacterNotFoundError) Is(target error) bool { return target == ErrCharacterNotFound }
+
+// AssetError は、参照画像1件の準備の失敗を表す ErrAssetPreparation です。
+type AssetError struct {
+	// ResourceType は "character" または "panel" です。
+	ResourceType string
+	ReferenceURL string
+	// CharacterID はキャラクター参照画像の場合に、その画像を持つキャラクターのIDです。
+	CharacterID string
+	Err         error
+}
+
+func (e *AssetError) Error() string {
+	if e.CharacterID != "" {
+		return fmt.Sprintf("%s asset preparation failed for '%s' (character_id: %s): %v", e.ResourceType, e.ReferenceURL, e.CharacterID, e.Err)
+	}
+	return fmt.Sprintf("%s asset preparation failed for '%s': %v", e.ResourceType, e.ReferenceURL, e.Err)
+}
+
+func (e *AssetError) Unwrap() error { return e.Err }
+
+// Is は ErrAssetPreparation との比較を可能にします。
+func (e *AssetError) Is(target error) bool { return target == ErrAssetPreparation }
+
+// GenerationError は、1回の生成（台本・デザインシート・パネル・ページ）の失敗を表します。
+// 原因は Err にラップされ、errors.Is / errors.As で ErrContentBlocked 等を判定できます。
+type GenerationError struct {
+	// Stage は StageScript/StageDesign/StagePanel/StagePage のいずれかです。
+	Stage string
+	// Index はパネル・ページの番号（1始まり）です。ステージ全体の失敗では 0 です。
+	Index int
+	// CharacterID はパネル生成の話者、またはデザイン対象のキャラクターIDです。
+	CharacterID string
+	// Attempts は失敗までの試行回数です。
+	Attempts int
+	Err      error
+}
+
+func (e *GenerationError) Error() string {
+	switch {
+	case e.Index > 0 && e.CharacterID != "":
+		return fmt.Sprintf("%s %d (character_id: %s) generation failed: %v", e.Stage, e.Index, e.CharacterID, e.Err)
+	case e.Index > 0:
+		return fmt.Sprintf("%s %d generation failed: %v", e.Stage, e.Index, e.Err)
+	default:
+		return fmt.Sprintf("%s generation failed: %v", e.Stage, e.Err)
+	}
+}
+
+func (e *GenerationError) Unwrap() error { return e.Err }
+
+// RateLimitError は、API から返されたクォータ・レート制限のエラーを表す ErrRateLimited です。
+type RateLimitError struct {
+	Err error
+}
+
+func (e *RateLimitError) Error() string { return fmt.Sprintf("rate limited: %v", e.Err) }
+
+func (e *RateLimitError) Unwrap() error { return e.Err }
+
+// Is は ErrRateLimited との比較を可能にします。
+func (e *RateLimitError) Is(target error) bool { return target == ErrRateLimited }
+
+// ContentBlockedError は、安全フィルター等による生成のブロックを表す ErrContentBlocked です。
+type ContentBlockedError struct {
+	// Reason は API が返したブロック理由（FinishReason 等）です。判別できない場合は空です。
+	Reason string
+	Err    error
+}
+
+func (e *ContentBlockedError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("content blocked (reason: %s): %v", e.Reason, e.Err)
+	}
+	return fmt.Sprintf("content blocked: %v", e.Err)
+}
+
+func (e *ContentBlockedError) Unwrap() error { return e.Err }
+
+// Is は ErrContentBlocked との比較を可能にします。
+func (e *ContentBlockedError) Is(target error) bool { return target == ErrContentBlocked }
+
+// ScriptParseError は、台本 JSON の解析失敗を表す ErrScriptParse です。
+type ScriptParseError struct {
+	// Snippet は解析対象の先頭部分（エラー表示用に切り詰めたもの）です。
+	Snippet string
+	Err     error
+}
+
+func (e *ScriptParseError) Error() string {
+	if e.Snippet != "" {
+		return fmt.Sprintf("script parse failed (snippet: %q): %v", e.Snippet, e.Err)
+	}
+	return fmt.Sprintf("script parse failed: %v", e.Err)
+}
+
+func (e *ScriptParseError) Unwrap() error { return e.Err }
+
+// Is は ErrScriptParse との比較を可能にします。
+func (e *ScriptParseError) Is(target error) bool { return target == ErrScriptParse }
+
+// ScriptValidationError は、修復を試みた後も台本に残った問題を表す ErrInvalidScript です。
+// 最後の応答を JSON として解析できなかった場合は Err に *ScriptParseError を保持し、
+// ErrScriptParse とも比較できます。
+type ScriptValidationError struct {
+	// Attempts は修復を含む生成の試行回数です。生成を伴わない検証では 0 です。
+	Attempts int
+	// Issues は最後の応答（または検証した台本）で見つかった問題です。
+	Issues []ScriptIssue
+	Err    error
+}
+
+func (e *ScriptValidationError) Error() string {
+	issues := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		issues[i] = issue.String()
+	}
+	if e.Attempts > 0 {
+		return fmt.Sprintf("invalid script after %d attempts: %s", e.Attempts, strings.Join(issues, "; "))
+	}
+	return fmt.Sprintf("invalid script: %s", strings.Join(issues, "; "))
+}
+
+func (e *ScriptValidationError) Unwrap() error { return e.Err }
+
+// Is は ErrInvalidScript との比較を可能にします。
+func (e *ScriptValidationError) Is(target error) bool { return target == ErrInvalidScript }
+
+// CountMismatchError は、生成結果の件数の不一致を表す ErrCountMismatch です。
+type CountMismatchError struct {
+	// Subject は件数を比較した対象（"panel images" 等）です。
+	Subject string
+	Want    int
+	Got     int
+}
+
+func (e *CountMismatchError) Error() string {
+	return fmt.Sprintf("%s count mismatch: want %d, got %d", e.Subject, e.Want, e.Got)
+}
+
+// Is は ErrCountMismatch との比較を可能にします。
+func (e *CountMismatchError) Is(target error) bool { return target == ErrCountMismatch }
+
+// IsRetryable は err が再試行によって解決し得るかを返します。
+// 呼び出し元のキャンセル、コンテンツのブロック、キャラクター未定義、参照画像の不備、台本の解析失敗・
+// 内容の不備、件数の不一致は再試行しても結果が変わらないため false を返します。
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch {
+	case errors.Is(err, ErrRequestTimeout), errors.Is(err, ErrRateLimited):
+		return true
+	case errors.Is(err, context.Canceled),
+		errors.Is(err, ErrContentBlocked),
+		errors.Is(err, ErrCharacterNotFound),
+		errors.Is(err, ErrInvalidAsset),
+		errors.Is(err, ErrReferenceURLNotAllowed),
+		errors.Is(err, ErrScriptParse),
+		errors.Is(err, ErrInvalidScript),
+		errors.Is(err, ErrCountMismatch),
+		errors.Is(err, ErrInvalidDesignOverride):
+		return false
+	default:
+		return true
+	}
+}
@@ -18,7 +18,19 @@ type ScriptPrompt interface {
 	Build(mode string, data *TemplateData) (string, error)
 }
 
+// TextExtractor は、入力の内容（HTML・PDF など）から台本の元になる本文を抽出する契約です。
+type TextExtractor interface {
+	Extract(ctx context.Context, data []byte) (*ExtractedText, error)
+}
+
 // ImagePrompt は、画像生成AI向けのプロンプトを構築する契約です。
+// パネルにはショット・感情・場面などの演出（Panel.Shot・Emotions・SceneID など）もそのまま渡されます。
+// Panel.StagingNotes を使うと、それらをプロンプトに添える形式で取得できます。
+//
+// 複数のセリフ（Panel.Lines）を導入する前の実装は Panel.Dialogue だけを読みます。パーサーと台本の
+// 生成は Panel.Normalize で Lines の本文を Dialogue にも入れるため、そのままでもセリフは渡ります。
+// パネルを直接組み立てる場合は Normalize を呼んでください。話者・種類ごとにセリフを扱うには
+// Panel.DialogueLines に移行してください。
 type ImagePrompt interface {
 	// BuildPanel は、単一の漫画パネル用のユーザープロンプトとシステムプロンプトを決定します。
 	BuildPanel(panel Panel, char *Character) (userPrompt string, systemPrompt string)
@@ -36,3 +48,10 @@ type PanelsImageGenerator interface {
 type PagesImageGenerator interface {
 	Execute(ctx context.Context, manga *MangaResponse) ([]*imagePorts.ImageResponse, error)
 }
+
+// StructuredGenerator は、JSON Schema で出力を制約したテキスト生成に対応する AI クライアントの契約です。
+// 台本生成の AI クライアントがこれを実装している場合、台本はスキーマに従う JSON として要求されます。
+type StructuredGenerator interface {
+	// GenerateStructured は、prompt に対して schema に従う JSON テキストを生成します。
+	GenerateStructured(ctx context.Context, modelName, prompt string, schema map[string]any) (string, error)
+}
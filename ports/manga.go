@@ -4,20 +4,93 @@ import imagePorts "github.com/shouni/gemini-image-kit/ports"
 
 // MangaResponse は AI モデルから返される台本全体の構造です。
 type MangaResponse struct {
-	Title       string  `json:"title"`
-	Description string  `json:"description"`
-	Panels      []Panel `json:"panels"`
+	Title       string  `json:"title" description:"漫画のタイトル"`
+	Description string  `json:"description" description:"漫画の概要"`
+	Panels      []Panel `json:"panels" description:"表示順のパネル"`
+	// Sources は台本の元になった入力の一覧です。台本の生成時に記録されます。
+	Sources []SourceRef `json:"sources,omitempty" description:"台本の元になった入力の一覧"`
 }
 
 // Panel は漫画の1ページまたは1パネルの構成、セリフ、話者情報を保持します。
+//
+// セリフは Dialogue・SpeakerID の1つだけの形式と、Lines の複数の形式のどちらでも書けます。
+// Lines を追加する前の台本もそのまま読み込めます。両方の形式を扱う場合は DialogueLines を使ってください。
+// Normalize は Lines だけで書かれたパネルの Dialogue・SpeakerID を補うため、Dialogue だけを読む
+// 既存の実装（ImagePrompt など）にもセリフが渡ります。
 type Panel struct {
-	Page         int    `json:"page"`
-	VisualAnchor string `json:"visual_anchor"`
-	Dialogue     string `json:"dialogue"`
-	SpeakerID    string `json:"speaker_id"`
-	ReferenceURL string `json:"reference_url"`
+	Page         int    `json:"page" description:"パネルを配置するページ番号（1 始まり）"`
+	VisualAnchor string `json:"visual_anchor" description:"パネルの情景・構図・キャラクターの動作の描写"`
+	Dialogue     string `json:"dialogue" description:"パネルのセリフ（1つだけの場合。複数のセリフは lines に書きます）"`
+	SpeakerID    string `json:"speaker_id" description:"パネルの中心となるキャラクターのID（lines が無い場合はセリフの話者）"`
+	ReferenceURL string `json:"reference_url" schema:"optional" description:"パネルの参照画像のURL"`
+	// Source はパネルの元になった入力のラベル（MangaResponse.Sources の Label）です。
+	Source string `json:"source,omitempty" description:"パネルの元になった入力のラベル"`
+	// Lines は表示順のセリフです。空でない場合は Dialogue より優先します。
+	Lines []DialogueLine `json:"lines,omitempty" description:"表示順のセリフ（複数の話者・種類を書き分ける場合）"`
+	// Narration はナレーション（キャプション）です。
+	Narration []string `json:"narration,omitempty" description:"ナレーションのキャプション"`
+	// SFX は描き文字の効果音です（例: "ドン"）。
+	SFX []string `json:"sfx,omitempty" description:"描き文字の効果音"`
+	// Shot はカメラのショットです。ShotType の定数のほか、任意の値も使えます。
+	Shot ShotType `json:"shot,omitempty" description:"カメラのショット（extreme_close_up・close_up・medium・full・long・establishing・over_the_shoulder・high_angle・low_angle など）"`
+	// Emotions は登場するキャラクターごとの感情です。
+	Emotions []CharacterEmotion `json:"emotions,omitempty" description:"登場するキャラクターごとの感情"`
+	// SceneID・LocationID は、同じ場面・場所のパネルで背景や雰囲気を揃えるための識別子です。
+	SceneID    string `json:"scene_id,omitempty" description:"場面の識別子（同じ場面のパネルで同じ値）"`
+	LocationID string `json:"location_id,omitempty" description:"場所の識別子（同じ場所のパネルで同じ値）"`
 }
 
+// DialogueKind はセリフの種類で、吹き出しの形を表します。
+type DialogueKind string
+
+const (
+	// DialogueSpeech は通常のセリフです。空の DialogueKind も通常のセリフとして扱います。
+	DialogueSpeech DialogueKind = "speech"
+	// DialogueThought は心の声（雲形の吹き出し）です。
+	DialogueThought DialogueKind = "thought"
+	// DialogueShout は叫び（ギザギザの吹き出し）です。
+	DialogueShout DialogueKind = "shout"
+	// DialogueWhisper はささやき（点線の吹き出し）です。
+	DialogueWhisper DialogueKind = "whisper"
+)
+
+// IsKnown は k が定義済みの種類（または空）かを返します。
+func (k DialogueKind) IsKnown() bool {
+	switch k {
+	case "", DialogueSpeech, DialogueThought, DialogueShout, DialogueWhisper:
+		return true
+	}
+	return false
+}
+
+// DialogueLine はパネル内の1つのセリフです。
+type DialogueLine struct {
+	SpeakerID string       `json:"speaker_id" description:"セリフの話者のキャラクターID（ナレーションではなく話者のいるセリフ）"`
+	Text      string       `json:"text" description:"セリフの本文"`
+	Kind      DialogueKind `json:"kind,omitempty" description:"セリフの種類（speech・thought・shout・whisper）。省略時は speech"`
+}
+
+// CharacterEmotion は、パネルでのキャラクターの感情です。
+type CharacterEmotion struct {
+	CharacterID string `json:"character_id" description:"キャラクターID"`
+	Emotion     string `json:"emotion" description:"表情・感情（例: 笑顔、驚き、怒り）"`
+}
+
+// ShotType はカメラのショットです。
+type ShotType string
+
+const (
+	ShotExtremeCloseUp  ShotType = "extreme_close_up"
+	ShotCloseUp         ShotType = "close_up"
+	ShotMedium          ShotType = "medium"
+	ShotFull            ShotType = "full"
+	ShotLong            ShotType = "long"
+	ShotEstablishing    ShotType = "establishing"
+	ShotOverTheShoulder ShotType = "over_the_shoulder"
+	ShotHighAngle       ShotType = "high_angle"
+	ShotLowAngle        ShotType = "low_angle"
+)
+
 // Panels は Panel のスライスに対するカスタム型です。
 type Panels []Panel
 
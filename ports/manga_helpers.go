@@ -1,14 +1,23 @@
 package ports
 
-import "sort"
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
 
-// UniqueSpeakerIDs はパネルのスライスから重複しない SpeakerID を抽出します。
+// UniqueSpeakerIDs はパネルのスライスから重複しない SpeakerID と、Lines の話者IDを抽出します。
 func (ps Panels) UniqueSpeakerIDs() []string {
 	set := make(map[string]struct{})
 	for _, panel := range ps {
 		if panel.SpeakerID != "" {
 			set[panel.SpeakerID] = struct{}{}
 		}
+		for _, line := range panel.Lines {
+			if line.SpeakerID != "" {
+				set[line.SpeakerID] = struct{}{}
+			}
+		}
 	}
 
 	uniqueIDs := make([]string, 0, len(set))
@@ -19,3 +28,88 @@ func (ps Panels) UniqueSpeakerIDs() []string {
 
 	return uniqueIDs
 }
+
+// DialogueLines はパネルのセリフを表示順に返します。Lines が空の場合は、Dialogue と SpeakerID を
+// 1つの通常のセリフとして返します（セリフが無い場合は nil です）。
+func (p Panel) DialogueLines() []DialogueLine {
+	if len(p.Lines) > 0 {
+		return p.Lines
+	}
+	if p.Dialogue == "" {
+		return nil
+	}
+	return []DialogueLine{{SpeakerID: p.SpeakerID, Text: p.Dialogue}}
+}
+
+// DialogueText はパネルのセリフの本文を表示順に改行で連結して返します。Dialogue と同じく
+// 1つの文字列でセリフを扱う場合に使います（話者・種類が必要な場合は DialogueLines を使ってください）。
+func (p Panel) DialogueText() string {
+	lines := p.DialogueLines()
+	texts := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line.Text != "" {
+			texts = append(texts, line.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// HasText はパネルにセリフ・ナレーション・効果音のいずれかがあるかを返します。
+func (p Panel) HasText() bool {
+	return p.Dialogue != "" || len(p.Lines) > 0 || len(p.Narration) > 0 || len(p.SFX) > 0
+}
+
+// Normalize は、Lines だけで書かれたパネルの SpeakerID を最初の話者で、Dialogue を DialogueText で
+// 補います。画像生成でキャラクターの参照画像を選ぶためと、Dialogue だけを読む ImagePrompt の実装に
+// セリフを渡すために使います。Lines がある場合、DialogueLines は引き続き Lines を返します。
+func (p *Panel) Normalize() {
+	if p.Dialogue == "" {
+		p.Dialogue = p.DialogueText()
+	}
+	if p.SpeakerID != "" {
+		return
+	}
+	for _, line := range p.Lines {
+		if line.SpeakerID != "" {
+			p.SpeakerID = line.SpeakerID
+			return
+		}
+	}
+}
+
+// Normalize はすべてのパネルに Panel.Normalize を適用します。
+func (m *MangaResponse) Normalize() {
+	if m == nil {
+		return
+	}
+	for i := range m.Panels {
+		m.Panels[i].Normalize()
+	}
+}
+
+// StagingNotes は、ショット・場面・場所・キャラクターの感情・効果音など、情景の描写以外の演出を
+// 画像生成のプロンプトに添えるための行の一覧にします。演出が無い場合は空文字列です。
+// セリフやナレーションの本文は画像に描かせないため含めません。
+func (p Panel) StagingNotes() string {
+	var notes []string
+	if p.Shot != "" {
+		notes = append(notes, "Shot: "+strings.ReplaceAll(string(p.Shot), "_", " "))
+	}
+	if p.SceneID != "" {
+		notes = append(notes, "Scene: "+p.SceneID)
+	}
+	if p.LocationID != "" {
+		notes = append(notes, "Location: "+p.LocationID)
+	}
+	if len(p.Emotions) > 0 {
+		emotions := make([]string, 0, len(p.Emotions))
+		for _, e := range p.Emotions {
+			emotions = append(emotions, fmt.Sprintf("%s: %s", e.CharacterID, e.Emotion))
+		}
+		notes = append(notes, "Emotions: "+strings.Join(emotions, ", "))
+	}
+	if len(p.SFX) > 0 {
+		notes = append(notes, "Sound effects: "+strings.Join(p.SFX, ", "))
+	}
+	return strings.Join(notes, "\n")
+}
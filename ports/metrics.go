@@ -0,0 +1,102 @@
+package ports
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	// StageScript は台本（JSON）生成ステージを表します。
+	StageScript = "script"
+	// StageDesign はキャラクターデザインシート生成ステージを表します。
+	StageDesign = "design"
+	// StageUpload は参照画像の File API へのアップロードを表します。
+	StageUpload = "upload"
+)
+
+// キャッシュの種類を表すラベルです。
+const (
+	// CacheAsset は MangaComposer が保持する ReferenceURL -> File API URI のキャッシュです。
+	CacheAsset = "asset"
+	// CacheImage は画像生成エンジンが保持する画像バイナリ・アップロード結果のキャッシュです。
+	CacheImage = "image"
+)
+
+// 失敗の分類です。MetricsRecorder.IncFailure の class に渡されます。
+const (
+	ErrorClassCanceled          = "canceled"
+	ErrorClassTimeout           = "timeout"
+	ErrorClassRateLimited       = "rate_limited"
+	ErrorClassContentBlocked    = "content_blocked"
+	ErrorClassCharacterNotFound = "character_not_found"
+	ErrorClassAssetPreparation  = "asset_preparation"
+	ErrorClassInvalidAsset      = "invalid_asset"
+	ErrorClassScriptParse       = "script_parse"
+	ErrorClassInvalidScript     = "invalid_script"
+	ErrorClassCountMismatch     = "count_mismatch"
+	ErrorClassUnknown           = "unknown"
+)
+
+// MetricsRecorder は生成パイプラインのメトリクスを記録するインターフェースです。
+// 実装は複数の goroutine から同時に呼び出されるため、並行安全である必要があります。
+type MetricsRecorder interface {
+	// ObserveGeneration は AI 呼び出し1回あたりの所要時間を記録します。
+	ObserveGeneration(stage, model string, d time.Duration)
+	// IncFailure は生成の失敗を分類ごとに記録します。
+	IncFailure(stage, class string)
+	// ObserveRateLimitWait はレートリミッターの Wait で待機した時間を記録します。
+	ObserveRateLimitWait(stage string, d time.Duration)
+	// IncAssetUpload は File API への参照画像のアップロードを記録します。
+	IncAssetUpload(resourceType string)
+	// IncCacheLookup はキャッシュの参照結果（ヒット・ミス）を記録します。
+	IncCacheLookup(cache string, hit bool)
+	// AddInFlight は実行中の AI 呼び出し数を delta だけ増減します。
+	AddInFlight(stage string, delta int)
+}
+
+// NopMetrics は何も記録しない MetricsRecorder です。
+type NopMetrics struct{}
+
+func (NopMetrics) ObserveGeneration(string, string, time.Duration) {}
+func (NopMetrics) IncFailure(string, string)                       {}
+func (NopMetrics) ObserveRateLimitWait(string, time.Duration)      {}
+func (NopMetrics) IncAssetUpload(string)                           {}
+func (NopMetrics) IncCacheLookup(string, bool)                     {}
+func (NopMetrics) AddInFlight(string, int)                         {}
+
+// MetricsOrNop は m が nil の場合に NopMetrics を返します。
+func MetricsOrNop(m MetricsRecorder) MetricsRecorder {
+	if m == nil {
+		return NopMetrics{}
+	}
+	return m
+}
+
+// ClassifyError は err をメトリクス用の失敗分類に変換します。
+func ClassifyError(err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.Is(err, ErrRequestTimeout), errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case errors.Is(err, ErrRateLimited):
+		return ErrorClassRateLimited
+	case errors.Is(err, ErrContentBlocked):
+		return ErrorClassContentBlocked
+	case errors.Is(err, ErrCharacterNotFound):
+		return ErrorClassCharacterNotFound
+	case errors.Is(err, ErrInvalidAsset), errors.Is(err, ErrReferenceURLNotAllowed):
+		return ErrorClassInvalidAsset
+	case errors.Is(err, ErrScriptParse):
+		return ErrorClassScriptParse
+	case errors.Is(err, ErrInvalidScript):
+		return ErrorClassInvalidScript
+	case errors.Is(err, ErrCountMismatch):
+		return ErrorClassCountMismatch
+	case errors.Is(err, ErrAssetPreparation):
+		return ErrorClassAssetPreparation
+	default:
+		return ErrorClassUnknown
+	}
+}
@@ -0,0 +1,112 @@
+package ports
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// ProgressEventKind は進捗イベントの種類です。
+type ProgressEventKind string
+
+const (
+	// ProgressStageStarted はステージ（台本生成・パネル生成・ページ生成・デザインシート生成）の開始を表します。
+	ProgressStageStarted ProgressEventKind = "stage_started"
+	// ProgressAssetUploaded は参照画像の File API へのアップロード完了を表します。
+	ProgressAssetUploaded ProgressEventKind = "asset_uploaded"
+	// ProgressQueued はパネル・ページが生成待ちのキューに入ったことを表します。
+	ProgressQueued ProgressEventKind = "queued"
+	// ProgressStarted はパネル・ページの生成開始（レート制限の待機後）を表します。
+	ProgressStarted ProgressEventKind = "started"
+	// ProgressRetried はパネル・ページの生成が失敗し、再試行されることを表します。
+	// 再試行を有効にした場合（layout.WithPanelMaxAttempts・layout.WithPageMaxAttempts）のみ通知されます。
+	ProgressRetried ProgressEventKind = "retried"
+	// ProgressCompleted はパネル・ページの生成完了を表します。
+	ProgressCompleted ProgressEventKind = "completed"
+	// ProgressFailed はパネル・ページの生成失敗（再試行を使い切った場合を含む）を表します。
+	ProgressFailed ProgressEventKind = "failed"
+	// ProgressStageFinished はステージの終了を表します。失敗時は Err が設定されます。
+	ProgressStageFinished ProgressEventKind = "stage_finished"
+)
+
+const (
+	// StagePanel はパネル単位の画像生成ステージを表します。
+	StagePanel = "panel"
+	// StagePage はページ単位の画像生成ステージを表します。
+	StagePage = "page"
+)
+
+// ProgressEvent は生成パイプラインの進捗を表す型付きイベントです。
+type ProgressEvent struct {
+	Kind  ProgressEventKind
+	Stage string
+	// Index はパネル・ページの番号（1始まり）です。ステージ単位のイベントでは 0 です。
+	Index int
+	// Total はステージ内のパネル・ページの総数です。
+	Total int
+	// Attempt は試行回数（1始まり）です。
+	Attempt int
+	Seed    int64
+	// Duration は Completed/Failed/StageFinished では処理時間、AssetUploaded ではアップロード時間です。
+	Duration time.Duration
+	// ReferenceURL は AssetUploaded の対象となった参照画像のURLです。
+	ReferenceURL string
+	Err          error
+	Time         time.Time
+}
+
+// ProgressObserver は進捗イベントを受け取るインターフェースです。
+// go-manga-kit は OnProgress を1つの Execute 内で直列に呼び出しますが、別の goroutine から
+// 呼び出されることがあります。OnProgress をブロックすると生成処理も待たされます。
+type ProgressObserver interface {
+	OnProgress(event ProgressEvent)
+}
+
+// ProgressObserverFunc は関数を ProgressObserver として扱うためのアダプタです。
+type ProgressObserverFunc func(event ProgressEvent)
+
+// OnProgress は ProgressObserver の実装です。
+func (f ProgressObserverFunc) OnProgress(event ProgressEvent) { f(event) }
+
+// ChannelProgressObserver は、受け取ったイベントを channel に送信する ProgressObserver です。
+type ChannelProgressObserver struct {
+	ch      chan<- ProgressEvent
+	dropped atomic.Int64
+}
+
+// ChannelObserver は、受け取ったイベントを ch に送信する ProgressObserver を返します。
+// UI やジョブダッシュボードなど、別の goroutine からイベントを消費する用途を想定しています。
+// 生成処理を止めないよう送信はノンブロッキングで、ch に空きが無い場合はイベントを破棄して
+// Dropped に数えます。取りこぼしを避けるには十分なバッファを用意してください。
+// ch は生成処理の完了後に呼び出し元が close してください。
+func ChannelObserver(ch chan<- ProgressEvent) *ChannelProgressObserver {
+	return &ChannelProgressObserver{ch: ch}
+}
+
+// OnProgress は ProgressObserver の実装です。
+func (o *ChannelProgressObserver) OnProgress(event ProgressEvent) {
+	select {
+	case o.ch <- event:
+	default:
+		o.dropped.Add(1)
+	}
+}
+
+// Dropped は、ch に空きが無かったために破棄したイベントの数を返します。
+func (o *ChannelProgressObserver) Dropped() int64 {
+	return o.dropped.Load()
+}
+
+type progressObserverKey struct{}
+
+// WithProgressObserver は、呼び出し単位で進捗イベントを受け取るための observer を ctx に設定します。
+// Runner のオプションで設定された observer がある場合は、両方にイベントが通知されます。
+func WithProgressObserver(ctx context.Context, observer ProgressObserver) context.Context {
+	return context.WithValue(ctx, progressObserverKey{}, observer)
+}
+
+// ProgressObserverFromContext は ctx に設定された observer を返します。未設定の場合は nil です。
+func ProgressObserverFromContext(ctx context.Context) ProgressObserver {
+	observer, _ := ctx.Value(progressObserverKey{}).(ProgressObserver)
+	return observer
+}
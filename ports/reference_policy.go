@@ -0,0 +1,193 @@
+package ports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// ErrReferenceURLNotAllowed は、参照画像の URL が ReferencePolicy で許可されていないことを表します。
+var ErrReferenceURLNotAllowed = errors.New("reference URL not allowed")
+
+// ReferenceURLError は、ReferencePolicy に違反した URL と理由を表す ErrReferenceURLNotAllowed です。
+type ReferenceURLError struct {
+	URL    string
+	Reason string
+}
+
+func (e *ReferenceURLError) Error() string {
+	return fmt.Sprintf("reference URL '%s' is not allowed: %s", e.URL, e.Reason)
+}
+
+// Is は ErrReferenceURLNotAllowed との比較を可能にします。
+func (e *ReferenceURLError) Is(target error) bool { return target == ErrReferenceURLNotAllowed }
+
+// HostResolver はホスト名を IP アドレスに解決します。*net.Resolver が実装しています。
+type HostResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// ReferencePolicy は、台本（パネル）の参照画像として取得を許可する URL の方針です。
+// AI が生成した台本にはプロンプトインジェクションにより任意の URL が含まれ得るため、
+// サーバーが内部ネットワーク等へアクセスすること（SSRF）を防ぎます。
+//
+// 名前解決の結果を検査した後に DNS の応答が変わる攻撃（DNS Rebinding）は防げないため、
+// HTTP クライアントには securenet.NewSafeHTTPClient などの接続時検査を併用してください。
+type ReferencePolicy struct {
+	// AllowedSchemes は許可するスキームです（"https", "http", "gs", "s3"）。
+	AllowedSchemes []string
+	// AllowedHosts は http(s) で許可するホスト名です。"*.example.com" はサブドメインに一致します。
+	// 空の場合は、プライベートネットワーク以外のすべてのホストを許可します。
+	AllowedHosts []string
+	// AllowPrivateNetworks を true にすると、ループバック・プライベート・リンクローカル等の
+	// アドレスに解決されるホストを許可します。
+	AllowPrivateNetworks bool
+	// AllowedGCSPrefixes は gs:// で許可する接頭辞です（例: "gs://my-bucket/assets/"）。
+	// 空の場合はすべてのバケットを許可します。
+	AllowedGCSPrefixes []string
+	// AllowLocalPaths を true にすると、スキームの無いローカルパス（例: "images/p1.png"）を許可します。
+	// ".." を含むパスは常に拒否します。
+	AllowLocalPaths bool
+	// AllowAIReferenceURLs を true にすると、AI が生成した台本の ReferenceURL を
+	// 方針に従って検査したうえで残します。false（既定）の場合はすべて取り除きます。
+	// AI にローカルのファイルを参照させない場合は、AllowLocalPaths を false にしてください。
+	AllowAIReferenceURLs bool
+	// Resolver はホスト名の解決に使います。nil の場合は net.DefaultResolver です。
+	Resolver HostResolver
+}
+
+// DefaultReferencePolicy は既定の方針を返します。人が書いた台本の参照画像を従来どおり使えるよう、
+// https・http・gs://・s3:// とローカルパスを許可します。プライベートネットワークへのアクセスと
+// ".." によるパスの遡りは禁止し、AI が生成した ReferenceURL は取り除きます。
+func DefaultReferencePolicy() ReferencePolicy {
+	return ReferencePolicy{
+		AllowedSchemes:  []string{"https", "http", "gs", "s3"},
+		AllowLocalPaths: true,
+	}
+}
+
+// Check は rawURL が方針に適合するかを検査し、違反している場合は *ReferenceURLError を返します。
+// http(s) の場合はホスト名を解決し、いずれかのアドレスが制限されたネットワークに属していれば拒否します。
+func (p ReferencePolicy) Check(ctx context.Context, rawURL string) error {
+	deny := func(format string, args ...any) error {
+		return &ReferenceURLError{URL: rawURL, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return deny("invalid URL: %v", err)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme == "" {
+		if !p.AllowLocalPaths {
+			return deny("scheme is missing")
+		}
+		if hasDotDot(rawURL) {
+			return deny("path traversal is not allowed")
+		}
+		return nil
+	}
+	if !slices.Contains(p.AllowedSchemes, scheme) {
+		return deny("scheme %q is not allowed", scheme)
+	}
+	if u.User != nil {
+		return deny("URLs with user info are not allowed")
+	}
+
+	switch scheme {
+	case "gs", "s3":
+		if u.Host == "" {
+			return deny("bucket is missing")
+		}
+		if hasDotDot(u.Path) {
+			return deny("path traversal is not allowed")
+		}
+		if scheme == "gs" && len(p.AllowedGCSPrefixes) > 0 &&
+			!slices.ContainsFunc(p.AllowedGCSPrefixes, func(prefix string) bool { return strings.HasPrefix(rawURL, prefix) }) {
+			return deny("bucket path is not in the allowlist")
+		}
+		return nil
+	case "http", "https":
+		return p.checkHost(ctx, strings.ToLower(u.Hostname()), deny)
+	default:
+		return deny("scheme %q is not supported", scheme)
+	}
+}
+
+// checkHost はホスト名の許可リストと、解決先アドレスを検査します。
+func (p ReferencePolicy) checkHost(ctx context.Context, host string, deny func(string, ...any) error) error {
+	if host == "" {
+		return deny("host is missing")
+	}
+	if len(p.AllowedHosts) > 0 && !slices.ContainsFunc(p.AllowedHosts, func(pattern string) bool { return matchHost(pattern, host) }) {
+		return deny("host %q is not in the allowlist", host)
+	}
+	if p.AllowPrivateNetworks {
+		return nil
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if isRestrictedAddr(addr) {
+			return deny("address %s is in a restricted network", addr)
+		}
+		return nil
+	}
+
+	resolver := p.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return deny("host %q could not be resolved: %v", host, err)
+	}
+	if len(addrs) == 0 {
+		return deny("host %q resolved to no addresses", host)
+	}
+	for _, a := range addrs {
+		addr, ok := netip.AddrFromSlice(a.IP)
+		if !ok || isRestrictedAddr(addr.Unmap()) {
+			return deny("host %q resolves to a restricted network (%s)", host, a.IP)
+		}
+	}
+	return nil
+}
+
+// hasDotDot は path に ".." の要素が含まれるかを返します。Windows の区切り文字も考慮します。
+func hasDotDot(path string) bool {
+	return slices.Contains(strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' }), "..")
+}
+
+// matchHost は host が pattern（"*.example.com" 形式を含む）に一致するかを返します。
+func matchHost(pattern, host string) bool {
+	pattern = strings.ToLower(pattern)
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
+
+// restrictedPrefixes は、プライベート・ループバック等の判定に含まれない予約済みアドレス帯です。
+var restrictedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// isRestrictedAddr は addr がサーバーからの取得を許可しないアドレスかを返します。
+func isRestrictedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return true
+	}
+	return slices.ContainsFunc(restrictedPrefixes, func(p netip.Prefix) bool { return p.Contains(addr) })
+}
@@ -0,0 +1,60 @@
+package ports
+
+import "io"
+
+// SourceKind は台本の入力の種類です。
+type SourceKind string
+
+const (
+	// SourceKindText は生のテキストの入力です。
+	SourceKindText SourceKind = "text"
+	// SourceKindURI は URL・GCS・ローカルパスなどから読み込む入力です。
+	SourceKindURI SourceKind = "uri"
+	// SourceKindReader はメモリ上の io.Reader から読み込む入力です。
+	SourceKindReader SourceKind = "reader"
+)
+
+// Source は台本を生成するための1つの入力です。Text・URI・Reader のいずれか1つを指定します。
+type Source struct {
+	// Label は入力を識別するラベルで、台本の出典（MangaResponse.Sources・Panel.Source）に記録されます。
+	// 空の場合は URI、URI も無い場合は "source-N"（N は入力の番号）を使います。
+	Label string
+	// Text は生のテキストです。
+	Text string
+	// URI は読み込む入力の URL・GCS URI・ローカルパスです。
+	URI string
+	// Reader はメモリ上の入力です。読み込みは1回のみ行い、クローズは呼び出し元の責務です。
+	Reader io.Reader
+	// ContentType は入力の MIME タイプ（例: "text/html"）です。空の場合は URI の拡張子と内容から判定します。
+	ContentType string
+}
+
+// Kind は入力の種類を返します。いずれも指定されていない場合は空文字列です。
+func (s Source) Kind() SourceKind {
+	switch {
+	case s.Reader != nil:
+		return SourceKindReader
+	case s.URI != "":
+		return SourceKindURI
+	case s.Text != "":
+		return SourceKindText
+	default:
+		return ""
+	}
+}
+
+// SourceRef は台本に記録する1つの入力の出典です。
+type SourceRef struct {
+	Label string     `json:"label" description:"入力のラベル。パネルの source はこの値を参照します"`
+	Kind  SourceKind `json:"kind" description:"入力の種類（text・uri・reader）"`
+	URI   string     `json:"uri,omitempty" description:"入力の URI（kind が uri の場合）"`
+	Title string     `json:"title,omitempty" description:"入力から抽出した文書のタイトル"`
+}
+
+// ExtractedText は、HTML・Markdown・PDF などの入力から抽出した本文とメタデータです。
+type ExtractedText struct {
+	// Title は文書のタイトルです。見つからない場合は空です。
+	Title string
+	// Text はナビゲーションやスクリプト、書式の情報を取り除いた本文です。
+	Text string
+}
@@ -0,0 +1,39 @@
+package ports
+
+import "fmt"
+
+// Severity は台本の問題の重大度です。
+type Severity string
+
+const (
+	// SeverityError は生成を止めるべき問題です。
+	SeverityError Severity = "error"
+	// SeverityWarning は生成は可能だが見直しを勧める問題です。
+	SeverityWarning Severity = "warning"
+)
+
+// ScriptIssue は台本の検証で見つかった1件の問題です。
+type ScriptIssue struct {
+	// Severity は問題の重大度です。
+	Severity Severity
+	// PanelIndex はパネルの番号（1始まり）です。台本全体の問題では 0 です。
+	PanelIndex int
+	// RuleID は問題を検出した規則の ID です。
+	RuleID string
+	// Field は問題のあるフィールドの JSON 上の名前です。JSON として解析できない場合は空です。
+	Field   string
+	Message string
+}
+
+func (i ScriptIssue) String() string {
+	switch {
+	case i.PanelIndex > 0 && i.Field != "":
+		return fmt.Sprintf("panel %d %s: %s", i.PanelIndex, i.Field, i.Message)
+	case i.PanelIndex > 0:
+		return fmt.Sprintf("panel %d: %s", i.PanelIndex, i.Message)
+	case i.Field != "":
+		return fmt.Sprintf("%s: %s", i.Field, i.Message)
+	default:
+		return i.Message
+	}
+}
@@ -34,10 +34,25 @@ type DesignRunner interface {
 // 差し替えるためのその場限りの上書き指定です。go-character-kit 側のキャラクター定義
 // （characters.json）そのものは変更しません。ReferenceURL/VisualCues が空の場合はそのフィール
 // ドのみキャラクター定義の値を使います。charIDs が複数（合成デザインシート）の場合、上書きは
-// どのキャラクターに適用すべきか一意に決まらないため無視されます。
+// どのキャラクターに適用すべきか一意に決まらないため無視されます。合成デザインシートの一部の
+// キャラクターだけを差し替える場合は Characters を使います。
 type DesignOverride struct {
 	ReferenceURL string
 	VisualCues   []string
+	// Characters はキャラクターIDごとの上書き指定で、charIDs の数に関わらず各キャラクターに
+	// 個別に適用されます。同じキャラクターに ReferenceURL/VisualCues と両方が当たる場合は
+	// こちらが優先されます。キーはすべて charIDs に含まれている必要があります。
+	Characters map[string]CharacterDesignOverride
+}
+
+// CharacterDesignOverride は DesignOverride.Characters の1キャラクター分の上書き指定です。
+// 空のフィールドはキャラクター定義の値を使います。
+type CharacterDesignOverride struct {
+	ReferenceURL string
+	VisualCues   []string
+	// Seed は、Run の seed 引数が 0 の場合に使う Seed 値です。複数のキャラクターに指定した
+	// 場合は charIDs で先に現れるキャラクターの値を使います。
+	Seed *int64
 }
 
 // ScriptRunner は、ソース（URLやテキスト）を解析し、構造化された漫画台本を生成する責務を持ちます。
@@ -45,6 +60,14 @@ type ScriptRunner interface {
 	Run(ctx context.Context, scriptURL string, mode string) (*MangaResponse, error)
 }
 
+// SourceScriptRunner は、複数の入力から台本を生成できる ScriptRunner です。
+// ScriptRunner の既存の実装を壊さないよう別のインターフェースとしており、型アサーションで確認します。
+type SourceScriptRunner interface {
+	ScriptRunner
+	// RunSources は、生のテキスト・URI・Reader を組み合わせた複数の入力から台本を生成し、出典を記録します。
+	RunSources(ctx context.Context, sources []Source, mode string) (*MangaResponse, error)
+}
+
 // PanelImageRunner は、解析済みの漫画データと対象パネルのインデックスを基に、パネル画像を生成する責務を持ちます。
 type PanelImageRunner interface {
 	Run(ctx context.Context, manga *MangaResponse) ([]*imagePorts.ImageResponse, error)
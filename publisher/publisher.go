@@ -12,6 +12,7 @@ import (
 	"github.com/shouni/go-remote-io/remoteio"
 
 	"github.com/shouni/go-manga-kit/asset"
+	"github.com/shouni/go-manga-kit/internal/telemetry"
 	"github.com/shouni/go-manga-kit/ports"
 )
 
@@ -77,8 +78,7 @@ func (p *MangaPublisher) Publish(ctx context.Context, manga *ports.MangaResponse
 
 	// Markdown書き込み
 	contentReader := strings.NewReader(content)
-	if err := p.writer.Write(ctx, markdownPath, contentReader,
-		remoteio.WithContentType(mdContentType),
+	if err := telemetry.Write(ctx, p.writer, markdownPath, mdContentType, contentReader,
 		remoteio.WithCacheControl(defaultCacheControl),
 	); err != nil {
 		return nil, fmt.Errorf("markdown 書き込み失敗: %w", err)
@@ -92,8 +92,7 @@ func (p *MangaPublisher) Publish(ctx context.Context, manga *ports.MangaResponse
 			return nil, fmt.Errorf("HTML 変換失敗: %w", err)
 		}
 		htmlPath = strings.TrimSuffix(markdownPath, path.Ext(markdownPath)) + ".html"
-		if err := p.writer.Write(ctx, htmlPath, htmlBuffer,
-			remoteio.WithContentType(htmlContentType),
+		if err := telemetry.Write(ctx, p.writer, htmlPath, htmlContentType, htmlBuffer,
 			remoteio.WithCacheControl(defaultCacheControl),
 		); err != nil {
 			return nil, fmt.Errorf("HTML 書き込み失敗: %w", err)
@@ -107,7 +106,7 @@ func (p *MangaPublisher) Publish(ctx context.Context, manga *ports.MangaResponse
 	}, nil
 }
 
-// BuildMarkdown は画像、話者、セリフ、確認用アンカーを含む Markdown を構築します。
+// BuildMarkdown は画像、ナレーション、話者、セリフ、効果音、確認用アンカーと演出を含む Markdown を構築します。
 func (p *MangaPublisher) BuildMarkdown(manga *ports.MangaResponse, opts ports.PublishOptions) string {
 	var sb strings.Builder
 
@@ -127,7 +126,7 @@ func (p *MangaPublisher) BuildMarkdown(manga *ports.MangaResponse, opts ports.Pu
 			currentImagePath = panel.ReferenceURL
 		}
 
-		if currentImagePath == "" && panel.Dialogue == "" {
+		if currentImagePath == "" && !panel.HasText() {
 			continue
 		}
 		if !firstPanel {
@@ -140,25 +139,51 @@ func (p *MangaPublisher) BuildMarkdown(manga *ports.MangaResponse, opts ports.Pu
 			fmt.Fprintf(&sb, "![Panel %d](%s)\n\n", i+1, currentImagePath)
 		}
 
-		// 2. セリフ
-		if panel.Dialogue != "" {
-			dialogue := escapeMarkdown(panel.Dialogue)
-			if panel.SpeakerID != "" {
-				fmt.Fprintf(&sb, "**%s**: %s\n\n", escapeMarkdown(panel.SpeakerID), dialogue)
+		// 2. ナレーション
+		for _, caption := range panel.Narration {
+			fmt.Fprintf(&sb, "*%s*\n\n", escapeMarkdown(caption))
+		}
+
+		// 3. セリフ
+		for _, line := range panel.DialogueLines() {
+			if line.Text == "" {
+				continue
+			}
+			text := escapeMarkdown(line.Text)
+			kind := ""
+			if line.Kind != "" && line.Kind != ports.DialogueSpeech {
+				kind = fmt.Sprintf(" (%s)", escapeMarkdown(string(line.Kind)))
+			}
+			if line.SpeakerID != "" {
+				fmt.Fprintf(&sb, "**%s**%s: %s\n\n", escapeMarkdown(line.SpeakerID), kind, text)
 			} else {
-				fmt.Fprintf(&sb, "%s\n\n", dialogue)
+				fmt.Fprintf(&sb, "%s\n\n", strings.TrimSpace(kind+" "+text))
 			}
 		}
 
-		// 3. VisualAnchor
+		// 4. 効果音
+		if len(panel.SFX) > 0 {
+			fmt.Fprintf(&sb, "**SFX:** %s\n\n", escapeMarkdown(strings.Join(panel.SFX, "、")))
+		}
+
+		// 5. VisualAnchor と演出
 		if panel.VisualAnchor != "" {
 			fmt.Fprintf(&sb, "> **Visual Anchor:** %s\n\n", escapeMarkdown(panel.VisualAnchor))
 		}
+		if staging := stagingSummary(panel); staging != "" {
+			fmt.Fprintf(&sb, "> **Staging:** %s\n\n", escapeMarkdown(staging))
+		}
 	}
 
 	return sb.String()
 }
 
+// stagingSummary は、効果音を除くパネルの演出（ショット・場面・場所・感情）を1行にまとめます。
+func stagingSummary(panel ports.Panel) string {
+	panel.SFX = nil
+	return strings.ReplaceAll(panel.StagingNotes(), "\n", " / ")
+}
+
 // escapeMarkdown は Markdown の制御文字と HTML 特殊文字を安全に置換します。
 func escapeMarkdown(text string) string {
 	return markdownEscaper.Replace(text)
@@ -1,49 +1,14 @@
 package publisher
 
 import (
-	"bytes"
 	"context"
-	"fmt"
-	"io"
 	"strings"
 	"testing"
 
+	"github.com/shouni/go-manga-kit/mangakittest"
 	"github.com/shouni/go-manga-kit/ports"
-	"github.com/shouni/go-remote-io/remoteio"
 )
 
-// --- Mocks ---
-
-type mockWriter struct {
-	files map[string][]byte
-}
-
-// remoteio.Writer インターフェースのシグネチャに合わせるため、可変長引数を追加します。
-func (m *mockWriter) Write(_ context.Context, path string, content io.Reader, _ ...remoteio.WriteOption) error {
-	data, err := io.ReadAll(content)
-	if err != nil {
-		return err
-	}
-	if m.files == nil {
-		m.files = make(map[string][]byte)
-	}
-	m.files[path] = data
-	return nil
-}
-
-type mockMDRunner struct {
-	runCalled bool
-}
-
-func (m *mockMDRunner) Run(title string, markdown []byte) (*bytes.Buffer, error) {
-	m.runCalled = true
-	// タイトルと本文を含む簡易的なHTMLをシミュレート
-	html := fmt.Sprintf("<html><head><title>%s</title></head><body>%s</body></html>", title, string(markdown))
-	return bytes.NewBufferString(html), nil
-}
-
-// --- Tests ---
-
 func TestMangaPublisher_BuildMarkdown(t *testing.T) {
 	p := NewMangaPublisher(nil, nil)
 
@@ -83,10 +48,46 @@ func TestMangaPublisher_BuildMarkdown(t *testing.T) {
 	}
 }
 
+func TestMangaPublisher_BuildMarkdown_RichPanel(t *testing.T) {
+	p := NewMangaPublisher(nil, nil)
+	manga := &ports.MangaResponse{
+		Title: "t",
+		Panels: []ports.Panel{{
+			SpeakerID:    "zundamon",
+			Dialogue:     "使われないセリフ",
+			VisualAnchor: "教室",
+			Narration:    []string{"放課後"},
+			Lines: []ports.DialogueLine{
+				{SpeakerID: "zundamon", Text: "おはよう"},
+				{SpeakerID: "metan", Text: "えっ", Kind: ports.DialogueThought},
+				{Text: "ガラッ", Kind: ports.DialogueShout},
+			},
+			SFX:      []string{"ドン", "バン"},
+			Shot:     ports.ShotCloseUp,
+			Emotions: []ports.CharacterEmotion{{CharacterID: "metan", Emotion: "驚き"}},
+		}},
+	}
+
+	got := p.BuildMarkdown(manga, ports.PublishOptions{})
+	want := "*放課後*\n\n" +
+		"**zundamon**: おはよう\n\n" +
+		"**metan** (thought): えっ\n\n" +
+		"(shout) ガラッ\n\n" +
+		"**SFX:** ドン、バン\n\n" +
+		"> **Visual Anchor:** 教室\n\n" +
+		"> **Staging:** Shot: close up / Emotions: metan: 驚き\n\n"
+	if !strings.HasSuffix(got, want) {
+		t.Errorf("Markdown =\n%s\nwant suffix\n%s", got, want)
+	}
+	if strings.Contains(got, "使われないセリフ") {
+		t.Errorf("Markdown contains the legacy dialogue although lines are set:\n%s", got)
+	}
+}
+
 func TestMangaPublisher_Publish(t *testing.T) {
 	ctx := context.Background()
-	writer := &mockWriter{files: make(map[string][]byte)}
-	mdRunner := &mockMDRunner{}
+	writer := &mangakittest.Writer{}
+	mdRunner := &mangakittest.MarkdownRunner{}
 	p := NewMangaPublisher(writer, mdRunner)
 
 	manga := &ports.MangaResponse{
@@ -121,15 +122,15 @@ func TestMangaPublisher_Publish(t *testing.T) {
 	}
 
 	// 2. 書き込み確認
-	if _, ok := writer.files[result.MarkdownPath]; !ok {
+	if _, ok := writer.File(result.MarkdownPath); !ok {
 		t.Errorf("Markdown file %s was not written to writer", result.MarkdownPath)
 	}
-	if _, ok := writer.files[result.HTMLPath]; !ok {
+	if _, ok := writer.File(result.HTMLPath); !ok {
 		t.Errorf("HTML file %s was not written to writer", result.HTMLPath)
 	}
 
 	// 3. MD Runner が呼ばれたか
-	if !mdRunner.runCalled {
+	if len(mdRunner.Calls()) == 0 {
 		t.Error("MD runner was not called")
 	}
 
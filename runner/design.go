@@ -8,13 +8,20 @@ import (
 	"fmt"
 	"log/slog"
 	"path"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	imagePorts "github.com/shouni/gemini-image-kit/ports"
 	"github.com/shouni/go-manga-kit/asset"
+	"github.com/shouni/go-manga-kit/internal/apierr"
+	"github.com/shouni/go-manga-kit/internal/deadline"
+	"github.com/shouni/go-manga-kit/internal/telemetry"
 	"github.com/shouni/go-manga-kit/layout"
 	"github.com/shouni/go-manga-kit/ports"
 	"github.com/shouni/go-remote-io/remoteio"
+	"golang.org/x/time/rate"
 )
 
 const (
@@ -58,6 +65,9 @@ const DesignLayoutSingleView = "single"
 // してください。
 type DesignOverride = ports.DesignOverride
 
+// CharacterDesignOverride は ports.CharacterDesignOverride のエイリアスです。
+type CharacterDesignOverride = ports.CharacterDesignOverride
+
 // fileNameSanitizer はファイル名として使用できない文字を置換します。
 var fileNameSanitizer = strings.NewReplacer(
 	"/", "_",
@@ -83,17 +93,36 @@ type MangaDesignRunner struct {
 	writer      remoteio.Writer
 	model       string
 	styleSuffix string
+	metrics     ports.MetricsRecorder
+	timeout     time.Duration
+
+	// limiter・maxConcurrency は RunBatch のリクエスト間隔と並列数です。
+	limiter        *rate.Limiter
+	maxConcurrency int
+	// observer はデザインシート生成の進捗イベントを受け取ります。progressMu は通知を直列にします。
+	observer   ports.ProgressObserver
+	progressMu sync.Mutex
 }
 
 // NewMangaDesignRunner は依存関係を注入して初期化します。
-func NewMangaDesignRunner(composer *layout.MangaComposer, generator DesignImageGenerator, writer remoteio.Writer, model, styleSuffix string) *MangaDesignRunner {
-	return &MangaDesignRunner{
+func NewMangaDesignRunner(composer *layout.MangaComposer, generator DesignImageGenerator, writer remoteio.Writer, model, styleSuffix string, opts ...DesignOption) *MangaDesignRunner {
+	dr := &MangaDesignRunner{
 		composer:    composer,
 		generator:   generator,
 		writer:      writer,
 		model:       model,
 		styleSuffix: styleSuffix,
+		metrics:     ports.NopMetrics{},
+
+		maxConcurrency: ports.DefaultMaxConcurrency,
+	}
+	for _, opt := range opts {
+		opt(dr)
 	}
+	if dr.limiter == nil {
+		dr.limiter = newDesignLimiter(defaultDesignRateInterval)
+	}
+	return dr
 }
 
 // Run は、指定されたキャラクターIDのデザインシートを生成し、指定されたディレクトリに保存します。
@@ -101,25 +130,96 @@ func NewMangaDesignRunner(composer *layout.MangaComposer, generator DesignImageG
 // aspectRatio は "1:1"/"9:16"/"16:9" のいずれかで、未サポート値や空文字の場合は
 // layout.DesignAspectRatio（16:9）にフォールバックします。layoutKind は DesignLayoutSingleView
 // を渡すと単一ポーズ（他の生成物のアスペクト比別参照アンカー向け）、空文字なら従来通りの
-// 3面図ターンアラウンドになります。
+// 3面図ターンアラウンドになります。override.Characters のキーが charIDs に含まれない場合は
+// ports.ErrInvalidDesignOverride を返します。
 func (dr *MangaDesignRunner) Run(ctx context.Context, charIDs []string, seed int64, outputDir, aspectRatio, layoutKind string, override DesignOverride) (string, int64, error) {
+	outputPath, resp, err := dr.generate(ctx, designJob{
+		charIDs:     charIDs,
+		seed:        seed,
+		outputDir:   outputDir,
+		aspectRatio: aspectRatio,
+		layoutKind:  layoutKind,
+		override:    override,
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	return outputPath, resp.UsedSeed, nil
+}
+
+// designJob は1回のデザインシート生成の指定です。
+type designJob struct {
+	charIDs     []string
+	seed        int64
+	outputDir   string
+	aspectRatio string
+	layoutKind  string
+	// nameSuffix は保存するファイル名の末尾（拡張子の前）に付ける文字列です。
+	nameSuffix string
+	override   DesignOverride
+	// textOnly を true にすると、参照画像を使わず名前と visual_cues だけから生成します。
+	textOnly bool
+}
+
+// generate はトレース・メトリクスの記録と進捗の通知を伴って run を実行します。
+// 進捗イベントはデザインシート1枚ごとに、ステージの開始・終了として通知されます。
+func (dr *MangaDesignRunner) generate(ctx context.Context, job designJob) (string, *imagePorts.ImageResponse, error) {
+	defer dr.composer.Lease()()
+
+	if job.seed == 0 {
+		job.seed = overrideSeed(job.charIDs, job.override)
+	}
+
+	ctx, span := telemetry.Start(ctx, "manga.design.generate",
+		telemetry.AttrStage.String(ports.StageDesign),
+		telemetry.AttrModel.String(dr.model),
+		telemetry.AttrSeed.Int64(job.seed),
+		telemetry.AttrAspectRatio.String(layout.NormalizeDesignAspectRatio(job.aspectRatio)),
+	)
+	ctx, progress := startProgress(ctx, &dr.progressMu, ports.StageDesign, dr.observer, ports.ProgressEvent{Seed: job.seed})
+	outputPath, resp, err := dr.run(ctx, job)
+	if err != nil {
+		dr.metrics.IncFailure(ports.StageDesign, ports.ClassifyError(err))
+	}
+	progress.finish(err)
+	telemetry.End(span, err)
+	return outputPath, resp, err
+}
+
+// run は Run の本体です。保存先と、生成された画像のレスポンスを返します。
+func (dr *MangaDesignRunner) run(ctx context.Context, job designJob) (string, *imagePorts.ImageResponse, error) {
+	charIDs := job.charIDs
+	if err := validateOverride(charIDs, job.override); err != nil {
+		return "", nil, err
+	}
+
 	// 1. 複数キャラの情報を集約
-	imageURIs, descriptions, err := dr.collectCharacterURIs(charIDs, override)
+	var imageURIs []imagePorts.ImageURI
+	var descriptions []string
+	var err error
+	if job.textOnly {
+		descriptions, err = dr.collectCharacterDescriptions(charIDs)
+	} else {
+		imageURIs, descriptions, err = dr.collectCharacterURIs(charIDs, job.override)
+	}
 	if err != nil {
-		return "", 0, fmt.Errorf("キャラクター資産の収集に失敗しました: %w", err)
+		return "", nil, fmt.Errorf("キャラクター資産の収集に失敗しました: %w", err)
 	}
 
 	slog.Info("Executing design work generation",
 		slog.Any("chars", charIDs),
 		slog.Int("ref_count", len(imageURIs)),
-		slog.String("aspect_ratio", aspectRatio),
-		slog.String("layout_kind", layoutKind),
+		slog.String("aspect_ratio", job.aspectRatio),
+		slog.String("layout_kind", job.layoutKind),
+		slog.Bool("text_only", job.textOnly),
 	)
 
+	telemetry.Annotate(ctx, telemetry.AttrAssetCount.Int(len(imageURIs)))
+
 	// 2. プロンプト構築
-	designPrompt := dr.buildDesignPrompt(descriptions, layoutKind)
+	designPrompt := dr.buildDesignPrompt(descriptions, job.layoutKind)
 	if designPrompt == "" {
-		return "", 0, fmt.Errorf("キャラクター情報が空のため、プロンプトを生成できませんでした")
+		return "", nil, fmt.Errorf("キャラクター情報が空のため、プロンプトを生成できませんでした")
 	}
 
 	// 3. 生成リクエスト
@@ -129,44 +229,50 @@ func (dr *MangaDesignRunner) Run(ctx context.Context, charIDs []string, seed int
 			Prompt:         designPrompt,
 			SystemPrompt:   designSystemPrompt,
 			NegativePrompt: designNegativePrompt,
-			AspectRatio:    layout.NormalizeDesignAspectRatio(aspectRatio),
+			AspectRatio:    layout.NormalizeDesignAspectRatio(job.aspectRatio),
 			ImageSize:      layout.ImageSize2K,
-			Seed:           ptrInt64(seed),
+			Seed:           ptrInt64(job.seed),
 		},
 		Images: imageURIs,
 	}
 
 	// 4. 生成実行
-	resp, err := dr.generator.GenerateFusedImage(ctx, pageReq)
+	dr.metrics.AddInFlight(ports.StageDesign, 1)
+	startTime := time.Now()
+	resp, err := deadline.Call(ctx, ports.StageDesign, dr.timeout, func(ctx context.Context) (*imagePorts.ImageResponse, error) {
+		resp, err := dr.generator.GenerateFusedImage(ctx, pageReq)
+		return resp, apierr.Classify(err)
+	})
+	dr.metrics.ObserveGeneration(ports.StageDesign, dr.model, time.Since(startTime))
+	dr.metrics.AddInFlight(ports.StageDesign, -1)
 	if err != nil {
 		slog.Error("Design generation failed", "error", err)
-		return "", 0, fmt.Errorf("画像の生成に失敗しました: %w", err)
+		return "", nil, &ports.GenerationError{Stage: ports.StageDesign, CharacterID: strings.Join(charIDs, ","), Attempts: 1, Err: err}
 	}
 
 	// 5. 画像の保存
-	outputPath, err := dr.saveResponseImage(ctx, *resp, charIDs, outputDir)
+	outputPath, err := dr.saveResponseImage(ctx, *resp, charIDs, job.outputDir, job.nameSuffix)
 	if err != nil {
 		slog.Error("Failed to save image", "error", err)
-		return "", 0, fmt.Errorf("画像の保存に失敗しました: %w", err)
+		return "", nil, fmt.Errorf("画像の保存に失敗しました: %w", err)
 	}
 
-	return outputPath, resp.UsedSeed, nil
+	return outputPath, resp, nil
 }
 
 // saveResponseImage は、生成された画像データを指定されたディレクトリに保存します。
-func (dr *MangaDesignRunner) saveResponseImage(ctx context.Context, resp imagePorts.ImageResponse, charIDs []string, outputDir string) (string, error) {
+func (dr *MangaDesignRunner) saveResponseImage(ctx context.Context, resp imagePorts.ImageResponse, charIDs []string, outputDir, nameSuffix string) (string, error) {
 	charTags := strings.Join(charIDs, "_")
 	sanitizedCharTags := fileNameSanitizer.Replace(charTags)
 
 	extension := getPreferredExtension(resp.MimeType)
-	relativePath := path.Join(asset.CharacterDesignDir, fmt.Sprintf("design_%s%s", sanitizedCharTags, extension))
+	relativePath := path.Join(asset.CharacterDesignDir, fmt.Sprintf("design_%s%s%s", sanitizedCharTags, nameSuffix, extension))
 	finalPath, err := asset.ResolveOutputPath(outputDir, relativePath)
 	if err != nil {
 		return "", fmt.Errorf("画像保存パスの生成に失敗しました (baseDir: %s, relativePath: %s): %w", outputDir, relativePath, err)
 	}
 
-	if err = dr.writer.Write(ctx, finalPath, bytes.NewReader(resp.Data),
-		remoteio.WithContentType(resp.MimeType),
+	if err = telemetry.Write(ctx, dr.writer, finalPath, resp.MimeType, bytes.NewReader(resp.Data),
 		remoteio.WithCacheControl(defaultCacheControl),
 	); err != nil {
 		return "", fmt.Errorf("画像の保存に失敗しました (path: %s): %w", finalPath, err)
@@ -219,7 +325,8 @@ func (dr *MangaDesignRunner) buildDesignPrompt(descriptions []string, layoutKind
 }
 
 // collectCharacterURIs はキャラクター情報を収集し、ImageURIスライスと説明文を返します。
-// override は ids が単一（合成デザインシートでない）場合のみ適用されます。
+// override の ReferenceURL/VisualCues は ids が単一（合成デザインシートでない）場合のみ、
+// override.Characters は各キャラクターに個別に適用されます。
 func (dr *MangaDesignRunner) collectCharacterURIs(ids []string, override DesignOverride) ([]imagePorts.ImageURI, []string, error) {
 	var uris []imagePorts.ImageURI
 	var descriptions []string
@@ -253,6 +360,15 @@ func (dr *MangaDesignRunner) collectCharacterURIs(ids []string, override DesignO
 		if applyOverride && len(override.VisualCues) > 0 {
 			visualCues = override.VisualCues
 		}
+		if co, ok := override.Characters[id]; ok {
+			if strings.TrimSpace(co.ReferenceURL) != "" {
+				referenceURL = co.ReferenceURL
+				fileURI = ""
+			}
+			if len(co.VisualCues) > 0 {
+				visualCues = co.VisualCues
+			}
+		}
 
 		if referenceURL == "" && fileURI == "" {
 			slog.Warn("キャラクターに有効な参照画像がないためスキップします", "id", id)
@@ -272,16 +388,90 @@ func (dr *MangaDesignRunner) collectCharacterURIs(ids []string, override DesignO
 	}
 
 	if len(missingIDs) > 0 {
-		return nil, nil, fmt.Errorf("一部のキャラクターIDが見つかりませんでした: %s", strings.Join(missingIDs, ", "))
+		return nil, nil, &ports.CharacterNotFoundError{CharacterIDs: missingIDs}
 	}
 
 	if len(uris) == 0 {
-		return nil, nil, fmt.Errorf("有効な参照画像を持つキャラクターが1つも見つかりませんでした (対象ID: %s)", strings.Join(ids, ", "))
+		return nil, nil, fmt.Errorf("%w: 有効な参照画像を持つキャラクターが1つも見つかりませんでした (対象ID: %s)",
+			ports.ErrAssetPreparation, strings.Join(ids, ", "))
 	}
 
 	return uris, descriptions, nil
 }
 
+// validateOverride は override.Characters のキーがすべて charIDs に含まれることを検証します。
+func validateOverride(charIDs []string, override DesignOverride) error {
+	if len(override.Characters) == 0 {
+		return nil
+	}
+	requested := make(map[string]struct{}, len(charIDs))
+	for _, id := range charIDs {
+		requested[id] = struct{}{}
+	}
+	var unknown []string
+	for id := range override.Characters {
+		if _, ok := requested[id]; !ok {
+			unknown = append(unknown, id)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("%w: 対象外のキャラクターIDが指定されています: %s (対象ID: %s)",
+			ports.ErrInvalidDesignOverride, strings.Join(unknown, ", "), strings.Join(charIDs, ", "))
+	}
+	return nil
+}
+
+// overrideSeed は override.Characters のうち charIDs で最初に現れる Seed の指定を返します。
+// 指定が無い場合は 0 を返します。
+func overrideSeed(charIDs []string, override DesignOverride) int64 {
+	for _, id := range charIDs {
+		if co, ok := override.Characters[id]; ok && co.Seed != nil {
+			return *co.Seed
+		}
+	}
+	return 0
+}
+
+// collectCharacterDescriptions は参照画像を使わない生成のために、名前と visual_cues から
+// キャラクターの説明文を組み立てます。
+func (dr *MangaDesignRunner) collectCharacterDescriptions(ids []string) ([]string, error) {
+	var descriptions []string
+	var missingIDs []string
+	processedIDs := make(map[string]struct{})
+
+	for _, id := range ids {
+		if _, exists := processedIDs[id]; exists {
+			continue
+		}
+		processedIDs[id] = struct{}{}
+
+		char := dr.composer.CharactersMap.GetCharacter(id)
+		if char == nil {
+			missingIDs = append(missingIDs, id)
+			continue
+		}
+		desc := char.Name
+		if len(char.VisualCues) > 0 {
+			desc = fmt.Sprintf("%s (%s)", char.Name, strings.Join(char.VisualCues, ", "))
+		}
+		if strings.TrimSpace(desc) == "" {
+			slog.Warn("キャラクターに名前も visual_cues もないためスキップします", "id", id)
+			continue
+		}
+		descriptions = append(descriptions, desc)
+	}
+
+	if len(missingIDs) > 0 {
+		return nil, &ports.CharacterNotFoundError{CharacterIDs: missingIDs}
+	}
+	if len(descriptions) == 0 {
+		return nil, fmt.Errorf("%w: 名前または visual_cues を持つキャラクターが1つも見つかりませんでした (対象ID: %s)",
+			ports.ErrAssetPreparation, strings.Join(ids, ", "))
+	}
+	return descriptions, nil
+}
+
 func ptrInt64(v int64) *int64 {
 	if v == 0 {
 		return nil
@@ -0,0 +1,272 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
+
+	"github.com/shouni/go-manga-kit/internal/telemetry"
+	"github.com/shouni/go-manga-kit/layout"
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// defaultDesignRateInterval は、一括生成時のリクエスト間のデフォルトの待機間隔です（layout と同じ値です）。
+const defaultDesignRateInterval = 60 * time.Second
+
+// DesignBatchRequest は RunBatch の入力です。
+type DesignBatchRequest struct {
+	// Characters は更新対象のキャラクター定義（characters.json の内容）です。
+	// 各キャラクターは MangaComposer の CharactersMap にも登録されている必要があります。
+	Characters []ports.Character
+	// CharacterIDs は生成対象のキャラクターIDです。空の場合は Characters のすべてが対象です。
+	CharacterIDs []string
+	// AspectRatios は生成するデザインシートのアスペクト比です。空の場合はサポート対象の
+	// すべて（"1:1"/"9:16"/"16:9"）です。
+	AspectRatios []string
+	// LayoutKind は Run の layoutKind と同じです。
+	LayoutKind string
+	// OutputDir はデザインシートの保存先ディレクトリです。
+	OutputDir string
+	// DocumentPath を設定すると、更新後のキャラクター定義を JSON で保存します。
+	DocumentPath string
+}
+
+// DesignSheet は RunBatch で生成した1枚のデザインシートです。
+type DesignSheet struct {
+	CharacterID string
+	AspectRatio string
+	Path        string
+	Seed        int64
+}
+
+// DesignBatchResult は RunBatch の結果です。
+type DesignBatchResult struct {
+	// Characters は Seed・ReferenceURL・ReferenceURLs を書き戻したキャラクター定義で、
+	// 入力の Characters と同じ順序です。生成に失敗したキャラクターは元の値のままです。
+	Characters []ports.Character
+	// Sheets は生成に成功したデザインシートです。
+	Sheets []DesignSheet
+	// DocumentPath は更新後のキャラクター定義の保存先です（保存しなかった場合は空）。
+	DocumentPath string
+}
+
+// Document は Characters を ports.Characters に読み込める JSON 文書として返します。
+func (r *DesignBatchResult) Document() ([]byte, error) {
+	data, err := json.MarshalIndent(r.Characters, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("キャラクター定義のエンコードに失敗しました: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// RunBatch は、対象キャラクターごとに各アスペクト比のデザインシートを生成し、結果をキャラクター定義に
+// 書き戻します。リクエストは共有のレートリミッターで間隔を空け、キャラクター単位で並列に実行します。
+//
+// 同じキャラクターのシートはすべて同じ Seed で生成します。キャラクターに Seed が無い場合は最初の
+// シートで使われた Seed を採用します。ReferenceURLs にはアスペクト比ごとのシートを、ReferenceURL には
+// layout.DesignAspectRatio のシート（生成しない場合は最初のシート）を設定します。
+//
+// 一部のキャラクターが失敗しても残りの生成は続け、成功した分を書き戻した結果と、失敗をまとめた
+// エラーを返します。
+func (dr *MangaDesignRunner) RunBatch(ctx context.Context, req DesignBatchRequest) (*DesignBatchResult, error) {
+	targets, err := batchTargets(req)
+	if err != nil {
+		return nil, err
+	}
+	ratios, err := batchAspectRatios(req.AspectRatios)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DesignBatchResult{Characters: make([]ports.Character, len(req.Characters))}
+	for i, char := range req.Characters {
+		result.Characters[i] = cloneCharacter(char)
+	}
+
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(dr.maxConcurrency)
+	for _, idx := range targets {
+		char := &result.Characters[idx]
+		eg.Go(func() error {
+			sheets, err := dr.designCharacter(egCtx, *char, ratios, req)
+			mu.Lock()
+			defer mu.Unlock()
+			result.Sheets = append(result.Sheets, sheets...)
+			if len(sheets) > 0 {
+				applySheets(char, sheets)
+			}
+			if err != nil {
+				errs = append(errs, err)
+			}
+			// キャンセル以外の失敗では他のキャラクターの生成を止めません。
+			return ctx.Err()
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	if req.DocumentPath != "" {
+		doc, err := result.Document()
+		if err != nil {
+			return nil, err
+		}
+		if err := telemetry.Write(ctx, dr.writer, req.DocumentPath, "application/json", bytes.NewReader(doc)); err != nil {
+			return nil, fmt.Errorf("キャラクター定義の保存に失敗しました (path: %s): %w", req.DocumentPath, err)
+		}
+		result.DocumentPath = req.DocumentPath
+	}
+
+	slog.InfoContext(ctx, "Design batch completed",
+		"characters", len(targets),
+		"sheets", len(result.Sheets),
+		"failures", len(errs),
+	)
+	return result, errors.Join(errs...)
+}
+
+// designCharacter は1キャラクター分のデザインシートを ratios の順に生成します。
+// 失敗した時点で打ち切り、それまでに生成したシートとエラーを返します。
+func (dr *MangaDesignRunner) designCharacter(ctx context.Context, char ports.Character, ratios []string, req DesignBatchRequest) ([]DesignSheet, error) {
+	var seed int64
+	if char.Seed != nil {
+		seed = *char.Seed
+	}
+
+	var sheets []DesignSheet
+	for _, ratio := range ratios {
+		if err := dr.wait(ctx); err != nil {
+			return sheets, err
+		}
+		suffix := "_" + strings.ReplaceAll(ratio, ":", "x")
+		outputPath, resp, err := dr.generate(ctx, designJob{
+			charIDs:     []string{char.ID},
+			seed:        seed,
+			outputDir:   req.OutputDir,
+			aspectRatio: ratio,
+			layoutKind:  req.LayoutKind,
+			nameSuffix:  suffix,
+		})
+		if err != nil {
+			return sheets, fmt.Errorf("キャラクター %s の %s のデザインシート生成に失敗しました: %w", char.ID, ratio, err)
+		}
+		if seed == 0 {
+			seed = resp.UsedSeed
+		}
+		sheets = append(sheets, DesignSheet{CharacterID: char.ID, AspectRatio: ratio, Path: outputPath, Seed: resp.UsedSeed})
+	}
+	return sheets, nil
+}
+
+// wait は共有のレートリミッターで待機し、待機時間をメトリクスに記録します。
+func (dr *MangaDesignRunner) wait(ctx context.Context) error {
+	start := time.Now()
+	err := dr.limiter.Wait(ctx)
+	dr.metrics.ObserveRateLimitWait(ports.StageDesign, time.Since(start))
+	return err
+}
+
+// applySheets は生成したシートを char の Seed・ReferenceURL・ReferenceURLs に書き戻します。
+func applySheets(char *ports.Character, sheets []DesignSheet) {
+	if char.ReferenceURLs == nil {
+		char.ReferenceURLs = make(map[string]string)
+	}
+	primary := sheets[0]
+	for _, sheet := range sheets {
+		char.ReferenceURLs[sheet.AspectRatio] = sheet.Path
+		if sheet.AspectRatio == layout.DesignAspectRatio {
+			primary = sheet
+		}
+	}
+	char.ReferenceURL = primary.Path
+	if primary.Seed != 0 {
+		seed := primary.Seed
+		char.Seed = &seed
+	}
+}
+
+// batchTargets は生成対象のキャラクターの req.Characters 上のインデックスを返します。
+func batchTargets(req DesignBatchRequest) ([]int, error) {
+	if len(req.Characters) == 0 {
+		return nil, errors.New("一括生成の対象となるキャラクター定義がありません")
+	}
+	byID := make(map[string]int, len(req.Characters))
+	for i, char := range req.Characters {
+		byID[char.ID] = i
+	}
+	if len(req.CharacterIDs) == 0 {
+		targets := make([]int, len(req.Characters))
+		for i := range targets {
+			targets[i] = i
+		}
+		return targets, nil
+	}
+
+	var targets []int
+	var missing []string
+	seen := make(map[string]struct{})
+	for _, id := range req.CharacterIDs {
+		if _, dup := seen[id]; dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		idx, ok := byID[id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+		targets = append(targets, idx)
+	}
+	if len(missing) > 0 {
+		return nil, &ports.CharacterNotFoundError{CharacterIDs: missing}
+	}
+	return targets, nil
+}
+
+// batchAspectRatios は生成するアスペクト比を検証して返します。
+func batchAspectRatios(ratios []string) ([]string, error) {
+	if len(ratios) == 0 {
+		return layout.DesignAspectRatios(), nil
+	}
+	for _, ratio := range ratios {
+		if !layout.IsDesignAspectRatio(ratio) {
+			return nil, fmt.Errorf("サポート対象外のアスペクト比です: %q", ratio)
+		}
+	}
+	return ratios, nil
+}
+
+// cloneCharacter は、書き戻しで呼び出し元の定義を変更しないよう char を複製します。
+func cloneCharacter(char ports.Character) ports.Character {
+	if char.Seed != nil {
+		seed := *char.Seed
+		char.Seed = &seed
+	}
+	if char.ReferenceURLs != nil {
+		urls := make(map[string]string, len(char.ReferenceURLs))
+		for k, v := range char.ReferenceURLs {
+			urls[k] = v
+		}
+		char.ReferenceURLs = urls
+	}
+	char.VisualCues = append([]string(nil), char.VisualCues...)
+	return char
+}
+
+// newDesignLimiter は interval 間隔のレートリミッターを作成します。
+func newDesignLimiter(interval time.Duration) *rate.Limiter {
+	return rate.NewLimiter(rate.Every(interval), 1)
+}
@@ -0,0 +1,178 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	imagePorts "github.com/shouni/gemini-image-kit/ports"
+	characterkit "github.com/shouni/go-character-kit/character"
+	"golang.org/x/time/rate"
+
+	"github.com/shouni/go-manga-kit/layout"
+	"github.com/shouni/go-manga-kit/mangakittest"
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// batchDesignGenerator はリクエストを記録し、failPrompt を含むプロンプトの生成を失敗させます。
+type batchDesignGenerator struct {
+	mu         sync.Mutex
+	reqs       []imagePorts.ImageFusionRequest
+	failPrompt string
+	data       []byte
+}
+
+func (g *batchDesignGenerator) GenerateFusedImage(_ context.Context, req imagePorts.ImageFusionRequest) (*imagePorts.ImageResponse, error) {
+	g.mu.Lock()
+	g.reqs = append(g.reqs, req)
+	g.mu.Unlock()
+	if g.failPrompt != "" && strings.Contains(req.Prompt, g.failPrompt) {
+		return nil, errors.New("generation failed")
+	}
+	seed := int64(777)
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+	data := g.data
+	if data == nil {
+		data = []byte("png")
+	}
+	return &imagePorts.ImageResponse{Data: data, MimeType: "image/png", UsedSeed: seed}, nil
+}
+
+func newBatchTestRunner(t *testing.T, defs []ports.Character) (*MangaDesignRunner, *batchDesignGenerator, *mangakittest.Writer) {
+	t.Helper()
+	cm, err := characterkit.NewCharacters(defs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	composer, err := layout.NewMangaComposer(&mangakittest.AssetManager{}, &mangakittest.Backend{VertexAI: true}, cm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen := &batchDesignGenerator{}
+	writer := &mangakittest.Writer{}
+	dr := NewMangaDesignRunner(composer, gen, writer, "model", "",
+		WithDesignRateLimiter(rate.NewLimiter(rate.Inf, 1)),
+	)
+	return dr, gen, writer
+}
+
+func batchTestCharacters() []ports.Character {
+	seed := int64(42)
+	return []ports.Character{
+		{ID: "tsumugi", Name: "Tsumugi", ReferenceURL: "gs://bucket/tsumugi.png", VisualCues: []string{"orange hair"}, IsDefault: true},
+		{ID: "zundamon", Name: "Zundamon", Seed: &seed, ReferenceURL: "gs://bucket/zunda.png", VisualCues: []string{"green hair"}},
+	}
+}
+
+func TestMangaDesignRunner_RunBatchWritesBackCharacters(t *testing.T) {
+	defs := batchTestCharacters()
+	dr, gen, writer := newBatchTestRunner(t, defs)
+
+	res, err := dr.RunBatch(context.Background(), DesignBatchRequest{
+		Characters:   defs,
+		OutputDir:    "gs://bucket/out",
+		DocumentPath: "gs://bucket/out/characters.json",
+	})
+	if err != nil {
+		t.Fatalf("RunBatch failed: %v", err)
+	}
+	if len(gen.reqs) != 6 || len(res.Sheets) != 6 {
+		t.Fatalf("requests = %d, sheets = %d, want 6 each", len(gen.reqs), len(res.Sheets))
+	}
+
+	tsumugi, zunda := res.Characters[0], res.Characters[1]
+	// Seed の無いキャラクターは最初のシートの Seed を採用し、以降のシートにも使います。
+	if tsumugi.Seed == nil || *tsumugi.Seed != 777 {
+		t.Errorf("tsumugi seed = %v, want 777", tsumugi.Seed)
+	}
+	if zunda.Seed == nil || *zunda.Seed != 42 {
+		t.Errorf("zundamon seed = %v, want 42", zunda.Seed)
+	}
+	for _, req := range gen.reqs {
+		if req.Seed == nil {
+			continue
+		}
+		if strings.Contains(req.Prompt, "Tsumugi") && *req.Seed != 777 {
+			t.Errorf("tsumugi request seed = %d, want 777", *req.Seed)
+		}
+	}
+
+	wantURLs := map[string]string{
+		"1:1":  "gs://bucket/out/character/design_zundamon_1x1.png",
+		"9:16": "gs://bucket/out/character/design_zundamon_9x16.png",
+		"16:9": "gs://bucket/out/character/design_zundamon_16x9.png",
+	}
+	for ratio, want := range wantURLs {
+		if got := zunda.ReferenceURLs[ratio]; got != want {
+			t.Errorf("ReferenceURLs[%s] = %q, want %q", ratio, got, want)
+		}
+	}
+	if zunda.ReferenceURL != wantURLs[layout.DesignAspectRatio] {
+		t.Errorf("ReferenceURL = %q, want the %s sheet", zunda.ReferenceURL, layout.DesignAspectRatio)
+	}
+	if defs[1].ReferenceURL != "gs://bucket/zunda.png" || defs[0].Seed != nil {
+		t.Error("RunBatch must not modify the input definitions")
+	}
+
+	data, ok := writer.File("gs://bucket/out/characters.json")
+	if !ok || res.DocumentPath != "gs://bucket/out/characters.json" {
+		t.Fatal("character definition document was not written")
+	}
+	var loaded []ports.Character
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := characterkit.NewCharacters(loaded); err != nil {
+		t.Fatalf("document is not loadable: %v", err)
+	}
+	if loaded[1].ReferenceURLs["9:16"] != wantURLs["9:16"] {
+		t.Errorf("document ReferenceURLs = %v", loaded[1].ReferenceURLs)
+	}
+}
+
+func TestMangaDesignRunner_RunBatchSubsetAndPartialFailure(t *testing.T) {
+	defs := batchTestCharacters()
+	dr, gen, _ := newBatchTestRunner(t, defs)
+	gen.failPrompt = "Zundamon"
+
+	res, err := dr.RunBatch(context.Background(), DesignBatchRequest{
+		Characters:   defs,
+		CharacterIDs: []string{"zundamon", "tsumugi"},
+		AspectRatios: []string{"1:1"},
+		OutputDir:    "gs://bucket/out",
+	})
+	if err == nil || !strings.Contains(err.Error(), "zundamon") {
+		t.Fatalf("err = %v, want the zundamon failure", err)
+	}
+	if res == nil || len(res.Sheets) != 1 || res.Sheets[0].CharacterID != "tsumugi" {
+		t.Fatalf("result = %+v, want tsumugi's sheet despite the failure", res)
+	}
+	if res.Characters[1].ReferenceURL != "gs://bucket/zunda.png" {
+		t.Errorf("failed character should keep its definition, got %q", res.Characters[1].ReferenceURL)
+	}
+	if got := res.Characters[0].ReferenceURL; got != "gs://bucket/out/character/design_tsumugi_1x1.png" {
+		t.Errorf("tsumugi ReferenceURL = %q", got)
+	}
+}
+
+func TestMangaDesignRunner_RunBatchValidatesRequest(t *testing.T) {
+	defs := batchTestCharacters()
+	dr, _, _ := newBatchTestRunner(t, defs)
+
+	_, err := dr.RunBatch(context.Background(), DesignBatchRequest{Characters: defs, CharacterIDs: []string{"unknown"}})
+	var notFound *ports.CharacterNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("err = %v, want CharacterNotFoundError", err)
+	}
+	if _, err := dr.RunBatch(context.Background(), DesignBatchRequest{Characters: defs, AspectRatios: []string{"4:3"}}); err == nil {
+		t.Error("unsupported aspect ratio should be rejected")
+	}
+	if _, err := dr.RunBatch(context.Background(), DesignBatchRequest{}); err == nil {
+		t.Error("empty character definitions should be rejected")
+	}
+}
@@ -0,0 +1,111 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+)
+
+// defaultBootstrapCandidates は Bootstrap で生成する候補の既定の数です。
+const defaultBootstrapCandidates = 4
+
+// DesignBootstrapRequest は Bootstrap の入力です。
+type DesignBootstrapRequest struct {
+	// CharacterIDs は対象のキャラクターIDです。複数指定すると合成デザインシートになります。
+	CharacterIDs []string
+	// Seeds は候補ごとに使う Seed 値です。空の場合は Count 個の Seed をランダムに選びます。
+	Seeds []int64
+	// Count は Seeds が空の場合に生成する候補の数です。0 以下の場合は 4 です。
+	Count int
+	// OutputDir・AspectRatio・LayoutKind は Run と同じです。
+	OutputDir   string
+	AspectRatio string
+	LayoutKind  string
+}
+
+// DesignCandidate は Seed を変えて生成したデザインシートの候補です。
+type DesignCandidate struct {
+	// Seed はリクエストに指定した Seed 値です。
+	Seed int64
+	// UsedSeed は生成に実際に使われた Seed 値です。characters.json の Seed にはこの値を設定します。
+	UsedSeed int64
+	// Path は候補の保存先です。ファイル名に Seed 値を含みます。
+	Path string
+}
+
+// Bootstrap は、参照画像を持たない新規キャラクターの最初のデザインシートを、名前と visual_cues だけから
+// 生成します。参照画像は送りません。Seed を変えて複数の候補を生成し、すべてを UsedSeed 付きで返すため、
+// その中から正式な見た目を選び、画像を ReferenceURL に、UsedSeed を Seed に登録してください。
+//
+// 候補はレートリミッターで間隔を空けて順に生成します。一部の候補が失敗しても残りの生成は続け、
+// 成功した候補と、失敗をまとめたエラーを返します。
+func (dr *MangaDesignRunner) Bootstrap(ctx context.Context, req DesignBootstrapRequest) ([]DesignCandidate, error) {
+	if len(req.CharacterIDs) == 0 {
+		return nil, errors.New("対象のキャラクターIDが指定されていません")
+	}
+	seeds := req.Seeds
+	if len(seeds) == 0 {
+		count := req.Count
+		if count <= 0 {
+			count = defaultBootstrapCandidates
+		}
+		seeds = randomSeeds(count)
+	}
+
+	candidates, _, err := dr.generateCandidates(ctx, designJob{
+		charIDs:     req.CharacterIDs,
+		outputDir:   req.OutputDir,
+		aspectRatio: req.AspectRatio,
+		layoutKind:  req.LayoutKind,
+		textOnly:    true,
+	}, seeds)
+	return candidates, err
+}
+
+// generateCandidates は job を seeds の各 Seed で生成し、ファイル名に Seed を含めて保存します。
+// 結果は seeds の順で、失敗した候補は含みません。images は各候補の画像データです。
+func (dr *MangaDesignRunner) generateCandidates(ctx context.Context, job designJob, seeds []int64) (candidates []DesignCandidate, images [][]byte, err error) {
+	var errs []error
+	for _, seed := range seeds {
+		if err := dr.wait(ctx); err != nil {
+			return candidates, images, err
+		}
+		job.seed = seed
+		job.nameSuffix = fmt.Sprintf("_seed%d", seed)
+		outputPath, resp, err := dr.generate(ctx, job)
+		if err != nil {
+			if ctx.Err() != nil {
+				return candidates, images, ctx.Err()
+			}
+			errs = append(errs, fmt.Errorf("seed %d の候補の生成に失敗しました: %w", seed, err))
+			continue
+		}
+		candidates = append(candidates, DesignCandidate{Seed: seed, UsedSeed: resp.UsedSeed, Path: outputPath})
+		images = append(images, resp.Data)
+	}
+
+	slog.InfoContext(ctx, "Design candidates generated",
+		"chars", job.charIDs,
+		"requested", len(seeds),
+		"generated", len(candidates),
+	)
+	return candidates, images, errors.Join(errs...)
+}
+
+// randomSeeds は重複しない n 個の正の Seed 値をランダムに選びます。
+func randomSeeds(n int) []int64 {
+	seeds := make([]int64, 0, n)
+	seen := make(map[int64]struct{}, n)
+	for len(seeds) < n {
+		seed := rand.Int64N(math.MaxInt32) + 1
+		if _, dup := seen[seed]; dup {
+			continue
+		}
+		seen[seed] = struct{}{}
+		seeds = append(seeds, seed)
+	}
+	return seeds
+}
@@ -0,0 +1,95 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+func TestMangaDesignRunner_BootstrapWithoutReferenceImage(t *testing.T) {
+	dr, gen, _ := newBatchTestRunner(t, []ports.Character{
+		{ID: "newcomer", Name: "Newcomer", VisualCues: []string{"silver hair", "red scarf"}, IsDefault: true},
+	})
+
+	// 参照画像が無いため、通常の Run は失敗します。
+	if _, _, err := dr.Run(context.Background(), []string{"newcomer"}, 1, "gs://bucket/out", "", "", DesignOverride{}); !errors.Is(err, ports.ErrAssetPreparation) {
+		t.Fatalf("Run err = %v, want ErrAssetPreparation", err)
+	}
+	gen.reqs = nil
+
+	candidates, err := dr.Bootstrap(context.Background(), DesignBootstrapRequest{
+		CharacterIDs: []string{"newcomer"},
+		Seeds:        []int64{11, 22},
+		OutputDir:    "gs://bucket/out",
+	})
+	if err != nil {
+		t.Fatalf("Bootstrap failed: %v", err)
+	}
+	if len(gen.reqs) != 2 {
+		t.Fatalf("requests = %d, want 2", len(gen.reqs))
+	}
+	for _, req := range gen.reqs {
+		if len(req.Images) != 0 {
+			t.Errorf("Images = %+v, want no reference images", req.Images)
+		}
+		if !containsAll(req.Prompt, "Newcomer", "silver hair", "red scarf") {
+			t.Errorf("Prompt = %q, want name and visual cues", req.Prompt)
+		}
+	}
+
+	want := []DesignCandidate{
+		{Seed: 11, UsedSeed: 11, Path: "gs://bucket/out/character/design_newcomer_seed11.png"},
+		{Seed: 22, UsedSeed: 22, Path: "gs://bucket/out/character/design_newcomer_seed22.png"},
+	}
+	if len(candidates) != len(want) {
+		t.Fatalf("candidates = %+v, want %+v", candidates, want)
+	}
+	for i := range want {
+		if candidates[i] != want[i] {
+			t.Errorf("candidate %d = %+v, want %+v", i, candidates[i], want[i])
+		}
+	}
+}
+
+func TestMangaDesignRunner_BootstrapRandomSeeds(t *testing.T) {
+	dr, gen, _ := newBatchTestRunner(t, []ports.Character{{ID: "newcomer", Name: "Newcomer", IsDefault: true}})
+
+	candidates, err := dr.Bootstrap(context.Background(), DesignBootstrapRequest{CharacterIDs: []string{"newcomer"}})
+	if err != nil {
+		t.Fatalf("Bootstrap failed: %v", err)
+	}
+	if len(candidates) != defaultBootstrapCandidates {
+		t.Fatalf("candidates = %d, want %d", len(candidates), defaultBootstrapCandidates)
+	}
+	seen := map[int64]bool{}
+	for i, c := range candidates {
+		if c.Seed <= 0 || seen[c.Seed] {
+			t.Errorf("candidate %d has invalid or duplicate seed %d", i, c.Seed)
+		}
+		seen[c.Seed] = true
+		if gen.reqs[i].Seed == nil || *gen.reqs[i].Seed != c.Seed {
+			t.Errorf("request %d seed = %v, want %d", i, gen.reqs[i].Seed, c.Seed)
+		}
+	}
+}
+
+func TestMangaDesignRunner_BootstrapUnknownCharacter(t *testing.T) {
+	dr, _, _ := newBatchTestRunner(t, []ports.Character{{ID: "newcomer", Name: "Newcomer"}})
+	_, err := dr.Bootstrap(context.Background(), DesignBootstrapRequest{CharacterIDs: []string{"ghost"}, Seeds: []int64{1}})
+	var notFound *ports.CharacterNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("err = %v, want CharacterNotFoundError", err)
+	}
+}
+
+func containsAll(s string, subs ...string) bool {
+	for _, sub := range subs {
+		if !strings.Contains(s, sub) {
+			return false
+		}
+	}
+	return true
+}
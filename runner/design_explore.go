@@ -0,0 +1,176 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/shouni/go-remote-io/remoteio"
+
+	"github.com/shouni/go-manga-kit/asset"
+	"github.com/shouni/go-manga-kit/imaging"
+	"github.com/shouni/go-manga-kit/internal/telemetry"
+	"github.com/shouni/go-manga-kit/layout"
+)
+
+const (
+	// defaultExploreVariants は Explore で生成するバリエーションの既定の数です（3×3 の一覧になります）。
+	defaultExploreVariants = 9
+	// defaultContactSheetCell はコンタクトシートの1区画の一辺の既定のピクセル数です。
+	defaultContactSheetCell = 512
+)
+
+// DesignExploreRequest は Explore の入力です。
+type DesignExploreRequest struct {
+	// CharacterIDs は対象のキャラクターIDです。複数指定すると合成デザインシートになります。
+	CharacterIDs []string
+	// Seeds は各バリエーションの Seed 値です。空の場合は Count 個の Seed をランダムに選びます。
+	Seeds []int64
+	// Count は Seeds が空の場合に生成するバリエーションの数です。0 以下の場合は 9 です。
+	Count int
+	// OutputDir・AspectRatio・LayoutKind・Override は Run と同じです。
+	OutputDir   string
+	AspectRatio string
+	LayoutKind  string
+	Override    DesignOverride
+	// ThumbnailSize はコンタクトシートの1区画の一辺のピクセル数です。0 以下の場合は 512 です。
+	ThumbnailSize int
+}
+
+// DesignExploration は Explore の結果で、マニフェストとして JSON で保存されます。
+type DesignExploration struct {
+	CharacterIDs []string `json:"character_ids"`
+	AspectRatio  string   `json:"aspect_ratio"`
+	// Columns はコンタクトシートの列数です。
+	Columns int `json:"columns"`
+	// ContactSheetPath は全バリエーションを並べたラベル付きの一覧画像の保存先です。
+	ContactSheetPath string `json:"contact_sheet"`
+	// ManifestPath はこのマニフェストの保存先です。
+	ManifestPath string `json:"-"`
+	// Variants はコンタクトシート上の位置順のバリエーションです。
+	Variants []ExploredVariant `json:"variants"`
+}
+
+// ExploredVariant はコンタクトシート上の1つのサムネイルと、その Seed の対応です。
+type ExploredVariant struct {
+	// Position はコンタクトシート上の番号（1 始まり）で、サムネイルのラベル "#n" と一致します。
+	Position int `json:"position"`
+	// Row・Column はコンタクトシート上の行・列（1 始まり）です。
+	Row      int    `json:"row"`
+	Column   int    `json:"column"`
+	Seed     int64  `json:"seed"`
+	UsedSeed int64  `json:"used_seed"`
+	Path     string `json:"path"`
+}
+
+// Explore は、同じデザインを Seed だけ変えて複数生成し、各バリエーションを Seed 入りのファイル名で
+// 保存します。さらに全バリエーションを "#番号 SEED 値" のラベル付きで並べたコンタクトシートと、
+// 位置と Seed の対応を記したマニフェスト（JSON）を保存し、1回の確認で Seed を選べるようにします。
+//
+// バリエーションはレートリミッターで間隔を空けて順に生成します。一部が失敗しても残りの生成は続け、
+// 成功した分の結果と、失敗をまとめたエラーを返します。すべて失敗した場合は結果を返しません。
+func (dr *MangaDesignRunner) Explore(ctx context.Context, req DesignExploreRequest) (*DesignExploration, error) {
+	if len(req.CharacterIDs) == 0 {
+		return nil, errors.New("対象のキャラクターIDが指定されていません")
+	}
+	if err := validateOverride(req.CharacterIDs, req.Override); err != nil {
+		return nil, err
+	}
+	seeds := req.Seeds
+	if len(seeds) == 0 {
+		count := req.Count
+		if count <= 0 {
+			count = defaultExploreVariants
+		}
+		seeds = randomSeeds(count)
+	}
+
+	candidates, images, genErr := dr.generateCandidates(ctx, designJob{
+		charIDs:     req.CharacterIDs,
+		outputDir:   req.OutputDir,
+		aspectRatio: req.AspectRatio,
+		layoutKind:  req.LayoutKind,
+		override:    req.Override,
+	}, seeds)
+	if len(candidates) == 0 {
+		if genErr == nil {
+			genErr = errors.New("バリエーションが1つも生成されませんでした")
+		}
+		return nil, genErr
+	}
+
+	exploration := &DesignExploration{
+		CharacterIDs: req.CharacterIDs,
+		AspectRatio:  layout.NormalizeDesignAspectRatio(req.AspectRatio),
+		Columns:      imaging.CollageColumns(len(candidates)),
+	}
+	tiles := make([]imaging.Tile, len(candidates))
+	for i, c := range candidates {
+		exploration.Variants = append(exploration.Variants, ExploredVariant{
+			Position: i + 1,
+			Row:      i/exploration.Columns + 1,
+			Column:   i%exploration.Columns + 1,
+			Seed:     c.Seed,
+			UsedSeed: c.UsedSeed,
+			Path:     c.Path,
+		})
+		tiles[i] = imaging.Tile{Label: fmt.Sprintf("#%d seed %d", i+1, c.UsedSeed)}
+		img, _, err := image.Decode(bytes.NewReader(images[i]))
+		if err != nil {
+			slog.WarnContext(ctx, "バリエーションをデコードできないため、コンタクトシートにはラベルのみ配置します",
+				"path", c.Path, "error", err)
+			continue
+		}
+		tiles[i].Image = img
+	}
+
+	cell := req.ThumbnailSize
+	if cell <= 0 {
+		cell = defaultContactSheetCell
+	}
+	sheet, err := imaging.Collage(tiles, cell)
+	if err != nil {
+		return nil, fmt.Errorf("コンタクトシートの作成に失敗しました: %w", err)
+	}
+
+	base := path.Join(asset.CharacterDesignDir,
+		fmt.Sprintf("design_%s_explore", fileNameSanitizer.Replace(strings.Join(req.CharacterIDs, "_"))))
+	if exploration.ContactSheetPath, err = dr.saveExploreFile(ctx, req.OutputDir, base+sheet.Extension(), sheet.MIMEType, sheet.Data); err != nil {
+		return nil, err
+	}
+	manifest, err := json.MarshalIndent(exploration, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("マニフェストのエンコードに失敗しました: %w", err)
+	}
+	if exploration.ManifestPath, err = dr.saveExploreFile(ctx, req.OutputDir, base+".json", "application/json", append(manifest, '\n')); err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "Design exploration completed",
+		"chars", req.CharacterIDs,
+		"variants", len(candidates),
+		"contact_sheet", exploration.ContactSheetPath,
+		"manifest", exploration.ManifestPath,
+	)
+	return exploration, genErr
+}
+
+// saveExploreFile は Explore の成果物を outputDir 配下の relativePath に保存し、保存先を返します。
+func (dr *MangaDesignRunner) saveExploreFile(ctx context.Context, outputDir, relativePath, mimeType string, data []byte) (string, error) {
+	finalPath, err := asset.ResolveOutputPath(outputDir, relativePath)
+	if err != nil {
+		return "", fmt.Errorf("保存パスの生成に失敗しました (baseDir: %s, relativePath: %s): %w", outputDir, relativePath, err)
+	}
+	if err := telemetry.Write(ctx, dr.writer, finalPath, mimeType, bytes.NewReader(data),
+		remoteio.WithCacheControl(defaultCacheControl),
+	); err != nil {
+		return "", fmt.Errorf("保存に失敗しました (path: %s): %w", finalPath, err)
+	}
+	return finalPath, nil
+}
@@ -0,0 +1,106 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+func TestMangaDesignRunner_ExploreWritesContactSheetAndManifest(t *testing.T) {
+	dr, gen, writer := newBatchTestRunner(t, batchTestCharacters())
+
+	variant := image.NewRGBA(image.Rect(0, 0, 160, 90))
+	draw.Draw(variant, variant.Bounds(), image.NewUniform(color.RGBA{R: 0xff, A: 0xff}), image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, variant); err != nil {
+		t.Fatal(err)
+	}
+	gen.data = buf.Bytes()
+	gen.failPrompt = "never"
+
+	res, err := dr.Explore(context.Background(), DesignExploreRequest{
+		CharacterIDs:  []string{"tsumugi"},
+		Seeds:         []int64{101, 202, 303, 404, 505},
+		OutputDir:     "gs://bucket/out",
+		ThumbnailSize: 128,
+	})
+	if err != nil {
+		t.Fatalf("Explore failed: %v", err)
+	}
+	if len(gen.reqs) != 5 {
+		t.Fatalf("requests = %d, want 5", len(gen.reqs))
+	}
+
+	// 5 枚は 3 列に並び、#4 は 2 行 1 列目になります。
+	if res.Columns != 3 || len(res.Variants) != 5 {
+		t.Fatalf("exploration = %+v", res)
+	}
+	want := ExploredVariant{Position: 4, Row: 2, Column: 1, Seed: 404, UsedSeed: 404,
+		Path: "gs://bucket/out/character/design_tsumugi_seed404.png"}
+	if res.Variants[3] != want {
+		t.Errorf("variant #4 = %+v, want %+v", res.Variants[3], want)
+	}
+	for _, v := range res.Variants {
+		if _, ok := writer.File(v.Path); !ok {
+			t.Errorf("variant %s was not written", v.Path)
+		}
+	}
+
+	if res.ContactSheetPath != "gs://bucket/out/character/design_tsumugi_explore.png" {
+		t.Errorf("ContactSheetPath = %q", res.ContactSheetPath)
+	}
+	data, ok := writer.File(res.ContactSheetPath)
+	if !ok {
+		t.Fatal("contact sheet was not written")
+	}
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 3*128 || cfg.Height != 2*128 {
+		t.Errorf("contact sheet = %dx%d, want 384x256", cfg.Width, cfg.Height)
+	}
+
+	manifest, ok := writer.File("gs://bucket/out/character/design_tsumugi_explore.json")
+	if !ok || res.ManifestPath != "gs://bucket/out/character/design_tsumugi_explore.json" {
+		t.Fatal("manifest was not written")
+	}
+	var loaded DesignExploration
+	if err := json.Unmarshal(manifest, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.ContactSheetPath != res.ContactSheetPath || len(loaded.Variants) != 5 || loaded.Variants[3] != want {
+		t.Errorf("manifest = %+v", loaded)
+	}
+}
+
+func TestMangaDesignRunner_ExploreAppliesOverrideAndReportsFailures(t *testing.T) {
+	dr, gen, _ := newBatchTestRunner(t, batchTestCharacters())
+	gen.failPrompt = "Tsumugi"
+
+	_, err := dr.Explore(context.Background(), DesignExploreRequest{
+		CharacterIDs: []string{"tsumugi"},
+		Seeds:        []int64{1, 2},
+		OutputDir:    "gs://bucket/out",
+	})
+	if err == nil {
+		t.Fatal("Explore should fail when every variant fails")
+	}
+
+	_, err = dr.Explore(context.Background(), DesignExploreRequest{
+		CharacterIDs: []string{"tsumugi"},
+		Seeds:        []int64{1},
+		Override:     DesignOverride{Characters: map[string]CharacterDesignOverride{"metan": {}}},
+	})
+	if err == nil || !errors.Is(err, ports.ErrInvalidDesignOverride) {
+		t.Errorf("err = %v, want ErrInvalidDesignOverride", err)
+	}
+}
@@ -1,43 +1,35 @@
 package runner
 
 import (
+	"bytes"
 	"context"
-	"io"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
 	"strings"
 	"testing"
 
 	imagePorts "github.com/shouni/gemini-image-kit/ports"
 	characterkit "github.com/shouni/go-character-kit/character"
 	"github.com/shouni/go-manga-kit/layout"
+	"github.com/shouni/go-manga-kit/mangakittest"
 	"github.com/shouni/go-manga-kit/ports"
-	"github.com/shouni/go-remote-io/remoteio"
 )
 
-type mockDesignAssetManager struct{}
-
-func (m *mockDesignAssetManager) UploadFile(_ context.Context, refURL string) (string, error) {
-	return "https://file-api.google.com/" + refURL, nil
-}
-
-func (m *mockDesignAssetManager) DeleteFile(_ context.Context, _ string) error { return nil }
-
-type mockDesignBackend struct{ isVertex bool }
-
-func (m *mockDesignBackend) IsVertexAI() bool { return m.isVertex }
-
 type mockDesignGenerator struct {
 	lastReq imagePorts.ImageFusionRequest
+	data    []byte
 }
 
 func (m *mockDesignGenerator) GenerateFusedImage(_ context.Context, req imagePorts.ImageFusionRequest) (*imagePorts.ImageResponse, error) {
 	m.lastReq = req
-	return &imagePorts.ImageResponse{Data: []byte("fake-png"), MimeType: "image/png", UsedSeed: 1}, nil
-}
-
-type mockDesignWriter struct{}
-
-func (m *mockDesignWriter) Write(_ context.Context, _ string, _ io.Reader, _ ...remoteio.WriteOption) error {
-	return nil
+	data := m.data
+	if data == nil {
+		data = []byte("fake-png")
+	}
+	return &imagePorts.ImageResponse{Data: data, MimeType: "image/png", UsedSeed: 1}, nil
 }
 
 func TestMangaDesignRunner_BuildDesignPromptLayoutKind(t *testing.T) {
@@ -86,12 +78,12 @@ func newTestDesignRunner(t *testing.T) (*MangaDesignRunner, *mockDesignGenerator
 	if err != nil {
 		t.Fatalf("NewCharacters failed: %v", err)
 	}
-	composer, err := layout.NewMangaComposer(&mockDesignAssetManager{}, &mockDesignBackend{isVertex: true}, cm)
+	composer, err := layout.NewMangaComposer(&mangakittest.AssetManager{}, &mangakittest.Backend{VertexAI: true}, cm)
 	if err != nil {
 		t.Fatalf("NewMangaComposer failed: %v", err)
 	}
 	genMock := &mockDesignGenerator{}
-	dr := NewMangaDesignRunner(composer, genMock, &mockDesignWriter{}, "gemini-2.0-flash", "")
+	dr := NewMangaDesignRunner(composer, genMock, &mangakittest.Writer{}, "gemini-2.0-flash", "")
 	return dr, genMock
 }
 
@@ -167,12 +159,12 @@ func TestMangaDesignRunner_RunIgnoresOverrideForMultipleCharacters(t *testing.T)
 	if err != nil {
 		t.Fatalf("NewCharacters failed: %v", err)
 	}
-	composer, err := layout.NewMangaComposer(&mockDesignAssetManager{}, &mockDesignBackend{isVertex: true}, cm)
+	composer, err := layout.NewMangaComposer(&mangakittest.AssetManager{}, &mangakittest.Backend{VertexAI: true}, cm)
 	if err != nil {
 		t.Fatalf("NewMangaComposer failed: %v", err)
 	}
 	genMock := &mockDesignGenerator{}
-	dr := NewMangaDesignRunner(composer, genMock, &mockDesignWriter{}, "gemini-2.0-flash", "")
+	dr := NewMangaDesignRunner(composer, genMock, &mangakittest.Writer{}, "gemini-2.0-flash", "")
 
 	override := DesignOverride{ReferenceURL: "gs://bucket/should-be-ignored.png"}
 	_, _, err = dr.Run(context.Background(), []string{"tsumugi", "metan"}, 42, "gs://bucket/out", "", "", override)
@@ -186,3 +178,128 @@ func TestMangaDesignRunner_RunIgnoresOverrideForMultipleCharacters(t *testing.T)
 		}
 	}
 }
+
+func TestMangaDesignRunner_RunWithViewsSplitsTurnaround(t *testing.T) {
+	dr, genMock := newTestDesignRunner(t)
+	writer := &mangakittest.Writer{}
+	dr.writer = writer
+
+	sheet := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	draw.Draw(sheet, sheet.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	for _, x := range []int{40, 180, 320} {
+		draw.Draw(sheet, image.Rect(x, 20, x+40, 180), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, sheet); err != nil {
+		t.Fatal(err)
+	}
+	genMock.data = buf.Bytes()
+
+	res, err := dr.RunWithViews(context.Background(), []string{"tsumugi"}, 42, "gs://bucket/out", "", DesignOverride{})
+	if err != nil {
+		t.Fatalf("RunWithViews failed: %v", err)
+	}
+	if !strings.Contains(genMock.lastReq.Prompt, "multiple views (front, side, back)") {
+		t.Errorf("Prompt = %q, want the multi-view layout", genMock.lastReq.Prompt)
+	}
+	if res.Path != "gs://bucket/out/character/design_tsumugi.png" || res.Seed != 1 {
+		t.Errorf("result = %+v", res)
+	}
+	if len(res.Views) != 3 {
+		t.Fatalf("Views = %+v, want 3", res.Views)
+	}
+	for i, name := range []string{"front", "side", "back"} {
+		view := res.Views[i]
+		if view.Name != name || view.Path != "gs://bucket/out/character/design_tsumugi_"+name+".png" {
+			t.Errorf("view %d = %+v", i, view)
+		}
+		data, ok := writer.File(view.Path)
+		if !ok {
+			t.Fatalf("view %s was not written", view.Path)
+		}
+		cfg, err := png.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Width != view.Bounds.Dx() || cfg.Height != view.Bounds.Dy() || cfg.Width >= 100 {
+			t.Errorf("view %s = %dx%d, bounds %v", name, cfg.Width, cfg.Height, view.Bounds)
+		}
+	}
+}
+
+func TestMangaDesignRunner_SplitViewsRejectsBlankSheet(t *testing.T) {
+	dr, _ := newTestDesignRunner(t)
+	blank := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(blank, blank.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, blank); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dr.SplitViews(context.Background(), buf.Bytes(), []string{"tsumugi"}, "gs://bucket/out"); err == nil {
+		t.Error("SplitViews should fail when no view is detected")
+	}
+}
+
+func newCompositeDesignRunner(t *testing.T) (*MangaDesignRunner, *mockDesignGenerator) {
+	t.Helper()
+	cm, err := characterkit.NewCharacters([]ports.Character{
+		{ID: "tsumugi", Name: "Tsumugi", ReferenceURL: "gs://bucket/tsumugi.png", VisualCues: []string{"orange hair"}, IsDefault: true},
+		{ID: "metan", Name: "Metan", ReferenceURL: "gs://bucket/metan.png", VisualCues: []string{"purple hair"}},
+	})
+	if err != nil {
+		t.Fatalf("NewCharacters failed: %v", err)
+	}
+	composer, err := layout.NewMangaComposer(&mangakittest.AssetManager{}, &mangakittest.Backend{VertexAI: true}, cm)
+	if err != nil {
+		t.Fatalf("NewMangaComposer failed: %v", err)
+	}
+	genMock := &mockDesignGenerator{}
+	return NewMangaDesignRunner(composer, genMock, &mangakittest.Writer{}, "gemini-2.0-flash", ""), genMock
+}
+
+func TestMangaDesignRunner_RunAppliesPerCharacterOverrides(t *testing.T) {
+	dr, genMock := newCompositeDesignRunner(t)
+
+	seed := int64(314)
+	override := DesignOverride{Characters: map[string]CharacterDesignOverride{
+		"metan": {ReferenceURL: "gs://bucket/metan-winter.png", VisualCues: []string{"winter coat"}, Seed: &seed},
+	}}
+	if _, _, err := dr.Run(context.Background(), []string{"tsumugi", "metan"}, 0, "gs://bucket/out", "", "", override); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	req := genMock.lastReq
+	if len(req.Images) != 2 || req.Images[0].ReferenceURL != "gs://bucket/tsumugi.png" || req.Images[1].ReferenceURL != "gs://bucket/metan-winter.png" {
+		t.Errorf("Images = %+v, want only metan's reference overridden", req.Images)
+	}
+	if !strings.Contains(req.Prompt, "Tsumugi (orange hair)") || !strings.Contains(req.Prompt, "Metan (winter coat)") {
+		t.Errorf("Prompt = %q, want tsumugi's own cues and metan's overridden cues", req.Prompt)
+	}
+	if req.Seed == nil || *req.Seed != 314 {
+		t.Errorf("Seed = %v, want the override seed 314", req.Seed)
+	}
+
+	// seed 引数の指定は上書き指定の Seed より優先されます。
+	if _, _, err := dr.Run(context.Background(), []string{"tsumugi", "metan"}, 7, "gs://bucket/out", "", "", override); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if genMock.lastReq.Seed == nil || *genMock.lastReq.Seed != 7 {
+		t.Errorf("Seed = %v, want the explicit seed 7", genMock.lastReq.Seed)
+	}
+}
+
+func TestMangaDesignRunner_RunRejectsOverrideForUnrequestedCharacter(t *testing.T) {
+	dr, genMock := newCompositeDesignRunner(t)
+
+	override := DesignOverride{Characters: map[string]CharacterDesignOverride{
+		"metan":    {VisualCues: []string{"winter coat"}},
+		"zundamon": {VisualCues: []string{"green hair"}},
+	}}
+	_, _, err := dr.Run(context.Background(), []string{"tsumugi", "metan"}, 0, "gs://bucket/out", "", "", override)
+	if !errors.Is(err, ports.ErrInvalidDesignOverride) || !strings.Contains(err.Error(), "zundamon") {
+		t.Fatalf("err = %v, want ErrInvalidDesignOverride naming zundamon", err)
+	}
+	if genMock.lastReq.Prompt != "" {
+		t.Error("no generation request should be sent for an invalid override")
+	}
+}
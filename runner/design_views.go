@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/jpeg" // デザインシートの入力形式として登録
+	"image/png"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/shouni/go-remote-io/remoteio"
+
+	"github.com/shouni/go-manga-kit/asset"
+	"github.com/shouni/go-manga-kit/imaging"
+	"github.com/shouni/go-manga-kit/internal/telemetry"
+)
+
+// multiViewNames は 3面図（designLayoutMultiView）の各ビューの名前で、左から順に並びます。
+var multiViewNames = []string{"front", "side", "back"}
+
+// DesignView はデザインシートから切り出した1つのビューです。
+type DesignView struct {
+	// Name はビューの名前です。3つのビューを検出した場合は "front"/"side"/"back"、
+	// それ以外の場合は左から "view1"、"view2"… です。
+	Name string
+	// Path は切り出した画像の保存先です。ポーズ別の参照画像として登録できます。
+	Path string
+	// Bounds はデザインシート上のビューの範囲です。
+	Bounds image.Rectangle
+}
+
+// DesignResult は RunWithViews の結果です。
+type DesignResult struct {
+	// Path はデザインシート全体の保存先です。
+	Path string
+	// Seed は生成に使われた Seed 値です。
+	Seed int64
+	// Views はデザインシートから切り出した各ビューです。
+	Views []DesignView
+}
+
+// RunWithViews は、3面図のデザインシートを生成して保存したうえで、SplitViews で各ビューを
+// 切り出して保存します。引数は Run と同じです（レイアウトは常に3面図です）。
+func (dr *MangaDesignRunner) RunWithViews(ctx context.Context, charIDs []string, seed int64, outputDir, aspectRatio string, override DesignOverride) (*DesignResult, error) {
+	outputPath, resp, err := dr.generate(ctx, designJob{
+		charIDs:     charIDs,
+		seed:        seed,
+		outputDir:   outputDir,
+		aspectRatio: aspectRatio,
+		override:    override,
+	})
+	if err != nil {
+		return nil, err
+	}
+	views, err := dr.SplitViews(ctx, resp.Data, charIDs, outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("デザインシート %s のビューの切り出しに失敗しました: %w", outputPath, err)
+	}
+	return &DesignResult{Path: outputPath, Seed: resp.UsedSeed, Views: views}, nil
+}
+
+// SplitViews は、白い無地背景のデザインシートから各ビュー（人物）を検出して個別の PNG 画像に
+// 切り出し、outputDir の asset.CharacterDesignDir 配下に保存します。
+// デザインシートは designSystemPrompt によりフラットな照明・無地の背景で生成されるため、
+// 列方向の射影で各ビューを区切れます。ビューを1つも検出できない場合はエラーを返します。
+func (dr *MangaDesignRunner) SplitViews(ctx context.Context, sheet []byte, charIDs []string, outputDir string) ([]DesignView, error) {
+	img, _, err := image.Decode(bytes.NewReader(sheet))
+	if err != nil {
+		return nil, fmt.Errorf("デザインシートのデコードに失敗しました: %w", err)
+	}
+	bounds := imaging.DetectFigures(img, imaging.SplitOptions{})
+	if len(bounds) == 0 {
+		return nil, fmt.Errorf("デザインシートからビューを検出できませんでした")
+	}
+
+	sanitizedCharTags := fileNameSanitizer.Replace(strings.Join(charIDs, "_"))
+	views := make([]DesignView, len(bounds))
+	for i, r := range bounds {
+		name := fmt.Sprintf("view%d", i+1)
+		if len(bounds) == len(multiViewNames) {
+			name = multiViewNames[i]
+		}
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, imaging.SubImage(img, r.Add(img.Bounds().Min))); err != nil {
+			return nil, fmt.Errorf("ビュー %s のエンコードに失敗しました: %w", name, err)
+		}
+		relativePath := path.Join(asset.CharacterDesignDir, fmt.Sprintf("design_%s_%s.png", sanitizedCharTags, name))
+		finalPath, err := asset.ResolveOutputPath(outputDir, relativePath)
+		if err != nil {
+			return nil, fmt.Errorf("画像保存パスの生成に失敗しました (baseDir: %s, relativePath: %s): %w", outputDir, relativePath, err)
+		}
+		if err := telemetry.Write(ctx, dr.writer, finalPath, "image/png", &buf,
+			remoteio.WithCacheControl(defaultCacheControl),
+		); err != nil {
+			return nil, fmt.Errorf("画像の保存に失敗しました (path: %s): %w", finalPath, err)
+		}
+		views[i] = DesignView{Name: name, Path: finalPath, Bounds: r}
+	}
+
+	if len(bounds) != len(multiViewNames) {
+		slog.WarnContext(ctx, "デザインシートのビュー数が3面図と一致しません", "chars", charIDs, "views", len(bounds))
+	}
+	return views, nil
+}
@@ -0,0 +1,80 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	characterkit "github.com/shouni/go-character-kit/character"
+	"github.com/shouni/go-manga-kit/layout"
+	"github.com/shouni/go-manga-kit/mangakittest"
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+func TestMangaDesignRunner_TypedErrors(t *testing.T) {
+	cm, err := characterkit.NewCharacters([]ports.Character{
+		{ID: "tsumugi", Name: "Tsumugi", ReferenceURL: "gs://bucket/tsumugi.png", IsDefault: true},
+	})
+	if err != nil {
+		t.Fatalf("NewCharacters failed: %v", err)
+	}
+	composer, err := layout.NewMangaComposer(&mangakittest.AssetManager{}, &mangakittest.Backend{VertexAI: true}, cm)
+	if err != nil {
+		t.Fatalf("NewMangaComposer failed: %v", err)
+	}
+
+	t.Run("unknown character", func(t *testing.T) {
+		dr := NewMangaDesignRunner(composer, &mockDesignGenerator{}, &mangakittest.Writer{}, "model", "")
+		_, _, err := dr.Run(context.Background(), []string{"tsumugi", "ghost"}, 1, "out", "", "", DesignOverride{})
+
+		var notFound *ports.CharacterNotFoundError
+		if !errors.As(err, &notFound) {
+			t.Fatalf("err = %v, want *CharacterNotFoundError", err)
+		}
+		if len(notFound.CharacterIDs) != 1 || notFound.CharacterIDs[0] != "ghost" {
+			t.Errorf("CharacterIDs = %v, want [ghost]", notFound.CharacterIDs)
+		}
+	})
+
+	t.Run("blocked generation", func(t *testing.T) {
+		gen := &mangakittest.ImageGenerator{Err: errors.New("generation failed with FinishReason: IMAGE_SAFETY")}
+		dr := NewMangaDesignRunner(composer, gen, &mangakittest.Writer{}, "model", "")
+		_, _, err := dr.Run(context.Background(), []string{"tsumugi"}, 1, "out", "", "", DesignOverride{})
+
+		var genErr *ports.GenerationError
+		if !errors.As(err, &genErr) || genErr.Stage != ports.StageDesign || genErr.CharacterID != "tsumugi" {
+			t.Fatalf("err = %v, want a design *GenerationError for tsumugi", err)
+		}
+		if !errors.Is(err, ports.ErrContentBlocked) {
+			t.Errorf("err = %v, want ErrContentBlocked", err)
+		}
+	})
+}
+
+func TestMangaScriptRunner_TypedErrors(t *testing.T) {
+	reader := &mangakittest.ContentReader{}
+	reader.SetFile("https://example.com/a.txt", []byte("本文"))
+
+	t.Run("unparsable response", func(t *testing.T) {
+		sr := NewMangaScriptRunner(&mangakittest.ScriptPrompt{}, &mangakittest.ContentGenerator{Text: "not json"}, reader, "model")
+		_, err := sr.Run(context.Background(), "https://example.com/a.txt", "dialogue")
+
+		var parseErr *ports.ScriptParseError
+		if !errors.As(err, &parseErr) || parseErr.Snippet != "not json" {
+			t.Errorf("err = %v, want *ScriptParseError with the response snippet", err)
+		}
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		ai := &mangakittest.ContentGenerator{Err: &ports.RateLimitError{Err: errors.New("429")}}
+		sr := NewMangaScriptRunner(&mangakittest.ScriptPrompt{}, ai, reader, "model")
+		_, err := sr.Run(context.Background(), "https://example.com/a.txt", "dialogue")
+
+		if !errors.Is(err, ports.ErrRateLimited) || !ports.IsRetryable(err) {
+			t.Errorf("err = %v, want a retryable ErrRateLimited", err)
+		}
+		if ports.ClassifyError(err) != ports.ErrorClassRateLimited {
+			t.Errorf("ClassifyError = %s", ports.ClassifyError(err))
+		}
+	})
+}
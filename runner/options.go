@@ -0,0 +1,209 @@
+package runner
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/shouni/go-manga-kit/extract"
+	"github.com/shouni/go-manga-kit/ports"
+	"github.com/shouni/go-manga-kit/speaker"
+	"github.com/shouni/go-manga-kit/validate"
+)
+
+// --- MangaScriptRunner Options ---
+
+// ScriptOption は MangaScriptRunner の設定を適用する関数型です。
+type ScriptOption func(*MangaScriptRunner)
+
+// WithScriptMetrics は、台本生成のメトリクスを記録する recorder を設定します。
+func WithScriptMetrics(m ports.MetricsRecorder) ScriptOption {
+	return func(r *MangaScriptRunner) {
+		r.metrics = ports.MetricsOrNop(m)
+	}
+}
+
+// WithScriptProgressObserver は、台本生成の進捗イベントを受け取る observer を設定します。
+func WithScriptProgressObserver(observer ports.ProgressObserver) ScriptOption {
+	return func(r *MangaScriptRunner) {
+		r.observer = observer
+	}
+}
+
+// WithScriptRequestTimeout は、台本生成の AI 呼び出し1回あたりの期限を設定します。
+func WithScriptRequestTimeout(d time.Duration) ScriptOption {
+	return func(r *MangaScriptRunner) {
+		if d > 0 {
+			r.timeout = d
+		}
+	}
+}
+
+// WithScriptReferencePolicy は、AI が生成した台本の ReferenceURL に適用する方針を設定します。
+// 既定は ports.DefaultReferencePolicy で、ReferenceURL はすべて取り除かれます。
+func WithScriptReferencePolicy(p ports.ReferencePolicy) ScriptOption {
+	return func(r *MangaScriptRunner) {
+		r.referencePolicy = p
+	}
+}
+
+// WithScriptStructuredOutput は、台本を構造化出力（JSON）で要求するかどうかを設定します。既定は true です。
+// 構造化出力は AI クライアントが ports.StructuredGenerator を実装している場合のみ有効です。
+// gemini.Client など実装していないクライアントや false の場合は通常のテキスト生成を行い、応答から JSON を抽出します。
+func WithScriptStructuredOutput(enabled bool) ScriptOption {
+	return func(r *MangaScriptRunner) {
+		r.structuredOutput = enabled
+	}
+}
+
+// WithScriptCharacters は、台本の検証で話者IDの照合に使うキャラクター定義を設定します。
+func WithScriptCharacters(cm *ports.Characters) ScriptOption {
+	return func(r *MangaScriptRunner) {
+		r.characters = cm
+	}
+}
+
+// WithScriptRepairAttempts は、台本を解析できない、または内容に問題（未定義の話者、空の描写、
+// ページ番号の欠落など validate の重大度 error の指摘）がある場合に、前回の出力と問題の一覧を添えて
+// 修正を依頼する最大回数を設定します。既定は 0 で、WithScriptValidation を指定しない限り内容の検証も行いません。
+// 回数を使い切っても問題が残る場合は、最後の問題を *ports.ScriptValidationError で返します。
+func WithScriptRepairAttempts(n int) ScriptOption {
+	return func(r *MangaScriptRunner) {
+		if n >= 0 {
+			r.repairAttempts = n
+		}
+	}
+}
+
+// WithScriptValidation は、生成した台本を rules で検証し、重大度 error の指摘があれば
+// *ports.ScriptValidationError を返すようにします（修復が有効な場合は先に修正を依頼します）。
+func WithScriptValidation(rules validate.Rules) ScriptOption {
+	return func(r *MangaScriptRunner) {
+		r.validation = true
+		r.rules = rules
+	}
+}
+
+// WithScriptSpeakerResolver は、解析した台本の SpeakerID を resolver でキャラクターIDに書き換えるようにします。
+// 解決できなかった話者は既定のキャラクターで黙って補わず、台本の問題として扱います
+// （修復が有効な場合は修正を依頼し、それ以外は *ports.ScriptValidationError を返します）。
+func WithScriptSpeakerResolver(resolver *speaker.Resolver) ScriptOption {
+	return func(r *MangaScriptRunner) {
+		r.speakers = resolver
+	}
+}
+
+// WithScriptLongDocument は長文モードを有効にします。読み込みの上限を 50MB に広げ、推定トークン数が
+// chunkTokens を超える入力は、見出しとトークン数で分割して各部分をストーリービートに要約し、
+// 全体のビートを共有しながら章ごとに台本を生成して、ページ番号を通しにした1つの台本にまとめます。
+// chunkTokens が 0 以下の場合は 30000 です。
+func WithScriptLongDocument(chunkTokens int) ScriptOption {
+	return func(r *MangaScriptRunner) {
+		if chunkTokens <= 0 {
+			chunkTokens = defaultChunkTokens
+		}
+		r.chunkTokens = chunkTokens
+		r.maxInputSize = maxLongInputSize
+	}
+}
+
+// WithScriptSourceSeparator は、RunSources で複数の入力を連結する区切り文字を設定します。
+// 既定は "\n\n---\n\n" です。
+func WithScriptSourceSeparator(sep string) ScriptOption {
+	return func(r *MangaScriptRunner) {
+		if sep != "" {
+			r.sourceSeparator = sep
+		}
+	}
+}
+
+// WithScriptExtractors は、入力の形式（MIME タイプ・拡張子・内容）ごとに本文を抽出する抽出器を設定します。
+// 既定は extract.Default() で、HTML の本文抽出・Markdown の front matter の除去・PDF のテキスト抽出を行います。
+// nil を指定すると、入力をそのままプロンプトに渡します。
+func WithScriptExtractors(reg *extract.Registry) ScriptOption {
+	return func(r *MangaScriptRunner) {
+		r.extractors = reg
+	}
+}
+
+// --- MangaPanelRunner Options ---
+
+// PanelOption は MangaPanelRunner の設定を適用する関数型です。
+type PanelOption func(*MangaPanelRunner)
+
+// WithPanelValidation は、生成の前に台本を rules で検証し、重大度 error の指摘があれば
+// 生成を始めずに *ports.ScriptValidationError を返すようにします。
+func WithPanelValidation(cm *ports.Characters, rules validate.Rules) PanelOption {
+	return func(r *MangaPanelRunner) {
+		r.gate = &validationGate{characters: cm, rules: rules}
+	}
+}
+
+// --- MangaPageRunner Options ---
+
+// PageOption は MangaPageRunner の設定を適用する関数型です。
+type PageOption func(*MangaPageRunner)
+
+// WithPageValidation は、生成の前に台本を rules で検証し、重大度 error の指摘があれば
+// 生成を始めずに *ports.ScriptValidationError を返すようにします。
+func WithPageValidation(cm *ports.Characters, rules validate.Rules) PageOption {
+	return func(r *MangaPageRunner) {
+		r.gate = &validationGate{characters: cm, rules: rules}
+	}
+}
+
+// --- MangaDesignRunner Options ---
+
+// DesignOption は MangaDesignRunner の設定を適用する関数型です。
+type DesignOption func(*MangaDesignRunner)
+
+// WithDesignMetrics は、デザインシート生成のメトリクスを記録する recorder を設定します。
+func WithDesignMetrics(m ports.MetricsRecorder) DesignOption {
+	return func(dr *MangaDesignRunner) {
+		dr.metrics = ports.MetricsOrNop(m)
+	}
+}
+
+// WithDesignProgressObserver は、デザインシート生成の進捗イベントを受け取る observer を設定します。
+func WithDesignProgressObserver(observer ports.ProgressObserver) DesignOption {
+	return func(dr *MangaDesignRunner) {
+		dr.observer = observer
+	}
+}
+
+// WithDesignRequestTimeout は、デザインシート生成の AI 呼び出し1回あたりの期限を設定します。
+func WithDesignRequestTimeout(d time.Duration) DesignOption {
+	return func(dr *MangaDesignRunner) {
+		if d > 0 {
+			dr.timeout = d
+		}
+	}
+}
+
+// WithDesignRateInterval は、RunBatch のリクエスト間の待機間隔を設定します。
+func WithDesignRateInterval(d time.Duration) DesignOption {
+	return func(dr *MangaDesignRunner) {
+		if d > 0 {
+			dr.limiter = newDesignLimiter(d)
+		}
+	}
+}
+
+// WithDesignRateLimiter は、RunBatch が使うレートリミッターを設定します。
+// 他の生成処理と同じリミッターを渡すと、API のレート制限を共有できます。
+func WithDesignRateLimiter(l *rate.Limiter) DesignOption {
+	return func(dr *MangaDesignRunner) {
+		if l != nil {
+			dr.limiter = l
+		}
+	}
+}
+
+// WithDesignMaxConcurrency は、RunBatch で並列に生成するキャラクター数の上限を設定します。
+func WithDesignMaxConcurrency(value int) DesignOption {
+	return func(dr *MangaDesignRunner) {
+		if value > 0 {
+			dr.maxConcurrency = value
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	imagePorts "github.com/shouni/gemini-image-kit/ports"
 	"github.com/shouni/go-manga-kit/asset"
+	"github.com/shouni/go-manga-kit/internal/telemetry"
 	"github.com/shouni/go-manga-kit/ports"
 	"github.com/shouni/go-remote-io/remoteio"
 )
@@ -16,17 +17,23 @@ import (
 type MangaPageRunner struct {
 	generator ports.PagesImageGenerator
 	writer    remoteio.Writer
+	gate      *validationGate
 }
 
 // NewMangaPageRunner は、設定、パーサー、生成エンジン、およびライターを依存性として注入し、MangaPageRunner を初期化します。
 func NewMangaPageRunner(
 	generator ports.PagesImageGenerator,
 	writer remoteio.Writer,
+	opts ...PageOption,
 ) *MangaPageRunner {
-	return &MangaPageRunner{
+	r := &MangaPageRunner{
 		generator: generator,
 		writer:    writer,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 // Run は、構造化された台本データを基に、最終的な漫画ページ画像を生成します。
@@ -40,6 +47,9 @@ func (r *MangaPageRunner) Run(ctx context.Context, manga *ports.MangaResponse) (
 	if len(manga.Panels) == 0 {
 		return nil, fmt.Errorf("プロットにページデータが含まれていません")
 	}
+	if err := r.gate.check(ctx, manga); err != nil {
+		return nil, err
+	}
 
 	slog.InfoContext(ctx, "MangaPageRunner: ページ生成を開始します",
 		"title", manga.Title,
@@ -98,8 +108,7 @@ func (r *MangaPageRunner) savePages(ctx context.Context, responses []*imagePorts
 			"path", pagePath,
 		)
 
-		if err = r.writer.Write(ctx, pagePath, bytes.NewReader(resp.Data),
-			remoteio.WithContentType(resp.MimeType),
+		if err = telemetry.Write(ctx, r.writer, pagePath, resp.MimeType, bytes.NewReader(resp.Data),
 			remoteio.WithCacheControl(defaultCacheControl),
 		); err != nil {
 			return nil, fmt.Errorf("第 %d ページの保存に失敗しました (path: %s): %w", i+1, pagePath, err)
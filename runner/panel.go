@@ -9,6 +9,7 @@ import (
 
 	imagePorts "github.com/shouni/gemini-image-kit/ports"
 	"github.com/shouni/go-manga-kit/asset"
+	"github.com/shouni/go-manga-kit/internal/telemetry"
 	"github.com/shouni/go-manga-kit/ports"
 	"github.com/shouni/go-remote-io/remoteio"
 )
@@ -19,17 +20,23 @@ const defaultCacheControl = "public, max-age=1800"
 type MangaPanelRunner struct {
 	generator ports.PanelsImageGenerator
 	writer    remoteio.Writer
+	gate      *validationGate
 }
 
 // NewMangaPanelRunner は、依存関係を注入して初期化します。
 func NewMangaPanelRunner(
 	generator ports.PanelsImageGenerator,
 	writer remoteio.Writer,
+	opts ...PanelOption,
 ) *MangaPanelRunner {
-	return &MangaPanelRunner{
+	r := &MangaPanelRunner{
 		generator: generator,
 		writer:    writer,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 // Run は、台本(MangaResponse)を受け取り、パネルの画像を生成します。
@@ -37,6 +44,9 @@ func (r *MangaPanelRunner) Run(ctx context.Context, manga *ports.MangaResponse)
 	if manga == nil {
 		return nil, fmt.Errorf("MangaResponse がありません")
 	}
+	if err := r.gate.check(ctx, manga); err != nil {
+		return nil, err
+	}
 
 	slog.Info("Starting parallel image generation")
 
@@ -72,7 +82,7 @@ func (r *MangaPanelRunner) RunAndSave(ctx context.Context, manga *ports.MangaRes
 	}
 
 	if len(images) != len(manga.Panels) {
-		return nil, fmt.Errorf("生成された画像の数(%d)とパネルの数(%d)が一致しません", len(images), len(manga.Panels))
+		return nil, &ports.CountMismatchError{Subject: "panel images", Want: len(manga.Panels), Got: len(images)}
 	}
 	for i, image := range images {
 		// 連番を付けて保存
@@ -86,8 +96,7 @@ func (r *MangaPanelRunner) RunAndSave(ctx context.Context, manga *ports.MangaRes
 			"path", panelPath,
 		)
 
-		if err := r.writer.Write(ctx, panelPath, bytes.NewReader(image.Data),
-			remoteio.WithContentType(image.MimeType),
+		if err := telemetry.Write(ctx, r.writer, panelPath, image.MimeType, bytes.NewReader(image.Data),
 			remoteio.WithCacheControl(defaultCacheControl),
 		); err != nil {
 			// エラー発生時は、それまでの成果物は返さず、nilとエラーを返す
@@ -108,8 +117,7 @@ func (r *MangaPanelRunner) RunAndSave(ctx context.Context, manga *ports.MangaRes
 	}
 
 	slog.InfoContext(ctx, "更新された台本を保存しています", "path", plotPath)
-	if err := r.writer.Write(ctx, plotPath, bytes.NewReader(plotData),
-		remoteio.WithContentType("application/json"),
+	if err := telemetry.Write(ctx, r.writer, plotPath, "application/json", bytes.NewReader(plotData),
 		remoteio.WithCacheControl(defaultCacheControl),
 	); err != nil {
 		return nil, fmt.Errorf("プロットファイルの保存に失敗しました: %w", err)
@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// progressReporter は、Runner のオプションと ctx の双方で設定された observer へ、
+// ステージ情報を付与した進捗イベントを直列に通知します。
+type progressReporter struct {
+	mu        *sync.Mutex
+	stage     string
+	observers []ports.ProgressObserver
+	// base はステージの開始・終了のイベントに共通する値、started は開始時刻です。
+	base    ports.ProgressEvent
+	started time.Time
+}
+
+// startProgress は ctx の observer と configured を束ねた reporter を生成し、ステージの開始を通知します。
+// 戻り値の ctx には reporter が設定されるため、参照画像のアップロードなどの進捗も同じ observer に届きます。
+// mu は同じ Runner の並行した呼び出しの間で通知を直列にするために使います。
+func startProgress(ctx context.Context, mu *sync.Mutex, stage string, configured ports.ProgressObserver, base ports.ProgressEvent) (context.Context, *progressReporter) {
+	r := &progressReporter{mu: mu, stage: stage, base: base, started: time.Now()}
+	if configured != nil {
+		r.observers = append(r.observers, configured)
+	}
+	if fromCtx := ports.ProgressObserverFromContext(ctx); fromCtx != nil {
+		r.observers = append(r.observers, fromCtx)
+	}
+	if len(r.observers) == 0 {
+		return ctx, r
+	}
+	event := base
+	event.Kind = ports.ProgressStageStarted
+	r.OnProgress(event)
+	return ports.WithProgressObserver(ctx, r), r
+}
+
+// finish はステージの終了を通知します。失敗時は Err に err を設定します。
+func (r *progressReporter) finish(err error) {
+	event := r.base
+	event.Kind = ports.ProgressStageFinished
+	event.Duration = time.Since(r.started)
+	event.Err = err
+	r.OnProgress(event)
+}
+
+// OnProgress は ports.ProgressObserver の実装です。
+func (r *progressReporter) OnProgress(event ports.ProgressEvent) {
+	if len(r.observers) == 0 {
+		return
+	}
+	if event.Stage == "" {
+		event.Stage = r.stage
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, observer := range r.observers {
+		observer.OnProgress(event)
+	}
+}
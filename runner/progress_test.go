@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/shouni/go-manga-kit/mangakittest"
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// recordingObserver は受け取った進捗イベントを記録するテスト用の observer です。
+type recordingObserver struct {
+	mu     sync.Mutex
+	events []ports.ProgressEvent
+}
+
+func (o *recordingObserver) OnProgress(event ports.ProgressEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, event)
+}
+
+func (o *recordingObserver) kinds() []ports.ProgressEventKind {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	kinds := make([]ports.ProgressEventKind, len(o.events))
+	for i, e := range o.events {
+		kinds[i] = e.Kind
+	}
+	return kinds
+}
+
+func TestMangaScriptRunner_ProgressEvents(t *testing.T) {
+	reader := &mangakittest.ContentReader{}
+	reader.SetFile("https://example.com/a.txt", []byte("本文"))
+	want := []ports.ProgressEventKind{ports.ProgressStageStarted, ports.ProgressStageFinished}
+
+	t.Run("notifies the configured and per-call observers", func(t *testing.T) {
+		configured, perCall := &recordingObserver{}, &recordingObserver{}
+		ai := &mangakittest.ContentGenerator{Text: `{"title":"t","panels":[{"page":1,"visual_anchor":"v","speaker_id":"zundamon"}]}`}
+		sr := NewMangaScriptRunner(&mangakittest.ScriptPrompt{}, ai, reader, "model", WithScriptProgressObserver(configured))
+
+		ctx := ports.WithProgressObserver(context.Background(), perCall)
+		if _, err := sr.Run(ctx, "https://example.com/a.txt", "dialogue"); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		for _, observer := range []*recordingObserver{configured, perCall} {
+			if got := observer.kinds(); !slices.Equal(got, want) {
+				t.Errorf("kinds = %v, want %v", got, want)
+			}
+			for _, e := range observer.events {
+				if e.Stage != ports.StageScript || e.Time.IsZero() {
+					t.Errorf("event = %+v, want the script stage and a timestamp", e)
+				}
+			}
+		}
+	})
+
+	t.Run("reports the error when the stage fails", func(t *testing.T) {
+		observer := &recordingObserver{}
+		wantErr := errors.New("boom")
+		sr := NewMangaScriptRunner(&mangakittest.ScriptPrompt{}, &mangakittest.ContentGenerator{Err: wantErr}, reader, "model",
+			WithScriptProgressObserver(observer))
+
+		if _, err := sr.Run(context.Background(), "https://example.com/a.txt", "dialogue"); err == nil {
+			t.Fatal("Run succeeded, want an error")
+		}
+		if got := observer.kinds(); !slices.Equal(got, want) {
+			t.Fatalf("kinds = %v, want %v", got, want)
+		}
+		if last := observer.events[1]; !errors.Is(last.Err, wantErr) {
+			t.Errorf("stage_finished Err = %v, want %v", last.Err, wantErr)
+		}
+	})
+}
+
+func TestMangaDesignRunner_ProgressEvents(t *testing.T) {
+	dr, _ := newTestDesignRunner(t)
+	observer := &recordingObserver{}
+	WithDesignProgressObserver(observer)(dr)
+
+	if _, _, err := dr.Run(context.Background(), []string{"tsumugi"}, 42, "gs://bucket/out", "", "", DesignOverride{}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	want := []ports.ProgressEventKind{ports.ProgressStageStarted, ports.ProgressStageFinished}
+	if got := observer.kinds(); !slices.Equal(got, want) {
+		t.Fatalf("kinds = %v, want %v", got, want)
+	}
+	for _, e := range observer.events {
+		if e.Stage != ports.StageDesign || e.Seed != 42 {
+			t.Errorf("event = %+v, want the design stage with seed 42", e)
+		}
+	}
+	if observer.events[1].Duration <= 0 || observer.events[1].Err != nil {
+		t.Errorf("stage_finished = %+v, want a duration and no error", observer.events[1])
+	}
+}
@@ -6,12 +6,23 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"reflect"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 	"unicode/utf8"
 
 	"github.com/shouni/go-gemini-client/gemini"
+
+	"github.com/shouni/go-manga-kit/extract"
+	"github.com/shouni/go-manga-kit/internal/apierr"
+	"github.com/shouni/go-manga-kit/internal/deadline"
+	"github.com/shouni/go-manga-kit/internal/telemetry"
 	"github.com/shouni/go-manga-kit/ports"
+	"github.com/shouni/go-manga-kit/schema"
+	"github.com/shouni/go-manga-kit/speaker"
+	"github.com/shouni/go-manga-kit/validate"
 )
 
 const (
@@ -22,6 +33,7 @@ const (
 )
 
 // jsonBlockRegex は、Markdown 形式の JSON ブロックを抽出するための正規表現です。
+// 構造化出力を使えない場合のフォールバックでのみ使います。
 var jsonBlockRegex = regexp.MustCompile("(?s)```(?:json)?\\s*(.*\\S)\\s*```")
 
 // MangaScriptRunner は、AIによる漫画プロット/スクリプト生成を実行します。
@@ -30,6 +42,36 @@ type MangaScriptRunner struct {
 	aiClient      gemini.ContentGenerator
 	reader        ports.ContentReader
 	aiModel       string
+	metrics       ports.MetricsRecorder
+	timeout       time.Duration
+	// referencePolicy は AI が生成したパネルの ReferenceURL に適用する方針です。
+	referencePolicy ports.ReferencePolicy
+	// structuredOutput が true の場合、AI クライアントが ports.StructuredGenerator を実装していれば
+	// 台本のスキーマで出力を制約します。
+	structuredOutput bool
+	// responseSchema は構造化出力で指定する台本のスキーマです。
+	responseSchema map[string]any
+	// characters を設定すると、検証で未定義の話者IDを検出します。
+	characters *ports.Characters
+	// repairAttempts は台本に問題があった場合に修復を依頼する最大回数です。
+	repairAttempts int
+	// validation が true の場合、修復の有無にかかわらず台本の内容を検証し、問題があればエラーにします。
+	validation bool
+	// rules は台本の内容の検証規則です。
+	rules validate.Rules
+	// speakers を設定すると、解析した台本の SpeakerID を表示名や表記ゆれからキャラクターIDに解決します。
+	speakers *speaker.Resolver
+	// maxInputSize は読み込みを許可する最大バイト数です。
+	maxInputSize int64
+	// chunkTokens が正の場合、推定トークン数がこれを超える入力は長文モードで分割して処理します。
+	chunkTokens int
+	// sourceSeparator は複数の入力を連結する区切り文字です。
+	sourceSeparator string
+	// extractors は入力の形式ごとに本文を抽出する抽出器です。nil の場合は入力をそのまま使います。
+	extractors *extract.Registry
+	// observer は台本生成の進捗イベントを受け取ります。progressMu は通知を直列にします。
+	observer   ports.ProgressObserver
+	progressMu sync.Mutex
 }
 
 // NewMangaScriptRunner は依存関係を注入して初期化します。
@@ -38,76 +80,182 @@ func NewMangaScriptRunner(
 	ai gemini.ContentGenerator,
 	r ports.ContentReader,
 	aiModel string,
+	opts ...ScriptOption,
 ) *MangaScriptRunner {
-	return &MangaScriptRunner{
-		promptBuilder: pb,
-		aiClient:      ai,
-		reader:        r,
-		aiModel:       aiModel,
+	sr := &MangaScriptRunner{
+		promptBuilder:    pb,
+		aiClient:         ai,
+		reader:           r,
+		aiModel:          aiModel,
+		metrics:          ports.NopMetrics{},
+		referencePolicy:  ports.DefaultReferencePolicy(),
+		structuredOutput: true,
+		responseSchema:   schema.For(reflect.TypeFor[ports.MangaResponse]()),
+		rules:            validate.DefaultRules(),
+		maxInputSize:     maxInputSize,
+		sourceSeparator:  defaultSourceSeparator,
+		extractors:       extract.Default(),
+	}
+	for _, opt := range opts {
+		opt(sr)
 	}
+	return sr
 }
 
 // Run は Web ページまたは GCS から内容を抽出し、Gemini を用いて漫画の台本 JSON を生成します。
 func (r *MangaScriptRunner) Run(ctx context.Context, sourceURL string, mode string) (*ports.MangaResponse, error) {
-	slog.Info("ScriptRunner: 処理を開始", "url", sourceURL)
+	return r.RunSources(ctx, []ports.Source{{URI: sourceURL}}, mode)
+}
 
+var _ ports.SourceScriptRunner = (*MangaScriptRunner)(nil)
+
+// RunSources は、生のテキスト・URI・Reader を組み合わせた複数の入力から台本 JSON を生成します。
+// 入力が複数の場合は、各入力をラベル付きの見出しとともに区切り文字で連結してプロンプトに渡し、
+// 各パネルの元になった入力のラベルを Panel.Source に記録させます。
+// 入力の一覧は出典として MangaResponse.Sources に記録されます。
+// 進捗イベントは WithScriptProgressObserver と ports.WithProgressObserver で設定した observer に通知されます。
+func (r *MangaScriptRunner) RunSources(ctx context.Context, sources []ports.Source, mode string) (*ports.MangaResponse, error) {
+	ctx, span := telemetry.Start(ctx, "manga.script.generate",
+		telemetry.AttrStage.String(ports.StageScript),
+		telemetry.AttrModel.String(r.aiModel),
+	)
+	ctx, progress := startProgress(ctx, &r.progressMu, ports.StageScript, r.observer, ports.ProgressEvent{})
+	manga, err := r.run(ctx, sources, mode)
+	if err != nil {
+		r.metrics.IncFailure(ports.StageScript, ports.ClassifyError(err))
+	}
+	progress.finish(err)
+	telemetry.End(span, err)
+	return manga, err
+}
+
+// run は RunSources の本体です。
+func (r *MangaScriptRunner) run(ctx context.Context, sources []ports.Source, mode string) (*ports.MangaResponse, error) {
 	// 1. ソースからテキストを取得
-	inputText, err := r.readContent(ctx, sourceURL)
+	inputText, refs, err := r.readSources(ctx, sources)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 台本を生成（長い文書は分割して要約・章ごとの台本生成を行い、1つの台本にまとめます）
+	var manga *ports.MangaResponse
+	if r.chunkTokens > 0 && estimateTokens(inputText) > r.chunkTokens {
+		manga, err = r.runLong(ctx, inputText, mode)
+	} else {
+		manga, err = r.runSingle(ctx, inputText, mode)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	// 2. プロンプトの構築
+	// 3. 出典を記録
+	recordProvenance(ctx, manga, refs)
+	return manga, nil
+}
+
+// runSingle は入力全体を1つのプロンプトで台本にします。
+func (r *MangaScriptRunner) runSingle(ctx context.Context, inputText, mode string) (*ports.MangaResponse, error) {
 	data := ports.TemplateData{InputText: inputText}
 	finalPrompt, err := r.promptBuilder.Build(mode, &data)
 	if err != nil {
 		return nil, fmt.Errorf("プロンプトの構築に失敗しました: %w", err)
 	}
+	return r.generateScript(ctx, finalPrompt)
+}
 
-	// 3. Gemini API を呼び出し
-	slog.Info("ScriptRunner: Gemini APIを呼び出し中", "model", r.aiModel)
-	resp, err := r.aiClient.GenerateContent(ctx, r.aiModel, finalPrompt)
+// generateScript は finalPrompt で台本を生成します。応答をパースし、問題があれば修復を依頼します。
+func (r *MangaScriptRunner) generateScript(ctx context.Context, finalPrompt string) (*ports.MangaResponse, error) {
+	text, err := r.call(ctx, finalPrompt, true)
 	if err != nil {
-		return nil, fmt.Errorf("geminiによるコンテンツ生成に失敗しました: %w", err)
+		return nil, err
 	}
 
-	// 4. AI の応答をパース
-	manga, err := r.parseResponse(resp.Text)
+	for attempt := 1; ; attempt++ {
+		manga, issues, err := r.check(text)
+		if err == nil && len(issues) == 0 {
+			r.sanitizeReferenceURLs(ctx, manga)
+			return manga, nil
+		}
+		if attempt > r.repairAttempts {
+			if err != nil && r.repairAttempts == 0 {
+				return nil, err
+			}
+			return nil, &ports.ScriptValidationError{Attempts: attempt, Issues: issues, Err: err}
+		}
+		slog.WarnContext(ctx, "ScriptRunner: 台本に問題があるため修復を依頼します",
+			"attempt", attempt,
+			"issues", len(issues),
+		)
+		if text, err = r.call(ctx, buildRepairPrompt(finalPrompt, text, issues), true); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// call は AI を1回呼び出し、応答テキストを返します。structured が true の場合は台本の JSON を要求します。
+func (r *MangaScriptRunner) call(ctx context.Context, prompt string, structured bool) (string, error) {
+	slog.Info("ScriptRunner: Gemini APIを呼び出し中", "model", r.aiModel)
+	r.metrics.AddInFlight(ports.StageScript, 1)
+	startTime := time.Now()
+	text, err := deadline.Call(ctx, ports.StageScript, r.timeout, func(ctx context.Context) (string, error) {
+		text, err := r.generate(ctx, prompt, structured)
+		return text, apierr.Classify(err)
+	})
+	r.metrics.ObserveGeneration(ports.StageScript, r.aiModel, time.Since(startTime))
+	r.metrics.AddInFlight(ports.StageScript, -1)
 	if err != nil {
-		return nil, err
+		return "", &ports.GenerationError{Stage: ports.StageScript, Attempts: 1, Err: err}
 	}
+	return text, nil
+}
 
-	return manga, nil
+// generate は AI クライアントでテキストを生成します。structured が true で構造化出力が有効な場合、
+// クライアントが ports.StructuredGenerator を実装していれば台本のスキーマで出力を制約します。
+// それ以外は通常のテキスト生成で、応答から JSON を抽出します。
+func (r *MangaScriptRunner) generate(ctx context.Context, prompt string, structured bool) (string, error) {
+	if client, ok := r.aiClient.(ports.StructuredGenerator); ok && structured && r.structuredOutput {
+		return client.GenerateStructured(ctx, r.aiModel, prompt, r.responseSchema)
+	}
+	resp, err := r.aiClient.GenerateContent(ctx, r.aiModel, prompt)
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
 }
 
 // readContent は、指定されたソースURLからコンテンツを取得します。
-func (r *MangaScriptRunner) readContent(ctx context.Context, url string) (string, error) {
+func (r *MangaScriptRunner) readContent(ctx context.Context, url string) ([]byte, error) {
 	rc, err := r.reader.Open(ctx, url)
 	if err != nil {
-		return "", fmt.Errorf("failed to read source: %w", err)
+		return nil, fmt.Errorf("failed to read source: %w", err)
 	}
 	defer func() {
 		if closeErr := rc.Close(); closeErr != nil {
 			slog.WarnContext(ctx, "ストリームのクローズに失敗しました", "error", closeErr)
 		}
 	}()
-	limitedReader := io.LimitReader(rc, int64(maxInputSize))
+	return r.readLimited(ctx, rc, url)
+}
+
+// readLimited は rc から最大 maxInputSize バイトを読み込みます。name はログに出力する入力の名前です。
+func (r *MangaScriptRunner) readLimited(ctx context.Context, rc io.Reader, name string) ([]byte, error) {
+	limitedReader := io.LimitReader(rc, r.maxInputSize)
 	content, err := io.ReadAll(limitedReader)
 	if err != nil {
-		return "", fmt.Errorf("読み込みに失敗しました: %w", err)
+		return nil, fmt.Errorf("読み込みに失敗しました: %w", err)
 	}
 
 	// 追加の読み込みを試みて切り捨てを判定
 	oneMoreByte := make([]byte, 1)
 	n, readErr := rc.Read(oneMoreByte)
 	if readErr != nil && readErr != io.EOF {
-		return "", fmt.Errorf("サイズ確認中にエラーが発生しました: %w", readErr)
+		return nil, fmt.Errorf("サイズ確認中にエラーが発生しました: %w", readErr)
 	}
 
 	if n > 0 {
 		slog.WarnContext(ctx, "制限サイズに達したため切り捨てられました",
-			"url", url,
-			"limit_bytes", maxInputSize)
+			"url", name,
+			"limit_bytes", r.maxInputSize)
 
 		// UTF-8の文字境界に合わせて末尾の不正なバイトを取り除く
 		if !utf8.Valid(content) {
@@ -121,11 +269,17 @@ func (r *MangaScriptRunner) readContent(ctx context.Context, url string) (string
 		}
 	}
 
-	return string(content), nil
+	return content, nil
 }
 
-// parseResponse は AI の応答から JSON を抽出し、構造体に変換します。
+// parseResponse は AI の応答を構造体に変換します。
+// 応答全体が JSON でない場合に限り、Markdown のコードブロックや最初と最後の波括弧から JSON を抽出します。
 func (r *MangaScriptRunner) parseResponse(raw string) (*ports.MangaResponse, error) {
+	var direct ports.MangaResponse
+	if err := json.Unmarshal([]byte(strings.TrimSpace(raw)), &direct); err == nil {
+		return &direct, nil
+	}
+
 	jsonStr := extractJSONString(raw)
 	if jsonStr == "" {
 		slog.Warn("AIの応答からJSONを抽出できませんでした。応答全体を対象にパースを試みます。",
@@ -135,13 +289,32 @@ func (r *MangaScriptRunner) parseResponse(raw string) (*ports.MangaResponse, err
 
 	var manga ports.MangaResponse
 	if err := json.Unmarshal([]byte(jsonStr), &manga); err != nil {
-		return nil, fmt.Errorf("AI応答JSONの解析に失敗しました (抜粋: %q): %w",
-			truncateString(raw, maxErrorResponseLength), err)
+		return nil, &ports.ScriptParseError{Snippet: truncateString(raw, maxErrorResponseLength), Err: err}
 	}
 
 	return &manga, nil
 }
 
+// sanitizeReferenceURLs は、AI が出力したパネルの ReferenceURL を参照方針に従って取り除きます。
+// 方針で AI の ReferenceURL が許可されていない場合はすべて、許可されている場合は違反するものを取り除きます。
+func (r *MangaScriptRunner) sanitizeReferenceURLs(ctx context.Context, manga *ports.MangaResponse) {
+	for i := range manga.Panels {
+		panel := &manga.Panels[i]
+		if panel.ReferenceURL == "" {
+			continue
+		}
+		if !r.referencePolicy.AllowAIReferenceURLs {
+			slog.WarnContext(ctx, "AIが出力した参照画像URLを除去しました", "panel_index", i+1, "reference_url", panel.ReferenceURL)
+			panel.ReferenceURL = ""
+			continue
+		}
+		if err := r.referencePolicy.Check(ctx, panel.ReferenceURL); err != nil {
+			slog.WarnContext(ctx, "参照方針に違反する参照画像URLを除去しました", "panel_index", i+1, "error", err)
+			panel.ReferenceURL = ""
+		}
+	}
+}
+
 // extractJSONString は文字列から JSON 部分を抽出します。
 func extractJSONString(raw string) string {
 	cleanRaw := strings.TrimSpace(raw)
@@ -0,0 +1,237 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+const (
+	// defaultChunkTokens は長文モードで1つのチャンクに含める既定の推定トークン数です。
+	defaultChunkTokens = 30000
+	// maxLongInputSize は長文モードで読み込みを許可する最大テキストサイズ (50MB) です。
+	maxLongInputSize = 50 * 1024 * 1024
+)
+
+// headingRegex は、長文を分割する見出し（Markdown の見出し、または「第N章」「第N話」などで始まる行）です。
+var headingRegex = regexp.MustCompile(`^(#{1,3}\s+\S|第[0-9０-９一二三四五六七八九十百千]+[章話部節幕])`)
+
+// sourceChunk は長文を分割した1つのチャンクで、1つの章として台本にします。
+type sourceChunk struct {
+	// Heading はチャンクの最初の見出しです。見出しが無い場合は空です。
+	Heading string
+	Text    string
+}
+
+// runLong は長文モードの本体です。inputText を見出しとトークン数の上限で分割し、各チャンクを
+// ストーリービートに要約（map）したうえで、全体のビートを共有しながら章ごとに台本を生成し、
+// ページ番号を通しにした1つの台本にまとめます（reduce）。
+func (r *MangaScriptRunner) runLong(ctx context.Context, inputText, mode string) (*ports.MangaResponse, error) {
+	chunks := splitSource(inputText, r.chunkTokens)
+	slog.InfoContext(ctx, "ScriptRunner: 長文モードで処理します",
+		"estimated_tokens", estimateTokens(inputText),
+		"chunks", len(chunks),
+	)
+
+	// 1. 各チャンクをストーリービートに要約
+	beats := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		summary, err := r.call(ctx, buildBeatsPrompt(chunk, i, len(chunks)), false)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d/%d 部の要約に失敗しました: %w", i+1, len(chunks), err)
+		}
+		beats[i] = strings.TrimSpace(summary)
+	}
+	synopsis := buildSynopsis(chunks, beats)
+
+	// 2. 全体のビートを共有しながら章ごとに台本を生成
+	chapters := make([]*ports.MangaResponse, len(chunks))
+	for i, chunk := range chunks {
+		data := ports.TemplateData{InputText: buildChapterInput(synopsis, chunk, i, len(chunks))}
+		prompt, err := r.promptBuilder.Build(mode, &data)
+		if err != nil {
+			return nil, fmt.Errorf("プロンプトの構築に失敗しました: %w", err)
+		}
+		chapter, err := r.generateScript(ctx, prompt)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d/%d 章の台本生成に失敗しました: %w", i+1, len(chunks), err)
+		}
+		chapters[i] = chapter
+	}
+
+	// 3. ページ番号を通しにして1つの台本にまとめる
+	return mergeChapters(chapters), nil
+}
+
+// splitSource は text を見出しで区切り、推定トークン数が budget 以内になるようにまとめ直します。
+// 見出しの無い長い区間は段落で、長すぎる段落は文字数で分割します。
+func splitSource(text string, budget int) []sourceChunk {
+	var sections []sourceChunk
+	var current strings.Builder
+	heading := ""
+	flush := func() {
+		if strings.TrimSpace(current.String()) != "" {
+			sections = append(sections, sourceChunk{Heading: heading, Text: strings.TrimSpace(current.String())})
+		}
+		current.Reset()
+	}
+	for line := range strings.Lines(text) {
+		if trimmed := strings.TrimSpace(line); headingRegex.MatchString(trimmed) {
+			flush()
+			heading = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+		}
+		current.WriteString(line)
+	}
+	flush()
+
+	var chunks []sourceChunk
+	for _, section := range sections {
+		for _, piece := range splitByBudget(section.Text, budget) {
+			last := len(chunks) - 1
+			// 小さな区間は直前のチャンクにまとめ、AI の呼び出し回数を抑えます。
+			if last >= 0 && estimateTokens(chunks[last].Text)+estimateTokens(piece) <= budget {
+				chunks[last].Text += "\n\n" + piece
+				continue
+			}
+			chunks = append(chunks, sourceChunk{Heading: section.Heading, Text: piece})
+		}
+	}
+	return chunks
+}
+
+// splitByBudget は text を段落の境界で、推定トークン数が budget 以内の断片に分割します。
+func splitByBudget(text string, budget int) []string {
+	if estimateTokens(text) <= budget {
+		return []string{text}
+	}
+	var pieces []string
+	var current strings.Builder
+	for _, para := range strings.Split(text, "\n\n") {
+		if para = strings.TrimSpace(para); para == "" {
+			continue
+		}
+		if current.Len() > 0 && estimateTokens(current.String())+estimateTokens(para) > budget {
+			pieces = append(pieces, current.String())
+			current.Reset()
+		}
+		for estimateTokens(para) > budget {
+			head, rest := cutTokens(para, budget)
+			if current.Len() > 0 {
+				pieces = append(pieces, current.String())
+				current.Reset()
+			}
+			pieces = append(pieces, head)
+			para = rest
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(para)
+	}
+	if current.Len() > 0 {
+		pieces = append(pieces, current.String())
+	}
+	return pieces
+}
+
+// cutTokens は s の先頭から推定トークン数が budget 以内の部分と残りに分けます。
+func cutTokens(s string, budget int) (head, rest string) {
+	tokens := 0.0
+	for i, r := range s {
+		tokens += runeTokens(r)
+		if tokens > float64(budget) {
+			return s[:i], s[i:]
+		}
+	}
+	return s, ""
+}
+
+// estimateTokens は s のトークン数を概算します。ASCII は4文字で1トークン、それ以外は1文字で1トークンとします。
+func estimateTokens(s string) int {
+	tokens := 0.0
+	for _, r := range s {
+		tokens += runeTokens(r)
+	}
+	return int(tokens + 0.5)
+}
+
+func runeTokens(r rune) float64 {
+	if r < utf8.RuneSelf {
+		return 0.25
+	}
+	return 1
+}
+
+// buildBeatsPrompt は、チャンクをストーリービートに要約するプロンプトを作成します。
+func buildBeatsPrompt(chunk sourceChunk, index, total int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "以下は長い文書を分割した第 %d/%d 部", index+1, total)
+	if chunk.Heading != "" {
+		fmt.Fprintf(&b, "（%s）", chunk.Heading)
+	}
+	b.WriteString("です。この部分の内容を、漫画の台本づくりに使うストーリービートとして箇条書きで要約してください。\n")
+	b.WriteString("各項目には出来事・登場人物・重要な発言や数値などの要点を含め、原文に無い情報は加えないでください。\n\n---\n\n")
+	b.WriteString(chunk.Text)
+	b.WriteString("\n")
+	return b.String()
+}
+
+// buildSynopsis は全チャンクのストーリービートを、章の見出し付きで1つにまとめます。
+func buildSynopsis(chunks []sourceChunk, beats []string) string {
+	var b strings.Builder
+	for i, chunk := range chunks {
+		fmt.Fprintf(&b, "### 第 %d 部", i+1)
+		if chunk.Heading != "" {
+			fmt.Fprintf(&b, ": %s", chunk.Heading)
+		}
+		b.WriteString("\n")
+		b.WriteString(beats[i])
+		b.WriteString("\n\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// buildChapterInput は、章ごとの台本生成でテンプレートに渡す入力テキストを作成します。
+func buildChapterInput(synopsis string, chunk sourceChunk, index, total int) string {
+	var b strings.Builder
+	b.WriteString("## 文書全体のストーリービート\n\n")
+	b.WriteString(synopsis)
+	fmt.Fprintf(&b, "\n\n## この章で漫画にする部分（第 %d/%d 部）\n\n", index+1, total)
+	b.WriteString(chunk.Text)
+	b.WriteString("\n\nこの章の部分だけを漫画にしてください。他の章は別に漫画にするため、" +
+		"文書全体のストーリービートは登場人物や話の流れを把握するためだけに使ってください。\n")
+	return b.String()
+}
+
+// mergeChapters は章ごとの台本を1つにまとめます。ページ番号は章をまたいで通しにし、
+// ページ番号の無いパネルは直前のパネルと同じページとして扱います。
+func mergeChapters(chapters []*ports.MangaResponse) *ports.MangaResponse {
+	merged := &ports.MangaResponse{}
+	var descriptions []string
+	offset := 0
+	for _, chapter := range chapters {
+		if merged.Title == "" {
+			merged.Title = chapter.Title
+		}
+		if chapter.Description != "" {
+			descriptions = append(descriptions, chapter.Description)
+		}
+		page, lastPage := 1, 0
+		for _, panel := range chapter.Panels {
+			if panel.Page > 0 {
+				page = panel.Page
+			}
+			lastPage = max(lastPage, page)
+			panel.Page = offset + page
+			merged.Panels = append(merged.Panels, panel)
+		}
+		offset += lastPage
+	}
+	merged.Description = strings.Join(descriptions, "\n")
+	return merged
+}
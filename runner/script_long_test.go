@@ -0,0 +1,114 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/shouni/go-manga-kit/mangakittest"
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+func TestSplitSource(t *testing.T) {
+	t.Run("splits on headings and merges small sections", func(t *testing.T) {
+		text := "前書き\n\n# 第一章\n" + strings.Repeat("あ", 40) + "\n\n第2章 旅立ち\n" + strings.Repeat("い", 40) + "\n## 付録\nおわり\n"
+		chunks := splitSource(text, 60)
+		if len(chunks) != 2 {
+			t.Fatalf("chunks = %+v, want 2", chunks)
+		}
+		if chunks[0].Heading != "" || !strings.Contains(chunks[0].Text, "# 第一章") {
+			t.Errorf("chunk 1 = %+v, want the preface merged with chapter 1", chunks[0])
+		}
+		if chunks[1].Heading != "第2章 旅立ち" || !strings.Contains(chunks[1].Text, "おわり") {
+			t.Errorf("chunk 2 = %+v, want chapter 2 with the appendix", chunks[1])
+		}
+	})
+
+	t.Run("splits long sections by budget", func(t *testing.T) {
+		text := strings.Repeat("う", 25) + "\n\n" + strings.Repeat("え", 25) + "\n\n" + strings.Repeat("お", 70)
+		chunks := splitSource(text, 30)
+		for i, c := range chunks {
+			if n := estimateTokens(c.Text); n > 30 {
+				t.Errorf("chunk %d has %d tokens, want <= 30", i+1, n)
+			}
+		}
+		var joined strings.Builder
+		for _, c := range chunks {
+			joined.WriteString(strings.ReplaceAll(c.Text, "\n", ""))
+		}
+		if want := strings.Repeat("う", 25) + strings.Repeat("え", 25) + strings.Repeat("お", 70); joined.String() != want {
+			t.Errorf("chunks lost text: %q", joined.String())
+		}
+	})
+}
+
+func TestEstimateTokens(t *testing.T) {
+	if got := estimateTokens("abcdefgh"); got != 2 {
+		t.Errorf("estimateTokens(ascii) = %d, want 2", got)
+	}
+	if got := estimateTokens("あいう"); got != 3 {
+		t.Errorf("estimateTokens(kana) = %d, want 3", got)
+	}
+}
+
+func TestMergeChapters(t *testing.T) {
+	merged := mergeChapters([]*ports.MangaResponse{
+		{Title: "一", Description: "d1", Panels: []ports.Panel{{Page: 1}, {Page: 1}, {Page: 2}}},
+		{Title: "二", Panels: []ports.Panel{{Page: 1}, {Page: 0}, {Page: 2}}},
+		{Title: "三", Description: "d3", Panels: []ports.Panel{{}}},
+	})
+	if merged.Title != "一" || merged.Description != "d1\nd3" {
+		t.Errorf("Title = %q, Description = %q", merged.Title, merged.Description)
+	}
+	var pages []int
+	for _, p := range merged.Panels {
+		pages = append(pages, p.Page)
+	}
+	if fmt.Sprint(pages) != "[1 1 2 3 3 4 5]" {
+		t.Errorf("pages = %v, want [1 1 2 3 3 4 5]", pages)
+	}
+}
+
+func TestMangaScriptRunner_LongDocument(t *testing.T) {
+	reader := &mangakittest.ContentReader{}
+	source := "# 第1章\n" + strings.Repeat("あ", 60) + "\n\n# 第2章\n" + strings.Repeat("い", 60) + "\n"
+	reader.SetFile("https://example.com/novel.txt", []byte(source))
+
+	chapter := 0
+	// 要約は GenerateContent、章の台本はスキーマ付きの GenerateStructured で生成されます。
+	ai := &mangakittest.ContentGenerator{
+		Text: "- beat",
+		StructuredFunc: func(context.Context, string, string, map[string]any) (string, error) {
+			chapter++
+			return fmt.Sprintf(`{"title":"章%d","panels":[{"page":1,"visual_anchor":"v","speaker_id":"zundamon"},{"page":2,"visual_anchor":"v","speaker_id":"zundamon"}]}`, chapter), nil
+		},
+	}
+	prompts := &mangakittest.ScriptPrompt{}
+	sr := NewMangaScriptRunner(prompts, ai, reader, "model", WithScriptLongDocument(80))
+
+	manga, err := sr.Run(context.Background(), "https://example.com/novel.txt", "dialogue")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(ai.Calls()) != 4 {
+		t.Errorf("calls = %d, want 2 summaries + 2 chapters", len(ai.Calls()))
+	}
+	if manga.Title != "章1" || len(manga.Panels) != 4 || manga.Panels[3].Page != 4 {
+		t.Errorf("manga = %+v, want 4 panels over 4 continuous pages", manga)
+	}
+
+	calls := prompts.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("Build calls = %d, want one per chapter", len(calls))
+	}
+	second := calls[1].Data.InputText
+	for _, want := range []string{"### 第 1 部: 第1章", "- beat", "第 2/2 部", strings.Repeat("い", 60)} {
+		if !strings.Contains(second, want) {
+			t.Errorf("chapter input lacks %q:\n%s", want, second)
+		}
+	}
+	if strings.Contains(second, strings.Repeat("あ", 60)) {
+		t.Error("chapter input should not contain the source text of other chapters")
+	}
+}
@@ -0,0 +1,90 @@
+package runner
+
+import (
+	"cmp"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/shouni/go-manga-kit/ports"
+	"github.com/shouni/go-manga-kit/validate"
+)
+
+// ruleParse は、応答を JSON として解析できなかったことを表す問題の規則 ID です。
+const ruleParse = "parse"
+
+// check は応答テキストを台本として解析し、話者の解決が有効な場合は SpeakerID を書き換え、
+// 修復または検証が有効な場合は内容を検証します。issues は重大度 error の指摘（解決できなかった
+// 話者を含む）で、警告はログに出力します。解析に失敗した場合は、その原因を1件の問題として issues にも含めます。
+func (r *MangaScriptRunner) check(text string) (*ports.MangaResponse, []ports.ScriptIssue, error) {
+	manga, err := r.parseResponse(text)
+	if err != nil {
+		message := err.Error()
+		var parseErr *ports.ScriptParseError
+		if errors.As(err, &parseErr) {
+			message = parseErr.Err.Error()
+		}
+		return nil, []ports.ScriptIssue{{
+			Severity: ports.SeverityError,
+			RuleID:   ruleParse,
+			Message:  "JSON として解析できません: " + message,
+		}}, err
+	}
+
+	var issues []ports.ScriptIssue
+	if r.speakers != nil {
+		report := r.speakers.Apply(manga)
+		for _, rw := range report.Rewrites {
+			slog.Info("ScriptRunner: 話者をキャラクターIDに解決しました",
+				"panel_index", rw.PanelIndex, "from", rw.From, "to", rw.To, "method", rw.Method)
+		}
+		issues = report.Issues()
+	}
+	// Lines だけで書かれたパネルは、最初の話者を SpeakerID として画像の参照に使います。
+	manga.Normalize()
+	if r.repairAttempts == 0 && !r.validation {
+		return manga, issues, nil
+	}
+
+	type issueKey struct {
+		panel int
+		field string
+	}
+	unresolved := make(map[issueKey]struct{}, len(issues))
+	for _, issue := range issues {
+		unresolved[issueKey{issue.PanelIndex, issue.Field}] = struct{}{}
+	}
+	for _, f := range validate.Validate(manga, r.characters, r.rules) {
+		if f.Severity == ports.SeverityWarning {
+			slog.Warn("ScriptRunner: 台本の検証で警告がありました", "rule", f.RuleID, "panel_index", f.PanelIndex, "message", f.Message)
+			continue
+		}
+		if _, dup := unresolved[issueKey{f.PanelIndex, f.Field}]; dup && f.RuleID == validate.RuleUnknownSpeaker {
+			continue
+		}
+		if f.Severity == ports.SeverityError {
+			issues = append(issues, f)
+		}
+	}
+	slices.SortStableFunc(issues, func(a, b ports.ScriptIssue) int { return cmp.Compare(a.PanelIndex, b.PanelIndex) })
+	return manga, issues, nil
+}
+
+// buildRepairPrompt は、前回の応答と問題の一覧を添えて修正済みの台本を依頼するプロンプトを作成します。
+func buildRepairPrompt(originalPrompt, previous string, issues []ports.ScriptIssue) string {
+	var b strings.Builder
+	b.WriteString(originalPrompt)
+	b.WriteString("\n\n---\n\n")
+	b.WriteString("上記の指示に対するあなたの前回の出力には、次の問題がありました。\n\n")
+	for _, issue := range issues {
+		b.WriteString("- ")
+		b.WriteString(issue.String())
+		b.WriteString("\n")
+	}
+	b.WriteString("\n前回の出力:\n```json\n")
+	b.WriteString(strings.TrimSpace(previous))
+	b.WriteString("\n```\n\n")
+	b.WriteString("すべての問題を修正した台本 JSON の全体を出力してください。問題の無い部分は変更せず、JSON 以外の文章は含めないでください。\n")
+	return b.String()
+}
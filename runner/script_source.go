@@ -0,0 +1,155 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// defaultSourceSeparator は複数の入力を連結する既定の区切り文字です。
+const defaultSourceSeparator = "\n\n---\n\n"
+
+// readSources は sources を読み込んで1つの入力テキストに連結し、台本に記録する出典を返します。
+// 入力が1つの場合は見出しを付けずにそのまま返します。複数の場合は各入力に「【ソース: ラベル】」の
+// 見出しを付けて区切り文字で連結し、パネルごとに元の入力のラベルを記入するよう指示を添えます。
+func (r *MangaScriptRunner) readSources(ctx context.Context, sources []ports.Source) (string, []ports.SourceRef, error) {
+	if len(sources) == 0 {
+		return "", nil, errors.New("入力が指定されていません")
+	}
+	refs := sourceRefs(sources)
+	texts := make([]string, len(sources))
+	for i, src := range sources {
+		slog.InfoContext(ctx, "ScriptRunner: 入力を読み込みます", "label", refs[i].Label, "kind", refs[i].Kind)
+		extracted, err := r.readSource(ctx, src, refs[i].Label)
+		if err != nil {
+			return "", nil, fmt.Errorf("入力 %q の読み込みに失敗しました: %w", refs[i].Label, err)
+		}
+		refs[i].Title = extracted.Title
+		texts[i] = extracted.Text
+	}
+	if len(sources) == 1 {
+		return texts[0], refs, nil
+	}
+
+	parts := make([]string, len(texts))
+	for i, text := range texts {
+		parts[i] = fmt.Sprintf("【ソース: %s】\n\n%s", refs[i].Label, strings.TrimSpace(text))
+	}
+	var b strings.Builder
+	b.WriteString(strings.Join(parts, r.sourceSeparator))
+	b.WriteString("\n\n各パネルの source には、そのパネルの元になった【ソース: ラベル】のラベルをそのまま記入してください。\n")
+	return b.String(), refs, nil
+}
+
+// readSource は1つの入力を読み込み、形式に応じた抽出器で本文とタイトルを抽出します。
+// テキストも maxInputSize を上限とします。
+func (r *MangaScriptRunner) readSource(ctx context.Context, src ports.Source, label string) (*ports.ExtractedText, error) {
+	var data []byte
+	var err error
+	switch src.Kind() {
+	case ports.SourceKindReader:
+		data, err = r.readLimited(ctx, src.Reader, label)
+	case ports.SourceKindURI:
+		data, err = r.readContent(ctx, src.URI)
+	case ports.SourceKindText:
+		data, err = r.readLimited(ctx, strings.NewReader(src.Text), label)
+	default:
+		err = errors.New("テキスト・URI・Reader のいずれも指定されていません")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if r.extractors == nil {
+		return &ports.ExtractedText{Text: string(data)}, nil
+	}
+	extracted, err := r.extractors.Extract(ctx, src.ContentType, src.URI, data)
+	if err != nil {
+		return nil, fmt.Errorf("本文の抽出に失敗しました: %w", err)
+	}
+	// 圧縮された PDF などは抽出後の本文が入力より大きくなり得るため、プロンプトに渡す前に再度制限します。
+	if int64(len(extracted.Text)) > r.maxInputSize {
+		slog.WarnContext(ctx, "抽出した本文が制限サイズに達したため切り捨てられました",
+			"source", label,
+			"limit_bytes", r.maxInputSize)
+		extracted.Text = truncateUTF8(extracted.Text, r.maxInputSize)
+	}
+	return extracted, nil
+}
+
+// truncateUTF8 は s を n バイト以内に、UTF-8 の文字の途中で切らないように切り詰めます。
+func truncateUTF8(s string, n int64) string {
+	if int64(len(s)) <= n {
+		return s
+	}
+	end := int(n)
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end]
+}
+
+// sourceRefs は sources の出典を返します。ラベルが空の入力には URI または "source-N" を使い、
+// 重複したラベルには "#2" のような番号を付けて一意にします。
+func sourceRefs(sources []ports.Source) []ports.SourceRef {
+	refs := make([]ports.SourceRef, len(sources))
+	seen := make(map[string]int, len(sources))
+	for i, src := range sources {
+		label := strings.TrimSpace(src.Label)
+		if label == "" {
+			label = src.URI
+		}
+		if label == "" {
+			label = fmt.Sprintf("source-%d", i+1)
+		}
+		seen[label]++
+		if n := seen[label]; n > 1 {
+			label = fmt.Sprintf("%s#%d", label, n)
+		}
+		refs[i] = ports.SourceRef{Label: label, Kind: src.Kind()}
+		if refs[i].Kind == ports.SourceKindURI {
+			refs[i].URI = src.URI
+		}
+	}
+	return refs
+}
+
+// recordProvenance は manga に出典を記録します。入力が1つの場合はすべてのパネルの Source をその入力の
+// ラベルにし、複数の場合は出典に無いラベルを取り除きます（元の入力が分からないパネルは空になります）。
+// AI がタイトルを付けなかった場合は、入力から抽出した最初のタイトルを使います。
+func recordProvenance(ctx context.Context, manga *ports.MangaResponse, refs []ports.SourceRef) {
+	manga.Sources = refs
+	if strings.TrimSpace(manga.Title) == "" {
+		for _, ref := range refs {
+			if ref.Title != "" {
+				manga.Title = ref.Title
+				break
+			}
+		}
+	}
+	if len(refs) == 1 {
+		for i := range manga.Panels {
+			manga.Panels[i].Source = refs[0].Label
+		}
+		return
+	}
+	known := make(map[string]struct{}, len(refs))
+	for _, ref := range refs {
+		known[ref.Label] = struct{}{}
+	}
+	for i := range manga.Panels {
+		panel := &manga.Panels[i]
+		if panel.Source == "" {
+			continue
+		}
+		if _, ok := known[panel.Source]; !ok {
+			slog.WarnContext(ctx, "パネルの出典が入力のラベルと一致しないため取り除きます",
+				"panel_index", i+1, "source", panel.Source)
+			panel.Source = ""
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package runner
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/shouni/go-manga-kit/extract"
+	"github.com/shouni/go-manga-kit/mangakittest"
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+func TestMangaScriptRunner_RunSourcesRecordsProvenance(t *testing.T) {
+	reader := &mangakittest.ContentReader{}
+	reader.SetFile("https://example.com/a.txt", []byte("記事の本文"))
+	prompts := &mangakittest.ScriptPrompt{}
+	ai := &mangakittest.ContentGenerator{Text: `{"title":"t","panels":[
+{"page":1,"source":"メモ"},
+{"page":1,"source":"https://example.com/a.txt"},
+{"page":2,"source":"存在しない"},
+{"page":2}]}`}
+	sr := NewMangaScriptRunner(prompts, ai, reader, "model", WithScriptSourceSeparator("\n=====\n"))
+
+	manga, err := sr.RunSources(context.Background(), []ports.Source{
+		{Label: "メモ", Text: "手書きのメモ"},
+		{URI: "https://example.com/a.txt"},
+		{Reader: strings.NewReader("議事録の本文")},
+	}, "dialogue")
+	if err != nil {
+		t.Fatalf("RunSources failed: %v", err)
+	}
+
+	wantRefs := []ports.SourceRef{
+		{Label: "メモ", Kind: ports.SourceKindText},
+		{Label: "https://example.com/a.txt", Kind: ports.SourceKindURI, URI: "https://example.com/a.txt"},
+		{Label: "source-3", Kind: ports.SourceKindReader},
+	}
+	if !slices.Equal(manga.Sources, wantRefs) {
+		t.Errorf("Sources = %+v, want %+v", manga.Sources, wantRefs)
+	}
+	var got []string
+	for _, p := range manga.Panels {
+		got = append(got, p.Source)
+	}
+	if want := []string{"メモ", "https://example.com/a.txt", "", ""}; !slices.Equal(got, want) {
+		t.Errorf("panel sources = %q, want %q", got, want)
+	}
+
+	input := prompts.Calls()[0].Data.InputText
+	for _, want := range []string{
+		"【ソース: メモ】\n\n手書きのメモ\n=====\n【ソース: https://example.com/a.txt】\n\n記事の本文",
+		"【ソース: source-3】\n\n議事録の本文",
+	} {
+		if !strings.Contains(input, want) {
+			t.Errorf("input text does not contain %q:\n%s", want, input)
+		}
+	}
+}
+
+func TestMangaScriptRunner_RunSingleSourceLabelsEveryPanel(t *testing.T) {
+	reader := &mangakittest.ContentReader{}
+	reader.SetFile("https://example.com/a.txt", []byte("本文"))
+	prompts := &mangakittest.ScriptPrompt{}
+	ai := &mangakittest.ContentGenerator{Text: `{"title":"t","panels":[{"page":1},{"page":1,"source":"x"}]}`}
+	sr := NewMangaScriptRunner(prompts, ai, reader, "model")
+
+	manga, err := sr.Run(context.Background(), "https://example.com/a.txt", "dialogue")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got := prompts.Calls()[0].Data.InputText; got != "本文" {
+		t.Errorf("input text = %q, want the source text without a header", got)
+	}
+	if len(manga.Sources) != 1 || manga.Sources[0].Label != "https://example.com/a.txt" {
+		t.Errorf("Sources = %+v", manga.Sources)
+	}
+	for i, p := range manga.Panels {
+		if p.Source != "https://example.com/a.txt" {
+			t.Errorf("panel %d Source = %q", i+1, p.Source)
+		}
+	}
+}
+
+func TestSourceRefs_DeduplicatesLabels(t *testing.T) {
+	refs := sourceRefs([]ports.Source{{Label: "a", Text: "1"}, {Label: "a", Text: "2"}, {Text: "3"}})
+	var got []string
+	for _, ref := range refs {
+		got = append(got, ref.Label)
+	}
+	if want := []string{"a", "a#2", "source-3"}; !slices.Equal(got, want) {
+		t.Errorf("labels = %q, want %q", got, want)
+	}
+}
+
+func TestMangaScriptRunner_RunSourcesRejectsEmptySource(t *testing.T) {
+	sr := NewMangaScriptRunner(&mangakittest.ScriptPrompt{}, &mangakittest.ContentGenerator{}, &mangakittest.ContentReader{}, "model")
+	if _, err := sr.RunSources(context.Background(), nil, "dialogue"); err == nil {
+		t.Error("RunSources(nil) succeeded, want an error")
+	}
+	if _, err := sr.RunSources(context.Background(), []ports.Source{{Label: "空"}}, "dialogue"); err == nil {
+		t.Error("RunSources with an empty source succeeded, want an error")
+	}
+}
+
+func TestMangaScriptRunner_ExtractsSourceText(t *testing.T) {
+	reader := &mangakittest.ContentReader{}
+	reader.SetFile("https://example.com/post.html", []byte(`<html><head><title>記事の題名</title><script>track()</script></head>
+<body><nav>メニュー</nav><p>記事の本文です。</p></body></html>`))
+	prompts := &mangakittest.ScriptPrompt{}
+	ai := &mangakittest.ContentGenerator{Text: `{"title":"","panels":[{"page":1}]}`}
+
+	t.Run("default extractors", func(t *testing.T) {
+		sr := NewMangaScriptRunner(prompts, ai, reader, "model")
+		manga, err := sr.Run(context.Background(), "https://example.com/post.html", "dialogue")
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		calls := prompts.Calls()
+		if got := calls[len(calls)-1].Data.InputText; got != "記事の本文です。" {
+			t.Errorf("input text = %q, want the extracted body", got)
+		}
+		if manga.Title != "記事の題名" || manga.Sources[0].Title != "記事の題名" {
+			t.Errorf("Title = %q, Sources = %+v; want the extracted title", manga.Title, manga.Sources)
+		}
+	})
+
+	t.Run("extraction disabled", func(t *testing.T) {
+		sr := NewMangaScriptRunner(prompts, ai, reader, "model", WithScriptExtractors(nil))
+		manga, err := sr.Run(context.Background(), "https://example.com/post.html", "dialogue")
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		calls := prompts.Calls()
+		if got := calls[len(calls)-1].Data.InputText; !strings.Contains(got, "<script>") {
+			t.Errorf("input text = %q, want the raw HTML", got)
+		}
+		if manga.Title != "" {
+			t.Errorf("Title = %q, want it empty", manga.Title)
+		}
+	})
+}
+
+// expandingExtractor は入力を繰り返した本文を返す抽出器です（圧縮された PDF の展開を模します）。
+type expandingExtractor struct{ times int }
+
+func (e expandingExtractor) Extract(_ context.Context, data []byte) (*ports.ExtractedText, error) {
+	return &ports.ExtractedText{Text: strings.Repeat(string(data), e.times)}, nil
+}
+
+func TestMangaScriptRunner_TruncatesExtractedText(t *testing.T) {
+	reg := extract.NewRegistry()
+	reg.SetFallback(expandingExtractor{times: 10})
+	prompts := &mangakittest.ScriptPrompt{}
+	ai := &mangakittest.ContentGenerator{Text: `{"title":"","panels":[{"page":1}]}`}
+	sr := NewMangaScriptRunner(prompts, ai, nil, "model", WithScriptExtractors(reg))
+	sr.maxInputSize = 20
+
+	if _, err := sr.RunSources(context.Background(), []ports.Source{{Text: "ずんだもち"}}, "dialogue"); err != nil {
+		t.Fatalf("RunSources failed: %v", err)
+	}
+	// 入力（15 バイト）は上限内ですが、抽出後の本文（150 バイト）を 20 バイトに収まる
+	// 6 文字までに切り詰め、文字の途中では切りません。
+	if got := prompts.Calls()[0].Data.InputText; got != "ずんだもちず" {
+		t.Errorf("input text = %q, want %q", got, "ずんだもちず")
+	}
+}
@@ -0,0 +1,257 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	characterkit "github.com/shouni/go-character-kit/character"
+	"github.com/shouni/go-gemini-client/gemini"
+
+	"github.com/shouni/go-manga-kit/mangakittest"
+	"github.com/shouni/go-manga-kit/ports"
+	"github.com/shouni/go-manga-kit/speaker"
+	"github.com/shouni/go-manga-kit/validate"
+)
+
+const referenceScript = `{"title":"t","panels":[
+{"speaker_id":"zundamon","reference_url":"http://169.254.169.254/latest/meta-data/"},
+{"speaker_id":"zundamon","reference_url":"https://cdn.example.com/bg.png"},
+{"speaker_id":"zundamon","reference_url":"gs://private-bucket/secret.png"}]}`
+
+func TestMangaScriptRunner_StripsAIReferenceURLsByDefault(t *testing.T) {
+	reader := &mangakittest.ContentReader{}
+	reader.SetFile("https://example.com/a.txt", []byte("本文"))
+	sr := NewMangaScriptRunner(&mangakittest.ScriptPrompt{}, &mangakittest.ContentGenerator{Text: referenceScript}, reader, "model")
+
+	manga, err := sr.Run(context.Background(), "https://example.com/a.txt", "dialogue")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	for i, p := range manga.Panels {
+		if p.ReferenceURL != "" {
+			t.Errorf("panel %d ReferenceURL = %q, want it stripped", i+1, p.ReferenceURL)
+		}
+	}
+}
+
+func TestMangaScriptRunner_FiltersAIReferenceURLsByPolicy(t *testing.T) {
+	reader := &mangakittest.ContentReader{}
+	reader.SetFile("https://example.com/a.txt", []byte("本文"))
+	policy := ports.DefaultReferencePolicy()
+	policy.AllowAIReferenceURLs = true
+	policy.AllowedGCSPrefixes = []string{"gs://public-assets/"}
+	policy.Resolver = &mangakittest.Resolver{}
+	sr := NewMangaScriptRunner(&mangakittest.ScriptPrompt{}, &mangakittest.ContentGenerator{Text: referenceScript}, reader, "model",
+		WithScriptReferencePolicy(policy),
+	)
+
+	manga, err := sr.Run(context.Background(), "https://example.com/a.txt", "dialogue")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	want := []string{"", "https://cdn.example.com/bg.png", ""}
+	for i, p := range manga.Panels {
+		if p.ReferenceURL != want[i] {
+			t.Errorf("panel %d ReferenceURL = %q, want %q", i+1, p.ReferenceURL, want[i])
+		}
+	}
+}
+
+func TestMangaScriptRunner_StructuredOutput(t *testing.T) {
+	ctx := context.Background()
+	reader := &mangakittest.ContentReader{}
+	reader.SetFile("https://example.com/a.txt", []byte("本文"))
+	// 波括弧を含むセリフは、最初と最後の波括弧による抽出では壊れやすい入力です。
+	script := `{"title":"t","description":"d","panels":[{"page":1,"visual_anchor":"v","dialogue":"{えっ}","speaker_id":"zundamon"}]}`
+
+	t.Run("uses the schema when the client supports it", func(t *testing.T) {
+		ai := &mangakittest.ContentGenerator{Text: script}
+		sr := NewMangaScriptRunner(&mangakittest.ScriptPrompt{}, ai, reader, "model")
+		manga, err := sr.Run(ctx, "https://example.com/a.txt", "dialogue")
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if manga.Panels[0].Dialogue != "{えっ}" {
+			t.Errorf("Dialogue = %q", manga.Panels[0].Dialogue)
+		}
+		calls := ai.Calls()
+		if len(calls) != 1 || calls[0].Schema["type"] != "object" {
+			t.Errorf("calls = %+v, want one GenerateStructured call with the MangaResponse schema", calls)
+		}
+	})
+
+	t.Run("extracts JSON from clients without schema support", func(t *testing.T) {
+		ai := &mangakittest.ContentGenerator{Text: "```json\n" + script + "\n```"}
+		// gemini.ContentGenerator だけを公開し、GenerateStructured を隠します。
+		client := struct{ gemini.ContentGenerator }{ai}
+		sr := NewMangaScriptRunner(&mangakittest.ScriptPrompt{}, client, reader, "model")
+		manga, err := sr.Run(ctx, "https://example.com/a.txt", "dialogue")
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if manga.Title != "t" || len(ai.Calls()) != 1 {
+			t.Errorf("Title = %q, GenerateContent calls = %d", manga.Title, len(ai.Calls()))
+		}
+	})
+
+	t.Run("falls back to extraction when disabled", func(t *testing.T) {
+		ai := &mangakittest.ContentGenerator{Text: "台本です:\n```json\n" + script + "\n```"}
+		sr := NewMangaScriptRunner(&mangakittest.ScriptPrompt{}, ai, reader, "model", WithScriptStructuredOutput(false))
+		manga, err := sr.Run(ctx, "https://example.com/a.txt", "dialogue")
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if calls := ai.Calls(); manga.Title != "t" || len(calls) != 1 || calls[0].Schema != nil {
+			t.Errorf("Title = %q, calls = %+v", manga.Title, calls)
+		}
+	})
+}
+
+func TestMangaScriptRunner_RepairsScript(t *testing.T) {
+	ctx := context.Background()
+	reader := &mangakittest.ContentReader{}
+	reader.SetFile("https://example.com/a.txt", []byte("本文"))
+	cm, err := characterkit.NewCharacters([]ports.Character{{ID: "zundamon", Name: "ずんだもん", IsDefault: true}})
+	if err != nil {
+		t.Fatalf("NewCharacters failed: %v", err)
+	}
+	broken := `{"title":"t","panels":[{"page":1,"visual_anchor":"","dialogue":"a","speaker_id":"ghost"}]}`
+	fixed := `{"title":"t","panels":[{"page":1,"visual_anchor":"教室","dialogue":"a","speaker_id":"zundamon"}]}`
+
+	t.Run("sends the previous output and issues back", func(t *testing.T) {
+		ai := &mangakittest.ContentGenerator{Responses: []string{"oops {", broken, fixed}}
+		sr := NewMangaScriptRunner(&mangakittest.ScriptPrompt{}, ai, reader, "model",
+			WithScriptCharacters(cm), WithScriptRepairAttempts(3))
+		manga, err := sr.Run(ctx, "https://example.com/a.txt", "dialogue")
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if manga.Panels[0].SpeakerID != "zundamon" {
+			t.Errorf("SpeakerID = %q", manga.Panels[0].SpeakerID)
+		}
+		calls := ai.Calls()
+		if len(calls) != 3 {
+			t.Fatalf("calls = %d, want 3", len(calls))
+		}
+		if !strings.Contains(calls[1].Prompt, "oops {") || !strings.Contains(calls[1].Prompt, "JSON として解析できません") {
+			t.Errorf("first repair prompt lacks the previous output or parse error:\n%s", calls[1].Prompt)
+		}
+		for _, want := range []string{broken, "panel 1 visual_anchor", `panel 1 speaker_id: 未定義の話者IDです: "ghost"`} {
+			if !strings.Contains(calls[2].Prompt, want) {
+				t.Errorf("second repair prompt lacks %q:\n%s", want, calls[2].Prompt)
+			}
+		}
+	})
+
+	t.Run("returns the last issues when attempts run out", func(t *testing.T) {
+		ai := &mangakittest.ContentGenerator{Text: broken}
+		sr := NewMangaScriptRunner(&mangakittest.ScriptPrompt{}, ai, reader, "model",
+			WithScriptCharacters(cm), WithScriptRepairAttempts(2))
+		_, err := sr.Run(ctx, "https://example.com/a.txt", "dialogue")
+
+		var verr *ports.ScriptValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("err = %v, want *ScriptValidationError", err)
+		}
+		if !errors.Is(err, ports.ErrInvalidScript) || errors.Is(err, ports.ErrScriptParse) {
+			t.Errorf("err = %v, want ErrInvalidScript only", err)
+		}
+		if verr.Attempts != 3 || len(ai.Calls()) != 3 {
+			t.Errorf("Attempts = %d, calls = %d, want 3", verr.Attempts, len(ai.Calls()))
+		}
+		want := []ports.ScriptIssue{
+			{Severity: ports.SeverityError, PanelIndex: 1, RuleID: validate.RuleBlankVisualAnchor, Field: "visual_anchor", Message: "情景の描写が空です"},
+			{Severity: ports.SeverityError, PanelIndex: 1, RuleID: validate.RuleUnknownSpeaker, Field: "speaker_id", Message: `未定義の話者IDです: "ghost"`},
+		}
+		if !slices.Equal(verr.Issues, want) {
+			t.Errorf("Issues = %+v, want %+v", verr.Issues, want)
+		}
+	})
+
+	t.Run("reports a parse failure as ErrScriptParse", func(t *testing.T) {
+		ai := &mangakittest.ContentGenerator{Text: "not json"}
+		sr := NewMangaScriptRunner(&mangakittest.ScriptPrompt{}, ai, reader, "model", WithScriptRepairAttempts(1))
+		_, err := sr.Run(ctx, "https://example.com/a.txt", "dialogue")
+		if !errors.Is(err, ports.ErrInvalidScript) || !errors.Is(err, ports.ErrScriptParse) {
+			t.Errorf("err = %v, want ErrInvalidScript wrapping ErrScriptParse", err)
+		}
+	})
+}
+
+func TestMangaScriptRunner_ValidationGate(t *testing.T) {
+	reader := &mangakittest.ContentReader{}
+	reader.SetFile("https://example.com/a.txt", []byte("本文"))
+	script := `{"title":"t","panels":[{"page":1,"visual_anchor":"教室","dialogue":"a","speaker_id":"zundamon"},{"page":0,"visual_anchor":"廊下","dialogue":"b","speaker_id":"zundamon"}]}`
+
+	ai := &mangakittest.ContentGenerator{Text: script}
+	sr := NewMangaScriptRunner(&mangakittest.ScriptPrompt{}, ai, reader, "model", WithScriptValidation(validate.DefaultRules()))
+	_, err := sr.Run(context.Background(), "https://example.com/a.txt", "dialogue")
+
+	var verr *ports.ScriptValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("err = %v, want *ScriptValidationError", err)
+	}
+	if len(verr.Issues) != 1 || verr.Issues[0].RuleID != validate.RuleMissingPage || verr.Issues[0].PanelIndex != 2 {
+		t.Errorf("Issues = %+v, want missing-page on panel 2", verr.Issues)
+	}
+	if len(ai.Calls()) != 1 {
+		t.Errorf("calls = %d, want 1 (no repair)", len(ai.Calls()))
+	}
+}
+
+func TestMangaScriptRunner_ResolvesSpeakers(t *testing.T) {
+	ctx := context.Background()
+	reader := &mangakittest.ContentReader{}
+	reader.SetFile("https://example.com/a.txt", []byte("本文"))
+	resolver, err := speaker.NewResolver([]ports.Character{
+		{ID: "zundamon", Name: "ずんだもん", IsDefault: true},
+		{ID: "metan", Name: "四国めたん"},
+	})
+	if err != nil {
+		t.Fatalf("NewResolver failed: %v", err)
+	}
+	script := `{"title":"t","panels":[{"page":1,"visual_anchor":"教室","speaker_id":"ずんだもん"},{"page":1,"visual_anchor":"廊下","speaker_id":"Metan"}]}`
+
+	t.Run("rewrites names to IDs", func(t *testing.T) {
+		ai := &mangakittest.ContentGenerator{Text: script}
+		sr := NewMangaScriptRunner(&mangakittest.ScriptPrompt{}, ai, reader, "model", WithScriptSpeakerResolver(resolver))
+		manga, err := sr.Run(ctx, "https://example.com/a.txt", "dialogue")
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if manga.Panels[0].SpeakerID != "zundamon" || manga.Panels[1].SpeakerID != "metan" {
+			t.Errorf("speakers = %q, %q", manga.Panels[0].SpeakerID, manga.Panels[1].SpeakerID)
+		}
+	})
+
+	t.Run("reports unresolved speakers instead of falling back", func(t *testing.T) {
+		ai := &mangakittest.ContentGenerator{Text: `{"title":"t","panels":[{"page":1,"visual_anchor":"教室","speaker_id":"謎の人物"}]}`}
+		sr := NewMangaScriptRunner(&mangakittest.ScriptPrompt{}, ai, reader, "model", WithScriptSpeakerResolver(resolver))
+		_, err := sr.Run(ctx, "https://example.com/a.txt", "dialogue")
+		var verr *ports.ScriptValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("err = %v, want *ScriptValidationError", err)
+		}
+		if len(verr.Issues) != 1 || verr.Issues[0].RuleID != validate.RuleUnknownSpeaker {
+			t.Errorf("Issues = %+v", verr.Issues)
+		}
+	})
+
+	t.Run("does not duplicate validator findings", func(t *testing.T) {
+		cm, err := characterkit.NewCharacters([]ports.Character{{ID: "zundamon", IsDefault: true}, {ID: "metan"}})
+		if err != nil {
+			t.Fatalf("NewCharacters failed: %v", err)
+		}
+		ai := &mangakittest.ContentGenerator{Text: `{"title":"t","panels":[{"page":1,"visual_anchor":"教室","speaker_id":"謎の人物"}]}`}
+		sr := NewMangaScriptRunner(&mangakittest.ScriptPrompt{}, ai, reader, "model",
+			WithScriptSpeakerResolver(resolver), WithScriptCharacters(cm), WithScriptValidation(validate.DefaultRules()))
+		_, err = sr.Run(ctx, "https://example.com/a.txt", "dialogue")
+		var verr *ports.ScriptValidationError
+		if !errors.As(err, &verr) || len(verr.Issues) != 1 {
+			t.Fatalf("err = %v, want a single unknown-speaker issue", err)
+		}
+	})
+}
@@ -0,0 +1,22 @@
+package runner
+
+import (
+	"context"
+
+	"github.com/shouni/go-manga-kit/ports"
+	"github.com/shouni/go-manga-kit/validate"
+)
+
+// validationGate は画像生成の前に台本を検証します。nil の場合は検証しません。
+type validationGate struct {
+	characters *ports.Characters
+	rules      validate.Rules
+}
+
+// check は manga を検証し、重大度 error の指摘があればエラーを返します。
+func (g *validationGate) check(ctx context.Context, manga *ports.MangaResponse) error {
+	if g == nil {
+		return nil
+	}
+	return validate.Gate(ctx, manga, g.characters, g.rules)
+}
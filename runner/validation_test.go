@@ -0,0 +1,74 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	imagePorts "github.com/shouni/gemini-image-kit/ports"
+
+	"github.com/shouni/go-manga-kit/mangakittest"
+	"github.com/shouni/go-manga-kit/ports"
+	"github.com/shouni/go-manga-kit/validate"
+)
+
+// countingPanelsGenerator は ports.PanelsImageGenerator を実装するテスト用モックです。
+type countingPanelsGenerator struct {
+	calls int
+}
+
+func (g *countingPanelsGenerator) Execute(_ context.Context, panels []ports.Panel) ([]*imagePorts.ImageResponse, error) {
+	g.calls++
+	return make([]*imagePorts.ImageResponse, len(panels)), nil
+}
+
+// countingPagesGenerator は ports.PagesImageGenerator を実装するテスト用モックです。
+type countingPagesGenerator struct {
+	calls int
+}
+
+func (g *countingPagesGenerator) Execute(_ context.Context, _ *ports.MangaResponse) ([]*imagePorts.ImageResponse, error) {
+	g.calls++
+	return nil, nil
+}
+
+func TestImageRunners_ValidationGate(t *testing.T) {
+	ctx := context.Background()
+	manga := &ports.MangaResponse{Panels: []ports.Panel{
+		{Page: 1, SpeakerID: "zundamon", VisualAnchor: "教室", Dialogue: "a"},
+		{Page: 1, SpeakerID: "zundamon", VisualAnchor: " ", Dialogue: "b"},
+	}}
+	rules := validate.DefaultRules()
+
+	t.Run("panel runner", func(t *testing.T) {
+		gen := &countingPanelsGenerator{}
+		r := NewMangaPanelRunner(gen, &mangakittest.Writer{}, WithPanelValidation(nil, rules))
+		if _, err := r.Run(ctx, manga); !errors.Is(err, ports.ErrInvalidScript) {
+			t.Errorf("err = %v, want ErrInvalidScript", err)
+		}
+		if gen.calls != 0 {
+			t.Errorf("generator called %d times, want 0", gen.calls)
+		}
+	})
+
+	t.Run("page runner", func(t *testing.T) {
+		gen := &countingPagesGenerator{}
+		r := NewMangaPageRunner(gen, &mangakittest.Writer{}, WithPageValidation(nil, rules))
+		if _, err := r.Run(ctx, manga); !errors.Is(err, ports.ErrInvalidScript) {
+			t.Errorf("err = %v, want ErrInvalidScript", err)
+		}
+		if gen.calls != 0 {
+			t.Errorf("generator called %d times, want 0", gen.calls)
+		}
+	})
+
+	t.Run("without a gate", func(t *testing.T) {
+		gen := &countingPanelsGenerator{}
+		if _, err := NewMangaPanelRunner(gen, &mangakittest.Writer{}).Run(ctx, manga); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if gen.calls != 1 {
+			t.Errorf("generator called %d times, want 1", gen.calls)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+// Package schema は、台本 JSON（ports.MangaResponse）の JSON Schema を Go の型から生成して提供します。
+//
+// 生成したスキーマは、AI への構造化出力の指定のほか、エディタの補完や検証、他のツールとの連携に使えます。
+// 同じ内容を manga_response.schema.json としても公開しています。
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+//go:generate sh -c "go run ../cmd/mangaschema > manga_response.schema.json"
+
+const (
+	// Draft はスキーマが準拠する JSON Schema の仕様です。
+	Draft = "https://json-schema.org/draft/2020-12/schema"
+	// MangaResponseID は台本 JSON のスキーマの識別子です。
+	MangaResponseID = "https://github.com/shouni/go-manga-kit/schema/manga_response.schema.json"
+)
+
+// MangaResponse は ports.MangaResponse の JSON Schema を返します。
+// 返す値は呼び出しごとに新しく生成するため、呼び出し元で変更しても構いません。
+func MangaResponse() map[string]any {
+	s := For(reflect.TypeFor[ports.MangaResponse]())
+	s["$schema"] = Draft
+	s["$id"] = MangaResponseID
+	s["title"] = "MangaResponse"
+	return s
+}
+
+// MangaResponseJSON は MangaResponse を整形した JSON として返します。
+func MangaResponseJSON() ([]byte, error) {
+	data, err := json.MarshalIndent(MangaResponse(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("スキーマのエンコードに失敗しました: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// For は t の JSON 表現に対応する JSON Schema を生成します。
+//
+// 構造体のフィールド名・省略可否は encoding/json の json タグに従い、omitempty の無いフィールドは
+// required になります。JSON の出力は変えずに省略可能にしたいフィールドには schema:"optional" を指定します。
+// description タグの値はフィールドの description になります。
+func For(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": For(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": For(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	default:
+		return map[string]any{}
+	}
+}
+
+// structSchema は構造体 t を object のスキーマに変換します。
+func structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	required := []string{}
+	for field := range fieldsOf(t) {
+		name, omitEmpty, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+		prop := For(field.Type)
+		if desc := field.Tag.Get("description"); desc != "" {
+			prop["description"] = desc
+		}
+		properties[name] = prop
+		if !omitEmpty && field.Tag.Get("schema") != "optional" {
+			required = append(required, name)
+		}
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// fieldsOf は t の公開フィールドを宣言順に返します。埋め込み構造体のフィールドは展開します。
+func fieldsOf(t reflect.Type) func(yield func(reflect.StructField) bool) {
+	return func(yield func(reflect.StructField) bool) {
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if field.Anonymous && field.Tag.Get("json") == "" {
+				ft := field.Type
+				if ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					for f := range fieldsOf(ft) {
+						if !yield(f) {
+							return
+						}
+					}
+					continue
+				}
+			}
+			if !field.IsExported() {
+				continue
+			}
+			if !yield(field) {
+				return
+			}
+		}
+	}
+}
+
+// jsonName は json タグからフィールドの JSON 上の名前と omitempty の有無を返します。
+// json:"-" のフィールドは ok が false です。
+func jsonName(field reflect.StructField) (name string, omitEmpty, ok bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	for opt := range strings.SplitSeq(opts, ",") {
+		if opt == "omitempty" || opt == "omitzero" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, true
+}
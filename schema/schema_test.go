@@ -0,0 +1,91 @@
+package schema
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestMangaResponse_FollowsJSONTags(t *testing.T) {
+	s := MangaResponse()
+	if s["$schema"] != Draft || s["$id"] != MangaResponseID {
+		t.Errorf("meta = %v / %v", s["$schema"], s["$id"])
+	}
+	if got := s["required"].([]string); !slices.Equal(got, []string{"title", "description", "panels"}) {
+		t.Errorf("required = %v", got)
+	}
+
+	panels := s["properties"].(map[string]any)["panels"].(map[string]any)
+	if panels["type"] != "array" {
+		t.Fatalf("panels.type = %v, want array", panels["type"])
+	}
+	panel := panels["items"].(map[string]any)
+	props := panel["properties"].(map[string]any)
+	if props["page"].(map[string]any)["type"] != "integer" {
+		t.Errorf("page.type = %v, want integer", props["page"])
+	}
+	if props["speaker_id"].(map[string]any)["description"] == nil {
+		t.Error("speaker_id should carry its description tag")
+	}
+	// reference_url は schema:"optional" のため必須ではありません。
+	if required := panel["required"].([]string); slices.Contains(required, "reference_url") {
+		t.Errorf("required = %v, reference_url should be optional", required)
+	}
+}
+
+func TestFor(t *testing.T) {
+	type inner struct {
+		N float64 `json:"n"`
+	}
+	type Embedded struct {
+		E bool `json:"e"`
+	}
+	type sample struct {
+		Embedded
+		Name    string            `json:"name"`
+		Skip    string            `json:"-"`
+		Opt     *int              `json:"opt,omitempty"`
+		Tags    map[string]string `json:"tags,omitzero"`
+		Inner   inner             `json:"inner"`
+		NoTag   string
+		private string
+	}
+
+	s := For(reflect.TypeFor[*sample]())
+	props := s["properties"].(map[string]any)
+	for _, name := range []string{"e", "name", "opt", "tags", "inner", "NoTag"} {
+		if _, ok := props[name]; !ok {
+			t.Errorf("property %q is missing", name)
+		}
+	}
+	for _, name := range []string{"Skip", "-", "private"} {
+		if _, ok := props[name]; ok {
+			t.Errorf("property %q should be skipped", name)
+		}
+	}
+	if got := s["required"].([]string); !slices.Equal(got, []string{"e", "name", "inner", "NoTag"}) {
+		t.Errorf("required = %v", got)
+	}
+	if props["opt"].(map[string]any)["type"] != "integer" {
+		t.Errorf("opt = %v", props["opt"])
+	}
+	if props["tags"].(map[string]any)["additionalProperties"].(map[string]any)["type"] != "string" {
+		t.Errorf("tags = %v", props["tags"])
+	}
+}
+
+func TestSchemaFileIsUpToDate(t *testing.T) {
+	want, err := MangaResponseJSON()
+	if err != nil {
+		t.Fatalf("MangaResponseJSON failed: %v", err)
+	}
+	got, err := os.ReadFile("manga_response.schema.json")
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("manga_response.schema.json is stale; run go generate ./schema")
+	}
+}
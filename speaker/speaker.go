@@ -0,0 +1,299 @@
+// Package speaker は、台本の話者の表記（表示名・別名・表記ゆれ）をキャラクターIDに解決します。
+//
+// AI は speaker_id に "ずんだもん" や "Zundamon" のような表示名を書くことがあり、そのままでは
+// 既定のキャラクターに置き換えられて別の顔で描かれてしまいます。Resolver は完全一致の ID、表示名、
+// 別名、大文字・小文字や全角・半角などを正規化した表記、編集距離の順に照合し、解決できなかった話者を報告します。
+package speaker
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/shouni/go-manga-kit/ports"
+	"github.com/shouni/go-manga-kit/validate"
+)
+
+// defaultMaxDistance は編集距離による照合で許容する既定の最大距離です。
+const defaultMaxDistance = 2
+
+// Method は話者を解決した方法です。
+type Method string
+
+const (
+	// MethodID はキャラクターIDとの完全一致です。
+	MethodID Method = "id"
+	// MethodName は表示名との完全一致です。
+	MethodName Method = "name"
+	// MethodAlias は別名との完全一致です。
+	MethodAlias Method = "alias"
+	// MethodNormalized は正規化した ID・表示名・別名との一致です。
+	MethodNormalized Method = "normalized"
+	// MethodFuzzy は正規化した表記どうしの編集距離による一致です。
+	MethodFuzzy Method = "fuzzy"
+)
+
+// Resolver は話者の表記をキャラクターIDに解決します。作成後は読み取りのみのため並行して使えます。
+type Resolver struct {
+	ids     map[string]struct{}
+	names   map[string]string
+	aliases map[string]string
+	// keys は正規化した表記からキャラクターIDへの対応です。複数のキャラクターに対応する表記は空文字列です。
+	keys        map[string]string
+	maxDistance int
+}
+
+// Option は Resolver の設定を適用する関数型です。
+type Option func(*resolverConfig)
+
+type resolverConfig struct {
+	aliases     map[string]string
+	maxDistance int
+}
+
+// WithAliases は、別名からキャラクターIDへの対応を追加します（例: "ずんだ" → "zundamon"）。
+func WithAliases(aliases map[string]string) Option {
+	return func(c *resolverConfig) {
+		for alias, id := range aliases {
+			c.aliases[alias] = id
+		}
+	}
+}
+
+// WithMaxDistance は、編集距離による照合で許容する最大距離を設定します。0 の場合は編集距離で照合しません。
+// 距離は正規化した表記の文字数の 1/3 以下である必要もあります。既定は 2 です。
+func WithMaxDistance(n int) Option {
+	return func(c *resolverConfig) {
+		if n >= 0 {
+			c.maxDistance = n
+		}
+	}
+}
+
+// NewResolver は characters から Resolver を作成します。
+// 別名が characters に無いキャラクターIDを指す場合は *ports.CharacterNotFoundError を返します。
+func NewResolver(characters []ports.Character, opts ...Option) (*Resolver, error) {
+	cfg := &resolverConfig{aliases: make(map[string]string), maxDistance: defaultMaxDistance}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	r := &Resolver{
+		ids:         make(map[string]struct{}, len(characters)),
+		names:       make(map[string]string, len(characters)),
+		aliases:     make(map[string]string, len(cfg.aliases)),
+		keys:        make(map[string]string),
+		maxDistance: cfg.maxDistance,
+	}
+	for _, char := range characters {
+		r.ids[char.ID] = struct{}{}
+	}
+	for _, char := range characters {
+		if char.Name != "" {
+			r.addUnique(r.names, char.Name, char.ID)
+		}
+		r.addKey(char.ID, char.ID)
+		r.addKey(char.Name, char.ID)
+	}
+	var missing []string
+	for alias, id := range cfg.aliases {
+		if _, ok := r.ids[id]; !ok {
+			missing = append(missing, id)
+			continue
+		}
+		r.addUnique(r.aliases, alias, id)
+		r.addKey(alias, id)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("話者の別名の対応先が定義にありません: %w", &ports.CharacterNotFoundError{CharacterIDs: missing})
+	}
+	return r, nil
+}
+
+// addUnique は m に value を追加します。既に別の ID が登録されている表記は曖昧なため空文字列にします。
+func (r *Resolver) addUnique(m map[string]string, key, id string) {
+	if existing, ok := m[key]; ok && existing != id {
+		m[key] = ""
+		return
+	}
+	m[key] = id
+}
+
+// addKey は s を正規化した表記を id に対応付けます。
+func (r *Resolver) addKey(s, id string) {
+	if key := normalize(s); key != "" {
+		r.addUnique(r.keys, key, id)
+	}
+}
+
+// Resolve は speaker をキャラクターIDに解決します。解決できない、または複数のキャラクターに
+// 同程度に一致する場合は ok が false です。
+func (r *Resolver) Resolve(speaker string) (id string, method Method, ok bool) {
+	if _, found := r.ids[speaker]; found {
+		return speaker, MethodID, true
+	}
+	if id := r.names[speaker]; id != "" {
+		return id, MethodName, true
+	}
+	if id := r.aliases[speaker]; id != "" {
+		return id, MethodAlias, true
+	}
+	key := normalize(speaker)
+	if key == "" {
+		return "", "", false
+	}
+	if id, found := r.keys[key]; found {
+		return id, MethodNormalized, id != ""
+	}
+	if id := r.closest(key); id != "" {
+		return id, MethodFuzzy, true
+	}
+	return "", "", false
+}
+
+// closest は key との編集距離が最も小さい表記のキャラクターIDを返します。
+// 許容範囲内に無い場合や、最小距離のキャラクターが複数ある場合は空文字列です。
+func (r *Resolver) closest(key string) string {
+	if r.maxDistance == 0 {
+		return ""
+	}
+	keyRunes := []rune(key)
+	best, bestID, tie := r.maxDistance+1, "", false
+	for candidate, id := range r.keys {
+		if id == "" {
+			continue
+		}
+		candidateRunes := []rune(candidate)
+		d := levenshtein(keyRunes, candidateRunes)
+		if d*3 > len(candidateRunes) {
+			continue
+		}
+		switch {
+		case d < best:
+			best, bestID, tie = d, id, false
+		case d == best && id != bestID:
+			tie = true
+		}
+	}
+	if tie {
+		return ""
+	}
+	return bestID
+}
+
+// Rewrite は書き換えた1パネルの話者です。
+type Rewrite struct {
+	// PanelIndex はパネルの番号（1始まり）です。
+	PanelIndex int
+	// Field は書き換えたフィールドです（例: "speaker_id"、"lines[1].speaker_id"）。
+	Field  string
+	From   string
+	To     string
+	Method Method
+}
+
+// Unresolved は解決できなかった1パネルの話者です。
+type Unresolved struct {
+	// PanelIndex はパネルの番号（1始まり）です。
+	PanelIndex int
+	// Field は話者のフィールドです（例: "speaker_id"、"emotions[0].character_id"）。
+	Field   string
+	Speaker string
+}
+
+// Report は Apply の結果です。
+type Report struct {
+	Rewrites   []Rewrite
+	Unresolved []Unresolved
+}
+
+// Issues は解決できなかった話者を、重大度 error の台本の問題として返します。
+func (rep Report) Issues() []ports.ScriptIssue {
+	issues := make([]ports.ScriptIssue, 0, len(rep.Unresolved))
+	for _, u := range rep.Unresolved {
+		issues = append(issues, ports.ScriptIssue{
+			Severity:   ports.SeverityError,
+			PanelIndex: u.PanelIndex,
+			RuleID:     validate.RuleUnknownSpeaker,
+			Field:      u.Field,
+			Message:    fmt.Sprintf("話者 %q をキャラクターIDに解決できません", u.Speaker),
+		})
+	}
+	return issues
+}
+
+// Apply は manga の各パネルの SpeakerID、セリフ（Lines）の話者、感情（Emotions）のキャラクターを
+// 解決したキャラクターIDに書き換えます。空の値はそのままにし、解決できなかった話者は書き換えずに
+// Report.Unresolved で報告します。
+func (r *Resolver) Apply(manga *ports.MangaResponse) Report {
+	var rep Report
+	if manga == nil {
+		return rep
+	}
+	for i := range manga.Panels {
+		panel := &manga.Panels[i]
+		r.apply(&rep, i+1, "speaker_id", &panel.SpeakerID)
+		for j := range panel.Lines {
+			r.apply(&rep, i+1, fmt.Sprintf("lines[%d].speaker_id", j), &panel.Lines[j].SpeakerID)
+		}
+		for j := range panel.Emotions {
+			r.apply(&rep, i+1, fmt.Sprintf("emotions[%d].character_id", j), &panel.Emotions[j].CharacterID)
+		}
+	}
+	return rep
+}
+
+// apply は id を解決したキャラクターIDに書き換え、結果を rep に記録します。
+func (r *Resolver) apply(rep *Report, panelIndex int, field string, id *string) {
+	if *id == "" {
+		return
+	}
+	resolved, method, ok := r.Resolve(*id)
+	if !ok {
+		rep.Unresolved = append(rep.Unresolved, Unresolved{PanelIndex: panelIndex, Field: field, Speaker: *id})
+		return
+	}
+	if method != MethodID {
+		rep.Rewrites = append(rep.Rewrites, Rewrite{PanelIndex: panelIndex, Field: field, From: *id, To: resolved, Method: method})
+		*id = resolved
+	}
+}
+
+// normalize は照合用に表記を正規化します。NFKC で全角英数字・半角カナを揃え、小文字化し、
+// カタカナをひらがなに寄せたうえで、空白・記号を取り除きます。
+func normalize(s string) string {
+	s = norm.NFKC.String(s)
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= 'ァ' && r <= 'ヶ':
+			b.WriteRune(r - ('ァ' - 'ぁ'))
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || r == 'ー':
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+// levenshtein は a と b の編集距離を返します。
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
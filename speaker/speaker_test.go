@@ -0,0 +1,129 @@
+package speaker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shouni/go-manga-kit/ports"
+	"github.com/shouni/go-manga-kit/validate"
+)
+
+func newTestResolver(t *testing.T, opts ...Option) *Resolver {
+	t.Helper()
+	r, err := NewResolver([]ports.Character{
+		{ID: "zundamon", Name: "ずんだもん", IsDefault: true},
+		{ID: "metan", Name: "四国めたん"},
+		{ID: "tsumugi", Name: "春日部つむぎ"},
+	}, opts...)
+	if err != nil {
+		t.Fatalf("NewResolver failed: %v", err)
+	}
+	return r
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	r := newTestResolver(t, WithAliases(map[string]string{"ずんだ": "zundamon", "めたん": "metan"}))
+
+	tests := []struct {
+		speaker string
+		want    string
+		method  Method
+	}{
+		{"zundamon", "zundamon", MethodID},
+		{"ずんだもん", "zundamon", MethodName},
+		{"めたん", "metan", MethodAlias},
+		{"Zundamon", "zundamon", MethodNormalized},
+		{"ＺＵＮＤＡＭＯＮ", "zundamon", MethodNormalized},
+		{"ズンダモン", "zundamon", MethodNormalized},
+		{"ｽﾞﾝﾀﾞﾓﾝ", "zundamon", MethodNormalized},
+		{"四国 めたん", "metan", MethodNormalized},
+		{"zundamonn", "zundamon", MethodFuzzy},
+		{"春日部つむぐ", "tsumugi", MethodFuzzy},
+	}
+	for _, tt := range tests {
+		id, method, ok := r.Resolve(tt.speaker)
+		if !ok || id != tt.want || method != tt.method {
+			t.Errorf("Resolve(%q) = (%q, %q, %v), want (%q, %q, true)", tt.speaker, id, method, ok, tt.want, tt.method)
+		}
+	}
+
+	for _, speaker := range []string{"ghost", "", "!!", "met"} {
+		if id, _, ok := r.Resolve(speaker); ok {
+			t.Errorf("Resolve(%q) = %q, want unresolved", speaker, id)
+		}
+	}
+}
+
+func TestResolver_AmbiguousAndDisabledFuzzy(t *testing.T) {
+	r, err := NewResolver([]ports.Character{{ID: "a1", Name: "Sora"}, {ID: "a2", Name: "sora"}})
+	if err != nil {
+		t.Fatalf("NewResolver failed: %v", err)
+	}
+	if id, _, ok := r.Resolve("SORA"); ok {
+		t.Errorf("Resolve(SORA) = %q, want ambiguous", id)
+	}
+	if id, _, ok := r.Resolve("Sora"); !ok || id != "a1" {
+		t.Errorf("Resolve(Sora) = %q, want the exact name", id)
+	}
+
+	strict := newTestResolver(t, WithMaxDistance(0))
+	if id, _, ok := strict.Resolve("zundamonn"); ok {
+		t.Errorf("Resolve(zundamonn) = %q, want unresolved without fuzzy matching", id)
+	}
+}
+
+func TestNewResolver_UnknownAliasTarget(t *testing.T) {
+	_, err := NewResolver([]ports.Character{{ID: "zundamon"}}, WithAliases(map[string]string{"x": "ghost"}))
+	if !errors.Is(err, ports.ErrCharacterNotFound) {
+		t.Errorf("err = %v, want ErrCharacterNotFound", err)
+	}
+}
+
+func TestResolver_Apply(t *testing.T) {
+	r := newTestResolver(t)
+	manga := &ports.MangaResponse{Panels: []ports.Panel{
+		{SpeakerID: "zundamon"},
+		{SpeakerID: "Metan"},
+		{SpeakerID: ""},
+		{SpeakerID: "ghost"},
+	}}
+
+	report := r.Apply(manga)
+	want := []string{"zundamon", "metan", "", "ghost"}
+	for i, p := range manga.Panels {
+		if p.SpeakerID != want[i] {
+			t.Errorf("panel %d SpeakerID = %q, want %q", i+1, p.SpeakerID, want[i])
+		}
+	}
+	if len(report.Rewrites) != 1 || report.Rewrites[0] != (Rewrite{PanelIndex: 2, Field: "speaker_id", From: "Metan", To: "metan", Method: MethodNormalized}) {
+		t.Errorf("Rewrites = %+v", report.Rewrites)
+	}
+	if len(report.Unresolved) != 1 || report.Unresolved[0] != (Unresolved{PanelIndex: 4, Field: "speaker_id", Speaker: "ghost"}) {
+		t.Errorf("Unresolved = %+v", report.Unresolved)
+	}
+	issues := report.Issues()
+	if len(issues) != 1 || issues[0].RuleID != validate.RuleUnknownSpeaker || issues[0].Severity != ports.SeverityError {
+		t.Errorf("Issues = %+v", issues)
+	}
+}
+
+func TestResolver_ApplyResolvesLinesAndEmotions(t *testing.T) {
+	r := newTestResolver(t)
+	manga := &ports.MangaResponse{Panels: []ports.Panel{{
+		SpeakerID: "zundamon",
+		Lines:     []ports.DialogueLine{{SpeakerID: "Zundamon", Text: "a"}, {SpeakerID: "ghost", Text: "b"}},
+		Emotions:  []ports.CharacterEmotion{{CharacterID: "Metan", Emotion: "驚き"}},
+	}}}
+
+	report := r.Apply(manga)
+	panel := manga.Panels[0]
+	if panel.Lines[0].SpeakerID != "zundamon" || panel.Emotions[0].CharacterID != "metan" {
+		t.Errorf("panel = %+v, want the line speaker and emotion character resolved", panel)
+	}
+	if len(report.Unresolved) != 1 || report.Unresolved[0] != (Unresolved{PanelIndex: 1, Field: "lines[1].speaker_id", Speaker: "ghost"}) {
+		t.Errorf("Unresolved = %+v", report.Unresolved)
+	}
+	if issues := report.Issues(); len(issues) != 1 || issues[0].Field != "lines[1].speaker_id" {
+		t.Errorf("Issues = %+v", issues)
+	}
+}
@@ -0,0 +1,211 @@
+// Package validate は、台本（ports.MangaResponse）の内容を検証するリンターを提供します。
+//
+// 未定義の話者、空の描写、長すぎるセリフ、パネルの多すぎるページ、重複したパネルなど、
+// JSON としては正しくても画像生成に渡すべきでない問題を、規則ごとの指摘として返します。
+package validate
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+// Finding は検証で見つかった1件の指摘です。
+type Finding = ports.ScriptIssue
+
+// Severity は指摘の重大度です。
+type Severity = ports.Severity
+
+// SeverityOff を Rules.Severities に指定した規則は検査しません。
+const SeverityOff Severity = "off"
+
+// 規則の ID です。
+const (
+	// RuleNoPanels は、パネルが1つも無い台本を検出します。
+	RuleNoPanels = "no-panels"
+	// RuleMissingPage は、ページ番号（1 以上）の無いパネルを検出します。
+	RuleMissingPage = "missing-page"
+	// RuleBlankVisualAnchor は、情景の描写が空のパネルを検出します。
+	RuleBlankVisualAnchor = "blank-visual-anchor"
+	// RuleUnknownSpeaker は、キャラクター定義に無い話者IDを検出します。
+	RuleUnknownSpeaker = "unknown-speaker"
+	// RuleDialogueTooLong は、Rules.MaxDialogueLength を超えるセリフを検出します。
+	RuleDialogueTooLong = "dialogue-too-long"
+	// RuleUnknownDialogueKind は、定義済みの種類（speech・thought・shout・whisper）以外のセリフを検出します。
+	RuleUnknownDialogueKind = "unknown-dialogue-kind"
+	// RuleTooManyPanelsPerPage は、Rules.MaxPanelsPerPage を超えるパネルを持つページを検出します。
+	RuleTooManyPanelsPerPage = "too-many-panels-per-page"
+	// RuleDuplicatePanel は、話者・セリフ・描写が前のパネルと同じパネルを検出します。
+	RuleDuplicatePanel = "duplicate-panel"
+)
+
+const (
+	// DefaultMaxDialogueLength はセリフ1つ（吹き出し1つ）の既定の最大文字数です。
+	DefaultMaxDialogueLength = 100
+	// DefaultMaxPanelsPerPage は1ページの既定の最大パネル数です（layout の既定値と同じです）。
+	DefaultMaxPanelsPerPage = 6
+)
+
+// defaultSeverities は規則ごとの既定の重大度です。
+var defaultSeverities = map[string]Severity{
+	RuleNoPanels:             ports.SeverityError,
+	RuleMissingPage:          ports.SeverityError,
+	RuleBlankVisualAnchor:    ports.SeverityError,
+	RuleUnknownSpeaker:       ports.SeverityError,
+	RuleDialogueTooLong:      ports.SeverityWarning,
+	RuleUnknownDialogueKind:  ports.SeverityWarning,
+	RuleTooManyPanelsPerPage: ports.SeverityWarning,
+	RuleDuplicatePanel:       ports.SeverityWarning,
+}
+
+// Rules は検証の設定です。
+type Rules struct {
+	// MaxDialogueLength はセリフ1つ（吹き出し1つ）の最大文字数です。0 以下の場合は検査しません。
+	MaxDialogueLength int
+	// MaxPanelsPerPage は同じページ番号を持つパネルの最大数です。0 以下の場合は検査しません。
+	MaxPanelsPerPage int
+	// Severities は規則 ID ごとの重大度の上書きです。SeverityOff を指定した規則は検査しません。
+	Severities map[string]Severity
+}
+
+// DefaultRules は既定の設定を返します。
+func DefaultRules() Rules {
+	return Rules{
+		MaxDialogueLength: DefaultMaxDialogueLength,
+		MaxPanelsPerPage:  DefaultMaxPanelsPerPage,
+	}
+}
+
+// severity は rule の重大度を返します。
+func (r Rules) severity(rule string) Severity {
+	if s, ok := r.Severities[rule]; ok {
+		return s
+	}
+	return defaultSeverities[rule]
+}
+
+// Validate は manga を rules に従って検証し、指摘をパネル順に返します。
+// characters が nil の場合、話者IDの検査は行いません。
+func Validate(manga *ports.MangaResponse, characters *ports.Characters, rules Rules) []Finding {
+	v := &validator{rules: rules}
+	if manga == nil || len(manga.Panels) == 0 {
+		v.add(RuleNoPanels, 0, "panels", "パネルがありません")
+		return v.findings
+	}
+
+	pageCounts := make(map[int]int)
+	type panelKey struct{ speaker, dialogue, anchor string }
+	firstIndex := make(map[panelKey]int)
+	for i, panel := range manga.Panels {
+		idx := i + 1
+		if panel.Page <= 0 {
+			v.add(RuleMissingPage, idx, "page", "ページ番号（1 以上）がありません")
+		} else {
+			pageCounts[panel.Page]++
+			if rules.MaxPanelsPerPage > 0 && pageCounts[panel.Page] == rules.MaxPanelsPerPage+1 {
+				v.add(RuleTooManyPanelsPerPage, idx, "page",
+					fmt.Sprintf("ページ %d のパネルが %d 個を超えています", panel.Page, rules.MaxPanelsPerPage))
+			}
+		}
+		if strings.TrimSpace(panel.VisualAnchor) == "" {
+			v.add(RuleBlankVisualAnchor, idx, "visual_anchor", "情景の描写が空です")
+		}
+		v.checkSpeaker(characters, idx, "speaker_id", panel.SpeakerID)
+		for j, line := range panel.Lines {
+			v.checkSpeaker(characters, idx, fmt.Sprintf("lines[%d].speaker_id", j), line.SpeakerID)
+			if !line.Kind.IsKnown() {
+				v.add(RuleUnknownDialogueKind, idx, fmt.Sprintf("lines[%d].kind", j),
+					fmt.Sprintf("未知のセリフの種類です: %q", line.Kind))
+			}
+		}
+		for j, e := range panel.Emotions {
+			v.checkSpeaker(characters, idx, fmt.Sprintf("emotions[%d].character_id", j), e.CharacterID)
+		}
+		var dialogue strings.Builder
+		for j, line := range panel.DialogueLines() {
+			field := "dialogue"
+			if len(panel.Lines) > 0 {
+				field = fmt.Sprintf("lines[%d].text", j)
+			}
+			if n := utf8.RuneCountInString(line.Text); rules.MaxDialogueLength > 0 && n > rules.MaxDialogueLength {
+				v.add(RuleDialogueTooLong, idx, field,
+					fmt.Sprintf("セリフが %d 文字あり、上限の %d 文字を超えています", n, rules.MaxDialogueLength))
+			}
+			fmt.Fprintf(&dialogue, "%s\x00%s\x00", line.SpeakerID, strings.TrimSpace(line.Text))
+		}
+		key := panelKey{panel.SpeakerID, dialogue.String(), strings.TrimSpace(panel.VisualAnchor)}
+		if first, dup := firstIndex[key]; dup {
+			v.add(RuleDuplicatePanel, idx, "", fmt.Sprintf("パネル %d と同じ内容です", first))
+		} else {
+			firstIndex[key] = idx
+		}
+	}
+	return v.findings
+}
+
+// HasErrors は findings に重大度 error の指摘が含まれるかを返します。
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == ports.SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors は findings のうち重大度 error の指摘を返します。
+func Errors(findings []Finding) []Finding {
+	var errs []Finding
+	for _, f := range findings {
+		if f.Severity == ports.SeverityError {
+			errs = append(errs, f)
+		}
+	}
+	return errs
+}
+
+// Gate は生成の前段で使う検証です。Validate の警告をログに出力し、重大度 error の指摘があれば
+// それらを *ports.ScriptValidationError（ports.ErrInvalidScript）として返します。
+func Gate(ctx context.Context, manga *ports.MangaResponse, characters *ports.Characters, rules Rules) error {
+	findings := Validate(manga, characters, rules)
+	for _, f := range findings {
+		if f.Severity == ports.SeverityWarning {
+			slog.WarnContext(ctx, "台本の検証で警告がありました", "rule", f.RuleID, "panel_index", f.PanelIndex, "message", f.Message)
+		}
+	}
+	if errs := Errors(findings); len(errs) > 0 {
+		return &ports.ScriptValidationError{Issues: errs}
+	}
+	return nil
+}
+
+// validator は有効な規則の指摘を集めます。
+type validator struct {
+	rules    Rules
+	findings []Finding
+}
+
+func (v *validator) add(rule string, panelIndex int, field, message string) {
+	severity := v.rules.severity(rule)
+	if severity == SeverityOff || severity == "" {
+		return
+	}
+	v.findings = append(v.findings, Finding{
+		Severity:   severity,
+		PanelIndex: panelIndex,
+		RuleID:     rule,
+		Field:      field,
+		Message:    message,
+	})
+}
+
+// checkSpeaker は、id が characters に定義されていなければ RuleUnknownSpeaker を指摘します。
+func (v *validator) checkSpeaker(characters *ports.Characters, panelIndex int, field, id string) {
+	if characters != nil && id != "" && characters.GetCharacter(id) == nil {
+		v.add(RuleUnknownSpeaker, panelIndex, field, fmt.Sprintf("未定義の話者IDです: %q", id))
+	}
+}
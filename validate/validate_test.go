@@ -0,0 +1,150 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	characterkit "github.com/shouni/go-character-kit/character"
+
+	"github.com/shouni/go-manga-kit/ports"
+)
+
+func TestValidate(t *testing.T) {
+	cm, err := characterkit.NewCharacters([]ports.Character{
+		{ID: "zundamon", Name: "ずんだもん", IsDefault: true},
+		{ID: "metan", Name: "めたん"},
+	})
+	if err != nil {
+		t.Fatalf("NewCharacters failed: %v", err)
+	}
+	panels := []ports.Panel{
+		{Page: 1, SpeakerID: "zundamon", VisualAnchor: "教室", Dialogue: "a"},
+		{Page: 1, SpeakerID: "ghost", VisualAnchor: "廊下", Dialogue: "b"},
+		{Page: 1, SpeakerID: "metan", VisualAnchor: "", Dialogue: strings.Repeat("あ", 11)},
+		{Page: 0, SpeakerID: "zundamon", VisualAnchor: "教室", Dialogue: "a"},
+	}
+	manga := &ports.MangaResponse{Title: "t", Panels: panels}
+	rules := Rules{MaxDialogueLength: 10, MaxPanelsPerPage: 2}
+
+	got := Validate(manga, cm, rules)
+	want := []struct {
+		panel    int
+		rule     string
+		severity Severity
+	}{
+		{2, RuleUnknownSpeaker, ports.SeverityError},
+		{3, RuleTooManyPanelsPerPage, ports.SeverityWarning},
+		{3, RuleBlankVisualAnchor, ports.SeverityError},
+		{3, RuleDialogueTooLong, ports.SeverityWarning},
+		{4, RuleMissingPage, ports.SeverityError},
+		{4, RuleDuplicatePanel, ports.SeverityWarning},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("findings = %+v, want %d findings", got, len(want))
+	}
+	for i, w := range want {
+		if got[i].PanelIndex != w.panel || got[i].RuleID != w.rule || got[i].Severity != w.severity {
+			t.Errorf("finding %d = %+v, want panel %d %s (%s)", i, got[i], w.panel, w.rule, w.severity)
+		}
+	}
+	if !HasErrors(got) || len(Errors(got)) != 3 {
+		t.Errorf("Errors = %+v, want 3", Errors(got))
+	}
+
+	t.Run("severity overrides", func(t *testing.T) {
+		rules := rules
+		rules.Severities = map[string]Severity{
+			RuleUnknownSpeaker:    SeverityOff,
+			RuleBlankVisualAnchor: ports.SeverityWarning,
+			RuleMissingPage:       SeverityOff,
+		}
+		findings := Validate(manga, cm, rules)
+		if HasErrors(findings) {
+			t.Errorf("findings = %+v, want no errors", findings)
+		}
+		for _, f := range findings {
+			if f.RuleID == RuleUnknownSpeaker || f.RuleID == RuleMissingPage {
+				t.Errorf("disabled rule reported: %+v", f)
+			}
+		}
+	})
+
+	t.Run("no characters skips speaker checks", func(t *testing.T) {
+		for _, f := range Validate(manga, nil, rules) {
+			if f.RuleID == RuleUnknownSpeaker {
+				t.Errorf("unexpected finding: %+v", f)
+			}
+		}
+	})
+
+	t.Run("empty script", func(t *testing.T) {
+		findings := Validate(&ports.MangaResponse{}, cm, DefaultRules())
+		if len(findings) != 1 || findings[0].RuleID != RuleNoPanels {
+			t.Errorf("findings = %+v, want no-panels", findings)
+		}
+	})
+}
+
+func TestValidate_DialogueLines(t *testing.T) {
+	cm, err := characterkit.NewCharacters([]ports.Character{
+		{ID: "zundamon", Name: "ずんだもん", IsDefault: true},
+		{ID: "metan", Name: "めたん"},
+	})
+	if err != nil {
+		t.Fatalf("NewCharacters failed: %v", err)
+	}
+	manga := &ports.MangaResponse{Panels: []ports.Panel{
+		{
+			Page: 1, SpeakerID: "zundamon", VisualAnchor: "教室",
+			Lines: []ports.DialogueLine{
+				{SpeakerID: "zundamon", Text: "短い"},
+				{SpeakerID: "ghost", Text: strings.Repeat("あ", 11), Kind: "sing"},
+			},
+			Emotions: []ports.CharacterEmotion{{CharacterID: "metan", Emotion: "笑顔"}, {CharacterID: "nobody", Emotion: "怒り"}},
+		},
+		{
+			Page: 1, SpeakerID: "zundamon", VisualAnchor: "教室",
+			Lines: []ports.DialogueLine{{SpeakerID: "zundamon", Text: "短い"}, {SpeakerID: "metan", Text: "別"}},
+		},
+	}}
+
+	got := Validate(manga, cm, Rules{MaxDialogueLength: 10})
+	want := []struct{ rule, field string }{
+		{RuleUnknownSpeaker, "lines[1].speaker_id"},
+		{RuleUnknownDialogueKind, "lines[1].kind"},
+		{RuleUnknownSpeaker, "emotions[1].character_id"},
+		{RuleDialogueTooLong, "lines[1].text"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("findings = %+v, want %d findings", got, len(want))
+	}
+	for i, w := range want {
+		if got[i].PanelIndex != 1 || got[i].RuleID != w.rule || got[i].Field != w.field {
+			t.Errorf("finding %d = %+v, want panel 1 %s %s", i, got[i], w.rule, w.field)
+		}
+	}
+}
+
+func TestGate(t *testing.T) {
+	ok := &ports.MangaResponse{Panels: []ports.Panel{
+		{Page: 1, SpeakerID: "zundamon", VisualAnchor: "教室", Dialogue: strings.Repeat("あ", 200)},
+	}}
+	if err := Gate(context.Background(), ok, nil, DefaultRules()); err != nil {
+		t.Errorf("warnings only should pass the gate: %v", err)
+	}
+
+	bad := &ports.MangaResponse{Panels: []ports.Panel{{Page: 1, SpeakerID: "zundamon"}}}
+	err := Gate(context.Background(), bad, nil, DefaultRules())
+	var verr *ports.ScriptValidationError
+	if !errors.As(err, &verr) || !errors.Is(err, ports.ErrInvalidScript) {
+		t.Fatalf("err = %v, want *ScriptValidationError", err)
+	}
+	if verr.Attempts != 0 || len(verr.Issues) != 1 || verr.Issues[0].RuleID != RuleBlankVisualAnchor {
+		t.Errorf("err = %+v", verr)
+	}
+	if !strings.HasPrefix(err.Error(), "invalid script: panel 1 visual_anchor") {
+		t.Errorf("Error() = %q", err.Error())
+	}
+}
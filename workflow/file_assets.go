@@ -0,0 +1,46 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	imagePorts "github.com/shouni/gemini-image-kit/ports"
+	"github.com/shouni/go-gemini-client/gemini"
+)
+
+// fileAPIAssets は、アップロードを GeminiImageCore に委譲しつつ、削除を File API へ直接行う
+// AssetManager です。GeminiImageCore.DeleteFile はキャッシュに残っているファイル名でしか
+// 削除できないため、File API URI からファイル名を導出して削除し、削除したファイルの URI を
+// 画像キャッシュからも取り除きます。
+type fileAPIAssets struct {
+	uploader imagePorts.AssetManager
+	client   gemini.GenerativeModel
+	cache    *imageCache
+}
+
+// UploadFile は imagePorts.AssetManager の実装です。
+func (a *fileAPIAssets) UploadFile(ctx context.Context, fileURI string) (string, error) {
+	return a.uploader.UploadFile(ctx, fileURI)
+}
+
+// DeleteFile は imagePorts.AssetManager の実装です。fileURI には File API URI を渡します。
+func (a *fileAPIAssets) DeleteFile(ctx context.Context, fileURI string) error {
+	name, err := fileNameFromURI(fileURI)
+	if err != nil {
+		return err
+	}
+	// 削除済みの URI がキャッシュから再利用されないよう、先にキャッシュから取り除きます。
+	a.cache.DeleteValues(fileURI, name)
+	return a.client.DeleteFile(ctx, name)
+}
+
+// fileNameFromURI は File API URI（例: https://generativelanguage.googleapis.com/v1beta/files/abc）
+// からファイル名（例: files/abc）を導出します。
+func fileNameFromURI(fileURI string) (string, error) {
+	idx := strings.LastIndex(fileURI, "/files/")
+	if idx < 0 || idx+len("/files/") == len(fileURI) {
+		return "", fmt.Errorf("File API URI からファイル名を特定できません: %s", fileURI)
+	}
+	return fileURI[idx+1:], nil
+}
@@ -6,15 +6,17 @@ import (
 	"fmt"
 
 	"github.com/shouni/gemini-image-kit/generator"
+	imagePorts "github.com/shouni/gemini-image-kit/ports"
 	"github.com/shouni/go-gemini-client/gemini"
 
+	"github.com/shouni/go-manga-kit/imaging"
 	"github.com/shouni/go-manga-kit/layout"
 	"github.com/shouni/go-manga-kit/ports"
 )
 
 // buildGenerationUnit は、特定の AI クライアントとモデル設定に基づき、 core, composer, generator をひとまとめにした LLM 構造体を構築します。
-func (m *manager) buildGenerationUnit(client gemini.GenerativeModel, modelName string) (*generationUnit, error) {
-	cache := newImageCache(defaultCacheExpiration)
+func (m *manager) buildGenerationUnit(client gemini.GenerativeModel, modelName string, registry ports.AssetRegistry) (*generationUnit, error) {
+	cache := newImageCache(defaultCacheExpiration, m.metrics)
 
 	core, err := m.buildCore(client, cache)
 	if err != nil {
@@ -22,7 +24,8 @@ func (m *manager) buildGenerationUnit(client gemini.GenerativeModel, modelName s
 		return nil, err
 	}
 
-	composer, err := m.buildComposer(core, m.promptDeps.Characters)
+	assets := &fileAPIAssets{uploader: core, client: client, cache: cache}
+	composer, err := m.buildComposer(assets, core, m.promptDeps.Characters, registry)
 	if err != nil {
 		cache.Stop()
 		return nil, err
@@ -40,6 +43,7 @@ func (m *manager) buildGenerationUnit(client gemini.GenerativeModel, modelName s
 		mangaComposer:  composer,
 		model:          modelName,
 		cache:          cache,
+		limiter:        layout.NewRateLimiter(m.cfg.RateInterval),
 	}, nil
 }
 
@@ -62,21 +66,51 @@ func (m *manager) buildCore(aiClient gemini.GenerativeModel, cache *imageCache)
 
 // buildComposer は提供された構成と依存関係を使用して MangaComposerインスタンスを初期化し、返します。
 func (m *manager) buildComposer(
+	assets imagePorts.AssetManager,
 	core *generator.GeminiImageCore,
 	chars *ports.Characters,
+	registry ports.AssetRegistry,
 ) (*layout.MangaComposer, error) {
-	composer, err := layout.NewMangaComposer(
-		core,
-		core,
-		chars,
-	)
+	opts := []layout.ComposerOption{
+		layout.WithComposerMetrics(m.metrics),
+		layout.WithUploadTimeout(m.cfg.TimeoutFor(ports.StageUpload)),
+		layout.WithAssetSource(m.reader),
+		layout.WithAssetPreflight(m.cfg.PreflightAssets),
+		layout.WithReferencePolicy(*m.cfg.ReferencePolicy),
+	}
+	if registry != nil {
+		opts = append(opts, layout.WithAssetRegistry(registry))
+	}
+	if m.cfg.ReferenceStagingDir != "" {
+		opts = append(opts, layout.WithReferencePreprocessing(m.referencePreprocessing()))
+	}
+	composer, err := layout.NewMangaComposer(assets, core, chars, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("MangaComposerの初期化に失敗しました: %w", err)
 	}
+	composer.StartSweeper(m.cfg.AssetSweepInterval, m.cfg.AssetMaxAge)
 
 	return composer, nil
 }
 
+// referencePreprocessing は Config から参照画像の前処理設定を組み立てます。
+func (m *manager) referencePreprocessing() layout.ReferencePreprocessing {
+	base := imaging.Options{
+		MaxEdge: m.cfg.ReferenceMaxEdge,
+		Format:  imaging.Format(m.cfg.ReferenceFormat),
+	}
+	character, panel := base, base
+	character.AspectRatio = m.cfg.CharacterReferenceAspectRatio
+	panel.AspectRatio = m.cfg.PanelReferenceAspectRatio
+
+	return layout.ReferencePreprocessing{
+		Character:  character,
+		Panel:      panel,
+		Writer:     m.writer,
+		StagingDir: m.cfg.ReferenceStagingDir,
+	}
+}
+
 // buildGenerator は提供された構成と依存関係を使用して ImageGenerator インスタンスを初期化し、返します。
 func (m *manager) buildGenerator(core *generator.GeminiImageCore) (*generator.GeminiGenerator, error) {
 	gen, err := generator.NewGeminiGenerator(core)
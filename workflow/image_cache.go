@@ -5,21 +5,24 @@ import (
 	"time"
 
 	"github.com/jellydator/ttlcache/v3"
+
+	"github.com/shouni/go-manga-kit/ports"
 )
 
 type imageCache struct {
 	cache   *ttlcache.Cache[string, any]
+	metrics ports.MetricsRecorder
 	started bool
 	mu      sync.Mutex
 }
 
-func newImageCache(defaultExpiration time.Duration) *imageCache {
+func newImageCache(defaultExpiration time.Duration, metrics ports.MetricsRecorder) *imageCache {
 	c := ttlcache.New[string, any](
 		ttlcache.WithTTL[string, any](defaultExpiration),
 		ttlcache.WithDisableTouchOnHit[string, any](),
 	)
 
-	return &imageCache{cache: c}
+	return &imageCache{cache: c, metrics: ports.MetricsOrNop(metrics)}
 }
 
 func (c *imageCache) Start() {
@@ -38,6 +41,7 @@ func (c *imageCache) Start() {
 
 func (c *imageCache) Get(key string) (any, bool) {
 	item := c.cache.Get(key)
+	c.metrics.IncCacheLookup(ports.CacheImage, item != nil)
 	if item == nil {
 		return nil, false
 	}
@@ -49,6 +53,21 @@ func (c *imageCache) Set(key string, value any, ttl time.Duration) {
 	c.cache.Set(key, value, ttl)
 }
 
+// DeleteValues は、値が values のいずれかに一致するエントリを削除します。
+func (c *imageCache) DeleteValues(values ...string) {
+	targets := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		targets[v] = struct{}{}
+	}
+	for key, item := range c.cache.Items() {
+		if s, ok := item.Value().(string); ok {
+			if _, hit := targets[s]; hit {
+				c.cache.Delete(key)
+			}
+		}
+	}
+}
+
 func (c *imageCache) Stop() {
 	if c == nil || c.cache == nil {
 		return
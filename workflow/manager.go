@@ -1,21 +1,32 @@
 package workflow
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	imagePorts "github.com/shouni/gemini-image-kit/ports"
 	"github.com/shouni/go-gemini-client/gemini"
 	"github.com/shouni/go-http-kit/httpkit"
+	"github.com/shouni/go-manga-kit/asset"
 	"github.com/shouni/go-manga-kit/layout"
+	"github.com/shouni/go-manga-kit/metrics"
 	"github.com/shouni/go-manga-kit/ports"
 	"github.com/shouni/go-remote-io/remoteio"
+	"golang.org/x/time/rate"
 )
 
 // PromptDeps はプロンプト関連の依存関係をまとめた構造体です。
 type PromptDeps struct {
-	Characters   *ports.Characters
-	ScriptPrompt ports.ScriptPrompt
-	ImagePrompt  ports.ImagePrompt
+	Characters *ports.Characters
+	// CharacterList は Characters の元になったキャラクター定義の一覧です（任意）。
+	// 設定すると、生成した台本の話者を表示名や表記ゆれからキャラクターIDに解決し、
+	// 解決できない話者を既定のキャラクターで補わずにエラーとして報告します。
+	CharacterList []ports.Character
+	ScriptPrompt  ports.ScriptPrompt
+	ImagePrompt   ports.ImagePrompt
 }
 
 // ManagerArgs は、ワークフローの初期化と管理に必要な引数の集合を表します。
@@ -27,6 +38,13 @@ type ManagerArgs struct {
 	AIClient        gemini.GenerativeModel
 	AIClientQuality gemini.GenerativeModel
 	PromptDeps      *PromptDeps
+	// MetricsRegisterer を設定すると、生成レイテンシ・失敗数・レート制限の待機時間・
+	// アセットのアップロード数などの Prometheus メトリクスを登録します（任意）。
+	// 同じ Registerer を複数のマネージャーに渡した場合、メトリクスは共有されます。
+	MetricsRegisterer prometheus.Registerer
+	// AssetRegistry を設定すると、File API にアップロードした参照画像の URI と有効期限を記録し、
+	// プロセスの再起動後も期限内であれば再利用します（任意）。asset.NewFileRegistry などを使います。
+	AssetRegistry ports.AssetRegistry
 }
 
 // generationUnit は、画像生成と構成を処理するユニットを表します
@@ -35,6 +53,8 @@ type generationUnit struct {
 	mangaComposer  *layout.MangaComposer
 	model          string
 	cache          *imageCache
+	// limiter は、このユニットのモデルを呼び出すすべての生成処理で共有するレートリミッターです。
+	limiter *rate.Limiter
 }
 
 // layoutManager は、レイアウトの生成単位を管理します
@@ -53,22 +73,33 @@ type manager struct {
 	aiClientQuality gemini.GenerativeModel
 	layoutManager   layoutManager
 	promptDeps      *PromptDeps
+	metrics         ports.MetricsRecorder
+	assetRegistry   ports.AssetRegistry
 }
 
-func (u *generationUnit) stop() {
-	if u != nil {
-		u.cache.Stop()
+// stop は、アップロード済みの File API アセットを削除したうえでキャッシュを停止します。
+func (u *generationUnit) stop(cleanupTimeout time.Duration) {
+	if u == nil {
+		return
 	}
+	if u.mangaComposer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+		if err := u.mangaComposer.Close(ctx); err != nil {
+			slog.Warn("File API アセットの削除に失敗しました", "model", u.model, "error", err)
+		}
+		cancel()
+	}
+	u.cache.Stop()
 }
 
-func (lm *layoutManager) stop() {
-	lm.Standard.stop()
-	lm.Quality.stop()
+func (lm *layoutManager) stop(cleanupTimeout time.Duration) {
+	lm.Standard.stop(cleanupTimeout)
+	lm.Quality.stop(cleanupTimeout)
 }
 
 func (m *manager) stop() {
 	if m != nil {
-		m.layoutManager.stop()
+		m.layoutManager.stop(m.cfg.AssetCleanupTimeout)
 	}
 }
 
@@ -94,17 +125,33 @@ func New(args ManagerArgs) (*ports.Workflows, error) {
 		aiClient:        args.AIClient,
 		aiClientQuality: aiClientQuality,
 		promptDeps:      args.PromptDeps,
+		metrics:         ports.NopMetrics{},
+		assetRegistry:   args.AssetRegistry,
+	}
+
+	if args.MetricsRegisterer != nil {
+		recorder, err := metrics.NewPrometheus(args.MetricsRegisterer)
+		if err != nil {
+			return nil, err
+		}
+		m.metrics = recorder
 	}
 
 	var err error
 
-	m.layoutManager.Standard, err = m.buildGenerationUnit(m.aiClient, cfg.ImageStandardModel)
+	m.layoutManager.Standard, err = m.buildGenerationUnit(m.aiClient, cfg.ImageStandardModel, m.assetRegistry)
 	if err != nil {
 		m.stop()
 		return nil, fmt.Errorf("standard GenerationUnit の構築に失敗: %w", err)
 	}
 
-	m.layoutManager.Quality, err = m.buildGenerationUnit(m.aiClientQuality, cfg.ImageQualityModel)
+	// File API のファイルはクライアント（API キー）ごとに独立しているため、
+	// 高品質用に別のクライアントを使う場合はレジストリの記録を分けます。
+	qualityRegistry := m.assetRegistry
+	if qualityRegistry != nil && args.AIClientQuality != nil {
+		qualityRegistry = asset.NewScopedRegistry(qualityRegistry, "quality")
+	}
+	m.layoutManager.Quality, err = m.buildGenerationUnit(m.aiClientQuality, cfg.ImageQualityModel, qualityRegistry)
 	if err != nil {
 		m.stop()
 		return nil, fmt.Errorf("quality GenerationUnit の構築に失敗: %w", err)
@@ -7,6 +7,8 @@ import (
 	"github.com/shouni/go-manga-kit/ports"
 	"github.com/shouni/go-manga-kit/publisher"
 	"github.com/shouni/go-manga-kit/runner"
+	"github.com/shouni/go-manga-kit/speaker"
+	"github.com/shouni/go-manga-kit/validate"
 	"github.com/shouni/go-prompt-kit/md/builder"
 )
 
@@ -44,7 +46,39 @@ func (m *manager) buildAllRunners() (*ports.Workflows, error) {
 
 // buildScriptRunner は、台本生成を担当する Runner を作成します。
 func (m *manager) buildScriptRunner() (*runner.MangaScriptRunner, error) {
-	return runner.NewMangaScriptRunner(m.promptDeps.ScriptPrompt, m.aiClient, m.reader, m.cfg.GeminiModel), nil
+	opts := []runner.ScriptOption{
+		runner.WithScriptMetrics(m.metrics),
+		runner.WithScriptRequestTimeout(m.cfg.TimeoutFor(ports.StageScript)),
+		runner.WithScriptReferencePolicy(*m.cfg.ReferencePolicy),
+		runner.WithScriptCharacters(m.promptDeps.Characters),
+		runner.WithScriptRepairAttempts(m.cfg.ScriptRepairAttempts),
+	}
+	if m.cfg.ValidateScripts {
+		opts = append(opts, runner.WithScriptValidation(m.validationRules()))
+	}
+	if m.cfg.LongDocumentChunkTokens > 0 {
+		opts = append(opts, runner.WithScriptLongDocument(m.cfg.LongDocumentChunkTokens))
+	}
+	if len(m.promptDeps.CharacterList) > 0 {
+		resolver, err := speaker.NewResolver(m.promptDeps.CharacterList, speaker.WithAliases(m.cfg.SpeakerAliases))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, runner.WithScriptSpeakerResolver(resolver))
+	}
+	return runner.NewMangaScriptRunner(m.promptDeps.ScriptPrompt, m.aiClient, m.reader, m.cfg.GeminiModel, opts...), nil
+}
+
+// validationRules は Config から台本の検証規則を作成します。
+func (m *manager) validationRules() validate.Rules {
+	rules := validate.DefaultRules()
+	if m.cfg.MaxDialogueLength > 0 {
+		rules.MaxDialogueLength = m.cfg.MaxDialogueLength
+	}
+	if m.cfg.MaxPanelsPerPage > 0 {
+		rules.MaxPanelsPerPage = m.cfg.MaxPanelsPerPage
+	}
+	return rules
 }
 
 // buildDesignRunner は、キャラクターデザインを担当する Runner を作成します。
@@ -56,6 +90,10 @@ func (m *manager) buildDesignRunner() (*runner.MangaDesignRunner, error) {
 		m.writer,
 		quality.model,
 		m.cfg.StyleSuffix,
+		runner.WithDesignMetrics(m.metrics),
+		runner.WithDesignRequestTimeout(m.cfg.TimeoutFor(ports.StageDesign)),
+		runner.WithDesignRateLimiter(quality.limiter),
+		runner.WithDesignMaxConcurrency(m.cfg.MaxConcurrency),
 	), nil
 }
 
@@ -68,25 +106,47 @@ func (m *manager) buildPanelImageRunner() (*runner.MangaPanelRunner, error) {
 		m.promptDeps.ImagePrompt,
 		standard.model,
 		layout.WithPanelMaxConcurrency(m.cfg.MaxConcurrency),
-		layout.WithPanelRateInterval(m.cfg.RateInterval),
+		layout.WithPanelRateLimiter(standard.limiter),
+		layout.WithPanelMetrics(m.metrics),
+		layout.WithPanelRequestTimeout(m.cfg.TimeoutFor(ports.StagePanel)),
 	)
 
-	return runner.NewMangaPanelRunner(panelsGen, m.writer), nil
+	var opts []runner.PanelOption
+	if m.cfg.ValidateScripts {
+		opts = append(opts, runner.WithPanelValidation(m.promptDeps.Characters, m.validationRules()))
+	}
+	return runner.NewMangaPanelRunner(panelsGen, m.writer, opts...), nil
 }
 
 // buildPageImageRunner は、Markdown からのページ画像一括生成を担当する Runner を作成します。
 func (m *manager) buildPageImageRunner() (*runner.MangaPageRunner, error) {
 	quality := m.layoutManager.Quality
+	opts := []layout.PageOption{
+		layout.WithPageRateLimiter(quality.limiter),
+		layout.WithMaxPanelsPerPage(m.cfg.MaxPanelsPerPage),
+		layout.WithPageMetrics(m.metrics),
+		layout.WithPageRequestTimeout(m.cfg.TimeoutFor(ports.StagePage)),
+		layout.WithPageAssetBudget(m.cfg.MaxReferenceImages),
+	}
+	if m.cfg.ReferenceCollage {
+		opts = append(opts, layout.WithReferenceCollage(layout.ReferenceCollage{
+			Writer:     m.writer,
+			StagingDir: m.cfg.ReferenceStagingDir,
+		}))
+	}
 	pagesGen := layout.NewPageGenerator(
 		quality.mangaComposer,
 		quality.imageGenerator,
 		m.promptDeps.ImagePrompt,
 		quality.model,
-		layout.WithPageRateInterval(m.cfg.RateInterval),
-		layout.WithMaxPanelsPerPage(m.cfg.MaxPanelsPerPage),
+		opts...,
 	)
 
-	return runner.NewMangaPageRunner(pagesGen, m.writer), nil
+	var runnerOpts []runner.PageOption
+	if m.cfg.ValidateScripts {
+		runnerOpts = append(runnerOpts, runner.WithPageValidation(m.promptDeps.Characters, m.validationRules()))
+	}
+	return runner.NewMangaPageRunner(pagesGen, m.writer, runnerOpts...), nil
 }
 
 // buildPublishRunner は、成果物のパブリッシュを担当する Runner を作成します。